@@ -0,0 +1,256 @@
+package logwell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Metadata keys of access logs built by AccessLogEntry, so that dashboards
+// see the same fields whichever HTTP framework served the request.
+const (
+	AccessLogMethod    = "method"
+	AccessLogRoute     = "route"
+	AccessLogStatus    = "status"
+	AccessLogDuration  = "duration_ms"
+	AccessLogBytesOut  = "bytes_out"
+	AccessLogRemoteIP  = "remote_ip"
+	AccessLogUserAgent = "user_agent"
+	AccessLogRequestID = "request_id"
+)
+
+// AccessLogInfo describes one served HTTP request for AccessLogEntry.
+type AccessLogInfo struct {
+	Method string
+
+	// Route is the matched route pattern, such as "/users/:id", or the
+	// path when the framework has none.
+	Route string
+
+	Status    int
+	Duration  time.Duration
+	BytesOut  int64
+	RemoteIP  string
+	UserAgent string
+	RequestID string
+}
+
+// AccessLogOption configures the access logs built by AccessLogEntry.
+type AccessLogOption func(*accessLogConfig)
+
+type accessLogConfig struct {
+	names   map[string]string
+	dropped map[string]bool
+	level   func(status int) LogLevel
+}
+
+// AccessLogWithField sends field, one of the AccessLog* keys, as name.
+func AccessLogWithField(field, name string) AccessLogOption {
+	return func(c *accessLogConfig) {
+		if c.names == nil {
+			c.names = make(map[string]string)
+		}
+		c.names[field] = name
+	}
+}
+
+// AccessLogWithoutFields leaves fields, AccessLog* keys, out of the
+// metadata.
+func AccessLogWithoutFields(fields ...string) AccessLogOption {
+	return func(c *accessLogConfig) {
+		if c.dropped == nil {
+			c.dropped = make(map[string]bool)
+		}
+		for _, field := range fields {
+			c.dropped[field] = true
+		}
+	}
+}
+
+// AccessLogWithLevels sets how the response status maps to the log level,
+// replacing AccessLogLevel.
+func AccessLogWithLevels(fn func(status int) LogLevel) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.level = fn
+	}
+}
+
+// AccessLogLevel is the default mapping of response status to level:
+// ERROR for 5xx, WARN for 4xx and INFO otherwise.
+func AccessLogLevel(status int) LogLevel {
+	switch {
+	case status >= 500:
+		return LevelError
+	case status >= 400:
+		return LevelWarn
+	default:
+		return LevelInfo
+	}
+}
+
+// AccessLogEntry builds the level, message and metadata of an access log
+// for info. HTTP middlewares use it so their logs share one schema; the
+// metadata holds every AccessLog* key, renamed or dropped by opts.
+//
+// Example:
+//
+//	level, msg, metadata := logwell.AccessLogEntry(info)
+//	client.Log(logwell.LogEntry{Level: level, Message: msg, Metadata: metadata})
+func AccessLogEntry(info AccessLogInfo, opts ...AccessLogOption) (LogLevel, string, M) {
+	cfg := accessLogConfig{level: AccessLogLevel}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg.entry(info)
+}
+
+// entry builds the access log for info.
+func (c *accessLogConfig) entry(info AccessLogInfo) (LogLevel, string, M) {
+	fields := [...]struct {
+		key   string
+		value any
+	}{
+		{AccessLogMethod, info.Method},
+		{AccessLogRoute, info.Route},
+		{AccessLogStatus, info.Status},
+		{AccessLogDuration, float64(info.Duration) / float64(time.Millisecond)},
+		{AccessLogBytesOut, info.BytesOut},
+		{AccessLogRemoteIP, info.RemoteIP},
+		{AccessLogUserAgent, info.UserAgent},
+		{AccessLogRequestID, info.RequestID},
+	}
+	metadata := make(M, len(fields))
+	for _, f := range fields {
+		if c.dropped[f.key] {
+			continue
+		}
+		key := f.key
+		if name, ok := c.names[key]; ok {
+			key = name
+		}
+		metadata[key] = f.value
+	}
+	message := fmt.Sprintf("%s %s %d", info.Method, info.Route, info.Status)
+	return c.level(info.Status), message, metadata
+}
+
+// AccessLogMiddleware returns net/http middleware that logs every request
+// through client with AccessLogEntry once the handler returns. The route
+// is the request path and the request ID is read from the X-Request-ID
+// header. The request's context is passed on as with the *Context methods.
+// Access logs have no source location, even with CaptureSourceLocation.
+// Requests whose panic was logged by RecoveryMiddleware are not logged
+// again.
+//
+// Example:
+//
+//	http.ListenAndServe(":8080", logwell.AccessLogMiddleware(client)(mux))
+func AccessLogMiddleware(client *Client, opts ...AccessLogOption) func(http.Handler) http.Handler {
+	cfg := accessLogConfig{level: AccessLogLevel}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			if rw.recovered {
+				return
+			}
+
+			remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				remoteIP = r.RemoteAddr
+			}
+			level, message, metadata := cfg.entry(AccessLogInfo{
+				Method:    r.Method,
+				Route:     r.URL.Path,
+				Status:    rw.status,
+				Duration:  time.Since(start),
+				BytesOut:  rw.written,
+				RemoteIP:  remoteIP,
+				UserAgent: r.UserAgent(),
+				RequestID: r.Header.Get("X-Request-ID"),
+			})
+			// Sent without a source location, which would be net/http
+			if entry, ok := client.newEntry(r.Context(), level, message, metadata); ok {
+				client.enqueue(r.Context(), entry)
+			}
+		})
+	}
+}
+
+// statusWriter records the status and body size of a response, and whether
+// it has started. recovered is set by RecoveryMiddleware once it has logged
+// a panic of the handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	written     int64
+	wroteHeader bool
+	recovered   bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Flush sends buffered data to the client when the wrapped ResponseWriter
+// supports it, so streaming handlers work behind the middleware.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection when the wrapped
+// ResponseWriter supports it, as for WebSocket upgrades.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("logwell: %T does not support hijacking", w.ResponseWriter)
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// ReadFrom copies r to the response with the wrapped ResponseWriter's
+// ReadFrom when it has one, such as the sendfile path of net/http.
+func (w *statusWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.wroteHeader = true
+	var (
+		n   int64
+		err error
+	)
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, r)
+	}
+	w.written += n
+	return n, err
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
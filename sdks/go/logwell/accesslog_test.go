@@ -0,0 +1,192 @@
+package logwell
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+)
+
+// accessLogKeys are the metadata keys every access log must carry,
+// whichever middleware built it.
+var accessLogKeys = []string{
+	"bytes_out", "duration_ms", "method", "remote_ip",
+	"request_id", "route", "status", "user_agent",
+}
+
+// assertAccessLogKeys checks that metadata has exactly accessLogKeys.
+func assertAccessLogKeys(t *testing.T, metadata map[string]any) {
+	t.Helper()
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) != len(accessLogKeys) {
+		t.Fatalf("metadata keys = %v, want %v", keys, accessLogKeys)
+	}
+	for i := range keys {
+		if keys[i] != accessLogKeys[i] {
+			t.Fatalf("metadata keys = %v, want %v", keys, accessLogKeys)
+		}
+	}
+}
+
+// TestAccessLogEntry tests the access log schema and its options.
+func TestAccessLogEntry(t *testing.T) {
+	info := AccessLogInfo{
+		Method:    "GET",
+		Route:     "/users/:id",
+		Status:    404,
+		Duration:  1500 * time.Microsecond,
+		BytesOut:  12,
+		RemoteIP:  "10.0.0.1",
+		UserAgent: "curl/8.0",
+		RequestID: "req-1",
+	}
+
+	level, message, metadata := AccessLogEntry(info)
+	assertAccessLogKeys(t, metadata)
+	if level != LevelWarn || message != "GET /users/:id 404" {
+		t.Errorf("AccessLogEntry() = %s %q, want WARN %q", level, message, "GET /users/:id 404")
+	}
+	if metadata["duration_ms"] != 1.5 || metadata["status"] != 404 || metadata["bytes_out"] != int64(12) {
+		t.Errorf("metadata = %v, want duration_ms 1.5, status 404 and bytes_out 12", metadata)
+	}
+
+	level, _, metadata = AccessLogEntry(info,
+		AccessLogWithField(AccessLogStatus, "http.status"),
+		AccessLogWithoutFields(AccessLogRemoteIP, AccessLogUserAgent),
+		AccessLogWithLevels(func(int) LogLevel { return LevelDebug }),
+	)
+	if level != LevelDebug {
+		t.Errorf("level with override = %s, want DEBUG", level)
+	}
+	if metadata["http.status"] != 404 || len(metadata) != len(accessLogKeys)-2 {
+		t.Errorf("metadata with options = %v, want status renamed and two fields dropped", metadata)
+	}
+	for _, key := range []string{"status", "remote_ip", "user_agent"} {
+		if _, ok := metadata[key]; ok {
+			t.Errorf("metadata with options has %q", key)
+		}
+	}
+
+	for status, want := range map[int]LogLevel{200: LevelInfo, 302: LevelInfo, 429: LevelWarn, 503: LevelError} {
+		if got := AccessLogLevel(status); got != want {
+			t.Errorf("AccessLogLevel(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
+
+// TestAccessLogMiddleware tests that the net/http middleware logs each
+// request with the shared schema and no source location.
+func TestAccessLogMiddleware(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts, WithBatchSize(100), WithCaptureSourceLocation(true))
+	defer client.Shutdown(context.Background())
+
+	handler := AccessLogMiddleware(client)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.RemoteAddr = "192.0.2.7:5123"
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Request-ID", "req-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 1)
+
+	log := logs[0]
+	assertAccessLogKeys(t, log.Metadata)
+	if log.Level != LevelInfo || log.Message != "POST /orders 201" {
+		t.Errorf("log = %s %q, want INFO %q", log.Level, log.Message, "POST /orders 201")
+	}
+	want := map[string]any{
+		"method": "POST", "route": "/orders", "status": float64(201), "bytes_out": float64(7),
+		"remote_ip": "192.0.2.7", "user_agent": "test-agent", "request_id": "req-42",
+	}
+	for k, v := range want {
+		if log.Metadata[k] != v {
+			t.Errorf("Metadata[%q] = %v, want %v", k, log.Metadata[k], v)
+		}
+	}
+	if log.SourceFile != "" || log.LineNumber != 0 || log.FunctionName != "" {
+		t.Errorf("source = %s:%d %s, want none", log.SourceFile, log.LineNumber, log.FunctionName)
+	}
+}
+
+// TestAccessLogMiddlewareStreaming tests that a handler behind the
+// middleware can flush a streamed response and hijack its connection.
+func TestAccessLogMiddlewareStreaming(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts, WithBatchSize(100), WithService("orders"))
+	defer client.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	handler := AccessLogMiddleware(client)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hijack" {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("Hijack() error = %v", err)
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+			buf.Flush()
+			return
+		}
+		w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("second\n"))
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatalf("GET /stream error = %v", err)
+	}
+	// The first line arrives while the handler still blocks, so it was flushed
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	close(release)
+	resp.Body.Close()
+	if err != nil || line != "first\n" {
+		t.Fatalf("first streamed line = %q, %v; want %q", line, err, "first\n")
+	}
+
+	resp, err = http.Get(server.URL + "/hijack")
+	if err != nil {
+		t.Fatalf("GET /hijack error = %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hijacked" {
+		t.Fatalf("hijacked body = %q, %v; want %q", body, err, "hijacked")
+	}
+
+	server.Close()
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 2)
+	for _, log := range logs {
+		if log.Metadata["route"] == "/stream" && log.Metadata["bytes_out"] != float64(len("first\nsecond\n")) {
+			t.Errorf("streamed bytes_out = %v, want %d", log.Metadata["bytes_out"], len("first\nsecond\n"))
+		}
+	}
+}
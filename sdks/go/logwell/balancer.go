@@ -0,0 +1,149 @@
+package logwell
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// balancerEjectAfter is how many retryable failures in a row take an
+// endpoint out of rotation.
+const balancerEjectAfter = 3
+
+// balancer spreads batches over several equivalent endpoints for
+// WithEndpoints. An endpoint that fails balancerEjectAfter times in a row
+// with a retryable error leaves the rotation and is probed every cooldown
+// until it answers, then rejoins it. When every endpoint is out, batches
+// go to all of them in turn rather than nowhere.
+type balancer struct {
+	endpoints  []*ingestEndpoint
+	strategy   BalanceStrategy
+	cooldown   time.Duration
+	httpClient *http.Client
+
+	counter atomic.Uint64 // round-robin position
+
+	mu       sync.Mutex
+	failures []int  // retryable failures in a row, per endpoint
+	ejected  []bool // out of rotation, per endpoint
+	timers   []*time.Timer
+	stopped  bool
+}
+
+// newBalancer creates a balancer over urls that picks endpoints with
+// strategy and probes ejected endpoints every cooldown.
+func newBalancer(urls []string, apiKeys map[string]string, strategy BalanceStrategy, cooldown time.Duration, httpClient *http.Client) *balancer {
+	return &balancer{
+		endpoints:  newIngestEndpoints(urls, apiKeys),
+		strategy:   strategy,
+		cooldown:   cooldown,
+		httpClient: httpClient,
+		failures:   make([]int, len(urls)),
+		ejected:    make([]bool, len(urls)),
+		timers:     make([]*time.Timer, len(urls)),
+	}
+}
+
+// first picks the endpoint a batch starts on.
+func (b *balancer) first() int {
+	return b.pick(-1)
+}
+
+// next picks the endpoint to retry on after ep, another one if possible.
+func (b *balancer) next(ep int) int {
+	return b.pick(ep)
+}
+
+// pick returns an endpoint in rotation other than skip, by the balancer's
+// strategy. It falls back to skip if it is the only one in rotation, and
+// to every endpoint if none is.
+func (b *balancer) pick(skip int) int {
+	b.mu.Lock()
+	candidates := make([]int, 0, len(b.endpoints))
+	for i := range b.endpoints {
+		if !b.ejected[i] && i != skip {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 && skip >= 0 && !b.ejected[skip] {
+		candidates = append(candidates, skip)
+	}
+	b.mu.Unlock()
+	if len(candidates) == 0 {
+		for i := range b.endpoints {
+			candidates = append(candidates, i)
+		}
+	}
+
+	if b.strategy == Random {
+		return candidates[rand.Intn(len(candidates))]
+	}
+	return candidates[(b.counter.Add(1)-1)%uint64(len(candidates))]
+}
+
+// all returns the endpoints in the order given to WithEndpoints.
+func (b *balancer) all() []*ingestEndpoint {
+	return b.endpoints
+}
+
+// active reports whether endpoint ep is in rotation.
+func (b *balancer) active(ep int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.ejected[ep]
+}
+
+// record updates the balancer with the outcome of one send to endpoint ep.
+func (b *balancer) record(ep int, err error, retryable bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil || !retryable {
+		// Any answer from the server shows the endpoint is up
+		b.failures[ep] = 0
+		return
+	}
+	b.failures[ep]++
+	if b.failures[ep] >= balancerEjectAfter && !b.ejected[ep] && !b.stopped {
+		b.ejected[ep] = true
+		b.timers[ep] = time.AfterFunc(b.cooldown, func() { b.probe(ep) })
+	}
+}
+
+// probe checks whether ejected endpoint ep answers, readmitting it if it
+// does and scheduling the next probe if not.
+func (b *balancer) probe(ep int) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.cooldown)
+	defer cancel()
+
+	reachable := probeEndpoint(ctx, b.httpClient, b.endpoints[ep])
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.ejected[ep] || b.stopped {
+		return
+	}
+	if reachable {
+		b.ejected[ep] = false
+		b.failures[ep] = 0
+		b.timers[ep] = nil
+		return
+	}
+	b.timers[ep] = time.AfterFunc(b.cooldown, func() { b.probe(ep) })
+}
+
+// stop ends probing.
+func (b *balancer) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopped = true
+	for i, timer := range b.timers {
+		if timer != nil {
+			timer.Stop()
+			b.timers[i] = nil
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package logwell
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newBalancedClient creates a client balancing over three switchable
+// servers with strategy.
+func newBalancedClient(t *testing.T, strategy BalanceStrategy) (*Client, []*switchableServer) {
+	t.Helper()
+	servers := []*switchableServer{newSwitchableServer(), newSwitchableServer(), newSwitchableServer()}
+	t.Cleanup(func() {
+		for _, s := range servers {
+			s.Close()
+		}
+	})
+
+	client, err := New(servers[0].URL, validAPIKey(),
+		WithBatchSize(100),
+		WithEndpoints(strategy, servers[1].URL, servers[2].URL),
+		WithEndpointCooldown(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { client.Shutdown(context.Background()) })
+	return client, servers
+}
+
+// sendBatches sends n batches of one entry each.
+func sendBatches(t *testing.T, client *Client, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		client.Info(fmt.Sprintf("batch %d", i))
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+}
+
+// TestClientEndpointsDistribution tests that batches are spread evenly over
+// the endpoints and counted per endpoint in Stats.
+func TestClientEndpointsDistribution(t *testing.T) {
+	t.Run("round robin", func(t *testing.T) {
+		client, servers := newBalancedClient(t, RoundRobin)
+		sendBatches(t, client, 30)
+
+		for i, s := range servers {
+			if got := len(s.getLogs()); got != 10 {
+				t.Errorf("server %d received %d batches, want 10", i, got)
+			}
+		}
+		stats := client.Stats().Endpoints
+		if len(stats) != 3 {
+			t.Fatalf("len(Stats().Endpoints) = %d, want 3", len(stats))
+		}
+		for i, s := range stats {
+			if s.URL != servers[i].URL || s.SentBatches != 10 || s.FailedAttempts != 0 || !s.Active {
+				t.Errorf("Stats().Endpoints[%d] = %+v, want 10 batches sent to %s", i, s, servers[i].URL)
+			}
+		}
+	})
+
+	t.Run("random", func(t *testing.T) {
+		client, servers := newBalancedClient(t, Random)
+		sendBatches(t, client, 300)
+
+		for i, s := range servers {
+			if got := len(s.getLogs()); got < 60 || got > 140 {
+				t.Errorf("server %d received %d of 300 batches, want about 100", i, got)
+			}
+		}
+	})
+}
+
+// TestClientEndpointsEjection tests that a failing endpoint leaves the
+// rotation without losing batches and rejoins once it answers again.
+func TestClientEndpointsEjection(t *testing.T) {
+	client, servers := newBalancedClient(t, RoundRobin)
+	failing := servers[1]
+	failing.down.Store(true)
+
+	sendBatches(t, client, 12)
+	total := 0
+	for _, s := range servers {
+		total += len(s.getLogs())
+	}
+	if total != 12 {
+		t.Errorf("servers received %d batches, want all 12", total)
+	}
+	stats := client.Stats().Endpoints
+	if stats[1].Active || stats[1].FailedAttempts != balancerEjectAfter {
+		t.Fatalf("failing endpoint stats = %+v, want out of rotation after %d failures", stats[1], balancerEjectAfter)
+	}
+
+	// Out of rotation, the endpoint gets no more attempts
+	attempts := failing.attempts.Load()
+	sendBatches(t, client, 6)
+	if got := failing.attempts.Load(); got != attempts {
+		t.Errorf("attempts on ejected endpoint = %d, want %d", got, attempts)
+	}
+
+	failing.down.Store(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for !client.Stats().Endpoints[1].Active {
+		if time.Now().After(deadline) {
+			t.Fatal("endpoint did not rejoin the rotation")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sendBatches(t, client, 3)
+	if got := len(failing.getLogs()); got != 1 {
+		t.Errorf("readmitted endpoint received %d batches, want 1", got)
+	}
+}
+
+// TestConfigValidateEndpoints tests validation of WithEndpoints.
+func TestConfigValidateEndpoints(t *testing.T) {
+	other := "https://az2.example.com"
+
+	_, err := New(validEndpoint(), validAPIKey(), WithEndpoints(RoundRobin, "not a url"))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	_, err = New(validEndpoint(), validAPIKey(), WithEndpoints(RoundRobin, other, other))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	_, err = New(validEndpoint(), validAPIKey(), WithEndpoints("least-loaded", other))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	_, err = New(validEndpoint(), validAPIKey(), WithEndpoints(Random, other), WithEndpointCooldown(0))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	_, err = New(validEndpoint(), validAPIKey(), WithEndpoints(Random, other), WithFallbackEndpoints("https://standby.example.com"))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	client, err := New(validEndpoint(), validAPIKey(), WithEndpoints(Random, other))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client.Shutdown(context.Background())
+}
@@ -2,7 +2,13 @@ package logwell
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ErrClientShutdown is returned when attempting to log after shutdown.
@@ -19,6 +25,46 @@ type Client struct {
 	// Child loggers share the parent's queue and transport.
 	parent *Client
 
+	// ownsQueue is true for children created with ChildWithQueue.
+	// childQueues tracks those children on the root so Shutdown can drain them.
+	ownsQueue   bool
+	childQueues []*Client
+
+	// level is this client's own minimum level (a LogLevel). When unset,
+	// the level is inherited from levelParent, the immediate parent.
+	level       atomic.Value
+	levelParent *Client
+
+	// baseMetadata is the precomputed metadata for logs without per-log
+	// metadata, and detectedMetadata the same with ServiceAutodetectedKey
+	// for those logs whose service is the autodetected one. Both are
+	// shared by those entries and never modified. baseDroppedKeys is the
+	// number of config metadata keys they lack because of the metadata
+	// allowlist.
+	baseMetadata     map[string]any
+	detectedMetadata map[string]any
+	baseDroppedKeys  int
+
+	// allowedKeys is the set of metadata keys that may be sent, or nil
+	// when every key may be.
+	allowedKeys map[string]bool
+
+	// serviceAutodetected reports whether the service name was derived
+	// from the executable, so logs left with it carry
+	// ServiceAutodetectedKey.
+	serviceAutodetected bool
+
+	// diag writes internal events when WithDiagnostics is set; nil otherwise.
+	diag *diagnostics
+
+	// heartbeat logs heartbeats on a root client when WithHeartbeat is
+	// set; nil otherwise.
+	heartbeat *heartbeat
+
+	// named caches loggers created by Named, keyed by dotted name.
+	namedMu sync.Mutex
+	named   map[string]*Client
+
 	mu       sync.Mutex
 	shutdown bool
 }
@@ -27,8 +73,11 @@ type Client struct {
 type ChildOption func(*childConfig)
 
 type childConfig struct {
-	service  string
-	metadata map[string]any
+	service     string
+	environment string
+	metadata    map[string]any
+	level       LogLevel
+	queueOpts   []Option
 }
 
 // ChildWithService sets the service name for the child logger.
@@ -39,11 +88,94 @@ func ChildWithService(service string) ChildOption {
 	}
 }
 
+// ChildWithEnvironment sets the deployment environment for the child
+// logger. If not set, the child inherits the parent's environment.
+func ChildWithEnvironment(environment string) ChildOption {
+	return func(c *childConfig) {
+		c.environment = environment
+	}
+}
+
 // ChildWithMetadata sets metadata for the child logger.
 // This metadata is merged with the parent's metadata (child values override parent).
+// Metadata options are applied in order, so later options see earlier changes.
+// Values are deep-copied, so later changes by the caller do not affect logs.
 func ChildWithMetadata(metadata map[string]any) ChildOption {
 	return func(c *childConfig) {
-		c.metadata = metadata
+		if c.metadata == nil {
+			c.metadata = make(map[string]any, len(metadata))
+		}
+		seen := map[uintptr]bool{reflect.ValueOf(metadata).Pointer(): true}
+		for k, v := range metadata {
+			c.metadata[k] = copyValue(v, seen)
+		}
+	}
+}
+
+// ChildWithTrace tags the child logger with trace context by setting the
+// traceId and spanId metadata keys, for correlating logs with a request.
+// Empty IDs are skipped. It is applied like ChildWithMetadata, so it
+// composes with the other child options in order.
+//
+// Example:
+//
+//	reqLog := client.Child(
+//	    logwell.ChildWithTrace(span.TraceID, span.SpanID),
+//	    logwell.ChildWithService("api"),
+//	)
+func ChildWithTrace(traceID, spanID string) ChildOption {
+	trace := make(map[string]any, 2)
+	if traceID != "" {
+		trace["traceId"] = traceID
+	}
+	if spanID != "" {
+		trace["spanId"] = spanID
+	}
+	return ChildWithMetadata(trace)
+}
+
+// ChildWithoutMetadata removes the given keys from the metadata inherited
+// from the parent (or added by earlier ChildWithMetadata options).
+// The parent's metadata is not affected.
+func ChildWithoutMetadata(keys ...string) ChildOption {
+	return func(c *childConfig) {
+		for _, key := range keys {
+			delete(c.metadata, key)
+		}
+	}
+}
+
+// ChildFreshMetadata discards all metadata inherited from the parent
+// (or added by earlier ChildWithMetadata options), so the child starts
+// from a clean slate. Later ChildWithMetadata options still apply.
+func ChildFreshMetadata() ChildOption {
+	return func(c *childConfig) {
+		c.metadata = nil
+	}
+}
+
+// ChildWithLevel sets an independent minimum level for the child logger.
+// If not set, the child follows its immediate parent's level, including
+// later changes made with SetLevel. An unknown level is an ErrInvalidConfig
+// error for NewChild; Child reports it through OnError and follows the
+// parent's level.
+func ChildWithLevel(level LogLevel) ChildOption {
+	return func(c *childConfig) {
+		c.level = level
+	}
+}
+
+// ChildWithQueue gives the child logger its own queue and flush timer,
+// configured by WithBatchSize, WithFlushInterval, WithMaxQueueSize, and
+// WithMaxConcurrentFlushes.
+// Other options are ignored. NewChild rejects invalid values with
+// ErrInvalidConfig; Child reports them through OnError and falls back to
+// the parent's.
+// The child still shares the parent's transport, and the root client's
+// Shutdown drains the child's pending entries.
+func ChildWithQueue(opts ...Option) ChildOption {
+	return func(c *childConfig) {
+		c.queueOpts = append(c.queueOpts, opts...)
 	}
 }
 
@@ -67,25 +199,171 @@ func New(endpoint, apiKey string, opts ...Option) (*Client, error) {
 		opt(cfg)
 	}
 
+	// Default the service name to the executable's
+	serviceAutodetected := false
+	if cfg.Service == "" && !cfg.DisableServiceAutodetect {
+		if service := detectService(); service != "" {
+			cfg.Service = service
+			serviceAutodetected = true
+		}
+	}
+
+	if cfg.Environment == "" {
+		cfg.Environment = detectEnvironment()
+	}
+
+	// Read the key from the key file, if any, before validating
+	var keyFile *apiKeyFile
+	if cfg.APIKeyFile != "" {
+		var err error
+		keyFile, err = newAPIKeyFile(cfg.APIKeyFile, cfg.APIKeyRefreshInterval, cfg.OnError)
+		if err != nil {
+			return nil, err
+		}
+		cfg.APIKey = keyFile.key
+	}
+
 	// Validate config
 	if err := validateConfig(cfg); err != nil {
 		return nil, err
 	}
 
-	transport := newHTTPTransport(endpoint, apiKey)
+	// Route internal events through the diagnostics writer as well
+	var diag *diagnostics
+	if cfg.Diagnostics != nil {
+		diag = newDiagnostics(cfg.Diagnostics, cfg.DiagnosticsLevel)
+		cfg.OnError = diag.onError(cfg.OnError)
+		cfg.OnRetry = diag.onRetry(cfg.OnRetry)
+		if keyFile != nil {
+			keyFile.onError = cfg.OnError
+		}
+	}
+
+	var spill *spillStore
+	if cfg.OverflowPolicy == SpillToDisk {
+		var err error
+		spill, err = openSpill(cfg.SpillDir, cfg.SpillMaxBytes, diag)
+		if err != nil {
+			return nil, NewErrorWithCause(ErrInvalidConfig, "cannot open spill directory", err)
+		}
+	}
+
+	transport := newConfiguredTransport(endpoint, cfg, keyFile)
+	transport.maxRetries = cfg.MaxRetries
+	transport.entryAgeLimit = cfg.MaxEntryAge * maxEntryAgeGiveUpFactor
+	transport.deliveryMode = cfg.DeliveryMode
+	if cfg.FallbackWriter != nil {
+		transport.fallback = newFallbackWriter(cfg.FallbackWriter, cfg.FallbackAfter, cfg.Service, diag)
+	}
+	if cfg.DryRun {
+		transport.dryRun = newDryRun(cfg.DryRunWriter, diag)
+	}
+	if !isDefaultEncoder(cfg.Encoder) {
+		transport.encode = cfg.Encoder
+	}
+	transport.compression = newCompressionLadder(cfg)
+	transport.compressThreshold = cfg.CompressionThreshold
+	if len(cfg.Compression) > 0 && cfg.CompressionThreshold == 0 {
+		transport.compressThreshold = DefaultCompressionThreshold
+	}
+
+	if cfg.InsecureSkipVerify && cfg.OnError != nil {
+		cfg.OnError(NewError(ErrInvalidConfig, "TLS certificate verification is disabled; do not use in production"))
+	}
 
 	// Create client first so we can pass flush callback to queue
 	c := &Client{
-		config:    cfg,
-		transport: transport,
+		config:      cfg,
+		diag:        diag,
+		transport:   transport,
+		allowedKeys: newAllowedKeys(cfg.MetadataAllowlist),
+
+		serviceAutodetected: serviceAutodetected,
+	}
+	c.baseMetadata, c.baseDroppedKeys = newBaseMetadata(cfg, c.allowedKeys)
+	c.detectedMetadata = c.markServiceAutodetected(c.baseMetadata)
+	c.level.Store(cfg.MinLevel)
+
+	if cfg.DeadLetter != nil {
+		transport.deadLetter = newDeadLetter(cfg.DeadLetter)
+	}
+	if cfg.SnapshotPath != "" {
+		transport.snapshot = newShutdownSnapshot(cfg.SnapshotPath, diag)
+	}
+	if cfg.RetryBudget > 0 {
+		transport.retryBudget = newRetryBudget(cfg.RetryBudget, cfg.RetryBudgetWindow)
+	}
+	switch {
+	case len(cfg.Endpoints) > 0:
+		transport.router = newBalancer(append([]string{endpoint}, cfg.Endpoints...), nil, cfg.BalanceStrategy, cfg.EndpointCooldown, transport.httpClient)
+	case len(cfg.FallbackEndpoints) > 0:
+		transport.router = newFailover(endpoint, cfg.FallbackEndpoints, cfg.FallbackAPIKeys, cfg.FailbackInterval, transport.httpClient)
+	}
+	if cfg.OfflineAfter > 0 {
+		offline := newOfflineDetector(cfg.OfflineAfter, cfg.OfflineProbeInterval, endpoint, transport.httpClient)
+		offline.onOffline = func(err error) {
+			offlineErr := NewErrorWithCause(ErrNetworkError, "endpoint unreachable, pausing flushes until it recovers", err)
+			offlineErr.Kind = errorKind(err)
+			c.reportError(offlineErr)
+		}
+		offline.onOnline = c.resumeFlushing
+		transport.offline = offline
 	}
 
 	// Create queue with timer-based auto-flush and overflow protection
 	c.queue = newBatchQueue(cfg.FlushInterval, c.flush, cfg.MaxQueueSize, cfg.OnError)
+	c.queue.onDrop = cfg.OnDrop
+	c.queue.overflowReportInterval = cfg.OverflowReportInterval
+	c.queue.dropLowest = cfg.OverflowPolicy == DropLowestSeverity
+	c.queue.batchSize.Store(int64(cfg.BatchSize))
+	c.queue.batchBytes = int64(cfg.BatchBytes)
+	c.queue.maxBytes = cfg.MaxInFlightBytes
+	c.queue.setMaxConcurrentFlushes(cfg.MaxConcurrentFlushes)
+	c.queue.setAdaptiveBatching(cfg.AdaptiveBatchMin, cfg.AdaptiveBatchMax)
+	c.queue.maxEntryAge = cfg.MaxEntryAge
+	c.queue.maxBufferAge = cfg.MaxBufferAge
+	c.queue.minFlushBytes = int64(cfg.MinFlushBytes)
+	c.queue.setPriorityLevels(cfg.PriorityLevels)
+	c.queue.setHighWatermark(cfg.QueueHighWatermark, cfg.OnQueueHighWatermark)
+	c.queue.spill = spill
+	if spill.len() > 0 {
+		// Send entries left in the spill directory by an earlier client
+		c.queue.startTimer()
+	}
+
+	if cfg.StartupDiagnostic {
+		c.Log(LogEntry{
+			Level:    LevelInfo,
+			Message:  "logwell client started",
+			Metadata: startupDiagnostic(cfg),
+		})
+	}
+	if cfg.HeartbeatInterval > 0 {
+		c.heartbeat = startHeartbeat(c, cfg.HeartbeatInterval, cfg.HeartbeatMetadata)
+	}
 
 	return c, nil
 }
 
+// startupDiagnostic describes the effective configuration for the
+// WithStartupDiagnostic log. Secrets such as the API key are left out.
+func startupDiagnostic(cfg *Config) map[string]any {
+	return map[string]any{
+		"endpoint":              cfg.Endpoint,
+		"service":               cfg.Service,
+		"environment":           cfg.Environment,
+		"batchSize":             cfg.BatchSize,
+		"flushInterval":         cfg.FlushInterval.String(),
+		"maxQueueSize":          cfg.MaxQueueSize,
+		"maxRetries":            cfg.MaxRetries,
+		"deliveryMode":          string(cfg.DeliveryMode),
+		"overflowPolicy":        string(cfg.OverflowPolicy),
+		"minLevel":              string(cfg.MinLevel),
+		"flattenMetadata":       cfg.FlattenSeparator != "",
+		"captureSourceLocation": cfg.CaptureSourceLocation,
+	}
+}
+
 // Child creates a child logger that shares the parent's queue and transport.
 // Child loggers inherit the parent's service name and metadata by default.
 // Use ChildWithService to override the service name, and ChildWithMetadata
@@ -99,8 +377,23 @@ func New(endpoint, apiKey string, opts ...Option) (*Client, error) {
 //	)
 //	child.Info("Processing payment")
 func (c *Client) Child(opts ...ChildOption) *Client {
-	// Apply child options
-	cfg := &childConfig{}
+	child, _ := c.newChild(opts, false)
+	return child
+}
+
+// NewChild creates a child logger like Child, but returns an error with
+// code ErrInvalidConfig instead of falling back to the parent's values
+// when a ChildWithLevel or ChildWithQueue option is invalid.
+func (c *Client) NewChild(opts ...ChildOption) (*Client, error) {
+	return c.newChild(opts, true)
+}
+
+// newChild implements Child and NewChild. An invalid level or queue
+// options are returned if strict is set, and otherwise reported and
+// replaced by the parent's values.
+func (c *Client) newChild(opts []ChildOption, strict bool) (*Client, error) {
+	// Apply child options, starting from a copy of the parent's metadata
+	cfg := &childConfig{metadata: mergeMetadata(c.config.Metadata)}
 	for _, opt := range opts {
 		opt(cfg)
 	}
@@ -113,60 +406,429 @@ func (c *Client) Child(opts ...ChildOption) *Client {
 
 	// Build child config
 	childCfg := &Config{
-		Endpoint:              c.config.Endpoint,
-		APIKey:                c.config.APIKey,
-		Service:               c.config.Service,
-		BatchSize:             c.config.BatchSize,
-		FlushInterval:         c.config.FlushInterval,
-		MaxQueueSize:          c.config.MaxQueueSize,
-		CaptureSourceLocation: c.config.CaptureSourceLocation,
-		OnError:               c.config.OnError,
-		OnFlush:               c.config.OnFlush,
-		// Merge parent metadata with child metadata (child overrides parent)
-		Metadata: mergeMetadata(c.config.Metadata, cfg.metadata),
+		Endpoint:                   c.config.Endpoint,
+		APIKey:                     c.config.APIKey,
+		Service:                    c.config.Service,
+		DisableServiceAutodetect:   c.config.DisableServiceAutodetect,
+		Environment:                c.config.Environment,
+		BatchSize:                  c.batchSize(),
+		BatchBytes:                 c.config.BatchBytes,
+		FlushInterval:              c.config.FlushInterval,
+		MaxQueueSize:               c.config.MaxQueueSize,
+		MaxInFlightBytes:           c.config.MaxInFlightBytes,
+		MaxEntryAge:                c.config.MaxEntryAge,
+		MaxBufferAge:               c.config.MaxBufferAge,
+		MinFlushBytes:              c.config.MinFlushBytes,
+		MaxRetries:                 c.config.MaxRetries,
+		MaxConcurrentFlushes:       c.config.MaxConcurrentFlushes,
+		AdaptiveBatchMin:           c.config.AdaptiveBatchMin,
+		AdaptiveBatchMax:           c.config.AdaptiveBatchMax,
+		DeliveryMode:               c.config.DeliveryMode,
+		OverflowPolicy:             c.config.OverflowPolicy,
+		SpillDir:                   c.config.SpillDir,
+		SpillMaxBytes:              c.config.SpillMaxBytes,
+		RequeueOnFailure:           c.config.RequeueOnFailure,
+		BeforeSend:                 c.config.BeforeSend,
+		Encoder:                    c.config.Encoder,
+		MetadataAllowlist:          c.config.MetadataAllowlist,
+		CompressionThreshold:       c.config.CompressionThreshold,
+		Compression:                c.config.Compression,
+		MinLevel:                   c.config.MinLevel,
+		PriorityLevels:             c.config.PriorityLevels,
+		LevelSampling:              c.config.LevelSampling,
+		LevelAliases:               c.config.LevelAliases,
+		TimestampSkewMax:           c.config.TimestampSkewMax,
+		TimestampSkewAction:        c.config.TimestampSkewAction,
+		CaptureSourceLocation:      c.config.CaptureSourceLocation,
+		ContextService:             c.config.ContextService,
+		ContextMetadata:            c.config.ContextMetadata,
+		ServiceFunc:                c.config.ServiceFunc,
+		OnError:                    c.config.OnError,
+		OnFlush:                    c.config.OnFlush,
+		OnRetry:                    c.config.OnRetry,
+		OnDrop:                     c.config.OnDrop,
+		FallbackWriter:             c.config.FallbackWriter,
+		FallbackAfter:              c.config.FallbackAfter,
+		SlowThreshold:              c.config.SlowThreshold,
+		ContextDeadlinePropagation: c.config.ContextDeadlinePropagation,
+		DryRun:                     c.config.DryRun,
+		ConnectionMetrics:          c.config.ConnectionMetrics,
+		DryRunWriter:               c.config.DryRunWriter,
+		DeadLetter:                 c.config.DeadLetter,
+		SnapshotPath:               c.config.SnapshotPath,
+		OnShutdown:                 c.config.OnShutdown,
+		OnShutdownStats:            c.config.OnShutdownStats,
+		OfflineAfter:               c.config.OfflineAfter,
+		OfflineProbeInterval:       c.config.OfflineProbeInterval,
+		FallbackEndpoints:          c.config.FallbackEndpoints,
+		FallbackAPIKeys:            c.config.FallbackAPIKeys,
+		FailbackInterval:           c.config.FailbackInterval,
+		Endpoints:                  c.config.Endpoints,
+		BalanceStrategy:            c.config.BalanceStrategy,
+		EndpointCooldown:           c.config.EndpointCooldown,
+		RetryBudget:                c.config.RetryBudget,
+		RetryBudgetWindow:          c.config.RetryBudgetWindow,
+		OverflowReportInterval:     c.config.OverflowReportInterval,
+		QueueHighWatermark:         c.config.QueueHighWatermark,
+		OnQueueHighWatermark:       c.config.OnQueueHighWatermark,
+		ShutdownTimeout:            c.config.ShutdownTimeout,
+		FlattenSeparator:           c.config.FlattenSeparator,
+		// Parent metadata with child options applied (child overrides parent)
+		Metadata: mergeMetadata(cfg.metadata),
 	}
 
 	// Override service if specified
 	if cfg.service != "" {
 		childCfg.Service = cfg.service
 	}
+	if cfg.environment != "" {
+		childCfg.Environment = cfg.environment
+	}
+
+	// Share the immediate parent's queue so grandchildren of a
+	// ChildWithQueue child batch together with it
+	child := &Client{
+		config:      childCfg,
+		queue:       c.queue,
+		transport:   root.transport,
+		parent:      root,
+		levelParent: c,
+		allowedKeys: c.allowedKeys,
+
+		serviceAutodetected: c.serviceAutodetected && cfg.service == "",
+	}
+	child.baseMetadata, child.baseDroppedKeys = newBaseMetadata(childCfg, child.allowedKeys)
+	child.detectedMetadata = child.markServiceAutodetected(child.baseMetadata)
+	if cfg.level != "" {
+		if err := validateChildLevel(cfg.level); err != nil {
+			if strict {
+				return nil, err
+			}
+			c.reportError(err)
+		} else {
+			child.level.Store(cfg.level)
+		}
+	}
+
+	if len(cfg.queueOpts) > 0 {
+		if err := applyQueueOptions(childCfg, cfg.queueOpts); err != nil {
+			if strict {
+				return nil, err
+			}
+			c.reportError(err)
+		}
+		child.queue = newBatchQueue(childCfg.FlushInterval, child.flush, childCfg.MaxQueueSize, childCfg.OnError)
+		child.queue.onDrop = childCfg.OnDrop
+		child.queue.overflowReportInterval = childCfg.OverflowReportInterval
+		child.queue.dropLowest = childCfg.OverflowPolicy == DropLowestSeverity
+		child.queue.batchSize.Store(int64(childCfg.BatchSize))
+		child.queue.batchBytes = int64(childCfg.BatchBytes)
+		child.queue.maxBytes = childCfg.MaxInFlightBytes
+		child.queue.setMaxConcurrentFlushes(childCfg.MaxConcurrentFlushes)
+		child.queue.setAdaptiveBatching(childCfg.AdaptiveBatchMin, childCfg.AdaptiveBatchMax)
+		child.queue.maxEntryAge = childCfg.MaxEntryAge
+		child.queue.maxBufferAge = childCfg.MaxBufferAge
+		child.queue.minFlushBytes = int64(childCfg.MinFlushBytes)
+		child.queue.setPriorityLevels(childCfg.PriorityLevels)
+		child.queue.setHighWatermark(childCfg.QueueHighWatermark, childCfg.OnQueueHighWatermark)
+		child.ownsQueue = true
+
+		root.mu.Lock()
+		root.childQueues = append(root.childQueues, child)
+		root.mu.Unlock()
+	}
+
+	return child, nil
+}
+
+// applyQueueOptions applies the queue-related fields of opts to cfg,
+// keeping the existing value for any field that fails validation. Returns
+// the first validation error.
+func applyQueueOptions(cfg *Config, opts []Option) error {
+	scratch := *cfg
+	for _, opt := range opts {
+		opt(&scratch)
+	}
+
+	var firstErr error
+	apply := func(err error, set func()) {
+		if err == nil {
+			set()
+		} else if firstErr == nil {
+			firstErr = err
+		}
+	}
+	apply(validateBatchSize(scratch.BatchSize), func() { cfg.BatchSize = scratch.BatchSize })
+	apply(validateFlushInterval(scratch.FlushInterval), func() { cfg.FlushInterval = scratch.FlushInterval })
+	apply(validateMaxQueueSize(scratch.MaxQueueSize), func() { cfg.MaxQueueSize = scratch.MaxQueueSize })
+	apply(validateMaxConcurrentFlushes(scratch.MaxConcurrentFlushes), func() {
+		cfg.MaxConcurrentFlushes = scratch.MaxConcurrentFlushes
+	})
+	return firstErr
+}
+
+// Named returns the child logger registered under a dotted name such as
+// "payments.refunds", creating it on first use. The child carries a
+// "logger" metadata field with the full name. Names are hierarchical:
+// "payments.refunds" is created as a child of "payments", inheriting any
+// options that "payments" was registered with. Options are only applied
+// when the logger is first created; later calls return the cached instance.
+// Safe for concurrent use.
+//
+// Example:
+//
+//	refunds := client.Named("payments.refunds", logwell.ChildWithLevel(logwell.LevelDebug))
+//	refunds.Info("Refund issued")
+func (c *Client) Named(name string, opts ...ChildOption) *Client {
+	if name == "" {
+		return c
+	}
+
+	c.namedMu.Lock()
+	defer c.namedMu.Unlock()
+
+	return c.namedLocked(name, opts)
+}
+
+// namedLocked implements Named; the caller must hold c.namedMu.
+func (c *Client) namedLocked(name string, opts []ChildOption) *Client {
+	if existing, ok := c.named[name]; ok {
+		return existing
+	}
+
+	// Create the parent segment first so its options are inherited
+	parent := c
+	if i := strings.LastIndex(name, "."); i > 0 {
+		parent = c.namedLocked(name[:i], nil)
+	}
+
+	childOpts := append([]ChildOption{ChildWithMetadata(M{"logger": name})}, opts...)
+	child := parent.Child(childOpts...)
+
+	if c.named == nil {
+		c.named = make(map[string]*Client)
+	}
+	c.named[name] = child
+
+	return child
+}
+
+// SetLevel changes the minimum level at runtime.
+// For child loggers this detaches the child from its parent's level.
+// Children that did not set their own level follow the change.
+// Returns an ErrInvalidConfig error for unknown levels.
+func (c *Client) SetLevel(level LogLevel) error {
+	if err := validateMinLevel(level); err != nil {
+		return err
+	}
+	c.level.Store(level)
+	return nil
+}
+
+// Level returns the current minimum level, resolving inherited levels.
+func (c *Client) Level() LogLevel {
+	if level, ok := c.level.Load().(LogLevel); ok {
+		return level
+	}
+	if c.levelParent != nil {
+		return c.levelParent.Level()
+	}
+	return c.config.MinLevel
+}
+
+// SetFlushInterval changes how long queued logs may wait before an
+// automatic flush. A pending flush timer is restarted with the new interval.
+// Children without their own queue share this client's queue, so the change
+// applies to them too.
+// Returns an ErrInvalidConfig error if d is outside 100ms-60s.
+func (c *Client) SetFlushInterval(d time.Duration) error {
+	if err := validateFlushInterval(d); err != nil {
+		return err
+	}
+	c.queue.setFlushInterval(d)
+	return nil
+}
+
+// SetBatchSize changes how many queued logs trigger a flush and how many
+// are sent per request. If the queue already holds n or more logs, they are
+// flushed before SetBatchSize returns. Children without their own queue
+// share this client's queue, so the change applies to them too.
+// Returns an ErrInvalidConfig error if n is outside 1-500.
+func (c *Client) SetBatchSize(n int) error {
+	if err := validateBatchSize(n); err != nil {
+		return err
+	}
+	c.queue.batchSize.Store(int64(n))
 
-	return &Client{
-		config:    childCfg,
-		queue:     root.queue,
-		transport: root.transport,
-		parent:    root,
+	if c.queue.size() >= n {
+		c.flush()
 	}
+	return nil
+}
+
+// Stats returns a snapshot of this client's queue activity. Children
+// without their own queue report the shared queue.
+func (c *Client) Stats() Stats {
+	q := c.queue
+	connsNew, connsReused := c.transport.conns.counts()
+	return Stats{
+		InFlightBatches: int(q.inFlight.Load()),
+		BatchSize:       c.batchSize(),
+		QueueLength:     q.size(),
+		EnqueuedEntries: q.enqueuedTotal.Load(),
+		DroppedEntries:  int64(q.droppedCount()),
+		SentBatches:     q.sentBatches.Load(),
+		SendDuration:    time.Duration(q.sendNanos.Load()),
+		ConnsNew:        connsNew,
+		ConnsReused:     connsReused,
+		Offline:         c.transport.offline.isOffline(),
+		Endpoints:       endpointStats(c.transport.router),
+	}
+}
+
+// Pending returns a copy of the logs waiting in this client's queue, in the
+// order they will be sent, without removing them. Metadata is deep-copied,
+// so the result can be inspected or modified freely. Children without their
+// own queue see the shared queue. Intended for tests and debugging.
+func (c *Client) Pending() []LogEntry {
+	entries := c.queue.snapshot()
+	for i := range entries {
+		entries[i].Metadata = copyMetadata(entries[i].Metadata)
+	}
+	return entries
+}
+
+// QueueLen returns the number of logs waiting in this client's queue.
+// Children without their own queue report the shared queue. It is safe to
+// call from any goroutine, for example to shed load as the queue fills.
+func (c *Client) QueueLen() int {
+	return c.queue.size()
+}
+
+// QueueCap returns the capacity of this client's queue, its MaxQueueSize.
+func (c *Client) QueueCap() int {
+	return c.queue.maxQueueSize
+}
+
+// batchSize returns the current batch size of this client's queue.
+func (c *Client) batchSize() int {
+	return int(c.queue.batchSize.Load())
+}
+
+// batchReady reports whether a queue holding n entries should be flushed:
+// the batch size is reached, or the estimated size of the queued entries
+// has reached BatchBytes.
+func (c *Client) batchReady(n int) bool {
+	return n >= c.batchSize() || c.queue.bytesReached()
 }
 
 // Debug logs a message at DEBUG level.
 // Accepts optional metadata maps that will be merged (later maps override earlier).
 func (c *Client) Debug(message string, metadata ...map[string]any) {
-	c.log(LevelDebug, message, metadata...)
+	c.log(context.Background(), LevelDebug, message, metadata...)
+}
+
+// DebugContext logs a message at DEBUG level using values from ctx.
+// The service name is taken from the WithContextService extractor when set.
+func (c *Client) DebugContext(ctx context.Context, message string, metadata ...map[string]any) {
+	c.log(ctx, LevelDebug, message, metadata...)
 }
 
 // Info logs a message at INFO level.
 // Accepts optional metadata maps that will be merged (later maps override earlier).
 func (c *Client) Info(message string, metadata ...map[string]any) {
-	c.log(LevelInfo, message, metadata...)
+	c.log(context.Background(), LevelInfo, message, metadata...)
+}
+
+// InfoContext logs a message at INFO level using values from ctx.
+// The service name is taken from the WithContextService extractor when set.
+func (c *Client) InfoContext(ctx context.Context, message string, metadata ...map[string]any) {
+	c.log(ctx, LevelInfo, message, metadata...)
 }
 
 // Warn logs a message at WARN level.
 // Accepts optional metadata maps that will be merged (later maps override earlier).
 func (c *Client) Warn(message string, metadata ...map[string]any) {
-	c.log(LevelWarn, message, metadata...)
+	c.log(context.Background(), LevelWarn, message, metadata...)
+}
+
+// WarnContext logs a message at WARN level using values from ctx.
+// The service name is taken from the WithContextService extractor when set.
+func (c *Client) WarnContext(ctx context.Context, message string, metadata ...map[string]any) {
+	c.log(ctx, LevelWarn, message, metadata...)
 }
 
 // Error logs a message at ERROR level.
 // Accepts optional metadata maps that will be merged (later maps override earlier).
 func (c *Client) Error(message string, metadata ...map[string]any) {
-	c.log(LevelError, message, metadata...)
+	c.log(context.Background(), LevelError, message, metadata...)
+}
+
+// ErrorContext logs a message at ERROR level using values from ctx.
+// The service name is taken from the WithContextService extractor when set.
+func (c *Client) ErrorContext(ctx context.Context, message string, metadata ...map[string]any) {
+	c.log(ctx, LevelError, message, metadata...)
 }
 
 // Fatal logs a message at FATAL level.
 // Accepts optional metadata maps that will be merged (later maps override earlier).
 func (c *Client) Fatal(message string, metadata ...map[string]any) {
-	c.log(LevelFatal, message, metadata...)
+	c.log(context.Background(), LevelFatal, message, metadata...)
+}
+
+// FatalContext logs a message at FATAL level using values from ctx.
+// The service name is taken from the WithContextService extractor when set.
+func (c *Client) FatalContext(ctx context.Context, message string, metadata ...map[string]any) {
+	c.log(ctx, LevelFatal, message, metadata...)
+}
+
+// Timer starts timing an operation and returns a function that, when
+// called, logs message at INFO level with the elapsed milliseconds in the
+// durationMs metadata field, overriding any durationMs in metadata:
+//
+//	defer client.Timer("handle request")()
+func (c *Client) Timer(message string, metadata ...map[string]any) func() {
+	start := clockNow()
+	return func() {
+		c.log(context.Background(), LevelInfo, message, timerMetadata(clockNow().Sub(start), "", metadata, nil)...)
+	}
+}
+
+// StartTimer starts timing operation and returns a function that, when
+// called, logs operation with the elapsed milliseconds in durationMs and
+// the operation name in operation, over metadata and then any extra
+// metadata passed to it. The log is at INFO level, or WARN once the elapsed
+// time reaches SlowThreshold. The returned function holds no resources, so
+// one that is never called leaks nothing:
+//
+//	stop := client.StartTimer("db.query", logwell.M{"table": "orders"})
+//	rows := query()
+//	stop(logwell.M{"rows": len(rows)})
+func (c *Client) StartTimer(operation string, metadata ...map[string]any) func(extra ...map[string]any) {
+	start := clockNow()
+	return func(extra ...map[string]any) {
+		elapsed := clockNow().Sub(start)
+		level := LevelInfo
+		if slow := c.config.SlowThreshold; slow > 0 && elapsed >= slow {
+			level = LevelWarn
+		}
+		c.log(context.Background(), level, operation, timerMetadata(elapsed, operation, metadata, extra)...)
+	}
+}
+
+// timerMetadata returns the metadata maps of a timer log: metadata, then
+// extra, then the elapsed milliseconds in durationMs and, when set, the
+// operation name in operation. The functions returned by Timer and
+// StartTimer call c.log themselves so that source capture skips the right
+// number of frames.
+func timerMetadata(elapsed time.Duration, operation string, metadata, extra []map[string]any) []map[string]any {
+	fields := map[string]any{"durationMs": float64(elapsed) / float64(time.Millisecond)}
+	if operation != "" {
+		fields["operation"] = operation
+	}
+	all := make([]map[string]any, 0, len(metadata)+len(extra)+1)
+	all = append(append(all, metadata...), extra...)
+	return append(all, fields)
 }
 
 // Log sends a custom log entry directly.
@@ -174,26 +836,151 @@ func (c *Client) Fatal(message string, metadata ...map[string]any) {
 // The entry's timestamp will be set to now if empty, and service will be set from config if empty.
 // Returns without logging if the client has been shut down.
 func (c *Client) Log(entry LogEntry) {
+	c.LogContext(context.Background(), entry)
+}
+
+// LogContext sends a custom log entry like Log. Under the Block overflow
+// policy it waits for queue space until ctx is done, and the entry is then
+// dropped and reported through OnDrop and OnError.
+func (c *Client) LogContext(ctx context.Context, entry LogEntry) {
+	c.logEntry(ctx, entry, true)
+}
+
+// LogSync sends a custom log entry like LogContext and then flushes the
+// queue, returning once the entry has been sent or has failed. It returns
+// the ErrEnqueueCanceled error when the entry is dropped waiting for queue
+// space under the Block overflow policy, and otherwise Flush's error. An
+// entry discarded by the min level, sampling or shutdown is not an error.
+func (c *Client) LogSync(ctx context.Context, entry LogEntry) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	queued, err := c.logEntry(ctx, entry, true)
+	if err != nil || !queued {
+		return err
+	}
+	return c.Flush(ctx)
+}
+
+// logEntry applies defaults to entry and enqueues it as LogContext does,
+// checking its timestamp with the skew guard if guardSkew is set.
+// Returns false if the entry was discarded by shutdown, the min level,
+// sampling or the skew guard before reaching the queue, and enqueue's
+// error if it was dropped waiting for space.
+func (c *Client) logEntry(ctx context.Context, entry LogEntry, guardSkew bool) (bool, error) {
 	c.mu.Lock()
 	if c.shutdown {
 		c.mu.Unlock()
-		return
+		return false, nil
 	}
 	c.mu.Unlock()
 
+	if !levelEnabled(entry.Level, c.Level()) || !c.sampled(entry.Level) {
+		return false, nil
+	}
+	if guardSkew && !c.guardTimestamp(&entry) {
+		return false, nil
+	}
+
 	// Set defaults if not provided
 	if entry.Timestamp == "" {
 		entry.Timestamp = now()
 	}
 	if entry.Service == "" {
-		entry.Service = c.config.Service
+		entry.Service = c.serviceFor(ctx, &entry)
+	}
+	if entry.Environment == "" {
+		entry.Environment = c.config.Environment
+	}
+	// Merge config and context metadata with entry metadata
+	entry.Metadata = c.entryMetadata(entry.Service, c.contextMetadata(ctx), entry.Metadata)
+
+	return true, c.enqueue(ctx, entry)
+}
+
+// LogBatch sends multiple pre-built log entries in one call.
+// Defaults are applied to each entry as in Log, but the entries are enqueued
+// under a single lock acquisition and trigger at most one flush.
+// The caller's slice is not modified.
+// Returns without logging if the client has been shut down.
+func (c *Client) LogBatch(entries []LogEntry) {
+	c.LogBatchContext(context.Background(), entries)
+}
+
+// LogBatchContext sends multiple pre-built log entries like LogBatch, with
+// defaults applied as in LogContext. Under the Block overflow policy the
+// entries wait for queue space one at a time until ctx is done; the entry
+// waiting then and those after it are dropped and reported through OnDrop
+// and a single OnError call.
+func (c *Client) LogBatchContext(ctx context.Context, entries []LogEntry) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	minLevel := c.Level()
+	prepared := make([]LogEntry, 0, len(entries))
+	sampledOut := 0
+	for _, entry := range entries {
+		if !levelEnabled(entry.Level, minLevel) {
+			continue
+		}
+		if !c.keepSample(entry.Level) {
+			sampledOut++
+			continue
+		}
+		if !c.guardTimestamp(&entry) {
+			continue
+		}
+		if entry.Timestamp == "" {
+			entry.Timestamp = now()
+		}
+		if entry.Service == "" {
+			entry.Service = c.serviceFor(ctx, &entry)
+		}
+		if entry.Environment == "" {
+			entry.Environment = c.config.Environment
+		}
+		entry.Metadata = c.entryMetadata(entry.Service, c.contextMetadata(ctx), entry.Metadata)
+		prepared = append(prepared, entry)
+	}
+	if sampledOut > 0 && c.config.OnDrop != nil {
+		c.config.OnDrop(sampledOut)
+	}
+
+	if len(prepared) == 0 {
+		return
+	}
+
+	// Under the Block policy entries wait for space one at a time
+	if c.config.OverflowPolicy == Block {
+		c.mu.Lock()
+		shutdown := c.shutdown
+		c.mu.Unlock()
+		if shutdown {
+			return
+		}
+		for i, entry := range prepared {
+			if err := c.enqueueBlocking(ctx, entry); err != nil {
+				c.dropBlocked(len(prepared)-i, err)
+				return
+			}
+		}
+		return
+	}
+	if c.config.ContextDeadlinePropagation {
+		deadline, _ := ctx.Deadline()
+		for i := range prepared {
+			prepared[i].deadline = deadline
+		}
 	}
-	// Merge config metadata with entry metadata
-	entry.Metadata = mergeMetadata(c.config.Metadata, entry.Metadata)
 
+	// Check shutdown and enqueue atomically so a concurrent Shutdown
+	// either sees the whole batch or none of it
 	c.mu.Lock()
-	c.queue.add(entry)
-	shouldFlush := c.queue.size() >= c.config.BatchSize
+	if c.shutdown {
+		c.mu.Unlock()
+		return
+	}
+	shouldFlush := c.batchReady(c.queue.addAll(prepared))
 	c.mu.Unlock()
 
 	if shouldFlush {
@@ -201,133 +988,889 @@ func (c *Client) Log(entry LogEntry) {
 	}
 }
 
+// guardTimestamp applies the timestamp skew guard to a timestamp set by
+// the caller, clamping it to now or reporting the entry as dropped.
+// Returns false if entry is to be dropped.
+func (c *Client) guardTimestamp(entry *LogEntry) bool {
+	skew := c.config.TimestampSkewMax
+	if skew <= 0 || entry.Timestamp == "" {
+		return true
+	}
+	ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+	if err != nil {
+		return true
+	}
+	current := clockNow()
+	if !ts.Before(current.Add(-skew)) && !ts.After(current.Add(skew)) {
+		return true
+	}
+
+	if c.config.TimestampSkewAction == DropSkewed {
+		c.reportError(NewError(ErrValidationError,
+			fmt.Sprintf("dropped log with timestamp %s, more than %s from now", entry.Timestamp, skew)))
+		if c.config.OnDrop != nil {
+			c.config.OnDrop(1)
+		}
+		return false
+	}
+	c.reportError(NewError(ErrValidationError,
+		fmt.Sprintf("replaced timestamp %s, more than %s from now, with the current time", entry.Timestamp, skew)))
+	entry.Timestamp = current.UTC().Format(timestampLayout)
+	return true
+}
+
 // log is the internal logging method used by all level methods.
 // Returns without logging if the client has been shut down.
-func (c *Client) log(level LogLevel, message string, metadata ...map[string]any) {
+func (c *Client) log(ctx context.Context, level LogLevel, message string, metadata ...map[string]any) {
+	entry, ok := c.newEntry(ctx, level, message, metadata...)
+	if !ok {
+		return
+	}
+
+	// Capture source location if enabled
+	// Skip 3 frames: captureSource -> log -> Debug/Info/Warn/Error/Fatal (or *Context)
+	if c.config.CaptureSourceLocation {
+		entry.SourceFile, entry.LineNumber, entry.FunctionName = captureSource(3)
+	}
+
+	c.enqueue(ctx, entry)
+}
+
+// newEntry builds the entry of a log call without its source location.
+// Returns false if the client has been shut down or the level is filtered
+// or sampled out.
+func (c *Client) newEntry(ctx context.Context, level LogLevel, message string, metadata ...map[string]any) (LogEntry, bool) {
 	c.mu.Lock()
 	if c.shutdown {
 		c.mu.Unlock()
-		return
+		return LogEntry{}, false
 	}
 	c.mu.Unlock()
 
+	if !levelEnabled(level, c.Level()) || !c.sampled(level) {
+		return LogEntry{}, false
+	}
+
 	entry := LogEntry{
-		Level:     level,
-		Message:   message,
-		Timestamp: now(),
-		Service:   c.config.Service,
-		Metadata:  mergeMetadata(c.config.Metadata, mergeMetadata(metadata...)),
+		Level:       level,
+		Message:     message,
+		Timestamp:   now(),
+		Environment: c.config.Environment,
+	}
+	// ServiceFunc sees only the metadata passed with the log
+	if c.config.ServiceFunc != nil {
+		entry.Metadata = mergeMetadata(metadata...)
 	}
+	entry.Service = c.serviceFor(ctx, &entry)
+	if m := c.contextMetadata(ctx); m != nil {
+		metadata = append([]map[string]any{m}, metadata...)
+	}
+	entry.Metadata = c.entryMetadata(entry.Service, metadata...)
+	return entry, true
+}
 
-	// Capture source location if enabled
-	// Skip 3 frames: captureSource -> log -> Debug/Info/Warn/Error/Fatal
-	if c.config.CaptureSourceLocation {
-		entry.SourceFile, entry.LineNumber = captureSource(3)
+// sampled applies level sampling to an entry at level, reporting it
+// through OnDrop if it is dropped. Returns true if the entry is kept.
+func (c *Client) sampled(level LogLevel) bool {
+	if c.keepSample(level) {
+		return true
+	}
+	if c.config.OnDrop != nil {
+		c.config.OnDrop(1)
 	}
+	return false
+}
 
-	c.mu.Lock()
-	c.queue.add(entry)
-	shouldFlush := c.queue.size() >= c.config.BatchSize
-	c.mu.Unlock()
+// keepSample decides at random whether to keep an entry at level, using
+// the level's LevelSampling rate.
+func (c *Client) keepSample(level LogLevel) bool {
+	rate, ok := c.config.LevelSampling[level]
+	if !ok || rate >= 1 {
+		return true
+	}
+	return rate > 0 && rand.Float64() < rate
+}
 
-	if shouldFlush {
-		c.flush()
+// enqueue adds entry to the queue and flushes once the batch size is
+// reached. Under the Block overflow policy a full queue makes it trigger a
+// flush and wait for space; if ctx ends or the client shuts down first, the
+// entry is dropped, reported and returned with ErrEnqueueCanceled.
+func (c *Client) enqueue(ctx context.Context, entry LogEntry) error {
+	if c.config.OverflowPolicy != Block {
+		if c.config.ContextDeadlinePropagation && ctx != nil {
+			entry.deadline, _ = ctx.Deadline()
+		}
+		if c.batchReady(c.queue.add(entry)) {
+			c.flush()
+		}
+		return nil
+	}
+	if err := c.enqueueBlocking(ctx, entry); err != nil {
+		c.dropBlocked(1, err)
+		return err
+	}
+	return nil
+}
+
+// enqueueBlocking adds entry to the queue under the Block overflow policy,
+// waiting for space as enqueue does. Returns the ErrEnqueueCanceled error,
+// unreported, if the entry was not added.
+func (c *Client) enqueueBlocking(ctx context.Context, entry LogEntry) *Error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if c.config.ContextDeadlinePropagation {
+		entry.deadline, _ = ctx.Deadline()
+	}
+
+	for {
+		// Check shutdown and enqueue atomically so an entry is never
+		// added after Shutdown has drained the queue
+		c.mu.Lock()
+		if c.shutdown {
+			c.mu.Unlock()
+			return NewError(ErrEnqueueCanceled, "client shut down while waiting for queue space")
+		}
+		n, space, ok := c.queue.tryAdd(entry)
+		c.mu.Unlock()
+
+		if ok {
+			if c.batchReady(n) {
+				c.flush()
+			}
+			return nil
+		}
+
+		// Flush in the background so the wait below stays responsive to
+		// ctx; a flush already in progress frees space the same way
+		go c.flush()
+
+		select {
+		case <-space:
+		case <-ctx.Done():
+			return NewErrorWithCause(ErrEnqueueCanceled, "context done while waiting for queue space", ctx.Err())
+		}
+	}
+}
+
+// dropBlocked reports n entries given up on while waiting for queue space.
+func (c *Client) dropBlocked(n int, err *Error) {
+	c.queue.addDropped(n)
+	if c.config.OnDrop != nil {
+		c.config.OnDrop(n)
+	}
+	if c.config.OnError != nil {
+		c.config.OnError(err)
+	}
+}
+
+// entryMetadata merges config metadata with per-log metadata (later maps
+// override earlier) and flattens the result if WithFlattenMetadata is set.
+// Keys outside the metadata allowlist are then removed and their number
+// reported through OnDrop. ServiceAutodetectedKey is added when service,
+// the entry's final service name, is the autodetected one.
+// Without per-log metadata the shared baseMetadata or detectedMetadata map
+// is returned as-is, so entry metadata must be treated as read-only and
+// copied before changing it.
+func (c *Client) entryMetadata(service string, metadata ...map[string]any) map[string]any {
+	detected := c.serviceAutodetected && service == c.config.Service
+	size := len(c.baseMetadata)
+	hasMetadata := false
+	for _, m := range metadata {
+		if len(m) > 0 {
+			size += len(m)
+			hasMetadata = true
+		}
+	}
+	if !hasMetadata {
+		c.reportDroppedKeys(c.baseDroppedKeys)
+		if detected {
+			return c.detectedMetadata
+		}
+		return c.baseMetadata
+	}
+
+	// Merge the unflattened config metadata so nested per-log maps
+	// override whole keys before flattening
+	merged := make(map[string]any, size)
+	for k, v := range c.config.Metadata {
+		merged[k] = v
+	}
+	for _, m := range metadata {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+
+	if c.config.FlattenSeparator != "" {
+		merged = flattenMetadata(merged, c.config.FlattenSeparator)
+	}
+	merged, dropped := allowMetadata(merged, c.allowedKeys)
+	c.reportDroppedKeys(dropped)
+	if detected && c.serviceFlagAllowed() {
+		// merged is this entry's own map, so the key is added in place
+		if merged == nil {
+			merged = make(map[string]any, 1)
+		}
+		merged[ServiceAutodetectedKey] = true
+	}
+	return merged
+}
+
+// serviceFlagAllowed reports whether the metadata allowlist lets
+// ServiceAutodetectedKey through.
+func (c *Client) serviceFlagAllowed() bool {
+	return c.allowedKeys == nil || c.allowedKeys[ServiceAutodetectedKey]
+}
+
+// markServiceAutodetected returns m with ServiceAutodetectedKey set when
+// the service name was autodetected and the allowlist lets the key through.
+// m is not modified.
+func (c *Client) markServiceAutodetected(m map[string]any) map[string]any {
+	if !c.serviceAutodetected || !c.serviceFlagAllowed() {
+		return m
+	}
+	marked := make(map[string]any, len(m)+1)
+	for k, v := range m {
+		marked[k] = v
+	}
+	marked[ServiceAutodetectedKey] = true
+	return marked
+}
+
+// reportDroppedKeys reports metadata keys removed by the allowlist
+// through OnDrop.
+func (c *Client) reportDroppedKeys(n int) {
+	if n > 0 && c.config.OnDrop != nil {
+		c.config.OnDrop(n)
+	}
+}
+
+// newBaseMetadata returns the metadata attached to logs made without
+// per-log metadata: the config metadata, flattened if configured and
+// limited to allowed keys, or nil when there is none. It also returns the
+// number of keys removed because they are not allowed.
+func newBaseMetadata(cfg *Config, allowed map[string]bool) (map[string]any, int) {
+	if len(cfg.Metadata) == 0 {
+		return nil, 0
+	}
+	base := cfg.Metadata
+	if cfg.FlattenSeparator != "" {
+		base = flattenMetadata(cfg.Metadata, cfg.FlattenSeparator)
 	}
+	return allowMetadata(base, allowed)
+}
+
+// newAllowedKeys returns the set of keys in allowlist, or nil when
+// allowlist is nil and every key is allowed.
+func newAllowedKeys(allowlist []string) map[string]bool {
+	if allowlist == nil {
+		return nil
+	}
+	allowed := make(map[string]bool, len(allowlist))
+	for _, key := range allowlist {
+		allowed[key] = true
+	}
+	return allowed
+}
+
+// allowMetadata returns m without the keys missing from allowed, and the
+// number of keys removed. m is returned unchanged when allowed is nil or
+// every key is allowed, and is never modified; nil is returned when no key
+// is left.
+func allowMetadata(m map[string]any, allowed map[string]bool) (map[string]any, int) {
+	if allowed == nil {
+		return m, 0
+	}
+	dropped := 0
+	for k := range m {
+		if !allowed[k] {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		return m, 0
+	}
+	if dropped == len(m) {
+		return nil, dropped
+	}
+
+	result := make(map[string]any, len(m)-dropped)
+	for k, v := range m {
+		if allowed[k] {
+			result[k] = v
+		}
+	}
+	return result, dropped
+}
+
+// serviceFor returns the service name for entry, made with ctx.
+// The context extractor wins when it returns a non-empty name, then
+// ServiceFunc. A nil ctx skips the context extractor.
+func (c *Client) serviceFor(ctx context.Context, entry *LogEntry) string {
+	if c.config.ContextService != nil && ctx != nil {
+		if service := c.config.ContextService(ctx); service != "" {
+			return service
+		}
+	}
+	if c.config.ServiceFunc != nil {
+		if service := c.config.ServiceFunc(entry); service != "" {
+			return service
+		}
+	}
+	return c.config.Service
+}
+
+// contextMetadata returns the metadata the context extractor derives from
+// ctx, or nil without an extractor or a ctx.
+func (c *Client) contextMetadata(ctx context.Context) map[string]any {
+	if c.config.ContextMetadata == nil || ctx == nil {
+		return nil
+	}
+	return c.config.ContextMetadata(ctx)
 }
 
 // flush sends all queued log entries to the server.
 // Internal method - does not respect context cancellation.
+// Entries are sent in BatchSize chunks.
 // Calls OnFlush callback on success and OnError callback on failure.
+//
+// At most MaxConcurrentFlushes flushes run per queue at a time (one by
+// default, so sends never overlap). A trigger that arrives while every
+// slot is taken returns immediately and a running flush performs one more
+// cycle on its behalf.
 func (c *Client) flush() {
-	entries := c.queue.flush()
-	if len(entries) == 0 {
-		return
+	c.queue.flushPending.Store(true)
+	c.runPendingFlushes()
+}
+
+// runPendingFlushes drains the queue while flush triggers are pending.
+// The pending flag is set before the semaphore is tried and re-checked after
+// it is released, so a trigger that loses the race is never lost.
+func (c *Client) runPendingFlushes() {
+	q := c.queue
+	for q.flushPending.Load() {
+		select {
+		case q.flushSem <- struct{}{}:
+		default:
+			return // A current holder will see the pending flag
+		}
+		for q.flushPending.Swap(false) {
+			_, err := c.drain(q.sendCtx, q.stopRetries)
+			// Keep reading back spilled entries while sends succeed
+			if err == nil && q.spilled() > 0 && !c.transport.offline.isOffline() && !q.stopping() {
+				q.flushPending.Store(true)
+			}
+		}
+		<-q.flushSem
 	}
+}
 
-	count := len(entries)
+// exclusiveDrain waits for all in-progress flushes to finish and then drains
+// the queue itself. Triggers that arrived meanwhile are run afterwards.
+// If ctx ends while waiting, nothing is sent and the queued entries are
+// reported as unsent.
+func (c *Client) exclusiveDrain(ctx context.Context) (ShutdownStats, error) {
+	q := c.queue
+	if !c.acquireAllFlushSlots(ctx) {
+		if q.stopping() {
+			// Shutdown gives up on running flushes; abort their requests
+			q.cancelSends()
+		}
+		remaining := q.size()
+		err := c.drainError(ctx, remaining)
+		if q.stopping() && q.spill == nil && (c.transport.deadLetter != nil || c.transport.snapshot != nil) {
+			entries := q.flush()
+			c.transport.deadLetter.send(entries, err)
+			c.transport.snapshot.add(entries)
+		}
+		return ShutdownStats{DroppedEntries: remaining}, err
+	}
+	stats, err := c.drain(ctx, nil)
+	for err == nil && ctx.Err() == nil && q.spilled() > 0 {
+		var more ShutdownStats
+		more, err = c.drain(ctx, nil)
+		stats.add(more)
+	}
+	c.releaseFlushSlots(cap(q.flushSem))
 
-	// Send logs with retry
-	ctx := context.Background()
-	_, err := c.transport.sendWithRetry(ctx, entries)
+	c.runPendingFlushes()
+	return stats, err
+}
 
-	// Call callbacks (non-blocking)
-	if err != nil {
-		if c.config.OnError != nil {
-			if logwellErr, ok := err.(*Error); ok {
-				c.config.OnError(logwellErr)
-			} else {
-				c.config.OnError(NewErrorWithCause(ErrNetworkError, "flush failed", err))
-			}
+// acquireAllFlushSlots takes every flushSem slot, waiting for running
+// flushes to finish. Only one caller at a time may hold all slots, so
+// concurrent Flush calls cannot deadlock on partially taken slots.
+// Returns false, holding nothing, if ctx ends first.
+func (c *Client) acquireAllFlushSlots(ctx context.Context) bool {
+	q := c.queue
+	select {
+	case q.exclusiveSem <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+
+	for acquired := 0; acquired < cap(q.flushSem); acquired++ {
+		select {
+		case q.flushSem <- struct{}{}:
+		case <-ctx.Done():
+			c.releaseFlushSlots(acquired)
+			return false
 		}
-		return
 	}
+	return true
+}
 
-	if c.config.OnFlush != nil {
-		c.config.OnFlush(count)
+// releaseFlushSlots releases n flushSem slots and the exclusive lock
+// taken by acquireAllFlushSlots.
+func (c *Client) releaseFlushSlots(n int) {
+	q := c.queue
+	for i := 0; i < n; i++ {
+		<-q.flushSem
 	}
+	<-q.exclusiveSem
 }
 
-// Flush sends all queued log entries immediately.
-// Respects context cancellation and timeout.
+// Flush sends all queued log entries immediately, in BatchSize chunks.
+// Respects context cancellation and timeout. A nil ctx is treated as context.Background().
+// If another flush is in progress, Flush waits for it to finish and then
+// runs its own cycle, so every entry queued before the call has been
+// attempted when it returns.
 // Calls OnFlush callback on success and OnError callback on failure.
-// Returns any error from the transport layer.
+// Returns any error from the transport layer, or an error reporting how
+// many entries were left unsent if ctx ends before the queue is drained.
 func (c *Client) Flush(ctx context.Context) error {
-	entries := c.queue.flush()
-	if len(entries) == 0 {
-		return nil
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	count := len(entries)
-	_, err := c.transport.sendWithRetry(ctx, entries)
+	_, err := c.exclusiveDrain(ctx)
+	return err
+}
 
-	// Call callbacks (non-blocking)
-	if err != nil {
-		if c.config.OnError != nil {
-			if logwellErr, ok := err.(*Error); ok {
-				c.config.OnError(logwellErr)
-			} else {
-				c.config.OnError(NewErrorWithCause(ErrNetworkError, "flush failed", err))
-			}
-		}
-		return err
+// resumeFlushing drains the queues of the client and of children created
+// with ChildWithQueue once the endpoint is reachable again after being
+// offline.
+func (c *Client) resumeFlushing() {
+	c.mu.Lock()
+	children := append([]*Client(nil), c.childQueues...)
+	c.mu.Unlock()
+
+	for _, child := range children {
+		go child.flush()
 	}
+	go c.flush()
+}
 
-	if c.config.OnFlush != nil {
-		c.config.OnFlush(count)
+// WaitForIdle blocks until the queue is empty and no flush is in progress,
+// or until ctx ends, in which case it returns ctx.Err(). It does not trigger
+// a flush itself: entries still waiting for BatchSize or FlushInterval keep
+// it blocked until they are sent. Use Flush to send them immediately.
+// A nil ctx is treated as context.Background().
+//
+// OnFlush, OnError and OnDrop calls made by a flush happen before
+// WaitForIdle returns, which makes it a deterministic barrier in tests:
+//
+//	client.Info("hello")
+//	if err := client.WaitForIdle(ctx); err != nil {
+//	    t.Fatal(err)
+//	}
+//
+// Only the client's own queue is awaited; children created with
+// ChildWithQueue must be waited on separately.
+func (c *Client) WaitForIdle(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	return nil
+	idle := c.queue.idle()
+	if idle == nil {
+		return nil
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Shutdown gracefully shuts down the client.
 // It stops accepting new logs, flushes any remaining queued logs,
 // and cleans up resources.
-// Respects context cancellation and timeout.
+// Respects context cancellation and timeout. A nil ctx is treated as context.Background().
 // Returns any error from flushing remaining logs.
 //
+// Background flushes waiting to retry are interrupted and their batches
+// are sent once more within ctx; logs still unsent when ctx ends are counted
+// as dropped and reported through OnDrop. No request is made after Shutdown
+// returns.
+//
 // For child loggers, Shutdown only marks the child as shut down;
 // it does NOT affect the parent or other children. The parent must
 // be shut down separately to flush remaining logs and stop the timer.
+// Children created with ChildWithQueue also flush their own queue, and
+// shutting down the root client shuts them down and drains them too.
 func (c *Client) Shutdown(ctx context.Context) error {
+	_, err := c.ShutdownWithStats(ctx)
+	return err
+}
+
+// Close shuts down the client using the configured ShutdownTimeout
+// (see WithShutdownTimeout), so that "defer client.Close()" is bounded even
+// when the server is unreachable. Close implements io.Closer and, like
+// Shutdown, is idempotent: calling it after Shutdown or Close returns nil.
+// Use Shutdown directly for a deadline other than ShutdownTimeout.
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.ShutdownTimeout)
+	defer cancel()
+	return c.Shutdown(ctx)
+}
+
+// ShutdownWithStats shuts down the client like Shutdown and also reports
+// how the final drain went: entries flushed, entries that failed to send,
+// entries dropped, and the time the drain took. Stats from children created
+// with ChildWithQueue are included. Calling it on a client that is already
+// shut down returns zero stats and a nil error.
+//
+// Example:
+//
+//	stats, err := client.ShutdownWithStats(ctx)
+//	fmt.Fprintf(os.Stderr, "logwell: flushed=%d failed=%d dropped=%d in %v\n",
+//	    stats.FlushedEntries, stats.FailedEntries, stats.DroppedEntries, stats.Elapsed)
+func (c *Client) ShutdownWithStats(ctx context.Context) (ShutdownStats, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+
 	c.mu.Lock()
 	if c.shutdown {
 		c.mu.Unlock()
-		return nil // Already shut down
+		return ShutdownStats{}, nil // Already shut down
 	}
 	c.shutdown = true
+	children := c.childQueues
+	c.childQueues = nil
 	c.mu.Unlock()
 
-	// Child loggers don't own the queue/transport, so they shouldn't
-	// stop the timer or flush. Only mark themselves as shut down.
+	// Child loggers don't own the transport, so they shouldn't
+	// stop the timer or flush unless they own their queue.
 	if c.parent != nil {
-		return nil
+		if !c.ownsQueue {
+			return ShutdownStats{}, nil
+		}
+		c.parent.removeChildQueue(c)
+		c.queue.stop()
+		c.queue.stopTimer()
+		stats, err := c.exclusiveDrain(ctx)
+		stats.DroppedEntries += c.queue.droppedCount()
+		stats.Elapsed = time.Since(start)
+		return stats, err
+	}
+
+	// End backoff waits in background flushes now; the batches they were
+	// retrying are requeued and sent below within ctx
+	c.heartbeat.stop()
+	c.queue.stop()
+	c.transport.offline.stop()
+	if c.transport.router != nil {
+		c.transport.router.stop()
+	}
+
+	// Drain children with their own queues, keeping the first error
+	var stats ShutdownStats
+	var firstErr error
+	for _, child := range children {
+		childStats, err := child.ShutdownWithStats(ctx)
+		stats.add(childStats)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
 
 	// Stop the queue timer to prevent further auto-flushes
 	c.queue.stopTimer()
 
 	// Flush remaining logs with context
-	return c.Flush(ctx)
+	rootStats, err := c.exclusiveDrain(ctx)
+	c.queue.closeSpill()
+	c.transport.deadLetter.close(ctx)
+	if _, snapErr := c.transport.snapshot.write(); snapErr != nil {
+		c.reportError(NewErrorWithCause(ErrSnapshot, "writing shutdown snapshot failed", snapErr))
+	}
+	rootStats.DroppedEntries += c.queue.droppedCount()
+	stats.add(rootStats)
+	stats.Elapsed = time.Since(start)
+	c.diag.logf(LevelInfo, "shutdown: flushed=%d failed=%d dropped=%d in %v",
+		stats.FlushedEntries, stats.FailedEntries, stats.DroppedEntries, stats.Elapsed)
+	if c.config.OnShutdown != nil {
+		c.config.OnShutdown(ctx)
+	}
+	if c.config.OnShutdownStats != nil {
+		c.config.OnShutdownStats(stats)
+	}
+	if err != nil {
+		return stats, err
+	}
+	return stats, firstErr
+}
+
+// drain sends all queued entries in BatchSize chunks.
+// Each chunk is sent separately so that a tight deadline still delivers
+// as many full batches as time allows instead of losing the whole backlog,
+// and so that a large backlog never goes out as one oversized request.
+// Chunks are dispatched in queue order; up to MaxConcurrentFlushes may be
+// in flight at once across the queue, and drain waits for its own chunks.
+// Returns an error reporting the number of unsent logs if ctx ends first,
+// and reports them through OnDrop.
+//
+// Background flushes pass the queue's stopRetries as stop. When shutdown
+// closes it, chunks waiting to retry are requeued instead, so the shutdown
+// drain sends them within its own deadline.
+func (c *Client) drain(ctx context.Context, stop <-chan struct{}) (ShutdownStats, error) {
+	q := c.queue
+	q.beginDrain()
+	defer q.endDrain()
+
+	// Background flushes leave the queue alone while offline
+	offline := c.transport.offline
+	if stop != nil && offline.isOffline() {
+		return ShutdownStats{}, nil
+	}
+	entries := q.flush()
+
+	c.mu.Lock()
+	requeue := c.config.RequeueOnFailure && !c.shutdown
+	keepOffline := offline != nil && !c.shutdown
+	// Entries the final flush cannot deliver are kept for the snapshot
+	var snapshot *shutdownSnapshot
+	if c.shutdown {
+		snapshot = c.transport.snapshot
+	}
+	c.mu.Unlock()
+
+	// With a spill store, entries left unsent when ctx ends stay queued,
+	// and at shutdown are written to the spill
+	keepUnsent := q.spill != nil
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		stats    ShutdownStats
+		firstErr error
+		unsent   int
+
+		// Chunks to requeue, indexed by dispatch order so they go back in
+		// queue order whichever finishes first
+		failed [][]LogEntry
+
+		// Entries of chunks cut short because ctx ended
+		abandoned []LogEntry
+
+		// Set when shutdown interrupted a retry, or when going offline
+		// left chunks to keep queued
+		stopped     bool
+		wentOffline bool
+
+		// paused is set when dispatch stopped because the client is
+		// offline; probed when a Flush has already tried one chunk then
+		paused, probed bool
+	)
+
+	for len(entries) > 0 && ctx.Err() == nil {
+		if keepOffline && offline.isOffline() {
+			if stop != nil || probed {
+				paused = true
+				break
+			}
+			probed = true
+		}
+
+		// Wait for a send slot, in queue order
+		select {
+		case q.sendSem <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+
+		n := c.batchSize()
+		if n > len(entries) {
+			n = len(entries)
+		}
+		// Cap the chunk so a BeforeSend that appends cannot overwrite
+		// the entries after it
+		chunk := entries[:n:n]
+		entries = entries[n:]
+		// Reserve this chunk's slot; running chunks write theirs under mu
+		mu.Lock()
+		index := len(failed)
+		failed = append(failed, nil)
+		mu.Unlock()
+
+		if c.config.BeforeSend != nil {
+			original := chunk
+			var send bool
+			chunk, send = c.config.BeforeSend(chunk)
+			if !send || len(chunk) == 0 {
+				<-q.sendSem
+				if !send {
+					mu.Lock()
+					if requeue {
+						failed[index] = original
+					} else {
+						stats.DroppedEntries += len(original)
+						if c.config.OnDrop != nil {
+							c.config.OnDrop(len(original))
+						}
+					}
+					mu.Unlock()
+				}
+				continue
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Background sends do not outlive the requests that logged
+			// the chunk
+			sendCtx := ctx
+			if dl, ok := earliestDeadline(chunk); ok && stop != nil {
+				var cancel context.CancelFunc
+				sendCtx, cancel = context.WithDeadline(ctx, dl)
+				defer cancel()
+			}
+			q.inFlight.Add(1)
+			start := time.Now()
+			_, err := c.transport.sendWithRetryUntil(sendCtx, stop, chunk)
+			elapsed := time.Since(start)
+			q.sentBatches.Add(1)
+			q.sendNanos.Add(int64(elapsed))
+			if sendCtx.Err() == nil {
+				q.adaptBatchSize(err, elapsed)
+			}
+			q.inFlight.Add(-1)
+			<-q.sendSem
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case isStoppedError(err):
+				stopped = true
+				failed[index] = chunk
+			case isOfflineError(err) && keepOffline:
+				// Kept queued without OnError; going offline was reported once
+				wentOffline = true
+				failed[index] = chunk
+				if firstErr == nil {
+					firstErr = err
+				}
+			case err != nil && ctx.Err() != nil && keepUnsent:
+				failed[index] = chunk
+			case err != nil && ctx.Err() != nil:
+				unsent += len(chunk)
+				abandoned = append(abandoned, chunk...)
+			case isExpiredError(err):
+				stats.DroppedEntries += len(chunk)
+				c.reportError(err)
+				c.transport.deadLetter.send(chunk, err)
+				if c.config.OnDrop != nil {
+					c.config.OnDrop(len(chunk))
+				}
+			case err != nil && requeue && IsRetryable(err):
+				failed[index] = chunk
+				if firstErr == nil {
+					firstErr = err
+				}
+				c.reportError(err)
+			case err != nil:
+				stats.FailedEntries += len(chunk)
+				if firstErr == nil {
+					firstErr = err
+				}
+				c.reportError(err)
+				c.transport.fallback.failed(chunk)
+				c.transport.deadLetter.send(chunk, err)
+				if IsRetryable(err) {
+					snapshot.add(chunk)
+				}
+			default:
+				c.transport.fallback.succeeded()
+				stats.FlushedEntries += len(chunk)
+				if c.config.OnFlush != nil {
+					c.config.OnFlush(len(chunk))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if requeue || stopped || wentOffline || paused || keepUnsent {
+		var retry []LogEntry
+		for _, chunk := range failed {
+			retry = append(retry, chunk...)
+		}
+		if paused || keepUnsent {
+			retry = append(retry, entries...)
+			entries = nil
+		}
+		q.requeue(retry)
+
+		if keepUnsent && ctx.Err() != nil && len(retry) > 0 && firstErr == nil {
+			firstErr = NewErrorWithCause(ErrNetworkError, fmt.Sprintf("%d logs left queued when the context ended", len(retry)), ctx.Err())
+		}
+	}
+
+	// Entries never dispatched because ctx ended
+	unsent += len(entries)
+	if unsent > 0 {
+		stats.DroppedEntries += unsent
+		if c.config.OnDrop != nil {
+			c.config.OnDrop(unsent)
+		}
+		firstErr = c.drainError(ctx, unsent)
+		abandoned = append(abandoned, entries...)
+		c.transport.deadLetter.send(abandoned, firstErr)
+		snapshot.add(abandoned)
+	}
+	return stats, firstErr
+}
+
+// drainError builds and reports the error for logs left unsent when ctx ends.
+func (c *Client) drainError(ctx context.Context, remaining int) error {
+	err := NewErrorWithCause(ErrNetworkError, fmt.Sprintf("shutdown ended with %d logs unsent", remaining), ctx.Err())
+	c.reportError(err)
+	return err
+}
+
+// reportError passes err to the OnError callback, wrapping non-SDK errors.
+func (c *Client) reportError(err error) {
+	if c.config.OnError == nil {
+		return
+	}
+	c.config.OnError(toError(err))
+}
+
+// toError returns err as an *Error, wrapping non-SDK errors.
+func toError(err error) *Error {
+	if logwellErr, ok := err.(*Error); ok {
+		return logwellErr
+	}
+	return NewErrorWithCause(ErrNetworkError, "flush failed", err)
+}
+
+// removeChildQueue stops tracking a ChildWithQueue child after it shuts down.
+func (c *Client) removeChildQueue(child *Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, existing := range c.childQueues {
+		if existing == child {
+			c.childQueues = append(c.childQueues[:i], c.childQueues[i+1:]...)
+			return
+		}
+	}
 }
 
 // mergeMetadata combines multiple metadata maps into one.
@@ -350,3 +1893,138 @@ func mergeMetadata(maps ...map[string]any) map[string]any {
 
 	return result
 }
+
+// copyMetadata returns a deep copy of m so the result shares no
+// nested maps or slices with the caller. A map or slice that contains one
+// of its ancestors is replaced by circularValue. Returns nil for a nil map.
+func copyMetadata(m map[string]any) map[string]any {
+	return copyMap(m, make(map[uintptr]bool))
+}
+
+// copyMap deep-copies m. seen holds the maps and slices on the current
+// path and is used to detect cycles.
+func copyMap(m map[string]any, seen map[uintptr]bool) map[string]any {
+	if m == nil {
+		return nil
+	}
+	ptr := reflect.ValueOf(m).Pointer()
+	seen[ptr] = true
+	defer delete(seen, ptr)
+
+	result := make(map[string]any, len(m))
+	for k, v := range m {
+		result[k] = copyValue(v, seen)
+	}
+	return result
+}
+
+// copyValue deep-copies the container types commonly used in metadata.
+// Other values are returned as-is.
+func copyValue(v any, seen map[uintptr]bool) any {
+	switch val := v.(type) {
+	case map[string]any:
+		if val != nil && seen[reflect.ValueOf(val).Pointer()] {
+			return circularValue
+		}
+		return copyMap(val, seen)
+	case M:
+		if val != nil && seen[reflect.ValueOf(val).Pointer()] {
+			return circularValue
+		}
+		return M(copyMap(val, seen))
+	case map[string]string:
+		if val == nil {
+			return val
+		}
+		result := make(map[string]string, len(val))
+		for k, s := range val {
+			result[k] = s
+		}
+		return result
+	case []any:
+		if len(val) == 0 {
+			return make([]any, 0)
+		}
+		ptr := reflect.ValueOf(val).Pointer()
+		if seen[ptr] {
+			return circularValue
+		}
+		seen[ptr] = true
+		defer delete(seen, ptr)
+
+		result := make([]any, len(val))
+		for i, item := range val {
+			result[i] = copyValue(item, seen)
+		}
+		return result
+	case []string:
+		return append([]string(nil), val...)
+	default:
+		return v
+	}
+}
+
+// maxFlattenDepth caps how many levels of nested maps are flattened.
+// Maps nested deeper are kept as-is under their flattened key.
+const maxFlattenDepth = 10
+
+// circularValue replaces a nested map that contains one of its ancestors.
+const circularValue = "[circular]"
+
+// flattenMetadata returns a copy of m with nested maps flattened into keys
+// joined by sep. Slices and scalar values are left intact.
+func flattenMetadata(m map[string]any, sep string) map[string]any {
+	if m == nil {
+		return nil
+	}
+
+	result := make(map[string]any, len(m))
+	flattenInto(result, "", m, sep, 0, make(map[uintptr]bool))
+	return result
+}
+
+// flattenInto writes the flattened entries of m into dst under prefix.
+// seen holds the maps on the current path and is used to detect cycles.
+func flattenInto(dst map[string]any, prefix string, m map[string]any, sep string, depth int, seen map[uintptr]bool) {
+	ptr := reflect.ValueOf(m).Pointer()
+	seen[ptr] = true
+	defer delete(seen, ptr)
+
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + sep + k
+		}
+
+		nested, ok := asMetadataMap(v)
+		switch {
+		case !ok || len(nested) == 0:
+			dst[key] = v
+		case seen[reflect.ValueOf(nested).Pointer()]:
+			dst[key] = circularValue
+		case depth+1 >= maxFlattenDepth:
+			dst[key] = v
+		default:
+			flattenInto(dst, key, nested, sep, depth+1, seen)
+		}
+	}
+}
+
+// asMetadataMap returns v as a map[string]any if it is one of the map
+// types commonly used in metadata.
+func asMetadataMap(v any) (map[string]any, bool) {
+	switch val := v.(type) {
+	case map[string]any:
+		return val, true
+	case M:
+		return val, true
+	case map[string]string:
+		result := make(map[string]any, len(val))
+		for k, s := range val {
+			result[k] = s
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
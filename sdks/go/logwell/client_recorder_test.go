@@ -0,0 +1,139 @@
+package logwell_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+	"github.com/Divkix/Logwell/sdks/go/logwelltest"
+)
+
+// recorderAPIKey is a well-formed API key for tests against a Recorder.
+const recorderAPIKey = "lw_abcdefghijklmnopqrstuvwxyz123456"
+
+// newRecordedClient creates a client sending to a new Recorder. Both are
+// shut down when the test ends.
+func newRecordedClient(t *testing.T, opts ...logwell.Option) (*logwell.Client, *logwelltest.Recorder) {
+	t.Helper()
+
+	rec, url := logwelltest.NewRecorder()
+	t.Cleanup(rec.Close)
+	return newClient(t, url, opts...), rec
+}
+
+// newClient creates a client sending to url that is shut down when the
+// test ends.
+func newClient(t *testing.T, url string, opts ...logwell.Option) *logwell.Client {
+	t.Helper()
+
+	client, err := logwell.New(url, recorderAPIKey, opts...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { client.Shutdown(context.Background()) })
+	return client
+}
+
+// TestClientLogLevels tests all log level methods.
+func TestClientLogLevels(t *testing.T) {
+	client, rec := newRecordedClient(t, logwell.WithBatchSize(1))
+
+	testCases := []struct {
+		name    string
+		logFn   func(string, ...map[string]any)
+		level   logwell.LogLevel
+		message string
+	}{
+		{"Debug", client.Debug, logwell.LevelDebug, "debug message"},
+		{"Info", client.Info, logwell.LevelInfo, "info message"},
+		{"Warn", client.Warn, logwell.LevelWarn, "warn message"},
+		{"Error", client.Error, logwell.LevelError, "error message"},
+		{"Fatal", client.Fatal, logwell.LevelFatal, "fatal message"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec.Reset()
+
+			tc.logFn(tc.message)
+
+			// Batch size 1 triggers an immediate flush
+			rec.WaitForCount(t, 1, time.Second)
+			last, _ := rec.LastEntry()
+			if last.Level != tc.level {
+				t.Errorf("Level = %q, want %q", last.Level, tc.level)
+			}
+			if last.Message != tc.message {
+				t.Errorf("Message = %q, want %q", last.Message, tc.message)
+			}
+		})
+	}
+}
+
+// TestClientMetadataMerging tests metadata merging from config and call.
+func TestClientMetadataMerging(t *testing.T) {
+	configMetadata := logwell.WithMetadata(logwell.M{"env": "test", "version": "1.0"})
+
+	testCases := []struct {
+		name     string
+		opts     []logwell.Option
+		metadata []map[string]any
+		want     logwell.M
+	}{
+		{
+			name: "config metadata is included in logs",
+			opts: []logwell.Option{configMetadata},
+			want: logwell.M{"env": "test", "version": "1.0"},
+		},
+		{
+			name:     "call metadata merges with config metadata",
+			opts:     []logwell.Option{configMetadata},
+			metadata: []map[string]any{{"request_id": "abc123"}},
+			want:     logwell.M{"env": "test", "version": "1.0", "request_id": "abc123"},
+		},
+		{
+			name:     "call metadata overrides config metadata",
+			opts:     []logwell.Option{configMetadata},
+			metadata: []map[string]any{{"env": "production"}},
+			want:     logwell.M{"env": "production", "version": "1.0"},
+		},
+		{
+			name:     "multiple metadata maps merge correctly",
+			metadata: []map[string]any{{"a": "1"}, {"b": "2"}, {"a": "3"}},
+			want:     logwell.M{"a": "3", "b": "2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, rec := newRecordedClient(t, append(tc.opts, logwell.WithBatchSize(1))...)
+
+			client.Info("test message", tc.metadata...)
+
+			rec.WaitForCount(t, 1, time.Second)
+			rec.AssertLogged(t, logwelltest.Message("test message"), logwelltest.Metadata(tc.want))
+		})
+	}
+}
+
+// TestClientBatchAutoFlush tests automatic flush when batch size is reached.
+func TestClientBatchAutoFlush(t *testing.T) {
+	batchSize := 5
+	client, rec := newRecordedClient(t,
+		logwell.WithBatchSize(batchSize),
+		logwell.WithFlushInterval(1*time.Minute), // Long interval to avoid timer flush
+	)
+
+	// Log exactly batch size entries
+	for i := 0; i < batchSize; i++ {
+		client.Info("message")
+	}
+
+	rec.WaitForCount(t, batchSize, time.Second)
+
+	// Verify exactly one request was made (all in one batch)
+	if batches := rec.Batches(); len(batches) != 1 {
+		t.Errorf("received %d requests, want 1", len(batches))
+	}
+}
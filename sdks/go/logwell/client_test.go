@@ -3,6 +3,9 @@ package logwell
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,6 +16,9 @@ import (
 )
 
 // testServer is a mock server that captures received logs for verification.
+// Tests in package logwell use it rather than logwelltest.Server because
+// logwelltest imports logwell, so importing it here would be a cycle;
+// black-box tests in package logwell_test use logwelltest.Server.
 type testServer struct {
 	*httptest.Server
 	mu       sync.Mutex
@@ -161,159 +167,168 @@ func TestClientNew(t *testing.T) {
 	})
 }
 
-// TestClientLogLevels tests logging at all severity levels.
-func TestClientLogLevels(t *testing.T) {
+// TestClientFlattenMetadata tests flattening nested metadata maps.
+func TestClientFlattenMetadata(t *testing.T) {
 	ts := newTestServer()
 	defer ts.Close()
 
-	client, err := New(ts.URL, validAPIKey(), WithBatchSize(1))
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
-	}
-	defer client.Shutdown(context.Background())
+	log := setupAndLogWithMetadata(t, ts,
+		[]Option{WithBatchSize(1), WithFlattenMetadata("."), WithMetadata(M{"app": M{"version": "1.0"}})},
+		"flatten",
+		M{
+			"user": map[string]any{
+				"id":      "u-1",
+				"address": map[string]any{"city": "Paris"},
+			},
+			"tags":   []any{"a", "b"},
+			"status": 200,
+		},
+	)
 
-	testCases := []struct {
-		name    string
-		logFn   func(string, ...map[string]any)
-		level   LogLevel
-		message string
-	}{
-		{"Debug", client.Debug, LevelDebug, "debug message"},
-		{"Info", client.Info, LevelInfo, "info message"},
-		{"Warn", client.Warn, LevelWarn, "warn message"},
-		{"Error", client.Error, LevelError, "error message"},
-		{"Fatal", client.Fatal, LevelFatal, "fatal message"},
+	assertLogMetadata(t, log, map[string]string{
+		"user.id":           "u-1",
+		"user.address.city": "Paris",
+		"app.version":       "1.0",
+	})
+	if _, ok := log.Metadata["user"]; ok {
+		t.Error("nested key user should have been flattened")
 	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			// Clear previous logs
-			ts.mu.Lock()
-			ts.logs = ts.logs[:0]
-			ts.mu.Unlock()
-
-			tc.logFn(tc.message)
-
-			// Wait for flush (batch size 1 triggers immediate flush)
-			time.Sleep(50 * time.Millisecond)
-
-			logs := ts.getLogs()
-			if len(logs) == 0 {
-				t.Fatal("expected 1 log, got 0")
-			}
-
-			lastLog := logs[len(logs)-1]
-			if lastLog.Level != tc.level {
-				t.Errorf("Level = %q, want %q", lastLog.Level, tc.level)
-			}
-			if lastLog.Message != tc.message {
-				t.Errorf("Message = %q, want %q", lastLog.Message, tc.message)
-			}
-		})
+	if tags, ok := log.Metadata["tags"].([]any); !ok || len(tags) != 2 {
+		t.Errorf("Metadata[tags] = %v, want slice left intact", log.Metadata["tags"])
+	}
+	if log.Metadata["status"] != float64(200) {
+		t.Errorf("Metadata[status] = %v, want 200", log.Metadata["status"])
 	}
 }
 
-// TestClientMetadataMerging tests metadata merging from config and call.
-func TestClientMetadataMerging(t *testing.T) {
-	ts := newTestServer()
-	defer ts.Close()
-
-	t.Run("config metadata is included in logs", func(t *testing.T) {
-		client, err := New(
-			ts.URL,
-			validAPIKey(),
-			WithBatchSize(1),
-			WithMetadata(M{"env": "test", "version": "1.0"}),
-		)
-		if err != nil {
-			t.Fatalf("New() error = %v", err)
+// TestFlattenMetadata tests the flattening helper directly.
+func TestFlattenMetadata(t *testing.T) {
+	t.Run("custom separator", func(t *testing.T) {
+		got := flattenMetadata(map[string]any{"a": map[string]string{"b": "c"}}, "_")
+		if got["a_b"] != "c" {
+			t.Errorf("flattenMetadata() = %v, want a_b=c", got)
 		}
-		defer client.Shutdown(context.Background())
-
-		log := logAndWait(client, ts, client.Info, "test message")
-		assertLogMetadata(t, log, map[string]string{
-			"env":     "test",
-			"version": "1.0",
-		})
 	})
 
-	t.Run("call metadata merges with config metadata", func(t *testing.T) {
-		log := setupAndLogWithMetadata(t, ts,
-			[]Option{WithBatchSize(1), WithMetadata(M{"env": "test", "version": "1.0"})},
-			"test message",
-			M{"request_id": "abc123"},
-		)
+	t.Run("cycles are replaced", func(t *testing.T) {
+		cyclic := map[string]any{"name": "root"}
+		cyclic["self"] = cyclic
 
-		assertLogMetadata(t, log, map[string]string{
-			"env":        "test",
-			"version":    "1.0",
-			"request_id": "abc123",
-		})
+		got := flattenMetadata(cyclic, ".")
+		if got["name"] != "root" || got["self"] != circularValue {
+			t.Errorf("flattenMetadata() = %v, want self=%q", got, circularValue)
+		}
 	})
 
-	t.Run("call metadata overrides config metadata", func(t *testing.T) {
-		log := setupAndLogWithMetadata(t, ts,
-			[]Option{WithBatchSize(1), WithMetadata(M{"env": "test", "version": "1.0"})},
-			"test message",
-			M{"env": "production"},
-		)
+	t.Run("depth is capped", func(t *testing.T) {
+		deep := map[string]any{"leaf": true}
+		for i := 0; i < maxFlattenDepth+5; i++ {
+			deep = map[string]any{"n": deep}
+		}
 
-		assertLogMetadata(t, log, map[string]string{
-			"env":     "production",
-			"version": "1.0",
-		})
+		got := flattenMetadata(deep, ".")
+		if len(got) != 1 {
+			t.Fatalf("flattenMetadata() has %d keys, want 1", len(got))
+		}
+		key := strings.TrimSuffix(strings.Repeat("n.", maxFlattenDepth), ".")
+		if _, ok := got[key].(map[string]any); !ok {
+			t.Errorf("flattenMetadata() = %v, want nested map kept under %q", got, key)
+		}
 	})
 
-	t.Run("multiple metadata maps merge correctly", func(t *testing.T) {
-		log := setupAndLogWithMetadata(t, ts,
-			[]Option{WithBatchSize(1)},
-			"test",
-			M{"a": "1"},
-			M{"b": "2"},
-			M{"a": "3"},
-		)
-
-		assertLogMetadata(t, log, map[string]string{
-			"a": "3",
-			"b": "2",
-		})
+	t.Run("empty nested map kept as-is", func(t *testing.T) {
+		got := flattenMetadata(map[string]any{"empty": map[string]any{}}, ".")
+		if _, ok := got["empty"].(map[string]any); !ok {
+			t.Errorf("flattenMetadata() = %v, want empty map kept", got)
+		}
 	})
 }
 
-// TestClientBatchAutoFlush tests auto-flush when batch size is reached.
-func TestClientBatchAutoFlush(t *testing.T) {
+// TestClientMetadataIsolation tests that mutating caller maps does not affect logs.
+// Run with -race to detect aliasing between the caller's map and the config.
+func TestClientMetadataIsolation(t *testing.T) {
 	ts := newTestServer()
 	defer ts.Close()
 
-	batchSize := 5
-	client, err := New(
-		ts.URL,
-		validAPIKey(),
-		WithBatchSize(batchSize),
-		WithFlushInterval(1*time.Minute), // Long interval to avoid timer flush
-	)
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
+	defaults := map[string]any{"env": "test"}
+	childMeta := map[string]any{"component": "worker"}
+
+	client := createTestClient(t, ts, WithBatchSize(10), WithMetadata(defaults))
+	child := client.Child(ChildWithMetadata(childMeta))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			defaults["env"] = "mutated"
+			defaults[fmt.Sprintf("key-%d", i)] = i
+			childMeta["component"] = "mutated"
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			client.Info("parent log")
+			child.Info("child log")
+		}
+	}()
+	wg.Wait()
+
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
 	}
-	defer client.Shutdown(context.Background())
 
-	// Log exactly batch size entries
-	for i := 0; i < batchSize; i++ {
-		client.Info("message")
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 100)
+	for _, log := range logs {
+		if log.Metadata["env"] != "test" {
+			t.Fatalf("Metadata[env] = %v, want %q", log.Metadata["env"], "test")
+		}
+		if len(log.Metadata) > 2 {
+			t.Fatalf("Metadata = %v, want no keys added after configuration", log.Metadata)
+		}
+		if log.Message == "child log" && log.Metadata["component"] != "worker" {
+			t.Fatalf("Metadata[component] = %v, want %q", log.Metadata["component"], "worker")
+		}
 	}
+}
 
-	// Wait for flush
-	time.Sleep(100 * time.Millisecond)
+// TestClientCyclicMetadata tests that metadata containing itself is copied
+// with the back-reference replaced instead of recursing forever.
+func TestClientCyclicMetadata(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
 
-	logs := ts.getLogs()
-	if len(logs) != batchSize {
-		t.Errorf("received %d logs, want %d", len(logs), batchSize)
+	defaults := map[string]any{"env": "test"}
+	defaults["self"] = defaults
+	tags := []any{"a", nil}
+	tags[1] = tags
+	childMeta := map[string]any{"tags": tags}
+	childMeta["self"] = childMeta
+
+	client := createTestClient(t, ts, WithBatchSize(10), WithMetadata(defaults))
+	defer client.Shutdown(context.Background())
+	child := client.Child(ChildWithMetadata(childMeta))
+
+	child.Info("cyclic")
+
+	pending := client.Pending()
+	if len(pending) != 1 || pending[0].Metadata["self"] != circularValue {
+		t.Fatalf("Pending() = %+v, want one entry with self=%q", pending, circularValue)
 	}
 
-	// Verify exactly one request was made (all in one batch)
-	requests := ts.getRequests()
-	if len(requests) != 1 {
-		t.Errorf("received %d requests, want 1", len(requests))
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 1)
+	meta := logs[0].Metadata
+	if meta["self"] != circularValue || meta["env"] != "test" {
+		t.Errorf("Metadata = %v, want env and self=%q", meta, circularValue)
+	}
+	if got, _ := meta["tags"].([]any); len(got) != 2 || got[0] != "a" || got[1] != circularValue {
+		t.Errorf("Metadata[tags] = %v, want [a %s]", meta["tags"], circularValue)
 	}
 }
 
@@ -397,7 +412,7 @@ func TestClientShutdown(t *testing.T) {
 	// Verify new logs are not sent after shutdown
 	client.Info("should not be sent")
 
-	time.Sleep(50 * time.Millisecond)
+	waitForIdle(t, client)
 
 	logs = ts.getLogs()
 	if len(logs) != 2 {
@@ -405,609 +420,3895 @@ func TestClientShutdown(t *testing.T) {
 	}
 }
 
-// TestClientShutdownIdempotent tests that multiple shutdowns are safe.
-func TestClientShutdownIdempotent(t *testing.T) {
+// TestClientShutdownChunkedDrain tests that Shutdown makes partial progress under a deadline.
+func TestClientShutdownChunkedDrain(t *testing.T) {
 	ts := newTestServer()
 	defer ts.Close()
 
-	client, err := New(ts.URL, validAPIKey())
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
+	var received int32
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		var req ingestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&received, int32(len(req.Logs)))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	})
+
+	var flushed int32
+	client := createTestClient(t, ts,
+		WithBatchSize(100),
+		WithMaxRetries(0),
+		WithOnFlush(func(n int) { atomic.AddInt32(&flushed, int32(n)) }),
+	)
+
+	// Queue a large backlog directly so no auto-flush happens first
+	backlog := make([]LogEntry, 1000)
+	for i := range backlog {
+		backlog[i] = LogEntry{Level: LevelInfo, Message: "backlog", Timestamp: now()}
 	}
+	client.queue.addAll(backlog)
 
-	// First shutdown
-	err = client.Shutdown(context.Background())
-	if err != nil {
-		t.Fatalf("first Shutdown() error = %v", err)
+	ctx, cancel := context.WithTimeout(context.Background(), 275*time.Millisecond)
+	defer cancel()
+
+	err := client.Shutdown(ctx)
+	if err == nil {
+		t.Fatal("Shutdown() error = nil, want error reporting unsent logs")
+	}
+	if !strings.Contains(err.Error(), "logs unsent") {
+		t.Errorf("Shutdown() error = %v, want remainder report", err)
 	}
 
-	// Second shutdown should not error
-	err = client.Shutdown(context.Background())
-	if err != nil {
-		t.Fatalf("second Shutdown() error = %v", err)
+	// The server may still count a batch whose response arrived after the deadline
+	sent := atomic.LoadInt32(&flushed)
+	if sent == 0 || sent >= 1000 {
+		t.Errorf("delivered %d logs, want partial delivery", sent)
+	}
+	if sent%100 != 0 {
+		t.Errorf("delivered %d logs, want whole batches of 100", sent)
+	}
+	if got := atomic.LoadInt32(&received); got < sent {
+		t.Errorf("server received %d logs, want at least %d", got, sent)
 	}
 }
 
-// TestClientChild tests child logger creation and inheritance.
-func TestClientChild(t *testing.T) {
-	ts := newTestServer()
-	defer ts.Close()
-
-	parent, err := New(
-		ts.URL,
-		validAPIKey(),
-		WithBatchSize(1),
-		WithService("parent-service"),
-		WithMetadata(M{"env": "test", "parent_key": "parent_value"}),
-	)
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
+// TestClientDrainLargeQueue tests that a large backlog is sent in BatchSize chunks.
+func TestClientDrainLargeQueue(t *testing.T) {
+	backlog := make([]LogEntry, 5000)
+	for i := range backlog {
+		backlog[i] = LogEntry{Level: LevelInfo, Message: "backlog", Timestamp: now()}
 	}
-	defer parent.Shutdown(context.Background())
 
-	t.Run("child inherits parent service", func(t *testing.T) {
-		log := childLogHelper(t, parent, ts, nil, "child message")
-		if log.Service != "parent-service" {
-			t.Errorf("Service = %q, want %q", log.Service, "parent-service")
+	t.Run("Shutdown", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts, WithBatchSize(500), WithMaxQueueSize(10000))
+		client.queue.addAll(backlog)
+
+		if err := client.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
 		}
-	})
 
-	t.Run("child can override service", func(t *testing.T) {
-		log := childLogHelper(t, parent, ts, []ChildOption{ChildWithService("child-service")}, "child message")
-		if log.Service != "child-service" {
-			t.Errorf("Service = %q, want %q", log.Service, "child-service")
+		requests := ts.getRequests()
+		if len(requests) != 10 {
+			t.Errorf("request count = %d, want 10", len(requests))
+		}
+		for i, req := range requests {
+			if len(req.Logs) != 500 {
+				t.Errorf("requests[%d] has %d logs, want 500", i, len(req.Logs))
+			}
 		}
+		assertLogCount(t, ts.getLogs(), 5000)
 	})
 
-	t.Run("child inherits parent metadata", func(t *testing.T) {
-		log := childLogHelper(t, parent, ts, nil, "child message")
-		assertLogMetadata(t, log, map[string]string{
-			"env":        "test",
-			"parent_key": "parent_value",
-		})
+	t.Run("Flush", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts, WithBatchSize(300), WithMaxQueueSize(10000))
+		defer client.Shutdown(context.Background())
+		client.queue.addAll(backlog[:1000])
+
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+
+		requests := ts.getRequests()
+		if len(requests) != 4 {
+			t.Fatalf("request count = %d, want 4", len(requests))
+		}
+		if len(requests[3].Logs) != 100 {
+			t.Errorf("last request has %d logs, want 100", len(requests[3].Logs))
+		}
 	})
 
-	t.Run("child metadata merges with parent", func(t *testing.T) {
-		log := childLogHelper(t, parent, ts, []ChildOption{ChildWithMetadata(M{"child_key": "child_value"})}, "child message")
-		assertLogMetadata(t, log, map[string]string{
-			"env":        "test",
-			"parent_key": "parent_value",
-			"child_key":  "child_value",
-		})
+	t.Run("unsent count reported when context ends", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts, WithBatchSize(500), WithMaxQueueSize(10000))
+		client.queue.addAll(backlog)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := client.Shutdown(ctx)
+		if err == nil || !strings.Contains(err.Error(), "5000 logs unsent") {
+			t.Errorf("Shutdown() error = %v, want report of 5000 unsent logs", err)
+		}
 	})
+}
 
-	t.Run("child metadata overrides parent", func(t *testing.T) {
-		log := childLogHelper(t, parent, ts, []ChildOption{ChildWithMetadata(M{"env": "production"})}, "child message")
-		assertLogMetadata(t, log, map[string]string{
-			"env":        "production",
-			"parent_key": "parent_value",
+// TestClientShutdownWithStats tests drain statistics reported by ShutdownWithStats.
+func TestClientShutdownWithStats(t *testing.T) {
+	t.Run("partially failing drain", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		var requestCount int32
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			var req ingestRequest
+			json.NewDecoder(r.Body).Decode(&req)
+
+			// Reject the second batch with a non-retryable error
+			if atomic.AddInt32(&requestCount, 1) == 2 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "bad batch"})
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
 		})
-	})
 
-	t.Run("child shares parent queue", func(t *testing.T) {
-		child := parent.Child()
-		if child.queue != parent.queue {
-			t.Error("child queue is not same as parent queue")
+		client := createTestClient(t, ts, WithBatchSize(2), WithMaxQueueSize(6))
+
+		// Queue 8 entries directly: 2 overflow, 6 remain for 3 batches
+		backlog := make([]LogEntry, 8)
+		for i := range backlog {
+			backlog[i] = LogEntry{Level: LevelInfo, Message: "backlog", Timestamp: now()}
+		}
+		client.queue.addAll(backlog)
+
+		stats, err := client.ShutdownWithStats(context.Background())
+		if err == nil {
+			t.Error("ShutdownWithStats() error = nil, want rejected batch error")
+		}
+
+		if stats.FlushedEntries != 4 {
+			t.Errorf("FlushedEntries = %d, want 4", stats.FlushedEntries)
+		}
+		if stats.FailedEntries != 2 {
+			t.Errorf("FailedEntries = %d, want 2", stats.FailedEntries)
+		}
+		if stats.DroppedEntries != 2 {
+			t.Errorf("DroppedEntries = %d, want 2", stats.DroppedEntries)
+		}
+		if stats.Elapsed <= 0 {
+			t.Errorf("Elapsed = %v, want > 0", stats.Elapsed)
 		}
 	})
 
-	t.Run("child shutdown does not affect parent", func(t *testing.T) {
-		child := parent.Child()
-		err := child.Shutdown(context.Background())
+	t.Run("includes child queues", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts, WithBatchSize(10))
+		child := client.Child(ChildWithQueue(WithBatchSize(100)))
+
+		client.Info("parent")
+		child.Info("child 1")
+		child.Info("child 2")
+
+		stats, err := client.ShutdownWithStats(context.Background())
 		if err != nil {
-			t.Fatalf("child Shutdown() error = %v", err)
+			t.Fatalf("ShutdownWithStats() error = %v", err)
 		}
+		if stats.FlushedEntries != 3 {
+			t.Errorf("FlushedEntries = %d, want 3", stats.FlushedEntries)
+		}
+	})
 
-		child.Info("should be dropped")
+	t.Run("second call returns zero stats", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
 
-		clearTestLogs(ts)
-		parent.Info("parent after child shutdown")
-		time.Sleep(50 * time.Millisecond)
+		client := createTestClient(t, ts)
+		client.Info("log")
+		client.Shutdown(context.Background())
 
-		logs := ts.getLogs()
-		assertLogCount(t, logs, 1)
+		stats, err := client.ShutdownWithStats(context.Background())
+		if err != nil {
+			t.Errorf("ShutdownWithStats() error = %v, want nil", err)
+		}
+		if stats != (ShutdownStats{}) {
+			t.Errorf("stats = %+v, want zero", stats)
+		}
 	})
 }
 
-// TestClientOnErrorCallback tests the OnError callback.
-func TestClientOnErrorCallback(t *testing.T) {
-	var errorReceived *Error
-	var errorMu sync.Mutex
-
+// TestClientShutdownIdempotent tests that multiple shutdowns are safe.
+func TestClientShutdownIdempotent(t *testing.T) {
 	ts := newTestServer()
 	defer ts.Close()
 
-	// Set handler to always return 500
-	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "test error"})
-	})
-
-	client, err := New(
-		ts.URL,
-		validAPIKey(),
-		WithBatchSize(1),
-		WithMaxRetries(0), // No retries to speed up test
-		WithOnError(func(e *Error) {
-			errorMu.Lock()
-			errorReceived = e
-			errorMu.Unlock()
-		}),
-	)
+	client, err := New(ts.URL, validAPIKey())
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
-	defer client.Shutdown(context.Background())
 
-	client.Info("trigger error")
-	time.Sleep(100 * time.Millisecond)
+	// First shutdown
+	err = client.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("first Shutdown() error = %v", err)
+	}
 
-	errorMu.Lock()
-	defer errorMu.Unlock()
-
-	if errorReceived == nil {
-		t.Fatal("OnError callback was not called")
-	}
-	if errorReceived.Code != ErrServerError {
-		t.Errorf("error code = %q, want %q", errorReceived.Code, ErrServerError)
+	// Second shutdown should not error
+	err = client.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("second Shutdown() error = %v", err)
 	}
 }
 
-// TestClientOnFlushCallback tests the OnFlush callback.
-func TestClientOnFlushCallback(t *testing.T) {
-	var flushCount int32
+// TestClientOnShutdown tests that the OnShutdown callback runs once, after
+// the final flush, with Shutdown's context, and even when the flush fails.
+func TestClientOnShutdown(t *testing.T) {
+	type ctxKey struct{}
+
+	t.Run("after delivery", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		var calls int32
+		var logsAtCall int
+		var gotValue any
+		client := createTestClient(t, ts, WithOnShutdown(func(ctx context.Context) {
+			atomic.AddInt32(&calls, 1)
+			logsAtCall = len(ts.getLogs())
+			gotValue = ctx.Value(ctxKey{})
+		}))
+
+		client.Info("last words")
+		ctx := context.WithValue(context.Background(), ctxKey{}, "shutdown")
+		if err := client.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+		client.Shutdown(ctx)
+		client.Close()
+
+		if got := atomic.LoadInt32(&calls); got != 1 {
+			t.Errorf("OnShutdown calls = %d, want 1", got)
+		}
+		if logsAtCall != 1 {
+			t.Errorf("server had %d logs when OnShutdown ran, want 1", logsAtCall)
+		}
+		if gotValue != "shutdown" {
+			t.Errorf("OnShutdown context value = %v, want Shutdown's context", gotValue)
+		}
+	})
+
+	t.Run("after failed flush", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		})
+
+		var calls int32
+		client := createTestClient(t, ts, WithOnShutdown(func(ctx context.Context) {
+			atomic.AddInt32(&calls, 1)
+		}))
+		child := client.Child()
+
+		client.Info("rejected")
+		if err := client.Shutdown(context.Background()); err == nil {
+			t.Fatal("Shutdown() error = nil, want validation error")
+		}
+		child.Shutdown(context.Background())
+		if got := atomic.LoadInt32(&calls); got != 1 {
+			t.Errorf("OnShutdown calls = %d, want 1", got)
+		}
+	})
+}
 
+// TestClientOnShutdownStats tests that the OnShutdownStats callback runs
+// once, after the final flush, with the stats ShutdownWithStats returns.
+func TestClientOnShutdownStats(t *testing.T) {
 	ts := newTestServer()
 	defer ts.Close()
 
-	client, err := New(
-		ts.URL,
-		validAPIKey(),
-		WithBatchSize(3),
-		WithOnFlush(func(count int) {
-			atomic.StoreInt32(&flushCount, int32(count))
+	var (
+		mu     sync.Mutex
+		events []string
+		got    ShutdownStats
+	)
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	var client *Client
+	client = createTestClient(t, ts,
+		WithBatchSize(100),
+		WithOnFlush(func(n int) { record(fmt.Sprintf("flush %d", n)) }),
+		WithOnShutdownStats(func(stats ShutdownStats) {
+			// Calling back into the client must not deadlock
+			client.Info("after shutdown")
+			client.Stats()
+			got = stats
+			record("shutdown")
 		}),
 	)
+
+	client.Info("one")
+	client.Info("two")
+	want, err := client.ShutdownWithStats(context.Background())
 	if err != nil {
-		t.Fatalf("New() error = %v", err)
+		t.Fatalf("ShutdownWithStats() error = %v", err)
 	}
-	defer client.Shutdown(context.Background())
+	client.Shutdown(context.Background())
 
-	// Log exactly batch size to trigger flush
-	client.Info("message 1")
-	client.Info("message 2")
-	client.Info("message 3")
-
-	time.Sleep(100 * time.Millisecond)
-
-	count := atomic.LoadInt32(&flushCount)
-	if count != 3 {
-		t.Errorf("OnFlush received count = %d, want 3", count)
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(events) != "[flush 2 shutdown]" {
+		t.Errorf("events = %v, want the final flush before shutdown, once", events)
+	}
+	if got.FlushedEntries != 2 || got != want {
+		t.Errorf("OnShutdownStats stats = %+v, want %+v with 2 flushed", got, want)
 	}
 }
 
-// TestClientSourceLocation tests source location capture.
-func TestClientSourceLocation(t *testing.T) {
-	ts := newTestServer()
-	defer ts.Close()
-
-	t.Run("source location disabled by default", func(t *testing.T) {
-		client, err := New(ts.URL, validAPIKey(), WithBatchSize(1))
-		if err != nil {
-			t.Fatalf("New() error = %v", err)
+// TestClientShutdownInterruptsRetries tests that Shutdown ends background
+// retry backoffs and makes the final attempt itself within its deadline.
+func TestClientShutdownInterruptsRetries(t *testing.T) {
+	// startRetrying logs one entry against a failing server and returns
+	// once the background flush is waiting before its third retry.
+	startRetrying := func(t *testing.T, ts *testServer, opts ...Option) *Client {
+		t.Helper()
+
+		retrying := make(chan struct{})
+		var once sync.Once
+		opts = append(opts,
+			WithBatchSize(1),
+			WithOnRetry(func(info RetryInfo) {
+				if info.Attempt == 3 {
+					once.Do(func() { close(retrying) })
+				}
+			}),
+		)
+		client := createTestClient(t, ts, opts...)
+		client.transport.maxRetries = 10
+
+		go client.Info("retried")
+		select {
+		case <-retrying:
+		case <-time.After(5 * time.Second):
+			t.Fatal("flush did not reach the third retry")
 		}
-		defer client.Shutdown(context.Background())
+		return client
+	}
 
-		ts.mu.Lock()
-		ts.logs = ts.logs[:0]
-		ts.mu.Unlock()
+	t.Run("no attempts after Shutdown returns", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
 
-		client.Info("test message")
-		time.Sleep(50 * time.Millisecond)
+		var attempts int32
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
 
-		logs := ts.getLogs()
-		if len(logs) == 0 {
-			t.Fatal("expected at least 1 log")
-		}
+		var dropped int32
+		client := startRetrying(t, ts, WithOnDrop(func(n int) { atomic.AddInt32(&dropped, int32(n)) }))
+		before := atomic.LoadInt32(&attempts)
 
-		lastLog := logs[len(logs)-1]
-		if lastLog.SourceFile != "" {
-			t.Errorf("SourceFile = %q, want empty when disabled", lastLog.SourceFile)
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		stats, err := client.ShutdownWithStats(ctx)
+		elapsed := time.Since(start)
+
+		if err == nil {
+			t.Error("ShutdownWithStats() error = nil, want unsent logs error")
 		}
-		if lastLog.LineNumber != 0 {
-			t.Errorf("LineNumber = %d, want 0 when disabled", lastLog.LineNumber)
+		// The interrupted backoff was at least 560ms
+		if elapsed >= 500*time.Millisecond {
+			t.Errorf("ShutdownWithStats() took %v, want it bounded by the 300ms deadline", elapsed)
+		}
+		after := atomic.LoadInt32(&attempts)
+		if after == before {
+			t.Error("Shutdown made no final attempt")
+		}
+		if stats.DroppedEntries != 1 {
+			t.Errorf("DroppedEntries = %d, want 1", stats.DroppedEntries)
+		}
+		if got := atomic.LoadInt32(&dropped); got != 1 {
+			t.Errorf("OnDrop count = %d, want 1", got)
+		}
+
+		// Outlast the interrupted backoff; a background retry would show here
+		time.Sleep(time.Second)
+		if got := atomic.LoadInt32(&attempts); got != after {
+			t.Errorf("%d attempts after Shutdown returned, want 0", got-after)
 		}
 	})
 
-	t.Run("source location captured when enabled", func(t *testing.T) {
-		client, err := New(
-			ts.URL,
-			validAPIKey(),
-			WithBatchSize(1),
-			WithCaptureSourceLocation(true),
-		)
+	t.Run("final attempt delivers the batch", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		var healthy atomic.Bool
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			var req ingestRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+		})
+
+		client := startRetrying(t, ts)
+		healthy.Store(true)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		stats, err := client.ShutdownWithStats(ctx)
 		if err != nil {
-			t.Fatalf("New() error = %v", err)
+			t.Fatalf("ShutdownWithStats() error = %v", err)
 		}
-		defer client.Shutdown(context.Background())
+		if stats.FlushedEntries != 1 {
+			t.Errorf("FlushedEntries = %d, want 1", stats.FlushedEntries)
+		}
+	})
+}
 
-		ts.mu.Lock()
-		ts.logs = ts.logs[:0]
-		ts.mu.Unlock()
+// TestClientNilContext tests that Flush and Shutdown accept a nil context.
+func TestClientNilContext(t *testing.T) {
+	t.Run("Shutdown with nil context delivers queued logs", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
 
-		client.Info("test message") // This line number matters
-		time.Sleep(50 * time.Millisecond)
+		client := createTestClient(t, ts, WithBatchSize(100))
+		client.Info("queued 1")
+		client.Info("queued 2")
 
-		logs := ts.getLogs()
-		if len(logs) == 0 {
-			t.Fatal("expected at least 1 log")
+		if err := client.Shutdown(nil); err != nil {
+			t.Fatalf("Shutdown(nil) error = %v", err)
 		}
 
-		lastLog := logs[len(logs)-1]
-		if lastLog.SourceFile == "" {
-			t.Error("SourceFile is empty when enabled")
-		}
-		if !strings.HasSuffix(lastLog.SourceFile, "_test.go") {
-			t.Errorf("SourceFile = %q, expected to end with _test.go", lastLog.SourceFile)
-		}
-		if lastLog.LineNumber == 0 {
-			t.Error("LineNumber = 0 when enabled")
+		assertLogCount(t, ts.getLogs(), 2)
+	})
+
+	t.Run("Flush with nil context delivers queued logs", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts, WithBatchSize(100))
+		defer client.Shutdown(context.Background())
+
+		client.Info("queued")
+
+		if err := client.Flush(nil); err != nil {
+			t.Fatalf("Flush(nil) error = %v", err)
 		}
+
+		assertLogCount(t, ts.getLogs(), 1)
 	})
 }
 
-// TestClientContextCancellation tests context cancellation during flush.
-func TestClientContextCancellation(t *testing.T) {
-	ts := newTestServer()
-	defer ts.Close()
+// TestClientClose tests the io.Closer implementation.
+func TestClientClose(t *testing.T) {
+	t.Run("implements io.Closer", func(t *testing.T) {
+		var _ io.Closer = (*Client)(nil)
+	})
 
-	// Set handler to delay response
-	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
-		time.Sleep(500 * time.Millisecond)
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+	t.Run("flushes remaining logs", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts, WithBatchSize(100), WithFlushInterval(60*time.Second))
+		client.Info("first")
+		client.Info("second")
+
+		if err := client.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		assertLogCount(t, ts.getLogs(), 2)
 	})
 
-	client, err := New(
-		ts.URL,
-		validAPIKey(),
-		WithBatchSize(100),
-	)
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
-	}
-	defer client.Shutdown(context.Background())
+	t.Run("timeout bounds a hanging server", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
 
-	client.Info("test message")
+		release := make(chan struct{})
+		defer close(release)
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		})
 
-	// Create context with short timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
-	defer cancel()
+		client := createTestClient(t, ts, WithBatchSize(100), WithShutdownTimeout(200*time.Millisecond))
+		client.Info("never delivered")
 
-	err = client.Flush(ctx)
-	if err == nil {
-		t.Fatal("Flush() expected error for context timeout")
-	}
+		start := time.Now()
+		err := client.Close()
+		elapsed := time.Since(start)
 
-	// Verify error is context-related
-	logwellErr, ok := err.(*Error)
-	if !ok {
-		t.Fatalf("error type = %T, want *Error", err)
-	}
-	if logwellErr.Code != ErrNetworkError {
-		t.Errorf("error code = %q, want %q", logwellErr.Code, ErrNetworkError)
-	}
+		if err == nil {
+			t.Error("Close() error = nil, want timeout error")
+		}
+		if elapsed > 2*time.Second {
+			t.Errorf("Close() took %v, want bounded by shutdown timeout", elapsed)
+		}
+	})
+
+	t.Run("Close after Shutdown is a no-op", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts)
+		if err := client.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+		if err := client.Close(); err != nil {
+			t.Errorf("Close() error = %v, want nil", err)
+		}
+		if err := client.Close(); err != nil {
+			t.Errorf("second Close() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("invalid shutdown timeout returns error", func(t *testing.T) {
+		_, err := New(validEndpoint(), validAPIKey(), WithShutdownTimeout(0))
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
 }
 
-// TestClientLogEntry tests the generic Log() method.
-func TestClientLogEntry(t *testing.T) {
+// TestClientChild tests child logger creation and inheritance.
+func TestClientChild(t *testing.T) {
 	ts := newTestServer()
 	defer ts.Close()
 
-	client, err := New(
+	parent, err := New(
 		ts.URL,
 		validAPIKey(),
 		WithBatchSize(1),
-		WithService("default-service"),
-		WithMetadata(M{"default_key": "default_value"}),
+		WithService("parent-service"),
+		WithMetadata(M{"env": "test", "parent_key": "parent_value"}),
 	)
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
-	defer client.Shutdown(context.Background())
-
-	t.Run("Log with full entry", func(t *testing.T) {
-		ts.mu.Lock()
-		ts.logs = ts.logs[:0]
-		ts.mu.Unlock()
-
-		entry := LogEntry{
-			Level:     LevelWarn,
-			Message:   "custom entry",
-			Service:   "custom-service",
-			Metadata:  M{"custom_key": "custom_value"},
-			Timestamp: "2024-01-01T00:00:00Z",
-		}
-		client.Log(entry)
-		time.Sleep(50 * time.Millisecond)
+	defer parent.Shutdown(context.Background())
 
-		logs := ts.getLogs()
-		if len(logs) == 0 {
-			t.Fatal("expected at least 1 log")
+	t.Run("child inherits parent service", func(t *testing.T) {
+		log := childLogHelper(t, parent, ts, nil, "child message")
+		if log.Service != "parent-service" {
+			t.Errorf("Service = %q, want %q", log.Service, "parent-service")
 		}
+	})
 
-		lastLog := logs[len(logs)-1]
-		if lastLog.Level != LevelWarn {
-			t.Errorf("Level = %q, want %q", lastLog.Level, LevelWarn)
-		}
-		if lastLog.Message != "custom entry" {
-			t.Errorf("Message = %q, want %q", lastLog.Message, "custom entry")
-		}
-		if lastLog.Service != "custom-service" {
-			t.Errorf("Service = %q, want %q", lastLog.Service, "custom-service")
-		}
-		if lastLog.Timestamp != "2024-01-01T00:00:00Z" {
-			t.Errorf("Timestamp = %q, want %q", lastLog.Timestamp, "2024-01-01T00:00:00Z")
+	t.Run("child can override service", func(t *testing.T) {
+		log := childLogHelper(t, parent, ts, []ChildOption{ChildWithService("child-service")}, "child message")
+		if log.Service != "child-service" {
+			t.Errorf("Service = %q, want %q", log.Service, "child-service")
 		}
 	})
 
-	t.Run("Log uses defaults for empty fields", func(t *testing.T) {
-		ts.mu.Lock()
-		ts.logs = ts.logs[:0]
+	t.Run("child inherits parent metadata", func(t *testing.T) {
+		log := childLogHelper(t, parent, ts, nil, "child message")
+		assertLogMetadata(t, log, map[string]string{
+			"env":        "test",
+			"parent_key": "parent_value",
+		})
+	})
+
+	t.Run("child metadata merges with parent", func(t *testing.T) {
+		log := childLogHelper(t, parent, ts, []ChildOption{ChildWithMetadata(M{"child_key": "child_value"})}, "child message")
+		assertLogMetadata(t, log, map[string]string{
+			"env":        "test",
+			"parent_key": "parent_value",
+			"child_key":  "child_value",
+		})
+	})
+
+	t.Run("child metadata overrides parent", func(t *testing.T) {
+		log := childLogHelper(t, parent, ts, []ChildOption{ChildWithMetadata(M{"env": "production"})}, "child message")
+		assertLogMetadata(t, log, map[string]string{
+			"env":        "production",
+			"parent_key": "parent_value",
+		})
+	})
+
+	t.Run("child shares parent queue", func(t *testing.T) {
+		child := parent.Child()
+		if child.queue != parent.queue {
+			t.Error("child queue is not same as parent queue")
+		}
+	})
+
+	t.Run("child shutdown does not affect parent", func(t *testing.T) {
+		child := parent.Child()
+		err := child.Shutdown(context.Background())
+		if err != nil {
+			t.Fatalf("child Shutdown() error = %v", err)
+		}
+
+		child.Info("should be dropped")
+
+		clearTestLogs(ts)
+		parent.Info("parent after child shutdown")
+		waitForIdle(t, parent)
+
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 1)
+	})
+}
+
+// TestClientChildMetadataRemoval tests removing inherited metadata from child loggers.
+func TestClientChildMetadataRemoval(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	parent := createTestClient(t, ts,
+		WithBatchSize(1),
+		WithMetadata(M{"env": "test", "userId": "user-1"}),
+	)
+	defer parent.Shutdown(context.Background())
+
+	t.Run("ChildWithoutMetadata removes inherited key", func(t *testing.T) {
+		child := parent.Child(ChildWithoutMetadata("userId"))
+		log := logAndWait(t, child, ts, child.Info, "maintenance", M{"task": "vacuum"})
+
+		if _, ok := log.Metadata["userId"]; ok {
+			t.Error("removed key userId reappeared in metadata")
+		}
+		assertLogMetadata(t, log, map[string]string{"env": "test", "task": "vacuum"})
+	})
+
+	t.Run("ChildFreshMetadata discards all inherited metadata", func(t *testing.T) {
+		child := parent.Child(ChildFreshMetadata(), ChildWithMetadata(M{"component": "cron"}))
+		log := logAndWait(t, child, ts, child.Info, "fresh")
+
+		if len(log.Metadata) != 1 || log.Metadata["component"] != "cron" {
+			t.Errorf("Metadata = %v, want only component=cron", log.Metadata)
+		}
+	})
+
+	t.Run("options apply in order", func(t *testing.T) {
+		child := parent.Child(
+			ChildWithMetadata(M{"requestId": "abc"}),
+			ChildWithoutMetadata("requestId", "env"),
+			ChildWithMetadata(M{"env": "staging"}),
+		)
+
+		if _, ok := child.config.Metadata["requestId"]; ok {
+			t.Error("requestId should have been removed")
+		}
+		if child.config.Metadata["env"] != "staging" {
+			t.Errorf("Metadata[env] = %v, want %q", child.config.Metadata["env"], "staging")
+		}
+		if child.config.Metadata["userId"] != "user-1" {
+			t.Errorf("Metadata[userId] = %v, want %q", child.config.Metadata["userId"], "user-1")
+		}
+
+		// Fresh metadata after additions drops them too
+		fresh := parent.Child(ChildWithMetadata(M{"a": 1}), ChildFreshMetadata())
+		if fresh.config.Metadata != nil {
+			t.Errorf("Metadata = %v, want nil", fresh.config.Metadata)
+		}
+	})
+
+	t.Run("grandchild does not regain removed key", func(t *testing.T) {
+		child := parent.Child(ChildWithoutMetadata("userId"))
+		grandchild := child.Child(ChildWithMetadata(M{"step": "2"}))
+
+		if _, ok := grandchild.config.Metadata["userId"]; ok {
+			t.Error("removed key userId reappeared in grandchild metadata")
+		}
+	})
+
+	t.Run("parent is unaffected", func(t *testing.T) {
+		parent.Child(ChildFreshMetadata())
+		parent.Child(ChildWithoutMetadata("env", "userId"))
+
+		log := logAndWait(t, parent, ts, parent.Info, "parent")
+		assertLogMetadata(t, log, map[string]string{"env": "test", "userId": "user-1"})
+	})
+}
+
+// TestClientChildWithTrace tests tagging a child logger with trace context.
+func TestClientChildWithTrace(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	parent := createTestClient(t, ts, WithBatchSize(1), WithMetadata(M{"env": "test"}))
+	defer parent.Shutdown(context.Background())
+
+	t.Run("sets traceId and spanId", func(t *testing.T) {
+		child := parent.Child(ChildWithTrace("4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"))
+		log := logAndWait(t, child, ts, child.Info, "traced")
+
+		assertLogMetadata(t, log, map[string]string{
+			"traceId": "4bf92f3577b34da6a3ce929d0e0e4736",
+			"spanId":  "00f067aa0ba902b7",
+			"env":     "test",
+		})
+	})
+
+	t.Run("composes with other child options", func(t *testing.T) {
+		child := parent.Child(
+			ChildWithService("api"),
+			ChildWithTrace("trace-1", "span-1"),
+			ChildWithMetadata(M{"spanId": "span-2"}),
+		)
+		log := logAndWait(t, child, ts, child.Info, "traced")
+
+		if log.Service != "api" {
+			t.Errorf("Service = %q, want %q", log.Service, "api")
+		}
+		assertLogMetadata(t, log, map[string]string{"traceId": "trace-1", "spanId": "span-2"})
+	})
+
+	t.Run("skips empty IDs", func(t *testing.T) {
+		child := parent.Child(ChildWithTrace("trace-1", ""))
+		if _, ok := child.config.Metadata["spanId"]; ok {
+			t.Error("empty spanId should not be set")
+		}
+	})
+}
+
+// TestClientChildLevel tests child loggers with independent minimum levels.
+func TestClientChildLevel(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	parent := createTestClient(t, ts, WithBatchSize(1), WithMinLevel(LevelInfo))
+	defer parent.Shutdown(context.Background())
+
+	payments := parent.Child(ChildWithService("payments"), ChildWithLevel(LevelDebug))
+	inherited := parent.Child(ChildWithService("inherited"))
+
+	t.Run("child debug arrives while parent debug is dropped", func(t *testing.T) {
+		clearTestLogs(ts)
+
+		parent.Debug("parent debug")
+		payments.Debug("payments debug")
+		waitForIdle(t, payments)
+
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 1)
+		if len(logs) == 1 && logs[0].Message != "payments debug" {
+			t.Errorf("Message = %q, want %q", logs[0].Message, "payments debug")
+		}
+	})
+
+	t.Run("grandchild inherits immediate parent level", func(t *testing.T) {
+		clearTestLogs(ts)
+
+		grandchild := payments.Child()
+		if grandchild.Level() != LevelDebug {
+			t.Errorf("grandchild Level() = %q, want %q", grandchild.Level(), LevelDebug)
+		}
+
+		grandchild.Debug("grandchild debug")
+		waitForIdle(t, grandchild)
+
+		assertLogCount(t, ts.getLogs(), 1)
+	})
+
+	t.Run("SetLevel on parent affects only inheriting children", func(t *testing.T) {
+		clearTestLogs(ts)
+
+		if err := parent.SetLevel(LevelError); err != nil {
+			t.Fatalf("SetLevel() error = %v", err)
+		}
+		defer parent.SetLevel(LevelInfo)
+
+		if inherited.Level() != LevelError {
+			t.Errorf("inherited Level() = %q, want %q", inherited.Level(), LevelError)
+		}
+		if payments.Level() != LevelDebug {
+			t.Errorf("payments Level() = %q, want %q", payments.Level(), LevelDebug)
+		}
+
+		parent.Warn("parent warn")
+		inherited.Warn("inherited warn")
+		payments.Debug("payments debug")
+		waitForIdle(t, payments)
+
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 1)
+		if len(logs) == 1 && logs[0].Service != "payments" {
+			t.Errorf("Service = %q, want %q", logs[0].Service, "payments")
+		}
+	})
+
+	t.Run("SetLevel on child does not affect parent", func(t *testing.T) {
+		child := parent.Child()
+		if err := child.SetLevel(LevelDebug); err != nil {
+			t.Fatalf("SetLevel() error = %v", err)
+		}
+
+		if parent.Level() != LevelInfo {
+			t.Errorf("parent Level() = %q, want %q", parent.Level(), LevelInfo)
+		}
+	})
+
+	t.Run("SetLevel rejects unknown level", func(t *testing.T) {
+		err := parent.SetLevel("verbose")
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("NewChild rejects unknown level", func(t *testing.T) {
+		_, err := parent.NewChild(ChildWithLevel("verbose"))
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("Child reports unknown level and inherits", func(t *testing.T) {
+		var reported []*Error
+		reporting := createTestClient(t, ts, WithMinLevel(LevelWarn), WithOnError(func(err *Error) {
+			reported = append(reported, err)
+		}))
+		defer reporting.Shutdown(context.Background())
+
+		child := reporting.Child(ChildWithLevel("verbose"))
+		if child.Level() != LevelWarn {
+			t.Errorf("child Level() = %q, want the parent's %q", child.Level(), LevelWarn)
+		}
+		if len(reported) != 1 || reported[0].Code != ErrInvalidConfig {
+			t.Errorf("OnError calls = %v, want one %s", reported, ErrInvalidConfig)
+		}
+	})
+}
+
+// TestClientChildQueue tests child loggers with independent queues.
+func TestClientChildQueue(t *testing.T) {
+	t.Run("child batches independently of parent", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		parent := createTestClient(t, ts, WithBatchSize(2), WithFlushInterval(10*time.Second))
+		defer parent.Shutdown(context.Background())
+
+		child := parent.Child(ChildWithQueue(WithBatchSize(5), WithFlushInterval(30*time.Second)))
+		if child.queue == parent.queue {
+			t.Fatal("child queue is same as parent queue")
+		}
+		if child.transport != parent.transport {
+			t.Error("child transport is not same as parent transport")
+		}
+		if child.config.BatchSize != 5 || child.config.FlushInterval != 30*time.Second {
+			t.Errorf("child BatchSize/FlushInterval = %d/%v, want 5/30s", child.config.BatchSize, child.config.FlushInterval)
+		}
+
+		for i := 0; i < 4; i++ {
+			child.Info("child log")
+		}
+
+		// Parent's batch size of 2 must not flush the child's 4 entries
+		assertLogCount(t, ts.getLogs(), 0)
+		if child.queue.size() != 4 {
+			t.Errorf("child queue size = %d, want 4", child.queue.size())
+		}
+
+		child.Info("child log")
+		waitForIdle(t, child)
+
+		assertLogCount(t, ts.getLogs(), 5)
+		if parent.queue.size() != 0 {
+			t.Errorf("parent queue size = %d, want 0", parent.queue.size())
+		}
+	})
+
+	t.Run("grandchild shares child queue", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		parent := createTestClient(t, ts)
+		defer parent.Shutdown(context.Background())
+
+		child := parent.Child(ChildWithQueue(WithBatchSize(100)))
+		grandchild := child.Child()
+		if grandchild.queue != child.queue {
+			t.Error("grandchild queue is not same as child queue")
+		}
+	})
+
+	t.Run("invalid queue options fall back to parent values", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		var reported []*Error
+		parent := createTestClient(t, ts, WithBatchSize(20), WithOnError(func(err *Error) {
+			reported = append(reported, err)
+		}))
+		defer parent.Shutdown(context.Background())
+
+		child := parent.Child(ChildWithQueue(WithBatchSize(0), WithFlushInterval(30*time.Second)))
+		if child.config.BatchSize != 20 {
+			t.Errorf("child BatchSize = %d, want 20", child.config.BatchSize)
+		}
+		if child.config.FlushInterval != 30*time.Second {
+			t.Errorf("child FlushInterval = %v, want the valid option applied", child.config.FlushInterval)
+		}
+		if len(reported) != 1 || reported[0].Code != ErrInvalidConfig {
+			t.Errorf("OnError calls = %v, want one %s", reported, ErrInvalidConfig)
+		}
+	})
+
+	t.Run("NewChild rejects invalid queue options", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		parent := createTestClient(t, ts)
+		defer parent.Shutdown(context.Background())
+
+		_, err := parent.NewChild(ChildWithQueue(WithMaxQueueSize(-1)))
+		assertConfigError(t, err, ErrInvalidConfig)
+		if len(parent.childQueues) != 0 {
+			t.Errorf("child queues = %d after a rejected child, want 0", len(parent.childQueues))
+		}
+
+		child, err := parent.NewChild(ChildWithQueue(WithBatchSize(50)))
+		if err != nil {
+			t.Fatalf("NewChild() error = %v", err)
+		}
+		if child.config.BatchSize != 50 || !child.ownsQueue {
+			t.Errorf("child BatchSize = %d, ownsQueue = %v, want 50 and its own queue", child.config.BatchSize, child.ownsQueue)
+		}
+	})
+
+	t.Run("parent shutdown drains child queue", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		parent := createTestClient(t, ts, WithBatchSize(10))
+		child := parent.Child(ChildWithQueue(WithBatchSize(500), WithFlushInterval(30*time.Second)))
+
+		parent.Info("parent pending")
+		child.Info("child pending 1")
+		child.Info("child pending 2")
+
+		if err := parent.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+
+		assertLogCount(t, ts.getLogs(), 3)
+
+		// Child is shut down along with its root
+		child.Info("after shutdown")
+		if child.queue.size() != 0 {
+			t.Errorf("child queue size after shutdown = %d, want 0", child.queue.size())
+		}
+	})
+
+	t.Run("child shutdown flushes own queue only", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		parent := createTestClient(t, ts, WithBatchSize(10))
+		defer parent.Shutdown(context.Background())
+
+		child := parent.Child(ChildWithQueue(WithBatchSize(500)))
+		parent.Info("parent pending")
+		child.Info("child pending")
+
+		if err := child.Shutdown(context.Background()); err != nil {
+			t.Fatalf("child Shutdown() error = %v", err)
+		}
+
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 1)
+		if len(logs) == 1 && logs[0].Message != "child pending" {
+			t.Errorf("Message = %q, want %q", logs[0].Message, "child pending")
+		}
+		if len(parent.childQueues) != 0 {
+			t.Errorf("len(childQueues) = %d, want 0", len(parent.childQueues))
+		}
+	})
+}
+
+// TestClientNamed tests the named logger registry.
+func TestClientNamed(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts, WithBatchSize(1), WithMinLevel(LevelInfo))
+	defer client.Shutdown(context.Background())
+
+	t.Run("returns cached instance", func(t *testing.T) {
+		first := client.Named("cache")
+		second := client.Named("cache", ChildWithService("ignored"))
+		if first != second {
+			t.Error("Named() returned different instances for the same name")
+		}
+		if second.config.Service == "ignored" {
+			t.Error("options on later calls should be ignored")
+		}
+	})
+
+	t.Run("attaches logger metadata", func(t *testing.T) {
+		logger := client.Named("payments.refunds")
+		log := logAndWait(t, logger, ts, logger.Info, "refund issued")
+		assertLogMetadata(t, log, map[string]string{"logger": "payments.refunds"})
+	})
+
+	t.Run("inherits options from parent names", func(t *testing.T) {
+		billing := client.Named("billing",
+			ChildWithService("billing-service"),
+			ChildWithLevel(LevelDebug),
+			ChildWithMetadata(M{"team": "billing"}),
+		)
+		invoices := client.Named("billing.invoices")
+
+		if invoices.levelParent != billing {
+			t.Error("billing.invoices is not a child of billing")
+		}
+
+		log := logAndWait(t, invoices, ts, invoices.Debug, "invoice rendered")
+		if log.Service != "billing-service" {
+			t.Errorf("Service = %q, want %q", log.Service, "billing-service")
+		}
+		assertLogMetadata(t, log, map[string]string{"team": "billing", "logger": "billing.invoices"})
+	})
+
+	t.Run("creates intermediate names", func(t *testing.T) {
+		deep := client.Named("a.b.c")
+		if client.Named("a.b") != deep.levelParent {
+			t.Error("a.b.c is not a child of the registered a.b")
+		}
+		if client.Named("a") != deep.levelParent.levelParent {
+			t.Error("a.b is not a child of the registered a")
+		}
+	})
+
+	t.Run("concurrent first use returns one instance", func(t *testing.T) {
+		const goroutines = 20
+		results := make([]*Client, goroutines)
+
+		var wg sync.WaitGroup
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = client.Named("concurrent.child")
+			}(i)
+		}
+		wg.Wait()
+
+		for i, result := range results {
+			if result != results[0] {
+				t.Fatalf("results[%d] differs from results[0]", i)
+			}
+		}
+	})
+
+	t.Run("empty name returns the client", func(t *testing.T) {
+		if client.Named("") != client {
+			t.Error("Named(\"\") should return the receiver")
+		}
+	})
+}
+
+// TestClientOnErrorCallback tests the OnError callback.
+func TestClientOnErrorCallback(t *testing.T) {
+	var errorReceived *Error
+	var errorMu sync.Mutex
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	// Set handler to always return 500
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "test error"})
+	})
+
+	client, err := New(
+		ts.URL,
+		validAPIKey(),
+		WithBatchSize(1),
+		WithMaxRetries(0), // No retries to speed up test
+		WithOnError(func(e *Error) {
+			errorMu.Lock()
+			errorReceived = e
+			errorMu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	client.Info("trigger error")
+	waitForIdle(t, client)
+
+	errorMu.Lock()
+	defer errorMu.Unlock()
+
+	if errorReceived == nil {
+		t.Fatal("OnError callback was not called")
+	}
+	if errorReceived.Code != ErrServerError {
+		t.Errorf("error code = %q, want %q", errorReceived.Code, ErrServerError)
+	}
+}
+
+// TestClientOnFlushCallback tests the OnFlush callback.
+func TestClientOnFlushCallback(t *testing.T) {
+	var flushCount int32
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	client, err := New(
+		ts.URL,
+		validAPIKey(),
+		WithBatchSize(3),
+		WithOnFlush(func(count int) {
+			atomic.StoreInt32(&flushCount, int32(count))
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	// Log exactly batch size to trigger flush
+	client.Info("message 1")
+	client.Info("message 2")
+	client.Info("message 3")
+
+	waitForIdle(t, client)
+
+	count := atomic.LoadInt32(&flushCount)
+	if count != 3 {
+		t.Errorf("OnFlush received count = %d, want 3", count)
+	}
+}
+
+// TestClientSourceLocation tests source location capture.
+func TestClientSourceLocation(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	t.Run("source location disabled by default", func(t *testing.T) {
+		client, err := New(ts.URL, validAPIKey(), WithBatchSize(1))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		ts.mu.Lock()
+		ts.logs = ts.logs[:0]
+		ts.mu.Unlock()
+
+		client.Info("test message")
+		waitForIdle(t, client)
+
+		logs := ts.getLogs()
+		if len(logs) == 0 {
+			t.Fatal("expected at least 1 log")
+		}
+
+		lastLog := logs[len(logs)-1]
+		if lastLog.SourceFile != "" {
+			t.Errorf("SourceFile = %q, want empty when disabled", lastLog.SourceFile)
+		}
+		if lastLog.LineNumber != 0 {
+			t.Errorf("LineNumber = %d, want 0 when disabled", lastLog.LineNumber)
+		}
+		if lastLog.FunctionName != "" {
+			t.Errorf("FunctionName = %q, want empty when disabled", lastLog.FunctionName)
+		}
+	})
+
+	t.Run("source location captured when enabled", func(t *testing.T) {
+		client, err := New(
+			ts.URL,
+			validAPIKey(),
+			WithBatchSize(1),
+			WithCaptureSourceLocation(true),
+		)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		ts.mu.Lock()
+		ts.logs = ts.logs[:0]
+		ts.mu.Unlock()
+
+		client.Info("test message") // This line number matters
+		waitForIdle(t, client)
+
+		logs := ts.getLogs()
+		if len(logs) == 0 {
+			t.Fatal("expected at least 1 log")
+		}
+
+		lastLog := logs[len(logs)-1]
+		if lastLog.SourceFile == "" {
+			t.Error("SourceFile is empty when enabled")
+		}
+		if !strings.HasSuffix(lastLog.SourceFile, "_test.go") {
+			t.Errorf("SourceFile = %q, expected to end with _test.go", lastLog.SourceFile)
+		}
+		if lastLog.LineNumber == 0 {
+			t.Error("LineNumber = 0 when enabled")
+		}
+	})
+
+	t.Run("function name captured when enabled", func(t *testing.T) {
+		client := createTestClient(t, ts, WithBatchSize(1), WithCaptureSourceLocation(true))
+		defer client.Shutdown(context.Background())
+
+		clearTestLogs(ts)
+		client.Warn("test message")
+
+		logs := ts.getLogs()
+		if len(logs) == 0 {
+			t.Fatal("expected at least 1 log")
+		}
+		// The log is emitted from this subtest's closure
+		got := logs[len(logs)-1].FunctionName
+		if !strings.HasPrefix(got, "logwell.TestClientSourceLocation.func") {
+			t.Errorf("FunctionName = %q, want logwell.TestClientSourceLocation.funcN", got)
+		}
+	})
+}
+
+// TestClientContextCancellation tests context cancellation during flush.
+func TestClientContextCancellation(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	// Set handler to delay response
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+	})
+
+	client, err := New(
+		ts.URL,
+		validAPIKey(),
+		WithBatchSize(100),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	client.Info("test message")
+
+	// Create context with short timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = client.Flush(ctx)
+	if err == nil {
+		t.Fatal("Flush() expected error for context timeout")
+	}
+
+	// Verify error is context-related
+	logwellErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("error type = %T, want *Error", err)
+	}
+	if logwellErr.Code != ErrNetworkError {
+		t.Errorf("error code = %q, want %q", logwellErr.Code, ErrNetworkError)
+	}
+}
+
+// TestClientContextDeadlinePropagation tests that a flush triggered by a
+// logging call is bounded by the earliest deadline of the batch's contexts.
+func TestClientContextDeadlinePropagation(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	errCh := make(chan *Error, 1)
+	client := createTestClient(t, ts,
+		WithBatchSize(2),
+		WithContextDeadlinePropagation(true),
+		WithOnError(func(err *Error) { errCh <- err }),
+	)
+	defer client.Shutdown(context.Background())
+
+	short, cancelShort := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelShort()
+	long, cancelLong := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelLong()
+
+	start := time.Now()
+	client.InfoContext(short, "short deadline")
+	client.InfoContext(long, "triggers the flush")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("triggered flush took %v, want it bounded by the 100ms deadline", elapsed)
+	}
+
+	select {
+	case err := <-errCh:
+		if err.Code != ErrNetworkError || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("OnError() = %v, want a network error caused by the deadline", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError() not called for the aborted flush")
+	}
+}
+
+// TestClientLogEntry tests the generic Log() method.
+func TestClientLogEntry(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client, err := New(
+		ts.URL,
+		validAPIKey(),
+		WithBatchSize(1),
+		WithService("default-service"),
+		WithMetadata(M{"default_key": "default_value"}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	t.Run("Log with full entry", func(t *testing.T) {
+		ts.mu.Lock()
+		ts.logs = ts.logs[:0]
+		ts.mu.Unlock()
+
+		entry := LogEntry{
+			Level:     LevelWarn,
+			Message:   "custom entry",
+			Service:   "custom-service",
+			Metadata:  M{"custom_key": "custom_value"},
+			Timestamp: "2024-01-01T00:00:00Z",
+		}
+		client.Log(entry)
+		waitForIdle(t, client)
+
+		logs := ts.getLogs()
+		if len(logs) == 0 {
+			t.Fatal("expected at least 1 log")
+		}
+
+		lastLog := logs[len(logs)-1]
+		if lastLog.Level != LevelWarn {
+			t.Errorf("Level = %q, want %q", lastLog.Level, LevelWarn)
+		}
+		if lastLog.Message != "custom entry" {
+			t.Errorf("Message = %q, want %q", lastLog.Message, "custom entry")
+		}
+		if lastLog.Service != "custom-service" {
+			t.Errorf("Service = %q, want %q", lastLog.Service, "custom-service")
+		}
+		if lastLog.Timestamp != "2024-01-01T00:00:00Z" {
+			t.Errorf("Timestamp = %q, want %q", lastLog.Timestamp, "2024-01-01T00:00:00Z")
+		}
+	})
+
+	t.Run("Log uses defaults for empty fields", func(t *testing.T) {
+		ts.mu.Lock()
+		ts.logs = ts.logs[:0]
+		ts.mu.Unlock()
+
+		entry := LogEntry{
+			Level:   LevelInfo,
+			Message: "minimal entry",
+		}
+		client.Log(entry)
+		waitForIdle(t, client)
+
+		logs := ts.getLogs()
+		if len(logs) == 0 {
+			t.Fatal("expected at least 1 log")
+		}
+
+		lastLog := logs[len(logs)-1]
+		if lastLog.Service != "default-service" {
+			t.Errorf("Service = %q, want %q (default)", lastLog.Service, "default-service")
+		}
+		if lastLog.Timestamp == "" {
+			t.Error("Timestamp should be auto-generated")
+		}
+	})
+
+	t.Run("Log merges config metadata with entry metadata", func(t *testing.T) {
+		ts.mu.Lock()
+		ts.logs = ts.logs[:0]
 		ts.mu.Unlock()
 
-		entry := LogEntry{
-			Level:   LevelInfo,
-			Message: "minimal entry",
+		entry := LogEntry{
+			Level:    LevelInfo,
+			Message:  "merge test",
+			Metadata: M{"entry_key": "entry_value"},
+		}
+		client.Log(entry)
+		waitForIdle(t, client)
+
+		logs := ts.getLogs()
+		if len(logs) == 0 {
+			t.Fatal("expected at least 1 log")
+		}
+
+		lastLog := logs[len(logs)-1]
+		if lastLog.Metadata["default_key"] != "default_value" {
+			t.Errorf("Metadata[default_key] = %v, want %q", lastLog.Metadata["default_key"], "default_value")
+		}
+		if lastLog.Metadata["entry_key"] != "entry_value" {
+			t.Errorf("Metadata[entry_key] = %v, want %q", lastLog.Metadata["entry_key"], "entry_value")
+		}
+	})
+}
+
+// TestClientLogBatch tests enqueuing multiple pre-built entries at once.
+func TestClientLogBatch(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts,
+		WithBatchSize(3),
+		WithService("default-service"),
+		WithMetadata(M{"default_key": "default_value"}),
+	)
+	defer client.Shutdown(context.Background())
+
+	entries := []LogEntry{
+		{Level: LevelInfo, Message: "first"},
+		{Level: LevelWarn, Message: "second", Service: "custom-service"},
+		{Level: LevelError, Message: "third", Metadata: M{"entry_key": "entry_value"}},
+	}
+	client.LogBatch(entries)
+	waitForIdle(t, client)
+
+	requests := ts.getRequests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 3)
+
+	for i, log := range logs {
+		if log.Message != entries[i].Message {
+			t.Errorf("logs[%d].Message = %q, want %q", i, log.Message, entries[i].Message)
+		}
+		if log.Timestamp == "" {
+			t.Errorf("logs[%d].Timestamp should be auto-generated", i)
+		}
+		if log.Metadata["default_key"] != "default_value" {
+			t.Errorf("logs[%d].Metadata[default_key] = %v, want %q", i, log.Metadata["default_key"], "default_value")
+		}
+	}
+
+	if logs[0].Service != "default-service" {
+		t.Errorf("logs[0].Service = %q, want %q", logs[0].Service, "default-service")
+	}
+	if logs[1].Service != "custom-service" {
+		t.Errorf("logs[1].Service = %q, want %q", logs[1].Service, "custom-service")
+	}
+	if logs[2].Metadata["entry_key"] != "entry_value" {
+		t.Errorf("logs[2].Metadata[entry_key] = %v, want %q", logs[2].Metadata["entry_key"], "entry_value")
+	}
+
+	// Caller's slice must not be modified
+	if entries[0].Timestamp != "" || entries[0].Service != "" {
+		t.Error("LogBatch modified the caller's entries")
+	}
+
+	t.Run("ignored after shutdown", func(t *testing.T) {
+		clearTestLogs(ts)
+		if err := client.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+
+		client.LogBatch(entries)
+		waitForIdle(t, client)
+
+		assertLogCount(t, ts.getLogs(), 0)
+	})
+}
+
+// TestClientFullFlow tests the complete lifecycle: create, log, flush, shutdown.
+func TestClientFullFlow(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	// Step 1: Create client
+	client, err := New(
+		ts.URL,
+		validAPIKey(),
+		WithService("integration-test"),
+		WithBatchSize(5),
+		WithFlushInterval(500*time.Millisecond),
+		WithMetadata(M{"test": "integration"}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Step 2: Log at various levels
+	client.Debug("debug message", M{"level": "debug"})
+	client.Info("info message", M{"level": "info"})
+	client.Warn("warn message", M{"level": "warn"})
+	client.Error("error message", M{"level": "error"})
+
+	// Verify logs are queued (not sent yet, batch size is 5)
+	logs := ts.getLogs()
+	if len(logs) != 0 {
+		t.Errorf("expected 0 logs before batch complete, got %d", len(logs))
+	}
+
+	// Step 3: Log one more to trigger batch flush
+	client.Fatal("fatal message", M{"level": "fatal"})
+
+	waitForIdle(t, client)
+
+	logs = ts.getLogs()
+	if len(logs) != 5 {
+		t.Errorf("expected 5 logs after batch complete, got %d", len(logs))
+	}
+
+	// Verify all levels present
+	levels := make(map[LogLevel]bool)
+	for _, log := range logs {
+		levels[log.Level] = true
+		// Verify service
+		if log.Service != "integration-test" {
+			t.Errorf("Service = %q, want %q", log.Service, "integration-test")
+		}
+		// Verify base metadata
+		if log.Metadata["test"] != "integration" {
+			t.Errorf("Metadata[test] = %v, want %q", log.Metadata["test"], "integration")
+		}
+	}
+
+	expectedLevels := []LogLevel{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal}
+	for _, level := range expectedLevels {
+		if !levels[level] {
+			t.Errorf("missing log level: %s", level)
+		}
+	}
+
+	// Step 4: Log more and manually flush
+	client.Info("post-batch message 1")
+	client.Info("post-batch message 2")
+
+	err = client.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	logs = ts.getLogs()
+	if len(logs) != 7 {
+		t.Errorf("expected 7 logs after manual flush, got %d", len(logs))
+	}
+
+	// Step 5: Shutdown gracefully
+	client.Info("pre-shutdown message")
+
+	err = client.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	logs = ts.getLogs()
+	if len(logs) != 8 {
+		t.Errorf("expected 8 logs after shutdown, got %d", len(logs))
+	}
+
+	// Verify no more logs accepted after shutdown
+	client.Info("post-shutdown message")
+	waitForIdle(t, client)
+
+	logs = ts.getLogs()
+	if len(logs) != 8 {
+		t.Errorf("expected 8 logs after post-shutdown log, got %d", len(logs))
+	}
+}
+
+// TestClientTimerFlush tests timer-based auto-flush.
+func TestClientTimerFlush(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	flushInterval := 200 * time.Millisecond
+	client, err := New(
+		ts.URL,
+		validAPIKey(),
+		WithBatchSize(100), // Large batch size to prevent batch-based flush
+		WithFlushInterval(flushInterval),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	// Log a message
+	client.Info("timer flush test")
+
+	// Verify no logs sent immediately
+	logs := ts.getLogs()
+	if len(logs) != 0 {
+		t.Errorf("expected 0 logs immediately, got %d", len(logs))
+	}
+
+	// Wait for timer flush
+	waitForIdle(t, client)
+
+	logs = ts.getLogs()
+	if len(logs) != 1 {
+		t.Errorf("expected 1 log after timer flush, got %d", len(logs))
+	}
+}
+
+// TestClientSetFlushInterval tests changing the flush interval at runtime.
+func TestClientSetFlushInterval(t *testing.T) {
+	t.Run("new interval restarts pending timer", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts,
+			WithBatchSize(100),
+			WithFlushInterval(60*time.Second),
+		)
+		defer client.Shutdown(context.Background())
+
+		client.Info("queued before change")
+		if err := client.SetFlushInterval(100 * time.Millisecond); err != nil {
+			t.Fatalf("SetFlushInterval() error = %v", err)
+		}
+
+		waitForIdle(t, client)
+		assertLogCount(t, ts.getLogs(), 1)
+
+		// Later entries follow the new cadence too
+		client.Info("queued after change")
+		waitForIdle(t, client)
+		assertLogCount(t, ts.getLogs(), 2)
+	})
+
+	t.Run("longer interval delays flush", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts,
+			WithBatchSize(100),
+			WithFlushInterval(100*time.Millisecond),
+		)
+		defer client.Shutdown(context.Background())
+
+		if err := client.SetFlushInterval(60 * time.Second); err != nil {
+			t.Fatalf("SetFlushInterval() error = %v", err)
+		}
+		client.Info("waits for the longer interval")
+
+		time.Sleep(250 * time.Millisecond)
+		assertLogCount(t, ts.getLogs(), 0)
+	})
+
+	t.Run("invalid interval is rejected", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts, WithFlushInterval(time.Second))
+		defer client.Shutdown(context.Background())
+
+		assertConfigError(t, client.SetFlushInterval(time.Millisecond), ErrInvalidConfig)
+		assertConfigError(t, client.SetFlushInterval(2*time.Minute), ErrInvalidConfig)
+	})
+}
+
+// TestClientSetBatchSize tests changing the batch size at runtime.
+func TestClientSetBatchSize(t *testing.T) {
+	t.Run("lowering below queue length flushes immediately", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts,
+			WithBatchSize(100),
+			WithFlushInterval(60*time.Second),
+		)
+		defer client.Shutdown(context.Background())
+
+		for i := 0; i < 5; i++ {
+			client.Info(fmt.Sprintf("queued %d", i))
+		}
+		assertLogCount(t, ts.getLogs(), 0)
+
+		if err := client.SetBatchSize(3); err != nil {
+			t.Fatalf("SetBatchSize() error = %v", err)
+		}
+
+		assertLogCount(t, ts.getLogs(), 5)
+		if got := len(ts.getRequests()); got != 2 {
+			t.Errorf("request count = %d, want 2 (chunks of 3)", got)
+		}
+	})
+
+	t.Run("new size is the flush threshold", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts,
+			WithBatchSize(100),
+			WithFlushInterval(60*time.Second),
+		)
+		defer client.Shutdown(context.Background())
+
+		if err := client.SetBatchSize(2); err != nil {
+			t.Fatalf("SetBatchSize() error = %v", err)
+		}
+
+		client.Info("first")
+		assertLogCount(t, ts.getLogs(), 0)
+		client.Info("second")
+		assertLogCount(t, ts.getLogs(), 2)
+	})
+
+	t.Run("invalid size is rejected", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts)
+		defer client.Shutdown(context.Background())
+
+		assertConfigError(t, client.SetBatchSize(0), ErrInvalidConfig)
+		assertConfigError(t, client.SetBatchSize(501), ErrInvalidConfig)
+	})
+}
+
+// TestClientService tests service name in logs.
+func TestClientService(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client, err := New(
+		ts.URL,
+		validAPIKey(),
+		WithBatchSize(1),
+		WithService("my-service"),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	client.Info("service test")
+	waitForIdle(t, client)
+
+	logs := ts.getLogs()
+	if len(logs) == 0 {
+		t.Fatal("expected at least 1 log")
+	}
+
+	if logs[0].Service != "my-service" {
+		t.Errorf("Service = %q, want %q", logs[0].Service, "my-service")
+	}
+}
+
+// tenantKey is the context key used by the context service tests.
+type tenantKey struct{}
+
+// TestClientContextMetadata tests that metadata from the context extractor
+// is merged under per-log metadata, for *Context methods and LogContext.
+func TestClientContextMetadata(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts,
+		WithBatchSize(1),
+		WithMetadata(map[string]any{"env": "test", "tenant": "config"}),
+		WithContextMetadata(func(ctx context.Context) map[string]any {
+			if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+				return map[string]any{"tenant": tenant, "source": "context"}
+			}
+			return nil
+		}),
+	)
+	defer client.Shutdown(context.Background())
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "tenant-a")
+	log := logAndWait(t, client, ts, func(msg string, md ...map[string]any) {
+		client.InfoContext(ctx, msg, append(md, map[string]any{"source": "log"})...)
+	}, "context log")
+	if log.Metadata["tenant"] != "tenant-a" || log.Metadata["source"] != "log" || log.Metadata["env"] != "test" {
+		t.Errorf("Metadata = %v, want context tenant under per-log source over config", log.Metadata)
+	}
+
+	log = logAndWait(t, client, ts, func(msg string, _ ...map[string]any) {
+		client.LogContext(ctx, LogEntry{Level: LevelWarn, Message: msg})
+	}, "entry log")
+	if log.Metadata["tenant"] != "tenant-a" || log.Metadata["source"] != "context" {
+		t.Errorf("LogContext Metadata = %v, want the context metadata", log.Metadata)
+	}
+
+	log = logAndWait(t, client, ts, func(msg string, md ...map[string]any) {
+		client.Info(msg, md...)
+	}, "plain log")
+	if log.Metadata["tenant"] != "config" {
+		t.Errorf("Metadata without context = %v, want the config tenant", log.Metadata)
+	}
+}
+
+// TestClientContextService tests service overrides read from context.
+func TestClientContextService(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts,
+		WithBatchSize(1),
+		WithService("default-service"),
+		WithContextService(func(ctx context.Context) string {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			return tenant
+		}),
+	)
+	defer client.Shutdown(context.Background())
+
+	t.Run("context service overrides config service", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), tenantKey{}, "tenant-a")
+		log := logAndWait(t, client, ts, func(msg string, md ...map[string]any) {
+			client.InfoContext(ctx, msg, md...)
+		}, "tenant log")
+
+		if log.Service != "tenant-a" {
+			t.Errorf("Service = %q, want %q", log.Service, "tenant-a")
+		}
+	})
+
+	t.Run("empty context service falls back to config", func(t *testing.T) {
+		log := logAndWait(t, client, ts, func(msg string, md ...map[string]any) {
+			client.WarnContext(context.Background(), msg, md...)
+		}, "default log")
+
+		if log.Service != "default-service" {
+			t.Errorf("Service = %q, want %q", log.Service, "default-service")
+		}
+	})
+
+	t.Run("child inherits context service", func(t *testing.T) {
+		child := client.Child(ChildWithService("child-service"))
+		ctx := context.WithValue(context.Background(), tenantKey{}, "tenant-b")
+		log := logAndWait(t, child, ts, func(msg string, md ...map[string]any) {
+			child.ErrorContext(ctx, msg, md...)
+		}, "child tenant log")
+
+		if log.Service != "tenant-b" {
+			t.Errorf("Service = %q, want %q", log.Service, "tenant-b")
+		}
+	})
+
+	t.Run("LogContext uses context service", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), tenantKey{}, "tenant-c")
+		log := logAndWait(t, client, ts, func(msg string, md ...map[string]any) {
+			client.LogContext(ctx, LogEntry{Level: LevelInfo, Message: msg})
+		}, "entry tenant log")
+
+		if log.Service != "tenant-c" {
+			t.Errorf("Service = %q, want %q", log.Service, "tenant-c")
+		}
+	})
+}
+
+// TestClientServiceFunc tests deriving the service name from each log.
+func TestClientServiceFunc(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var seen []map[string]any
+	var mu sync.Mutex
+	client := createTestClient(t, ts,
+		WithBatchSize(1),
+		WithService("orders"),
+		WithMetadata(M{"region": "eu"}),
+		WithServiceFunc(func(entry *LogEntry) string {
+			mu.Lock()
+			seen = append(seen, entry.Metadata)
+			mu.Unlock()
+			if shard, ok := entry.Metadata["shard"].(int); ok {
+				return fmt.Sprintf("orders-shard-%d", shard)
+			}
+			return ""
+		}),
+	)
+	defer client.Shutdown(context.Background())
+
+	t.Run("service derived from metadata", func(t *testing.T) {
+		log := logAndWait(t, client, ts, client.Info, "shard log", M{"shard": 3})
+
+		if log.Service != "orders-shard-3" {
+			t.Errorf("Service = %q, want %q", log.Service, "orders-shard-3")
+		}
+		if log.Metadata["region"] != "eu" {
+			t.Errorf("Metadata[region] = %v, want eu", log.Metadata["region"])
+		}
+	})
+
+	t.Run("runs before metadata merge", func(t *testing.T) {
+		mu.Lock()
+		seen = nil
+		mu.Unlock()
+		logAndWait(t, client, ts, client.Info, "shard log", M{"shard": 1})
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(seen) != 1 {
+			t.Fatalf("ServiceFunc called %d times, want 1", len(seen))
+		}
+		if _, ok := seen[0]["region"]; ok {
+			t.Errorf("ServiceFunc saw merged metadata %v", seen[0])
+		}
+	})
+
+	t.Run("empty result falls back to config", func(t *testing.T) {
+		log := logAndWait(t, client, ts, client.Info, "no shard")
+
+		if log.Service != "orders" {
+			t.Errorf("Service = %q, want %q", log.Service, "orders")
+		}
+	})
+
+	t.Run("Log derives service for entries without one", func(t *testing.T) {
+		clearTestLogs(ts)
+		client.Log(LogEntry{Level: LevelInfo, Message: "derived", Metadata: M{"shard": 7}})
+		client.Log(LogEntry{Level: LevelInfo, Message: "explicit", Service: "billing", Metadata: M{"shard": 7}})
+		waitForIdle(t, client)
+
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 2)
+		if logs[0].Service != "orders-shard-7" {
+			t.Errorf("derived Service = %q, want %q", logs[0].Service, "orders-shard-7")
+		}
+		if logs[1].Service != "billing" {
+			t.Errorf("explicit Service = %q, want %q", logs[1].Service, "billing")
+		}
+	})
+}
+
+// TestClientStartupDiagnostic tests the WithStartupDiagnostic log.
+func TestClientStartupDiagnostic(t *testing.T) {
+	t.Run("logs effective config without API key", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts,
+			WithStartupDiagnostic(true),
+			WithService("diag-service"),
+			WithBatchSize(25),
+			WithFlushInterval(2*time.Second),
+		)
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 1)
+		log := logs[0]
+
+		if log.Level != LevelInfo {
+			t.Errorf("Level = %q, want %q", log.Level, LevelInfo)
+		}
+		if log.Message != "logwell client started" {
+			t.Errorf("Message = %q, want %q", log.Message, "logwell client started")
+		}
+		if log.Metadata["service"] != "diag-service" {
+			t.Errorf("Metadata[service] = %v, want %q", log.Metadata["service"], "diag-service")
+		}
+		if log.Metadata["batchSize"] != float64(25) {
+			t.Errorf("Metadata[batchSize] = %v, want 25", log.Metadata["batchSize"])
+		}
+		if log.Metadata["flushInterval"] != "2s" {
+			t.Errorf("Metadata[flushInterval] = %v, want %q", log.Metadata["flushInterval"], "2s")
+		}
+
+		for key, value := range log.Metadata {
+			if s, ok := value.(string); ok && strings.Contains(s, validAPIKey()) {
+				t.Errorf("Metadata[%s] contains the API key", key)
+			}
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts)
+		client.Flush(context.Background())
+		defer client.Shutdown(context.Background())
+
+		assertLogCount(t, ts.getLogs(), 0)
+	})
+}
+
+// TestClientConcurrency tests thread-safety of client operations.
+func TestClientConcurrency(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client, err := New(
+		ts.URL,
+		validAPIKey(),
+		WithBatchSize(10),
+		WithFlushInterval(100*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	numGoroutines := 10
+	logsPerGoroutine := 50
+
+	// Concurrent logging
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < logsPerGoroutine; j++ {
+				client.Info("concurrent log", M{"goroutine": id, "iteration": j})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	// Shutdown to flush all remaining logs
+	err = client.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	expectedTotal := numGoroutines * logsPerGoroutine
+	logs := ts.getLogs()
+	if len(logs) != expectedTotal {
+		t.Errorf("expected %d logs, got %d", expectedTotal, len(logs))
+	}
+}
+
+// TestClientSingleFlightFlush stresses concurrent flush triggers and checks
+// that sends never overlap, no empty batches are sent, and no entries are lost.
+func TestClientSingleFlightFlush(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var inFlight, maxInFlight, requests, emptyRequests, received int32
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			prev := atomic.LoadInt32(&maxInFlight)
+			if current <= prev || atomic.CompareAndSwapInt32(&maxInFlight, prev, current) {
+				break
+			}
+		}
+
+		var req ingestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		if len(req.Logs) == 0 {
+			atomic.AddInt32(&emptyRequests, 1)
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&received, int32(len(req.Logs)))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	})
+
+	client := createTestClient(t, ts,
+		WithBatchSize(5),
+		WithFlushInterval(100*time.Millisecond),
+		WithMaxQueueSize(10000),
+	)
+
+	var wg sync.WaitGroup
+	numGoroutines := 300
+	logsPerGoroutine := 20
+
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < logsPerGoroutine; j++ {
+				client.Info("stress log", M{"goroutine": id, "iteration": j})
+				if j%5 == 0 {
+					if err := client.Flush(context.Background()); err != nil {
+						t.Errorf("Flush() error = %v", err)
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if got := atomic.LoadInt32(&maxInFlight); got != 1 {
+		t.Errorf("max concurrent sends = %d, want 1", got)
+	}
+	if got := atomic.LoadInt32(&emptyRequests); got != 0 {
+		t.Errorf("empty requests = %d, want 0", got)
+	}
+	if got, want := atomic.LoadInt32(&received), int32(numGoroutines*logsPerGoroutine); got != want {
+		t.Errorf("received %d entries in %d requests, want %d", got, atomic.LoadInt32(&requests), want)
+	}
+}
+
+// TestClientMaxConcurrentFlushes tests that at most MaxConcurrentFlushes
+// batches are in flight and that Shutdown waits for all of them.
+func TestClientMaxConcurrentFlushes(t *testing.T) {
+	const maxConcurrent = 3
+
+	var inFlight, maxSeen, received int32
+	release := make(chan struct{})
+	ts := newTestServer()
+	defer ts.Close()
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		var req ingestRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			seen := atomic.LoadInt32(&maxSeen)
+			if n <= seen || atomic.CompareAndSwapInt32(&maxSeen, seen, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&inFlight, -1)
+		atomic.AddInt32(&received, int32(len(req.Logs)))
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	})
+
+	client := createTestClient(t, ts,
+		WithBatchSize(2),
+		WithFlushInterval(60*time.Second),
+		WithMaxConcurrentFlushes(maxConcurrent),
+	)
+
+	// Six batches are queued at once; the handler holds every request
+	entries := make([]LogEntry, 12)
+	for i := range entries {
+		entries[i] = LogEntry{Level: LevelInfo, Message: fmt.Sprintf("entry %d", i)}
+	}
+	go client.LogBatch(entries)
+
+	// Wait until the limit is reached on both sides; maxSeen catches any
+	// request beyond it
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Stats().InFlightBatches < maxConcurrent || atomic.LoadInt32(&inFlight) < maxConcurrent {
+		if time.Now().After(deadline) {
+			t.Fatalf("in-flight batches = %d, server requests = %d, want %d each",
+				client.Stats().InFlightBatches, atomic.LoadInt32(&inFlight), maxConcurrent)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if got := client.Stats().InFlightBatches; got != maxConcurrent {
+		t.Errorf("InFlightBatches = %d, want %d", got, maxConcurrent)
+	}
+	if got := atomic.LoadInt32(&inFlight); got != maxConcurrent {
+		t.Errorf("server in-flight requests = %d, want %d", got, maxConcurrent)
+	}
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- client.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-shutdownDone:
+		t.Fatal("Shutdown returned while batches were in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-shutdownDone; err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if got := atomic.LoadInt32(&received); got != 12 {
+		t.Errorf("received %d entries, want 12", got)
+	}
+	if got := atomic.LoadInt32(&maxSeen); got > maxConcurrent {
+		t.Errorf("max concurrent requests = %d, want <= %d", got, maxConcurrent)
+	}
+	if got := client.Stats().InFlightBatches; got != 0 {
+		t.Errorf("InFlightBatches after Shutdown = %d, want 0", got)
+	}
+}
+
+// TestClientAdaptiveBatching tests that the batch size shrinks while the
+// server rejects large payloads and grows back once it accepts them.
+func TestClientAdaptiveBatching(t *testing.T) {
+	var maxAccepted atomic.Int32
+	maxAccepted.Store(8)
+
+	ts := newTestServer()
+	defer ts.Close()
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		var req ingestRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if limit := maxAccepted.Load(); limit > 0 && len(req.Logs) > int(limit) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(map[string]string{"error": "payload too large"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	})
+
+	client := createTestClient(t, ts,
+		WithBatchSize(64),
+		WithFlushInterval(60*time.Second),
+		WithAdaptiveBatching(2, 64),
+		WithMaxRetries(0),
+	)
+	defer client.Shutdown(context.Background())
+
+	flushRound := func() {
+		t.Helper()
+		for i := 0; i < 40; i++ {
+			client.queue.add(LogEntry{Level: LevelInfo, Message: "adaptive"})
+		}
+		client.Flush(context.Background())
+	}
+
+	if got := client.Stats().BatchSize; got != 64 {
+		t.Fatalf("initial BatchSize = %d, want 64", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		flushRound()
+	}
+	if got := client.Stats().BatchSize; got > 10 {
+		t.Errorf("BatchSize while server rejects large payloads = %d, want <= 10", got)
+	}
+
+	maxAccepted.Store(0)
+	for i := 0; i < 20; i++ {
+		flushRound()
+	}
+	if got := client.Stats().BatchSize; got != 64 {
+		t.Errorf("BatchSize after server recovers = %d, want 64", got)
+	}
+}
+
+// TestClientFlushWaitsForInProgress verifies that an explicit Flush waits for
+// a running flush and then sends entries queued during it.
+func TestClientFlushWaitsForInProgress(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var received int32
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		var req ingestRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		select {
+		case started <- struct{}{}:
+			<-release
+		default:
+		}
+		atomic.AddInt32(&received, int32(len(req.Logs)))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	})
+
+	client := createTestClient(t, ts, WithBatchSize(100))
+	defer client.Shutdown(context.Background())
+
+	client.Info("first")
+	go client.Flush(context.Background())
+	<-started
+
+	client.Info("second")
+	done := make(chan error, 1)
+	go func() { done <- client.Flush(context.Background()) }()
+
+	select {
+	case <-done:
+		t.Fatal("Flush returned while another flush was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&received); got != 2 {
+		t.Errorf("received %d entries, want 2", got)
+	}
+}
+
+// BenchmarkClientInfoParallel measures concurrent logging throughput,
+// dominated by enqueueing since the server accepts and discards batches.
+func BenchmarkClientInfoParallel(b *testing.B) {
+	client := newBenchmarkClient(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			client.Info("parallel benchmark log")
+		}
+	})
+}
+
+// TestClientInfoAllocations guards the allocation count of the logging hot
+// path. Batch size and flush interval are large so no flush runs.
+func TestClientInfoAllocations(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts,
+		WithBatchSize(500),
+		WithFlushInterval(60*time.Second),
+		WithMaxQueueSize(10000),
+		WithMetadata(M{"env": "test", "version": "1.2.3"}),
+	)
+	defer client.Shutdown(context.Background())
+
+	t.Run("without call metadata", func(t *testing.T) {
+		allocs := testing.AllocsPerRun(100, func() {
+			client.Info("no metadata")
+		})
+		if allocs > 1 {
+			t.Errorf("Info allocated %v times per call, want <= 1", allocs)
+		}
+		client.queue.flush()
+	})
+
+	t.Run("shares config metadata", func(t *testing.T) {
+		client.Info("first")
+		client.Info("second")
+		entries := client.queue.flush()
+		if len(entries) != 2 {
+			t.Fatalf("len(entries) = %d, want 2", len(entries))
+		}
+		if entries[0].Metadata["env"] != "test" {
+			t.Errorf("Metadata[env] = %v, want %q", entries[0].Metadata["env"], "test")
+		}
+		if fmt.Sprintf("%p", entries[0].Metadata) != fmt.Sprintf("%p", entries[1].Metadata) {
+			t.Error("entries without call metadata should share the config metadata map")
+		}
+	})
+}
+
+// TestNowCachesTimestamp tests that timestamps within one millisecond share
+// one string and use ISO8601 with millisecond precision.
+func TestNowCachesTimestamp(t *testing.T) {
+	ts := now()
+	if _, err := time.Parse(timestampLayout, ts); err != nil {
+		t.Fatalf("now() = %q does not parse: %v", ts, err)
+	}
+	if !strings.HasSuffix(ts, "Z") {
+		t.Errorf("now() = %q, want UTC", ts)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = now()
+	})
+	if allocs > 1 {
+		t.Errorf("now() allocated %v times per call, want <= 1", allocs)
+	}
+}
+
+// BenchmarkClientInfo measures a metadata-free Info call.
+func BenchmarkClientInfo(b *testing.B) {
+	client := newBenchmarkClient(b, WithMetadata(M{"env": "bench"}))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.Info("benchmark log")
+	}
+}
+
+// BenchmarkClientInfoWithMetadata measures an Info call that merges call
+// metadata with config metadata.
+func BenchmarkClientInfoWithMetadata(b *testing.B) {
+	client := newBenchmarkClient(b, WithMetadata(M{"env": "bench"}))
+	metadata := M{"requestId": "abc-123", "status": 200}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.Info("benchmark log", metadata)
+	}
+}
+
+// newBenchmarkClient creates a client whose server accepts and discards
+// batches, shut down when the benchmark ends.
+func newBenchmarkClient(b *testing.B, opts ...Option) *Client {
+	b.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"accepted":500}`))
+	}))
+	b.Cleanup(server.Close)
+
+	opts = append([]Option{WithBatchSize(500), WithMaxQueueSize(10000)}, opts...)
+	client, err := New(server.URL, validAPIKey(), opts...)
+	if err != nil {
+		b.Fatalf("New() error = %v", err)
+	}
+	b.Cleanup(func() { client.Shutdown(context.Background()) })
+
+	return client
+}
+
+// fakeClock replaces clockNow for the duration of a test.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock(t *testing.T) *fakeClock {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	orig := clockNow
+	clockNow = clock.Now
+	t.Cleanup(func() { clockNow = orig })
+	return clock
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
+
+// TestClientMaxEntryAge tests that entries are flushed before the max age
+// and that batches failing past 10x the max age are dropped.
+func TestClientMaxEntryAge(t *testing.T) {
+	t.Run("flushes before max age", func(t *testing.T) {
+		clock := newFakeClock(t)
+		ts := newTestServer()
+		defer ts.Close()
+
+		var requests atomic.Int32
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			var req ingestRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if requests.Add(1) == 1 {
+				// A failure that stays within the age limit is retried
+				clock.Advance(time.Second)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+		})
+
+		var dropped atomic.Int32
+		flushed := make(chan int, 1)
+		client := createTestClient(t, ts,
+			WithFlushInterval(60*time.Second),
+			WithMaxEntryAge(200*time.Millisecond),
+			WithOnDrop(func(n int) { dropped.Add(int32(n)) }),
+			WithOnFlush(func(n int) { flushed <- n }),
+		)
+		defer client.Shutdown(context.Background())
+
+		client.Info("aging")
+		if entry := client.queue.flush(); len(entry) != 1 || !entry[0].enqueuedAt.Equal(clock.Now()) {
+			t.Fatalf("queued entry = %+v, want enqueue time %v", entry, clock.Now())
+		}
+
+		client.Info("aging")
+		select {
+		case n := <-flushed:
+			if n != 1 {
+				t.Errorf("flushed %d entries, want 1", n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("entry was not flushed before max age despite 60s flush interval")
+		}
+		if got := dropped.Load(); got != 0 {
+			t.Errorf("dropped = %d, want 0", got)
+		}
+	})
+
+	t.Run("drops entries past the give-up age", func(t *testing.T) {
+		clock := newFakeClock(t)
+		ts := newTestServer()
+		defer ts.Close()
+
+		var requests atomic.Int32
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			clock.Advance(2 * time.Second)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		var dropped atomic.Int32
+		var errs []*Error
+		var errMu sync.Mutex
+		client := createTestClient(t, ts,
+			WithFlushInterval(60*time.Second),
+			WithMaxEntryAge(200*time.Millisecond),
+			WithOnDrop(func(n int) { dropped.Add(int32(n)) }),
+			WithOnError(func(err *Error) {
+				errMu.Lock()
+				errs = append(errs, err)
+				errMu.Unlock()
+			}),
+		)
+		defer client.Shutdown(context.Background())
+
+		client.Info("first")
+		client.Info("second")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v, want nil for dropped entries", err)
+		}
+
+		if got := dropped.Load(); got != 2 {
+			t.Errorf("dropped = %d, want 2", got)
+		}
+		if got := requests.Load(); got != 1 {
+			t.Errorf("requests = %d, want 1 (no retries past the give-up age)", got)
+		}
+		errMu.Lock()
+		defer errMu.Unlock()
+		if len(errs) != 1 || !isExpiredError(errs[0]) {
+			t.Errorf("OnError received %v, want one expired error", errs)
+		}
+	})
+}
+
+// TestClientPriorityLevels tests that an error logged behind a full queue
+// of debug entries survives overflow and is sent in the first batch.
+func TestClientPriorityLevels(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts,
+		WithBatchSize(500),
+		WithFlushInterval(60*time.Second),
+		WithMaxQueueSize(100),
+		WithPriorityLevels(LevelError, LevelFatal),
+	)
+	defer client.Shutdown(context.Background())
+
+	for i := 0; i < 100; i++ {
+		client.Debug("noise")
+	}
+	client.Error("the one that matters")
+	for i := 0; i < 150; i++ {
+		client.Debug("more noise")
+	}
+
+	client.SetBatchSize(10)
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	assertLogCount(t, ts.getLogs(), 100)
+	requests := ts.getRequests()
+	if len(requests) == 0 {
+		t.Fatal("no requests received")
+	}
+	first := requests[0].Logs[0]
+	if first.Level != LevelError || first.Message != "the one that matters" {
+		t.Errorf("first log sent = %s %q, want the error", first.Level, first.Message)
+	}
+	for _, log := range requests[0].Logs[1:] {
+		if log.Message != "more noise" {
+			t.Errorf("log after the error = %q, want only the newest debug entries", log.Message)
+			break
+		}
+	}
+}
+
+// TestClientDropLowestSeverity tests that with DropLowestSeverity an error
+// survives a queue overflowing with debug logs.
+func TestClientDropLowestSeverity(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var dropCount int32
+	client := createTestClient(t, ts,
+		WithBatchSize(100),
+		WithMaxQueueSize(5),
+		WithOverflowPolicy(DropLowestSeverity),
+		WithOnDrop(func(n int) { atomic.AddInt32(&dropCount, int32(n)) }),
+	)
+	defer client.Shutdown(context.Background())
+
+	client.Debug("debug 0")
+	client.Error("keep me")
+	for i := 1; i < 10; i++ {
+		client.Debug(fmt.Sprintf("debug %d", i))
+	}
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 5)
+	if logs[0].Message != "keep me" || logs[0].Level != LevelError {
+		t.Errorf("first log = %q at %s, want the error", logs[0].Message, logs[0].Level)
+	}
+	if got := atomic.LoadInt32(&dropCount); got != 6 {
+		t.Errorf("OnDrop total = %d, want 6", got)
+	}
+}
+
+// TestClientBatchBytes tests that large entries trigger a flush once their
+// estimated size reaches BatchBytes, long before the batch size.
+func TestClientBatchBytes(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts,
+		WithBatchSize(100),
+		WithBatchBytes(10_000),
+	)
+	defer client.Shutdown(context.Background())
+
+	payload := strings.Repeat("x", 4096)
+	for i := 0; i < 7; i++ {
+		client.Info(fmt.Sprintf("large %d", i), M{"payload": payload})
+	}
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// Every third entry crosses 10KB; Flush sends the seventh
+	requests := ts.getRequests()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	for i, want := range []int{3, 3, 1} {
+		if got := len(requests[i].Logs); got != want {
+			t.Errorf("request %d sent %d logs, want %d", i, got, want)
+		}
+	}
+}
+
+// TestClientMaxInFlightBytes tests that large entries overflow the queue at
+// the byte cap, long before the count cap, and are shed oldest first.
+func TestClientMaxInFlightBytes(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var dropCount int32
+	client := createTestClient(t, ts,
+		WithBatchSize(100),
+		WithMaxQueueSize(100),
+		WithMaxInFlightBytes(10_000),
+		WithOnDrop(func(n int) { atomic.AddInt32(&dropCount, int32(n)) }),
+	)
+	defer client.Shutdown(context.Background())
+
+	payload := strings.Repeat("x", 4096)
+	for i := 0; i < 5; i++ {
+		client.Info(fmt.Sprintf("large %d", i), M{"payload": payload})
+	}
+	if got := client.QueueLen(); got != 2 {
+		t.Errorf("QueueLen() = %d, want 2 entries under 10KB", got)
+	}
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 2)
+	if logs[0].Message != "large 3" || logs[1].Message != "large 4" {
+		t.Errorf("sent %q and %q, want the two newest", logs[0].Message, logs[1].Message)
+	}
+	if got := atomic.LoadInt32(&dropCount); got != 3 {
+		t.Errorf("OnDrop total = %d, want 3", got)
+	}
+}
+
+// TestClientBlockOverflowPolicy tests that a full queue makes producers wait
+// for a flush under the Block policy, and that ctx ends the wait with a drop.
+func TestClientBlockOverflowPolicy(t *testing.T) {
+	// newBlockedClient returns a client whose queue is full while a flush
+	// is stuck on the server until release is closed.
+	newBlockedClient := func(t *testing.T, opts ...Option) (*Client, *testServer, chan struct{}) {
+		t.Helper()
+		ts := newTestServer()
+		t.Cleanup(ts.Close)
+
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			var req ingestRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+			ts.mu.Lock()
+			ts.logs = append(ts.logs, req.Logs...)
+			ts.mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+		})
+
+		opts = append([]Option{
+			WithBatchSize(500),
+			WithFlushInterval(60 * time.Second),
+			WithMaxQueueSize(5),
+			WithOverflowPolicy(Block),
+		}, opts...)
+		client := createTestClient(t, ts, opts...)
+
+		for i := 0; i < 5; i++ {
+			client.Info("first batch")
+		}
+		go client.Flush(context.Background())
+		<-started
+		for i := 0; i < 5; i++ {
+			client.Info("second batch")
+		}
+		return client, ts, release
+	}
+
+	t.Run("blocks until a flush completes", func(t *testing.T) {
+		var dropped atomic.Int32
+		client, ts, release := newBlockedClient(t, WithOnDrop(func(n int) { dropped.Add(int32(n)) }))
+		defer client.Shutdown(context.Background())
+
+		done := make(chan struct{})
+		go func() {
+			client.InfoContext(context.Background(), "waited")
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("InfoContext returned while the queue was full")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		close(release)
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("InfoContext still blocked after the flush completed")
+		}
+
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		assertLogCount(t, ts.getLogs(), 11)
+		if got := dropped.Load(); got != 0 {
+			t.Errorf("dropped = %d, want 0", got)
+		}
+	})
+
+	t.Run("context cancellation drops the entry", func(t *testing.T) {
+		var dropped atomic.Int32
+		errs := make(chan *Error, 10)
+		client, _, release := newBlockedClient(t,
+			WithOnDrop(func(n int) { dropped.Add(int32(n)) }),
+			WithOnError(func(err *Error) { errs <- err }),
+		)
+		defer client.Shutdown(context.Background())
+		defer close(release)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			client.InfoContext(ctx, "abandoned")
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("InfoContext returned while the queue was full")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("InfoContext still blocked after ctx was canceled")
+		}
+
+		if got := dropped.Load(); got != 1 {
+			t.Errorf("dropped = %d, want 1", got)
+		}
+		select {
+		case err := <-errs:
+			if err.Code != ErrEnqueueCanceled || !errors.Is(err.Cause, context.Canceled) {
+				t.Errorf("OnError = %v (cause %v), want ErrEnqueueCanceled caused by context.Canceled", err, err.Cause)
+			}
+		default:
+			t.Error("OnError was not called for the dropped entry")
+		}
+		if got := client.queue.size(); got != 5 {
+			t.Errorf("queue size = %d, want 5 (abandoned entry not queued)", got)
+		}
+	})
+
+	t.Run("LogBatchContext cancellation drops the rest", func(t *testing.T) {
+		var dropped atomic.Int32
+		errs := make(chan *Error, 10)
+		client, _, release := newBlockedClient(t,
+			WithOnDrop(func(n int) { dropped.Add(int32(n)) }),
+			WithOnError(func(err *Error) { errs <- err }),
+		)
+		defer client.Shutdown(context.Background())
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		client.LogBatchContext(ctx, []LogEntry{
+			{Level: LevelInfo, Message: "abandoned 1"},
+			{Level: LevelInfo, Message: "abandoned 2"},
+			{Level: LevelInfo, Message: "abandoned 3"},
+		})
+
+		if got := dropped.Load(); got != 3 {
+			t.Errorf("dropped = %d, want 3", got)
+		}
+		if len(errs) != 1 {
+			t.Fatalf("OnError calls = %d, want 1", len(errs))
+		}
+		if err := <-errs; err.Code != ErrEnqueueCanceled || !errors.Is(err.Cause, context.DeadlineExceeded) {
+			t.Errorf("OnError = %v (cause %v), want ErrEnqueueCanceled caused by the deadline", err, err.Cause)
+		}
+	})
+
+	t.Run("LogSync returns the drop", func(t *testing.T) {
+		client, _, release := newBlockedClient(t)
+		defer client.Shutdown(context.Background())
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := client.LogSync(ctx, LogEntry{Level: LevelInfo, Message: "abandoned"})
+		var logwellErr *Error
+		if !errors.As(err, &logwellErr) || logwellErr.Code != ErrEnqueueCanceled {
+			t.Errorf("LogSync() error = %v, want %s", err, ErrEnqueueCanceled)
+		}
+	})
+}
+
+// TestClientLogSync tests that LogSync returns once the entry has been
+// sent, with the send's error.
+func TestClientLogSync(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts, WithBatchSize(100), WithFlushInterval(60*time.Second), WithMinLevel(LevelInfo))
+	defer client.Shutdown(context.Background())
+
+	if err := client.LogSync(context.Background(), LogEntry{Level: LevelInfo, Message: "audit"}); err != nil {
+		t.Fatalf("LogSync() error = %v", err)
+	}
+	assertLogCount(t, ts.getLogs(), 1)
+
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	if err := client.LogSync(context.Background(), LogEntry{Level: LevelInfo, Message: "rejected"}); err == nil {
+		t.Error("LogSync() error = nil, want the rejection")
+	}
+	if err := client.LogSync(context.Background(), LogEntry{Level: LevelDebug, Message: "filtered"}); err != nil {
+		t.Errorf("LogSync() below the min level error = %v, want nil", err)
+	}
+}
+
+// TestClientQueueLenAndWatermark tests the queue accessors while the server
+// is stalled and that the high watermark fires once per crossing.
+func TestClientQueueLenAndWatermark(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+	})
+
+	var fired atomic.Int32
+	client := createTestClient(t, ts,
+		WithBatchSize(500),
+		WithFlushInterval(60*time.Second),
+		WithMaxQueueSize(10),
+		WithQueueHighWatermark(0.5, func() { fired.Add(1) }),
+	)
+	defer client.Shutdown(context.Background())
+	defer close(release)
+
+	if got := client.QueueCap(); got != 10 {
+		t.Errorf("QueueCap() = %d, want 10", got)
+	}
+
+	for i := 0; i < 4; i++ {
+		client.Info("below watermark")
+	}
+	if got := client.QueueLen(); got != 4 {
+		t.Errorf("QueueLen() = %d, want 4", got)
+	}
+	if got := fired.Load(); got != 0 {
+		t.Errorf("watermark fired %d times below the threshold, want 0", got)
+	}
+
+	client.Info("at watermark")
+	for i := 0; i < 8; i++ {
+		client.Info("above watermark") // overflows, length stays at 10
+	}
+	if got := client.QueueLen(); got != 10 {
+		t.Errorf("QueueLen() = %d, want 10", got)
+	}
+	if got := fired.Load(); got != 1 {
+		t.Errorf("watermark fired %d times, want 1", got)
+	}
+
+	// The queue empties while the flush is stuck on the server, re-arming
+	// the watermark
+	go client.Flush(context.Background())
+	<-started
+	if got := client.QueueLen(); got != 0 {
+		t.Errorf("QueueLen() during stalled flush = %d, want 0", got)
+	}
+	if got := client.Stats().InFlightBatches; got != 1 {
+		t.Errorf("InFlightBatches = %d, want 1", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		client.Info("second rise")
+	}
+	if got := fired.Load(); got != 2 {
+		t.Errorf("watermark fired %d times after re-arming, want 2", got)
+	}
+}
+
+// TestClientMaxBufferAge tests that FlushInterval acts as a debounce with
+// MaxBufferAge set, and that MaxBufferAge forces a flush under constant load.
+func TestClientMaxBufferAge(t *testing.T) {
+	t.Run("flushes within max age under continuous logging", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		flushed := make(chan time.Time, 10)
+		client := createTestClient(t, ts,
+			WithBatchSize(500),
+			WithFlushInterval(200*time.Millisecond),
+			WithMaxBufferAge(300*time.Millisecond),
+			WithOnFlush(func(int) { flushed <- time.Now() }),
+		)
+		defer client.Shutdown(context.Background())
+
+		// Log more often than the debounce interval so it never fires
+		stop := make(chan struct{})
+		defer close(stop)
+		start := time.Now()
+		go func() {
+			ticker := time.NewTicker(20 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					client.Info("constant load")
+				}
+			}
+		}()
+
+		select {
+		case at := <-flushed:
+			if elapsed := at.Sub(start); elapsed > 600*time.Millisecond {
+				t.Errorf("first flush after %v, want within about 300ms", elapsed)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("no flush under continuous logging")
+		}
+	})
+
+	t.Run("flush interval debounces", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts,
+			WithBatchSize(500),
+			WithFlushInterval(150*time.Millisecond),
+			WithMaxBufferAge(10*time.Second),
+		)
+		defer client.Shutdown(context.Background())
+
+		client.Info("first")
+		time.Sleep(100 * time.Millisecond)
+		client.Info("second") // restarts the debounce
+
+		time.Sleep(100 * time.Millisecond)
+		if got := len(ts.getLogs()); got != 0 {
+			t.Errorf("received %d logs before the debounce elapsed, want 0", got)
+		}
+
+		waitForIdle(t, client)
+		assertLogCount(t, ts.getLogs(), 2)
+	})
+}
+
+// TestClientMinFlushBytes tests that the inactivity flush waits for
+// MinFlushBytes of logs or MaxBufferAge, and that Flush does not.
+func TestClientMinFlushBytes(t *testing.T) {
+	t.Run("defers until the age cap", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		flushed := make(chan time.Time, 10)
+		client := createTestClient(t, ts,
+			WithBatchSize(500),
+			WithFlushInterval(100*time.Millisecond),
+			WithMaxBufferAge(600*time.Millisecond),
+			WithMinFlushBytes(1<<20),
+			WithOnFlush(func(int) { flushed <- time.Now() }),
+		)
+		defer client.Shutdown(context.Background())
+
+		start := time.Now()
+		client.Info("tiny")
+
+		select {
+		case at := <-flushed:
+			if elapsed := at.Sub(start); elapsed < 500*time.Millisecond {
+				t.Errorf("flushed after %v, want the 600ms age cap", elapsed)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("tiny log never flushed")
+		}
+		assertLogCount(t, ts.getLogs(), 1)
+	})
+
+	t.Run("flushes once the threshold is reached", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts,
+			WithBatchSize(500),
+			WithFlushInterval(100*time.Millisecond),
+			WithMaxBufferAge(10*time.Second),
+			WithMinFlushBytes(2000),
+		)
+		defer client.Shutdown(context.Background())
+
+		message := strings.Repeat("x", 600)
+		client.Info(message)
+		time.Sleep(300 * time.Millisecond)
+		if got := len(ts.getLogs()); got != 0 {
+			t.Fatalf("received %d logs below the threshold, want 0", got)
+		}
+
+		for i := 0; i < 3; i++ {
+			client.Info(message)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for len(ts.getLogs()) < 4 && time.Now().Before(deadline) {
+			time.Sleep(20 * time.Millisecond)
+		}
+		assertLogCount(t, ts.getLogs(), 4)
+	})
+
+	t.Run("Flush ignores the threshold", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts,
+			WithBatchSize(500),
+			WithFlushInterval(100*time.Millisecond),
+			WithMaxBufferAge(10*time.Second),
+			WithMinFlushBytes(1<<20),
+		)
+		defer client.Shutdown(context.Background())
+
+		client.Info("tiny")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		assertLogCount(t, ts.getLogs(), 1)
+	})
+}
+
+// TestClientPending tests that Pending returns queued entries in order
+// without removing them.
+func TestClientPending(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts,
+		WithBatchSize(10),
+		WithFlushInterval(60*time.Second),
+		WithMetadata(M{"env": "test"}),
+	)
+	defer client.Shutdown(context.Background())
+
+	if got := client.Pending(); len(got) != 0 {
+		t.Errorf("Pending() on empty queue = %v, want empty", got)
+	}
+
+	for _, msg := range []string{"one", "two", "three"} {
+		client.Info(msg)
+	}
+
+	pending := client.Pending()
+	if len(pending) != 3 {
+		t.Fatalf("len(Pending()) = %d, want 3", len(pending))
+	}
+	for i, want := range []string{"one", "two", "three"} {
+		if pending[i].Message != want {
+			t.Errorf("Pending()[%d].Message = %q, want %q", i, pending[i].Message, want)
+		}
+	}
+
+	// Modifying the copy leaves the queue untouched
+	pending[0].Message = "changed"
+	pending[0].Metadata["env"] = "changed"
+	if got := client.QueueLen(); got != 3 {
+		t.Errorf("QueueLen() after Pending() = %d, want 3", got)
+	}
+
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 3)
+	if logs[0].Message != "one" || logs[0].Metadata["env"] != "test" {
+		t.Errorf("sent log = %q %v, want the original entry", logs[0].Message, logs[0].Metadata)
+	}
+}
+
+// TestClientRequeueOnFailure tests that failed batches are retried in order on the next flush.
+func TestClientRequeueOnFailure(t *testing.T) {
+	// failing serves the given status to the first fail requests and
+	// records the logs of later ones
+	failing := func(ts *testServer, status, fail int32) {
+		var calls int32
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) <= fail {
+				w.WriteHeader(int(status))
+				return
+			}
+			var req ingestRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			ts.mu.Lock()
+			ts.logs = append(ts.logs, req.Logs...)
+			ts.mu.Unlock()
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+		})
+	}
+
+	t.Run("retryable failure is delivered in order on the next flush", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+		failing(ts, http.StatusServiceUnavailable, 1)
+
+		client := createTestClient(t, ts,
+			WithFlushInterval(60*time.Second),
+			WithRequeueOnFailure(true),
+		)
+		client.transport.maxRetries = 0
+		defer client.Shutdown(context.Background())
+
+		client.Info("one")
+		client.Info("two")
+		client.Info("three")
+
+		if err := client.Flush(context.Background()); !IsRetryable(err) {
+			t.Fatalf("Flush() error = %v, want retryable error", err)
+		}
+		if got := client.QueueLen(); got != 3 {
+			t.Fatalf("QueueLen() after failure = %d, want 3", got)
+		}
+
+		client.Info("four")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
 		}
-		client.Log(entry)
-		time.Sleep(50 * time.Millisecond)
 
 		logs := ts.getLogs()
-		if len(logs) == 0 {
-			t.Fatal("expected at least 1 log")
+		assertLogCount(t, logs, 4)
+		for i, want := range []string{"one", "two", "three", "four"} {
+			if logs[i].Message != want {
+				t.Errorf("logs[%d].Message = %q, want %q", i, logs[i].Message, want)
+			}
 		}
+	})
 
-		lastLog := logs[len(logs)-1]
-		if lastLog.Service != "default-service" {
-			t.Errorf("Service = %q, want %q (default)", lastLog.Service, "default-service")
+	t.Run("non-retryable failure is not requeued", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+		failing(ts, http.StatusUnauthorized, 1)
+
+		client := createTestClient(t, ts,
+			WithFlushInterval(60*time.Second),
+			WithRequeueOnFailure(true),
+		)
+		defer client.Shutdown(context.Background())
+
+		client.Info("rejected")
+		if err := client.Flush(context.Background()); err == nil {
+			t.Fatal("Flush() error = nil, want error")
 		}
-		if lastLog.Timestamp == "" {
-			t.Error("Timestamp should be auto-generated")
+		if got := client.QueueLen(); got != 0 {
+			t.Errorf("QueueLen() = %d, want 0", got)
 		}
 	})
 
-	t.Run("Log merges config metadata with entry metadata", func(t *testing.T) {
-		ts.mu.Lock()
-		ts.logs = ts.logs[:0]
-		ts.mu.Unlock()
+	t.Run("requeue is bounded by MaxQueueSize", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+		failing(ts, http.StatusServiceUnavailable, 1)
 
-		entry := LogEntry{
-			Level:    LevelInfo,
-			Message:  "merge test",
-			Metadata: M{"entry_key": "entry_value"},
+		var dropped int32
+		client := createTestClient(t, ts,
+			WithFlushInterval(60*time.Second),
+			WithMaxQueueSize(3),
+			WithRequeueOnFailure(true),
+			WithOnDrop(func(n int) { atomic.AddInt32(&dropped, int32(n)) }),
+		)
+		client.transport.maxRetries = 0
+		defer client.Shutdown(context.Background())
+
+		client.Info("one")
+		client.Info("two")
+		client.Info("three")
+
+		// New logs arrive while the taken batch is failing
+		entries := client.queue.flush()
+		client.Info("four")
+		client.Info("five")
+		_, err := client.transport.sendWithRetry(context.Background(), entries)
+		if !IsRetryable(err) {
+			t.Fatalf("send error = %v, want retryable error", err)
 		}
-		client.Log(entry)
-		time.Sleep(50 * time.Millisecond)
+		client.queue.requeue(entries)
+
+		if got := atomic.LoadInt32(&dropped); got != 2 {
+			t.Errorf("dropped = %d, want 2", got)
+		}
+		pending := client.Pending()
+		var got []string
+		for _, e := range pending {
+			got = append(got, e.Message)
+		}
+		if strings.Join(got, " ") != "three four five" {
+			t.Errorf("Pending() = %v, want [three four five]", got)
+		}
+	})
+
+	t.Run("shutdown does not requeue", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+		failing(ts, http.StatusServiceUnavailable, 1)
+
+		client := createTestClient(t, ts,
+			WithFlushInterval(60*time.Second),
+			WithRequeueOnFailure(true),
+		)
+		client.transport.maxRetries = 0
+
+		client.Info("lost")
+		stats, err := client.ShutdownWithStats(context.Background())
+		if err == nil {
+			t.Fatal("ShutdownWithStats() error = nil, want error")
+		}
+		if stats.FailedEntries != 1 {
+			t.Errorf("FailedEntries = %d, want 1", stats.FailedEntries)
+		}
+		if got := client.QueueLen(); got != 0 {
+			t.Errorf("QueueLen() after shutdown = %d, want 0", got)
+		}
+	})
+}
+
+// TestClientLevelSampling tests that sampled-out levels are dropped and reported.
+func TestClientLevelSampling(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var dropped int32
+	client := createTestClient(t, ts,
+		WithBatchSize(100),
+		WithFlushInterval(60*time.Second),
+		WithLevelSampling(map[LogLevel]float64{LevelInfo: 0, LevelError: 1}),
+		WithOnDrop(func(n int) { atomic.AddInt32(&dropped, int32(n)) }),
+	)
+	defer client.Shutdown(context.Background())
+
+	for i := 0; i < 20; i++ {
+		client.Info("sampled out")
+		client.Error("kept")
+	}
+	client.Warn("unsampled level")
+	client.Log(LogEntry{Level: LevelInfo, Message: "sampled out"})
+	client.LogBatch([]LogEntry{
+		{Level: LevelInfo, Message: "sampled out"},
+		{Level: LevelError, Message: "kept"},
+	})
+
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 22)
+	for _, log := range logs {
+		if log.Level == LevelInfo {
+			t.Errorf("info log %q was sent, want all sampled out", log.Message)
+		}
+	}
+	if got := atomic.LoadInt32(&dropped); got != 22 {
+		t.Errorf("dropped = %d, want 22", got)
+	}
+}
+
+// TestClientStatsCounters tests the lifetime counters reported by Stats.
+func TestClientStatsCounters(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts,
+		WithBatchSize(100),
+		WithFlushInterval(60*time.Second),
+		WithMaxQueueSize(3),
+	)
+	defer client.Shutdown(context.Background())
+
+	for i := 0; i < 5; i++ {
+		client.Info("message")
+	}
+
+	stats := client.Stats()
+	if stats.EnqueuedEntries != 5 || stats.DroppedEntries != 2 || stats.QueueLength != 3 {
+		t.Errorf("Stats() = %+v, want 5 enqueued, 2 dropped, 3 queued", stats)
+	}
+
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	stats = client.Stats()
+	if stats.SentBatches != 1 || stats.SendDuration <= 0 || stats.QueueLength != 0 {
+		t.Errorf("Stats() after flush = %+v, want 1 batch with a duration and an empty queue", stats)
+	}
+}
+
+// TestClientBeforeSend tests replacing and canceling batches before they are sent.
+func TestClientBeforeSend(t *testing.T) {
+	t.Run("filters debug entries at send time", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts,
+			WithBatchSize(100),
+			WithFlushInterval(60*time.Second),
+			WithBeforeSend(func(logs []LogEntry) ([]LogEntry, bool) {
+				kept := logs[:0]
+				for _, entry := range logs {
+					if entry.Level != LevelDebug {
+						entry.Metadata = mergeMetadata(entry.Metadata, M{"batch": len(logs)})
+						kept = append(kept, entry)
+					}
+				}
+				return kept, true
+			}),
+		)
+		defer client.Shutdown(context.Background())
+
+		client.Debug("dropped")
+		client.Info("kept")
+		client.Debug("dropped")
+		client.Warn("kept")
+
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 2)
+		for _, log := range logs {
+			if log.Level == LevelDebug {
+				t.Errorf("debug entry %q was sent", log.Message)
+			}
+			if log.Metadata["batch"] != float64(4) {
+				t.Errorf("Metadata[batch] = %v, want 4", log.Metadata["batch"])
+			}
+		}
+	})
+
+	t.Run("canceled batch is dropped", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		var dropped int32
+		client := createTestClient(t, ts,
+			WithBatchSize(100),
+			WithFlushInterval(60*time.Second),
+			WithBeforeSend(func(logs []LogEntry) ([]LogEntry, bool) { return logs, false }),
+			WithOnDrop(func(n int) { atomic.AddInt32(&dropped, int32(n)) }),
+		)
+		defer client.Shutdown(context.Background())
+
+		client.Info("one")
+		client.Info("two")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+
+		assertLogCount(t, ts.getLogs(), 0)
+		if got := atomic.LoadInt32(&dropped); got != 2 {
+			t.Errorf("dropped = %d, want 2", got)
+		}
+		if got := client.QueueLen(); got != 0 {
+			t.Errorf("QueueLen() = %d, want 0", got)
+		}
+	})
+
+	t.Run("canceled batch is requeued", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		var cancel atomic.Bool
+		cancel.Store(true)
+		client := createTestClient(t, ts,
+			WithBatchSize(100),
+			WithFlushInterval(60*time.Second),
+			WithRequeueOnFailure(true),
+			WithBeforeSend(func(logs []LogEntry) ([]LogEntry, bool) { return logs, !cancel.Load() }),
+		)
+		defer client.Shutdown(context.Background())
+
+		client.Info("one")
+		client.Info("two")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		if got := client.QueueLen(); got != 2 {
+			t.Fatalf("QueueLen() after cancel = %d, want 2", got)
+		}
+
+		cancel.Store(false)
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		assertLogCount(t, ts.getLogs(), 2)
+	})
+}
+
+// TestClientWaitForIdle tests waiting for the queue to drain.
+func TestClientWaitForIdle(t *testing.T) {
+	t.Run("returns immediately when idle", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts)
+		defer client.Shutdown(context.Background())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := client.WaitForIdle(ctx); err != nil {
+			t.Errorf("WaitForIdle() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("waits for in-flight flush and its callbacks", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		release := make(chan struct{})
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+		})
+
+		var flushed int32
+		client := createTestClient(t, ts,
+			WithBatchSize(1),
+			WithOnFlush(func(n int) { atomic.AddInt32(&flushed, int32(n)) }),
+		)
+		defer client.Shutdown(context.Background())
 
-		logs := ts.getLogs()
-		if len(logs) == 0 {
-			t.Fatal("expected at least 1 log")
+		go client.Info("slow")
+		deadline := time.Now().Add(2 * time.Second)
+		for client.Stats().InFlightBatches == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
 		}
 
-		lastLog := logs[len(logs)-1]
-		if lastLog.Metadata["default_key"] != "default_value" {
-			t.Errorf("Metadata[default_key] = %v, want %q", lastLog.Metadata["default_key"], "default_value")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := client.WaitForIdle(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("WaitForIdle() during send error = %v, want %v", err, context.DeadlineExceeded)
 		}
-		if lastLog.Metadata["entry_key"] != "entry_value" {
-			t.Errorf("Metadata[entry_key] = %v, want %q", lastLog.Metadata["entry_key"], "entry_value")
+
+		close(release)
+		waitForIdle(t, client)
+		if got := atomic.LoadInt32(&flushed); got != 1 {
+			t.Errorf("OnFlush count = %d, want 1", got)
 		}
 	})
+
+	t.Run("waits for timer flush", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts,
+			WithBatchSize(100),
+			WithFlushInterval(100*time.Millisecond),
+		)
+		defer client.Shutdown(context.Background())
+
+		client.Info("queued")
+		waitForIdle(t, client)
+		assertLogCount(t, ts.getLogs(), 1)
+	})
 }
 
-// TestClientFullFlow tests the complete lifecycle: create, log, flush, shutdown.
-func TestClientFullFlow(t *testing.T) {
+// TestClientTimer tests that Timer logs the elapsed time as durationMs.
+func TestClientTimer(t *testing.T) {
 	ts := newTestServer()
 	defer ts.Close()
 
-	// Step 1: Create client
-	client, err := New(
-		ts.URL,
-		validAPIKey(),
-		WithService("integration-test"),
-		WithBatchSize(5),
-		WithFlushInterval(500*time.Millisecond),
-		WithMetadata(M{"test": "integration"}),
+	client := createTestClient(t, ts,
+		WithBatchSize(100),
+		WithFlushInterval(time.Minute),
+		WithCaptureSourceLocation(true),
+		WithSlowThreshold(time.Millisecond),
 	)
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
-	}
+	defer client.Shutdown(context.Background())
 
-	// Step 2: Log at various levels
-	client.Debug("debug message", M{"level": "debug"})
-	client.Info("info message", M{"level": "info"})
-	client.Warn("warn message", M{"level": "warn"})
-	client.Error("error message", M{"level": "error"})
+	clock := newFakeClock(t)
+	stop := client.Timer("handle request", M{"route": "/users", "durationMs": "overridden"})
+	clock.Advance(20 * time.Millisecond)
+	stop()
 
-	// Verify logs are queued (not sent yet, batch size is 5)
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
 	logs := ts.getLogs()
-	if len(logs) != 0 {
-		t.Errorf("expected 0 logs before batch complete, got %d", len(logs))
+	assertLogCount(t, logs, 1)
+
+	log := logs[0]
+	if log.Level != LevelInfo || log.Message != "handle request" {
+		t.Errorf("log = %s %q, want INFO %q", log.Level, log.Message, "handle request")
+	}
+	if log.Metadata["route"] != "/users" {
+		t.Errorf("route = %v, want %q", log.Metadata["route"], "/users")
+	}
+	duration, ok := log.Metadata["durationMs"].(float64)
+	if !ok {
+		t.Fatalf("durationMs = %#v, want a number", log.Metadata["durationMs"])
 	}
+	if duration != 20 {
+		t.Errorf("durationMs = %v, want 20", duration)
+	}
+	if _, ok := log.Metadata["operation"]; ok {
+		t.Errorf("operation = %v, want no operation field", log.Metadata["operation"])
+	}
+	if log.SourceFile != "client_test.go" || log.FunctionName != "logwell.TestClientTimer" {
+		t.Errorf("source = %s:%d %s, want the call in TestClientTimer", log.SourceFile, log.LineNumber, log.FunctionName)
+	}
+}
 
-	// Step 3: Log one more to trigger batch flush
-	client.Fatal("fatal message", M{"level": "fatal"})
+// TestClientStartTimer tests that StartTimer logs the operation with its
+// elapsed time and merged metadata, escalating to WARN from SlowThreshold.
+func TestClientStartTimer(t *testing.T) {
+	clock := newFakeClock(t)
+	ts := newTestServer()
+	defer ts.Close()
 
-	time.Sleep(100 * time.Millisecond)
+	client := createTestClient(t, ts, WithBatchSize(100), WithSlowThreshold(time.Second))
+	defer client.Shutdown(context.Background())
 
-	logs = ts.getLogs()
-	if len(logs) != 5 {
-		t.Errorf("expected 5 logs after batch complete, got %d", len(logs))
+	fast := client.StartTimer("db.query", M{"table": "orders", "rows": 0})
+	slow := client.StartTimer("db.query", M{"table": "users"})
+	client.StartTimer("never stopped")
+	clock.Advance(250 * time.Millisecond)
+	fast(M{"rows": 3})
+	clock.Advance(time.Second)
+	slow()
+
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
 	}
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 2)
 
-	// Verify all levels present
-	levels := make(map[LogLevel]bool)
-	for _, log := range logs {
-		levels[log.Level] = true
-		// Verify service
-		if log.Service != "integration-test" {
-			t.Errorf("Service = %q, want %q", log.Service, "integration-test")
+	tests := []struct {
+		level    LogLevel
+		table    string
+		duration float64
+	}{
+		{LevelInfo, "orders", 250},
+		{LevelWarn, "users", 1250},
+	}
+	for i, tt := range tests {
+		log := logs[i]
+		if log.Level != tt.level || log.Message != "db.query" || log.Metadata["operation"] != "db.query" {
+			t.Errorf("log %d = %s %q operation %v, want %s db.query", i, log.Level, log.Message, log.Metadata["operation"], tt.level)
 		}
-		// Verify base metadata
-		if log.Metadata["test"] != "integration" {
-			t.Errorf("Metadata[test] = %v, want %q", log.Metadata["test"], "integration")
+		if log.Metadata["table"] != tt.table || log.Metadata["durationMs"] != tt.duration {
+			t.Errorf("log %d metadata = %v, want table %s and durationMs %v", i, log.Metadata, tt.table, tt.duration)
 		}
 	}
-
-	expectedLevels := []LogLevel{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal}
-	for _, level := range expectedLevels {
-		if !levels[level] {
-			t.Errorf("missing log level: %s", level)
-		}
+	if logs[0].Metadata["rows"] != float64(3) {
+		t.Errorf("rows = %v, want 3 from the extra metadata", logs[0].Metadata["rows"])
 	}
+}
 
-	// Step 4: Log more and manually flush
-	client.Info("post-batch message 1")
-	client.Info("post-batch message 2")
+// TestClientMetadataAllowlist tests that only allowlisted metadata keys
+// are sent and removed keys are reported through OnDrop.
+func TestClientMetadataAllowlist(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
 
-	err = client.Flush(context.Background())
-	if err != nil {
-		t.Fatalf("Flush() error = %v", err)
-	}
+	// logWith logs one entry on a client built with opts and returns it,
+	// with the OnDrop total.
+	logWith := func(t *testing.T, opts ...Option) (LogEntry, int32) {
+		t.Helper()
+		var dropped int32
+		opts = append(opts,
+			WithMetadata(M{"env": "prod", "user": M{"id": "u1", "email": "a@example.com"}}),
+			WithOnDrop(func(n int) { atomic.AddInt32(&dropped, int32(n)) }),
+		)
+		client := createTestClient(t, ts, opts...)
+		defer client.Shutdown(context.Background())
 
-	logs = ts.getLogs()
-	if len(logs) != 7 {
-		t.Errorf("expected 7 logs after manual flush, got %d", len(logs))
+		clearTestLogs(ts)
+		client.Info("checkout", M{"orderId": "o1", "card": "4111"})
+		client.Flush(context.Background())
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 1)
+		return logs[0], atomic.LoadInt32(&dropped)
 	}
 
-	// Step 5: Shutdown gracefully
-	client.Info("pre-shutdown message")
+	t.Run("allow subset", func(t *testing.T) {
+		log, dropped := logWith(t, WithMetadataAllowlist("orderId", "env", "user.id"), WithFlattenMetadata("."))
+		want := M{"orderId": "o1", "env": "prod", "user.id": "u1"}
+		if len(log.Metadata) != len(want) {
+			t.Errorf("metadata = %v, want %v", log.Metadata, want)
+		}
+		for k, v := range want {
+			if log.Metadata[k] != v {
+				t.Errorf("metadata[%q] = %v, want %v", k, log.Metadata[k], v)
+			}
+		}
+		if dropped != 2 {
+			t.Errorf("OnDrop total = %d, want 2 (card, user.email)", dropped)
+		}
+	})
 
-	err = client.Shutdown(context.Background())
-	if err != nil {
-		t.Fatalf("Shutdown() error = %v", err)
-	}
+	t.Run("allow none", func(t *testing.T) {
+		log, dropped := logWith(t, WithMetadataAllowlist())
+		if log.Metadata != nil {
+			t.Errorf("metadata = %v, want none", log.Metadata)
+		}
+		if dropped != 4 {
+			t.Errorf("OnDrop total = %d, want 4", dropped)
+		}
+	})
 
-	logs = ts.getLogs()
-	if len(logs) != 8 {
-		t.Errorf("expected 8 logs after shutdown, got %d", len(logs))
-	}
+	t.Run("unset allows all", func(t *testing.T) {
+		log, dropped := logWith(t)
+		if len(log.Metadata) != 4 {
+			t.Errorf("metadata = %v, want all 4 keys", log.Metadata)
+		}
+		if dropped != 0 {
+			t.Errorf("OnDrop total = %d, want 0", dropped)
+		}
+	})
 
-	// Verify no more logs accepted after shutdown
-	client.Info("post-shutdown message")
-	time.Sleep(50 * time.Millisecond)
+	t.Run("config metadata without per-log metadata", func(t *testing.T) {
+		var dropped int32
+		client := createTestClient(t, ts,
+			WithMetadata(M{"env": "prod", "secret": "s"}),
+			WithMetadataAllowlist("env"),
+			WithOnDrop(func(n int) { atomic.AddInt32(&dropped, int32(n)) }),
+		)
+		defer client.Shutdown(context.Background())
 
-	logs = ts.getLogs()
-	if len(logs) != 8 {
-		t.Errorf("expected 8 logs after post-shutdown log, got %d", len(logs))
-	}
+		clearTestLogs(ts)
+		client.Info("no metadata")
+		client.Flush(context.Background())
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 1)
+		if len(logs[0].Metadata) != 1 || logs[0].Metadata["env"] != "prod" {
+			t.Errorf("metadata = %v, want only env", logs[0].Metadata)
+		}
+		if got := atomic.LoadInt32(&dropped); got != 1 {
+			t.Errorf("OnDrop total = %d, want 1", got)
+		}
+	})
 }
 
-// TestClientTimerFlush tests timer-based auto-flush.
-func TestClientTimerFlush(t *testing.T) {
+// TestClientEnvironment tests that the environment is sent with every log
+// as the environment field, inherited by children unless they override it,
+// and kept when a log sets its own.
+func TestClientEnvironment(t *testing.T) {
 	ts := newTestServer()
 	defer ts.Close()
 
-	flushInterval := 200 * time.Millisecond
-	client, err := New(
-		ts.URL,
-		validAPIKey(),
-		WithBatchSize(100), // Large batch size to prevent batch-based flush
-		WithFlushInterval(flushInterval),
-	)
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
+	client := createTestClient(t, ts, WithEnvironment("production"))
+	inherited := client.Child(ChildWithService("worker"))
+	overridden := client.Child(ChildWithEnvironment("canary"))
+
+	client.Info("parent")
+	inherited.Info("inherited")
+	overridden.Info("overridden")
+	client.Log(LogEntry{Level: LevelInfo, Message: "own", Environment: "dev"})
+	client.LogBatch([]LogEntry{{Level: LevelInfo, Message: "batch"}})
+	client.Shutdown(context.Background())
+
+	want := map[string]string{
+		"parent":     "production",
+		"inherited":  "production",
+		"overridden": "canary",
+		"own":        "dev",
+		"batch":      "production",
 	}
-	defer client.Shutdown(context.Background())
-
-	// Log a message
-	client.Info("timer flush test")
-
-	// Verify no logs sent immediately
 	logs := ts.getLogs()
-	if len(logs) != 0 {
-		t.Errorf("expected 0 logs immediately, got %d", len(logs))
+	assertLogCount(t, logs, len(want))
+	for _, log := range logs {
+		if log.Environment != want[log.Message] {
+			t.Errorf("%s: Environment = %q, want %q", log.Message, log.Environment, want[log.Message])
+		}
 	}
 
-	// Wait for timer flush
-	time.Sleep(flushInterval + 100*time.Millisecond)
-
-	logs = ts.getLogs()
-	if len(logs) != 1 {
-		t.Errorf("expected 1 log after timer flush, got %d", len(logs))
+	data, err := json.Marshal(LogEntry{Level: LevelInfo, Message: "m", Environment: "production"})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"environment":"production"`) {
+		t.Errorf("JSON = %s, want the environment field", data)
+	}
+	if data, _ := json.Marshal(LogEntry{Level: LevelInfo, Message: "m"}); strings.Contains(string(data), "environment") {
+		t.Errorf("JSON = %s, want no environment field when unset", data)
 	}
 }
 
-// TestClientService tests service name in logs.
-func TestClientService(t *testing.T) {
-	ts := newTestServer()
-	defer ts.Close()
+// TestClientTimestampSkewGuard tests that implausible timestamps set by the
+// caller are clamped to now or dropped and reported, while plausible and
+// generated timestamps are kept.
+func TestClientTimestampSkewGuard(t *testing.T) {
+	const future = "2099-01-01T00:00:00.000Z"
 
-	client, err := New(
-		ts.URL,
-		validAPIKey(),
-		WithBatchSize(1),
-		WithService("my-service"),
-	)
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
-	}
-	defer client.Shutdown(context.Background())
+	t.Run("clamp", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
 
-	client.Info("service test")
-	time.Sleep(50 * time.Millisecond)
+		var errs []*Error
+		var mu sync.Mutex
+		client := createTestClient(t, ts,
+			WithTimestampSkewGuard(time.Hour),
+			WithOnError(func(err *Error) {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, err)
+			}),
+		)
+		recent := time.Now().Add(-time.Minute).UTC().Format(timestampLayout)
 
-	logs := ts.getLogs()
-	if len(logs) == 0 {
-		t.Fatal("expected at least 1 log")
-	}
+		before := time.Now().Add(-time.Second)
+		client.Log(LogEntry{Level: LevelInfo, Message: "typo", Timestamp: future})
+		client.LogBatch([]LogEntry{{Level: LevelInfo, Message: "batch typo", Timestamp: "1999-01-01T00:00:00Z"}})
+		client.Log(LogEntry{Level: LevelInfo, Message: "recent", Timestamp: recent})
+		client.Info("generated")
+		client.Shutdown(context.Background())
+		after := time.Now().Add(time.Second)
 
-	if logs[0].Service != "my-service" {
-		t.Errorf("Service = %q, want %q", logs[0].Service, "my-service")
-	}
-}
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 4)
+		for _, log := range logs {
+			if log.Message == "recent" {
+				if log.Timestamp != recent {
+					t.Errorf("recent timestamp = %q, want %q kept", log.Timestamp, recent)
+				}
+				continue
+			}
+			stamp, err := time.Parse(time.RFC3339Nano, log.Timestamp)
+			if err != nil || stamp.Before(before) || stamp.After(after) {
+				t.Errorf("%s: timestamp = %q, want now", log.Message, log.Timestamp)
+			}
+		}
 
-// TestClientConcurrency tests thread-safety of client operations.
-func TestClientConcurrency(t *testing.T) {
-	ts := newTestServer()
-	defer ts.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		if len(errs) != 2 {
+			t.Fatalf("OnError calls = %d, want 2", len(errs))
+		}
+		for _, err := range errs {
+			if err.Code != ErrValidationError {
+				t.Errorf("OnError code = %s, want %s", err.Code, ErrValidationError)
+			}
+		}
+	})
 
-	client, err := New(
-		ts.URL,
-		validAPIKey(),
-		WithBatchSize(10),
-		WithFlushInterval(100*time.Millisecond),
-	)
-	if err != nil {
-		t.Fatalf("New() error = %v", err)
-	}
+	t.Run("drop", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
 
-	var wg sync.WaitGroup
-	numGoroutines := 10
-	logsPerGoroutine := 50
+		var dropped, reported int32
+		client := createTestClient(t, ts,
+			WithTimestampSkewGuard(time.Hour),
+			WithTimestampSkewAction(DropSkewed),
+			WithOnDrop(func(n int) { atomic.AddInt32(&dropped, int32(n)) }),
+			WithOnError(func(*Error) { atomic.AddInt32(&reported, 1) }),
+		)
+		client.Log(LogEntry{Level: LevelInfo, Message: "typo", Timestamp: future})
+		client.Info("generated")
+		client.Shutdown(context.Background())
 
-	// Concurrent logging
-	for i := 0; i < numGoroutines; i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			for j := 0; j < logsPerGoroutine; j++ {
-				client.Info("concurrent log", M{"goroutine": id, "iteration": j})
-			}
-		}(i)
-	}
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 1)
+		if logs[0].Message != "generated" {
+			t.Errorf("sent = %q, want only the generated log", logs[0].Message)
+		}
+		if dropped, reported := atomic.LoadInt32(&dropped), atomic.LoadInt32(&reported); dropped != 1 || reported != 1 {
+			t.Errorf("OnDrop total = %d, OnError calls = %d, want 1 and 1", dropped, reported)
+		}
+	})
 
-	wg.Wait()
+	t.Run("replay is exempt", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
 
-	// Shutdown to flush all remaining logs
-	err = client.Shutdown(context.Background())
-	if err != nil {
-		t.Fatalf("Shutdown() error = %v", err)
-	}
+		client := createTestClient(t, ts, WithTimestampSkewGuard(time.Hour))
+		input := `{"level":"info","message":"captured","timestamp":"2020-01-01T00:00:00.000Z"}` + "\n"
+		if _, err := client.Replay(context.Background(), strings.NewReader(input)); err != nil {
+			t.Fatalf("Replay() error = %v", err)
+		}
+		client.Shutdown(context.Background())
 
-	expectedTotal := numGoroutines * logsPerGoroutine
-	logs := ts.getLogs()
-	if len(logs) != expectedTotal {
-		t.Errorf("expected %d logs, got %d", expectedTotal, len(logs))
-	}
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 1)
+		if logs[0].Timestamp != "2020-01-01T00:00:00.000Z" {
+			t.Errorf("replayed timestamp = %q, want it kept", logs[0].Timestamp)
+		}
+	})
 }
@@ -0,0 +1,117 @@
+package logwell
+
+import (
+	"compress/gzip"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCompressionThreshold is the encoded body size from which request
+// bodies are compressed when WithCompression is used without
+// WithCompressionThreshold.
+const DefaultCompressionThreshold = 1024
+
+// Compressor compresses request bodies for one Content-Encoding. Encoders
+// are often costly to create, so implementations should reuse them across
+// requests. A Compressor must be safe for concurrent use.
+//
+// The core SDK provides CompressionGzip; the logwellzstd module provides
+// zstd.
+type Compressor interface {
+	// ContentEncoding returns the Content-Encoding header value for the
+	// compressed body, such as "gzip".
+	ContentEncoding() string
+
+	// NewWriter returns a writer that compresses into w. Close flushes the
+	// compressed stream, without closing w, and may release the writer
+	// for reuse; the writer must not be used after Close.
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+// CompressionGzip compresses request bodies with gzip, reusing pooled
+// writers.
+var CompressionGzip Compressor = gzipCompressor{}
+
+// gzipWriterPool holds gzip writers for request compression, which are
+// costly to allocate per request.
+var gzipWriterPool = sync.Pool{
+	New: func() any { return gzip.NewWriter(io.Discard) },
+}
+
+// gzipCompressor is CompressionGzip.
+type gzipCompressor struct{}
+
+// ContentEncoding returns "gzip".
+func (gzipCompressor) ContentEncoding() string { return "gzip" }
+
+// NewWriter returns a pooled gzip writer that compresses into w.
+func (gzipCompressor) NewWriter(w io.Writer) io.WriteCloser {
+	zw := gzipWriterPool.Get().(*gzip.Writer)
+	zw.Reset(w)
+	return &pooledGzipWriter{Writer: zw}
+}
+
+// pooledGzipWriter returns its gzip writer to the pool on Close.
+type pooledGzipWriter struct {
+	*gzip.Writer
+}
+
+// Close flushes the gzip stream and returns the writer to the pool.
+func (w *pooledGzipWriter) Close() error {
+	err := w.Writer.Close()
+	gzipWriterPool.Put(w.Writer)
+	w.Writer = nil
+	return err
+}
+
+// compressionLadder holds the encodings a transport may use, most preferred
+// first, and how far down it has stepped. A 415 response to a compressed
+// body steps down to the next encoding and, past the last, to uncompressed
+// bodies.
+type compressionLadder struct {
+	compressors []Compressor
+	level       atomic.Int32
+}
+
+// newCompressionLadder returns the ladder for cfg: the compressors from
+// WithCompression followed by gzip, or gzip alone when only a threshold is
+// set. It returns nil when compression is disabled.
+func newCompressionLadder(cfg *Config) *compressionLadder {
+	if len(cfg.Compression) == 0 {
+		if cfg.CompressionThreshold == 0 {
+			return nil
+		}
+		return &compressionLadder{compressors: []Compressor{CompressionGzip}}
+	}
+
+	l := &compressionLadder{}
+	hasGzip := false
+	for _, c := range cfg.Compression {
+		hasGzip = hasGzip || c.ContentEncoding() == "gzip"
+		l.compressors = append(l.compressors, c)
+	}
+	if !hasGzip {
+		l.compressors = append(l.compressors, CompressionGzip)
+	}
+	return l
+}
+
+// current returns the compressor to use and its level, or nil once every
+// encoding was rejected. It returns nil on a nil receiver.
+func (l *compressionLadder) current() (Compressor, int32) {
+	if l == nil {
+		return nil, 0
+	}
+	level := l.level.Load()
+	if int(level) >= len(l.compressors) {
+		return nil, level
+	}
+	return l.compressors[level], level
+}
+
+// reject steps down from level after the server rejected its encoding.
+// Concurrent rejections of the same level step down once.
+func (l *compressionLadder) reject(level int32) {
+	l.level.CompareAndSwap(level, level+1)
+}
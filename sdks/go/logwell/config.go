@@ -1,9 +1,13 @@
 package logwell
 
 import (
+	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -13,6 +17,96 @@ const (
 	DefaultFlushInterval = 5 * time.Second
 	DefaultMaxQueueSize  = 1000
 	DefaultMaxRetries    = 3
+
+	DefaultMaxConcurrentFlushes = 1
+
+	DefaultShutdownTimeout = 10 * time.Second
+
+	DefaultAPIKeyRefreshInterval = time.Minute
+
+	// Defaults for the HTTP client the SDK builds. DefaultIdleConnTimeout
+	// stays below the 60 second idle timeout common to load balancers, so
+	// the client closes idle connections before they are cut under it.
+	DefaultRequestTimeout      = 30 * time.Second
+	DefaultDialTimeout         = 10 * time.Second
+	DefaultIdleConnTimeout     = 50 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+
+	DefaultFailbackInterval = 30 * time.Second
+	DefaultEndpointCooldown = 30 * time.Second
+
+	// maxEntryAgeGiveUpFactor multiplies MaxEntryAge to get the age at
+	// which failing entries are dropped instead of retried.
+	maxEntryAgeGiveUpFactor = 10
+)
+
+// BalanceStrategy controls how WithEndpoints spreads batches over endpoints.
+type BalanceStrategy string
+
+// Balance strategy constants.
+const (
+	// RoundRobin sends batches to each endpoint in turn.
+	RoundRobin BalanceStrategy = "round-robin"
+
+	// Random sends each batch to an endpoint picked at random.
+	Random BalanceStrategy = "random"
+)
+
+// DeliveryMode controls how the client trades duplicates against loss.
+type DeliveryMode string
+
+// Delivery mode constants.
+const (
+	// AtLeastOnce retries every retryable failure. A batch may be delivered
+	// twice if the server stored it but the response was lost, for example
+	// after a timeout. This is the default.
+	AtLeastOnce DeliveryMode = "at-least-once"
+
+	// AtMostOnce never retries network failures that happen after the
+	// request was fully written, such as response timeouts, because the
+	// server may already have stored the batch. Batches are never duplicated,
+	// but may be lost. Failures before the request is sent are still retried.
+	AtMostOnce DeliveryMode = "at-most-once"
+)
+
+// OverflowPolicy controls what happens when a log is added to a full queue.
+type OverflowPolicy string
+
+// Overflow policy constants.
+const (
+	// DropOldest evicts the oldest queued entry to make room, so logging
+	// never blocks. This is the default.
+	DropOldest OverflowPolicy = "drop-oldest"
+
+	// Block makes the logging call wait until a flush frees queue space.
+	// The *Context methods and LogContext give up when their context ends,
+	// reporting the entry through OnDrop and an ErrEnqueueCanceled error.
+	Block OverflowPolicy = "block"
+
+	// SpillToDisk writes entries that do not fit in the queue to files in
+	// a directory and sends them, in order, once there is room again. Set
+	// it with WithSpillToDisk, which also sets the directory and size cap.
+	SpillToDisk OverflowPolicy = "spill-to-disk"
+
+	// DropLowestSeverity evicts the least severe queued entry, the oldest
+	// among equals, so debug and info logs are shed before warnings and
+	// errors. An incoming entry less severe than everything queued is
+	// dropped itself.
+	DropLowestSeverity OverflowPolicy = "drop-lowest-severity"
+)
+
+// TimestampSkewAction controls what WithTimestampSkewGuard does with a
+// log whose timestamp is too far from now.
+type TimestampSkewAction string
+
+// Timestamp skew action constants.
+const (
+	// ClampSkewed replaces the timestamp with the current time. This is
+	// the default.
+	ClampSkewed TimestampSkewAction = "clamp"
+
+	// DropSkewed drops the entry, reporting it through OnDrop.
+	DropSkewed TimestampSkewAction = "drop"
 )
 
 // Validation bounds.
@@ -25,6 +119,9 @@ const (
 	MaxMaxQueueSize  = 10000
 	MinMaxRetries    = 0
 	MaxMaxRetries    = 10
+
+	MinMaxConcurrentFlushes = 1
+	MaxMaxConcurrentFlushes = 32
 )
 
 // apiKeyRegex matches valid Logwell API keys: lw_ followed by 32+ alphanumeric chars including - and _.
@@ -35,44 +132,341 @@ type Config struct {
 	// Endpoint is the Logwell server URL (required).
 	Endpoint string
 
-	// APIKey is the Logwell API key (required).
+	// APIKey is the Logwell API key (required unless APIKeyFile is set).
 	APIKey string
 
-	// Service is the service name to attach to all logs.
+	// APIKeyFile is a file holding the API key. When set, the key is read
+	// from it at New and re-read as it rotates, replacing APIKey.
+	APIKeyFile string
+
+	// TokenProvider, when set, is called before each request to obtain the
+	// bearer token, and APIKey is neither validated nor sent.
+	TokenProvider func(context.Context) (string, error)
+
+	// BasicAuthUser and BasicAuthPassword, when BasicAuthUser is set, are
+	// sent as HTTP Basic credentials in the Authorization header, and the
+	// API key moves to the X-Logwell-Authorization header.
+	BasicAuthUser     string
+	BasicAuthPassword string
+
+	// APIKeyRefreshInterval is how often APIKeyFile is re-read.
+	// Zero re-reads only after the server rejects the key.
+	// Default: 1m.
+	APIKeyRefreshInterval time.Duration
+
+	// Service is the service name to attach to all logs. When empty, it
+	// is derived from the executable unless DisableServiceAutodetect is set.
 	Service string
 
+	// DisableServiceAutodetect keeps Service empty when it is not set.
+	// Default: false.
+	DisableServiceAutodetect bool
+
+	// Environment is the deployment environment to attach to all logs.
+	// When empty, it is read from LOGWELL_ENV, ENVIRONMENT, ENV or
+	// APP_ENV, in that order. Default: "".
+	Environment string
+
 	// Metadata is default metadata to attach to all logs.
 	Metadata map[string]any
 
+	// MetadataAllowlist, when non-nil, lists the only metadata keys that
+	// are sent; an empty list sends none. Default: nil (all keys are sent).
+	MetadataAllowlist []string
+
 	// BatchSize is the number of logs to batch before sending.
 	// Default: 10, Range: 1-500.
 	BatchSize int
 
-	// FlushInterval is the maximum time to wait before flushing.
+	// BatchBytes, when positive, also triggers a flush once the estimated
+	// serialized size of the queued entries reaches it.
+	// Default: 0 (disabled).
+	BatchBytes int
+
+	// FlushInterval is the maximum time to wait before flushing, measured
+	// from the oldest queued entry. With MaxBufferAge set it is instead
+	// the inactivity period after the newest entry.
 	// Default: 5s, Range: 100ms-60s.
 	FlushInterval time.Duration
 
+	// MaxBufferAge, when positive, turns FlushInterval into a debounce and
+	// caps how long the oldest entry waits under constant load.
+	// Default: 0 (disabled), Range: 0 or at least 100ms.
+	MaxBufferAge time.Duration
+
+	// MinFlushBytes, when positive, makes the inactivity flush wait until
+	// the estimated size of the queued entries reaches it; MaxBufferAge
+	// still flushes them. Requires MaxBufferAge. Default: 0 (disabled).
+	MinFlushBytes int
+
+	// MaxEntryAge, when positive, bounds how long an entry waits in the
+	// client: the queue flushes before its oldest entry reaches this age,
+	// and entries still failing at 10x this age are dropped.
+	// Default: 0 (disabled), Range: 0 or at least 100ms.
+	MaxEntryAge time.Duration
+
 	// MaxQueueSize is the maximum number of logs to hold in queue.
 	// Default: 1000, Range: 1-10000.
 	MaxQueueSize int
 
+	// MaxInFlightBytes, when positive, also caps the estimated serialized
+	// size of the logs held in the queue; going past it is handled like a
+	// full queue, by OverflowPolicy. Default: 0 (no byte cap).
+	MaxInFlightBytes int64
+
 	// MaxRetries is the maximum number of retry attempts for failed requests.
 	// Default: 3, Range: 0-10.
 	MaxRetries int
 
+	// AdaptiveBatchMin and AdaptiveBatchMax bound the effective batch size
+	// when adaptive batching is enabled. Both zero disables it.
+	// Default: 0 (disabled), Range: 1-500 with min <= max.
+	AdaptiveBatchMin int
+	AdaptiveBatchMax int
+
+	// MaxConcurrentFlushes is the maximum number of batches sent at once.
+	// Default: 1, Range: 1-32.
+	MaxConcurrentFlushes int
+
+	// OverflowPolicy controls whether a full queue evicts old entries,
+	// makes logging calls wait, or spills to disk. Default: DropOldest.
+	OverflowPolicy OverflowPolicy
+
+	// SpillDir and SpillMaxBytes are the directory and total size cap of
+	// the spill files used by the SpillToDisk overflow policy.
+	SpillDir      string
+	SpillMaxBytes int64
+
+	// RequeueOnFailure puts batches that failed with a retryable error back
+	// at the front of the queue for the next flush. Default: false.
+	RequeueOnFailure bool
+
+	// ContextDeadlinePropagation bounds background sends by the deadlines
+	// of the contexts the batch was logged with. Default: false.
+	ContextDeadlinePropagation bool
+
+	// BeforeSend is called with each batch before it is sent, and may
+	// replace it or cancel the send. Default: nil.
+	BeforeSend func(logs []LogEntry) ([]LogEntry, bool)
+
+	// DeliveryMode controls whether ambiguous failures are retried.
+	// Default: AtLeastOnce.
+	DeliveryMode DeliveryMode
+
+	// FlattenSeparator, when non-empty, flattens nested metadata maps into
+	// keys joined by this separator (e.g. "user.id"). Default: "" (disabled).
+	FlattenSeparator string
+
+	// Encoder marshals each request body before it is sent.
+	// Responses are always decoded with encoding/json.
+	// Default: json.Marshal, writing into pooled buffers.
+	Encoder func(any) ([]byte, error)
+
+	// CompressionThreshold is the encoded request body size, in bytes,
+	// from which bodies are compressed.
+	// Default: 0 (compression disabled), or DefaultCompressionThreshold
+	// when Compression is set.
+	CompressionThreshold int
+
+	// Compression lists the encodings request bodies are compressed with,
+	// most preferred first. Gzip is always tried after them.
+	// Default: nil (gzip only, when CompressionThreshold is set).
+	Compression []Compressor
+
+	// MinLevel is the minimum level sent to the server.
+	// Logs below this level are discarded. Default: debug.
+	MinLevel LogLevel
+
+	// PriorityLevels are levels queued in a priority lane that is flushed
+	// first and evicted last on overflow. Default: none.
+	PriorityLevels []LogLevel
+
+	// LevelSampling maps levels to the fraction of their entries kept;
+	// levels not in the map are always kept. Default: nil (keep all),
+	// Range: 0-1 per level.
+	LevelSampling map[LogLevel]float64
+
+	// LevelAliases maps levels to the names sent for them to the server;
+	// unmapped levels are sent as is. Default: nil.
+	LevelAliases map[LogLevel]string
+
+	// TimestampSkewMax, when positive, is how far from now a timestamp
+	// set by the caller may be before TimestampSkewAction is applied.
+	// Default: 0 (timestamps are not checked), ClampSkewed.
+	TimestampSkewMax    time.Duration
+	TimestampSkewAction TimestampSkewAction
+
 	// CaptureSourceLocation enables capturing source file and line number.
 	// Default: false.
 	CaptureSourceLocation bool
 
+	// SlowThreshold is the elapsed time from which StartTimer logs at WARN
+	// instead of INFO. Default: 0 (always INFO).
+	SlowThreshold time.Duration
+
+	// StartupDiagnostic makes New enqueue an info log describing the
+	// effective configuration. The API key is never included.
+	// Default: false.
+	StartupDiagnostic bool
+
+	// HeartbeatInterval, when positive, makes the client log a heartbeat
+	// every interval; HeartbeatMetadata, when set, adds metadata to each.
+	// Default: 0 (disabled).
+	HeartbeatInterval time.Duration
+	HeartbeatMetadata func() M
+
 	// HTTPClient is a custom HTTP client for making requests.
 	// Default: http.DefaultClient.
 	HTTPClient *http.Client
 
+	// InsecureSkipVerify disables TLS certificate verification when no
+	// custom HTTPClient is set. For local development only.
+	// Default: false.
+	InsecureSkipVerify bool
+
+	// RequestTimeout limits how long one request attempt may take, from
+	// dialing to reading the response. Retries each get their own.
+	// Ignored when a custom HTTPClient is set. 0 means no limit.
+	// Default: DefaultRequestTimeout.
+	RequestTimeout time.Duration
+
+	// DialTimeout limits how long establishing a connection may take.
+	// Ignored when a custom HTTPClient is set. 0 means no limit.
+	// Default: DefaultDialTimeout.
+	DialTimeout time.Duration
+
+	// IdleConnTimeout is how long an idle keep-alive connection is kept
+	// open for the next flush. Ignored when a custom HTTPClient is set.
+	// 0 means no limit. Default: DefaultIdleConnTimeout.
+	IdleConnTimeout time.Duration
+
+	// ResponseHeaderTimeout limits how long to wait for the server's
+	// response headers after sending a request.
+	// Ignored when a custom HTTPClient is set. Default: 0 (no limit).
+	ResponseHeaderTimeout time.Duration
+
+	// ContextService extracts a per-request service name from the context
+	// passed to the *Context logging methods. An empty result falls back
+	// to Service.
+	ContextService func(context.Context) string
+
+	// ContextMetadata extracts metadata from the context passed to the
+	// *Context logging methods. Per-log metadata overrides it.
+	// Default: nil.
+	ContextMetadata func(context.Context) map[string]any
+
+	// ServiceFunc derives the service name of each log that has none of
+	// its own. ContextService takes precedence; an empty result falls back
+	// to Service.
+	ServiceFunc func(entry *LogEntry) string
+
+	// ShutdownTimeout bounds how long Close waits for Shutdown.
+	// Default: 10s.
+	ShutdownTimeout time.Duration
+
 	// OnError is called when an error occurs during logging.
 	OnError func(*Error)
 
 	// OnFlush is called after a successful flush with the count of logs sent.
 	OnFlush func(int)
+
+	// OnRetry is called before each retry of a failed batch.
+	OnRetry func(RetryInfo)
+
+	// TransportDebug is called after each ingest request with the request
+	// (credentials redacted), the response and its body, and the error.
+	// Default: nil.
+	TransportDebug func(req *http.Request, resp *http.Response, body []byte, err error)
+
+	// ResponseValidator, when set, is called with the status and body of
+	// each 2xx ingest response; an error fails the send. Default: nil.
+	ResponseValidator func(status int, body []byte) error
+
+	// ConnectionMetrics counts new and reused connections of ingest
+	// requests in Stats. Default: false.
+	ConnectionMetrics bool
+
+	// Diagnostics, when set, receives SDK-internal events at
+	// DiagnosticsLevel and above. Default: nil (silent).
+	Diagnostics      io.Writer
+	DiagnosticsLevel LogLevel
+
+	// DeadLetter, when set, is called with entries abandoned for good and
+	// the error that ended them. Default: nil.
+	DeadLetter func(entries []LogEntry, cause *Error)
+
+	// SnapshotPath, when set, is the file Shutdown writes the entries its
+	// final flush could not deliver to. Default: "" (none).
+	SnapshotPath string
+
+	// OnShutdown, when set, is called once by Shutdown after the final
+	// flush, with Shutdown's context. Default: nil.
+	OnShutdown func(ctx context.Context)
+
+	// OnShutdownStats, when set, is called once by Shutdown after
+	// OnShutdown, with the drain's stats. Default: nil.
+	OnShutdownStats func(stats ShutdownStats)
+
+	// FallbackWriter, when set, receives batches that failed to send as
+	// JSON lines once FallbackAfter batches in a row have failed.
+	// Default: nil (failed batches are dropped).
+	FallbackWriter io.Writer
+	FallbackAfter  int
+
+	// DryRun, when true, makes the client accept batches without sending
+	// them, writing them to DryRunWriter as JSON lines when it is set.
+	// Default: false.
+	DryRun       bool
+	DryRunWriter io.Writer
+
+	// FallbackEndpoints are tried in order when sends to the endpoint fail
+	// with a retryable error. FallbackAPIKeys maps fallbacks that do not
+	// share the API key to their own. While pinned to a fallback, the
+	// endpoint is probed every FailbackInterval.
+	// Default: nil (no failover).
+	FallbackEndpoints []string
+	FallbackAPIKeys   map[string]string
+	FailbackInterval  time.Duration
+
+	// Endpoints, when set, are load balanced with the endpoint by
+	// BalanceStrategy. An endpoint that keeps failing leaves the rotation
+	// and is probed every EndpointCooldown until it rejoins.
+	// Default: nil (single endpoint).
+	Endpoints        []string
+	BalanceStrategy  BalanceStrategy
+	EndpointCooldown time.Duration
+
+	// OfflineAfter, when positive, enables offline mode: after this many
+	// consecutive network errors, flushes pause and logs stay queued while
+	// the endpoint is probed every OfflineProbeInterval.
+	// Default: 0 (disabled).
+	OfflineAfter         int
+	OfflineProbeInterval time.Duration
+
+	// RetryBudget, when positive, caps the retries made across all batches
+	// to this many per RetryBudgetWindow; once it is spent, failed sends
+	// are not retried until the window resets.
+	// Default: 0 (each batch retries up to MaxRetries).
+	RetryBudget       int
+	RetryBudgetWindow time.Duration
+
+	// OnDrop is called with the number of entries dropped due to queue
+	// overflow, level sampling, or a flush or shutdown deadline, and with
+	// the number of metadata keys removed by MetadataAllowlist.
+	OnDrop func(int)
+
+	// QueueHighWatermark is the fraction of MaxQueueSize at which
+	// OnQueueHighWatermark is called. Default: 0 (disabled), Range: 0-1.
+	QueueHighWatermark float64
+
+	// OnQueueHighWatermark is called each time the queue length rises to
+	// QueueHighWatermark, and again only after a flush empties the queue.
+	OnQueueHighWatermark func()
+
+	// OverflowReportInterval limits overflow notifications to one summary
+	// per interval instead of one per overflowing call.
+	// Default: 0 (report every drop).
+	OverflowReportInterval time.Duration
 }
 
 // Option is a functional option for configuring the client.
@@ -86,6 +480,17 @@ func WithBatchSize(n int) Option {
 	}
 }
 
+// WithBatchBytes triggers a flush once the estimated serialized size of
+// the queued entries reaches n bytes, in addition to the batch size and
+// flush interval; whichever is reached first flushes. The size is
+// estimated per entry as it is queued, without marshaling. Must be 0
+// (disabled) or positive.
+func WithBatchBytes(n int) Option {
+	return func(c *Config) {
+		c.BatchBytes = n
+	}
+}
+
 // WithFlushInterval sets the maximum time to wait before flushing.
 // Must be between 100ms and 60s.
 func WithFlushInterval(d time.Duration) Option {
@@ -94,6 +499,52 @@ func WithFlushInterval(d time.Duration) Option {
 	}
 }
 
+// WithMaxBufferAge separates the two flush timers. FlushInterval becomes a
+// debounce, flushing once no log has been added for that long, and the queue
+// is also flushed when its oldest entry has waited d, so constant traffic
+// cannot postpone a flush indefinitely. Without it, FlushInterval alone is
+// measured from the oldest entry. Must be 0 (disabled) or at least 100ms.
+//
+// Example:
+//
+//	// Flush after 1s of quiet, and at least every 10s under load
+//	logwell.WithFlushInterval(time.Second),
+//	logwell.WithMaxBufferAge(10*time.Second),
+func WithMaxBufferAge(d time.Duration) Option {
+	return func(c *Config) {
+		c.MaxBufferAge = d
+	}
+}
+
+// WithMinFlushBytes keeps timer-triggered flushes from sending tiny
+// requests: the FlushInterval inactivity flush is skipped while the
+// estimated serialized size of the queued entries is below n bytes, and
+// the entries wait for more logs or for MaxBufferAge, which always
+// flushes, so nothing waits longer than that. Flush, Shutdown and full
+// batches are not affected. Requires WithMaxBufferAge.
+//
+// Example:
+//
+//	// Send at least 16 KiB per timed flush, and anything within 30s
+//	logwell.WithMaxBufferAge(30*time.Second),
+//	logwell.WithMinFlushBytes(16<<10),
+func WithMinFlushBytes(n int) Option {
+	return func(c *Config) {
+		c.MinFlushBytes = n
+	}
+}
+
+// WithMaxEntryAge guarantees that entries do not wait in the client much
+// longer than d. The queue flushes once its oldest entry reaches 90% of d,
+// even if FlushInterval is longer, and a batch whose oldest entry is still
+// failing at 10x d is dropped through OnDrop instead of being retried.
+// Must be 0 (disabled) or at least 100ms.
+func WithMaxEntryAge(d time.Duration) Option {
+	return func(c *Config) {
+		c.MaxEntryAge = d
+	}
+}
+
 // WithMaxQueueSize sets the maximum queue size.
 // Must be between 1 and 10000.
 func WithMaxQueueSize(n int) Option {
@@ -102,6 +553,18 @@ func WithMaxQueueSize(n int) Option {
 	}
 }
 
+// WithMaxInFlightBytes caps the memory held by queued logs: when the
+// estimated serialized size of the queue would go past n bytes, the queue
+// counts as full, as it does at MaxQueueSize, and the overflow policy
+// applies, evicting as many entries as needed. A single entry larger than
+// n is still accepted into an empty queue. Sizes are estimated per entry
+// as it is queued. Must be 0 (no byte cap) or positive.
+func WithMaxInFlightBytes(n int64) Option {
+	return func(c *Config) {
+		c.MaxInFlightBytes = n
+	}
+}
+
 // WithMaxRetries sets the maximum number of retry attempts.
 // Must be between 0 and 10.
 func WithMaxRetries(n int) Option {
@@ -110,152 +573,1407 @@ func WithMaxRetries(n int) Option {
 	}
 }
 
-// WithService sets the service name attached to all logs.
-func WithService(s string) Option {
+// WithAdaptiveBatching lets the client tune its batch size between minSize
+// and maxSize. The effective size starts at BatchSize (clamped to the range),
+// grows toward maxSize while flushes succeed quickly, and halves when the
+// server answers 413 Payload Too Large, a request times out, or a flush is
+// slow. A batch rejected with 413 is reported as failed. The current size
+// is reported by Client.Stats. Both bounds must be between 1 and 500.
+func WithAdaptiveBatching(minSize, maxSize int) Option {
 	return func(c *Config) {
-		c.Service = s
+		c.AdaptiveBatchMin = minSize
+		c.AdaptiveBatchMax = maxSize
 	}
 }
 
-// WithMetadata sets default metadata attached to all logs.
-func WithMetadata(m map[string]any) Option {
+// WithMaxConcurrentFlushes allows up to n batches to be in flight at once,
+// raising throughput when round trips are slow. Batches are taken from the
+// queue in order, and entries within a batch keep their order, but batches
+// may be acknowledged out of order, so cross-batch order on the server is
+// best-effort when n > 1. OnFlush and OnError may then be called from
+// several goroutines at once. Must be between 1 and 32.
+func WithMaxConcurrentFlushes(n int) Option {
 	return func(c *Config) {
-		c.Metadata = m
+		c.MaxConcurrentFlushes = n
 	}
 }
 
-// WithOnError sets the error callback.
-func WithOnError(fn func(*Error)) Option {
+// WithOverflowPolicy sets what happens when the queue is full. DropOldest
+// (the default) evicts the oldest entry. DropLowestSeverity evicts the
+// least severe entry instead, keeping errors over debug noise. Block makes
+// the producer wait for a flush to free space, for logs that must not be
+// dropped such as audit trails; the wait is bounded only by the context of
+// the *Context methods, LogContext, LogBatchContext and LogSync, so with
+// Block, calls without a context wait for as long as the server takes to
+// accept a batch.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
 	return func(c *Config) {
-		c.OnError = fn
+		c.OverflowPolicy = policy
 	}
 }
 
-// WithOnFlush sets the flush callback.
-func WithOnFlush(fn func(int)) Option {
+// WithSpillToDisk sets the SpillToDisk overflow policy: entries that do not
+// fit in MaxQueueSize are appended to files in dir instead of evicting
+// queued ones, and read back oldest first, ahead of newer entries, as
+// flushes make room. While entries are spilled, new entries are spilled
+// behind them, so logs are sent in the order they were made. When the
+// files reach maxBytes the oldest are deleted, reporting their entries
+// through OnDrop and an ErrQueueOverflow error.
+//
+// Spilled entries count toward QueueLen. Entries still queued at Shutdown
+// are left in dir and sent by the next client that uses it, so dir must not
+// be shared by clients running at the same time. Children created with
+// ChildWithQueue do not spill; their queues drop the oldest entry.
+func WithSpillToDisk(dir string, maxBytes int64) Option {
 	return func(c *Config) {
-		c.OnFlush = fn
+		c.OverflowPolicy = SpillToDisk
+		c.SpillDir = dir
+		c.SpillMaxBytes = maxBytes
 	}
 }
 
-// WithCaptureSourceLocation enables or disables source location capture.
-func WithCaptureSourceLocation(enabled bool) Option {
+// WithRequeueOnFailure puts a batch whose send failed with a retryable error
+// (see IsRetryable) back at the front of the queue, preserving order, so it
+// is sent again with the next flush instead of being discarded. Requeued
+// entries count toward MaxQueueSize; when they do not all fit, the oldest
+// are dropped. Batches failing during Shutdown are not requeued.
+func WithRequeueOnFailure(enabled bool) Option {
 	return func(c *Config) {
-		c.CaptureSourceLocation = enabled
+		c.RequeueOnFailure = enabled
 	}
 }
 
-// WithHTTPClient sets a custom HTTP client.
-func WithHTTPClient(client *http.Client) Option {
+// WithContextDeadlinePropagation makes a flush triggered in the background,
+// such as by an InfoContext call filling the batch, inherit the deadline of
+// the contexts its logs were logged with, the earliest one if there are
+// several, so the send cannot outlive the request. A send cut short fails
+// like a network error: it is requeued under WithRequeueOnFailure and
+// reported through OnError otherwise. Deadlines that passed before the
+// flush started are ignored, and Flush and Shutdown use their own context.
+func WithContextDeadlinePropagation(enabled bool) Option {
 	return func(c *Config) {
-		c.HTTPClient = client
+		c.ContextDeadlinePropagation = enabled
 	}
 }
 
-// newDefaultConfig creates a Config with default values.
-func newDefaultConfig(endpoint, apiKey string) *Config {
-	return &Config{
-		Endpoint:              endpoint,
-		APIKey:                apiKey,
-		BatchSize:             DefaultBatchSize,
-		FlushInterval:         DefaultFlushInterval,
-		MaxQueueSize:          DefaultMaxQueueSize,
-		MaxRetries:            DefaultMaxRetries,
-		CaptureSourceLocation: false,
-		HTTPClient:            http.DefaultClient,
+// WithBeforeSend sets a hook called with each batch just before it is sent.
+// The returned slice is sent in place of logs, so the hook can filter
+// entries or add metadata; an empty slice sends nothing. Returning false
+// cancels the send: the batch is requeued when WithRequeueOnFailure is
+// enabled and the client is not shutting down, and dropped and reported
+// through OnDrop otherwise. The hook may modify logs in place. It runs on
+// the flushing goroutine, concurrently with itself when MaxConcurrentFlushes
+// is above 1, and must be fast.
+func WithBeforeSend(fn func(logs []LogEntry) ([]LogEntry, bool)) Option {
+	return func(c *Config) {
+		c.BeforeSend = fn
 	}
 }
 
-// validateEndpoint validates the endpoint configuration.
-func validateEndpoint(endpoint string) error {
-	if endpoint == "" {
-		return NewError(ErrInvalidConfig, "endpoint is required")
+// WithDeliveryMode sets the delivery guarantee. AtLeastOnce (the default)
+// retries all transient failures and may produce duplicates; AtMostOnce
+// avoids duplicates by not retrying failures after the request was sent,
+// at the cost of possibly losing those batches.
+func WithDeliveryMode(mode DeliveryMode) Option {
+	return func(c *Config) {
+		c.DeliveryMode = mode
 	}
+}
 
-	parsedURL, err := url.Parse(endpoint)
-	if err != nil {
-		return NewError(ErrInvalidConfig, "endpoint is not a valid URL: "+err.Error())
+// WithEncoder replaces json.Marshal for encoding request bodies, e.g. with a
+// faster JSON library. The encoder must produce JSON that encoding/json
+// would accept, and honour the json struct tags on LogEntry.
+//
+// Example:
+//
+//	client, err := logwell.New(endpoint, apiKey,
+//	    logwell.WithEncoder(sonic.Marshal),
+//	)
+func WithEncoder(encode func(any) ([]byte, error)) Option {
+	return func(c *Config) {
+		c.Encoder = encode
 	}
+}
 
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return NewError(ErrInvalidConfig, "endpoint must use http or https scheme")
+// WithCompressionThreshold gzip-compresses request bodies of at least
+// bytes bytes once encoded, sending them with a Content-Encoding: gzip
+// header. Smaller bodies are sent as plain JSON, since compressing a few
+// hundred bytes costs more CPU and latency than it saves; around 1024 is a
+// good starting point. Batches estimated at more than 1 MiB encoded, which
+// the default encoder streams instead of encoding up front, are always
+// compressed. The ingest server, or a proxy in front of it, must accept
+// gzip request bodies. 0, the default, disables compression. Use
+// WithCompression to choose the encoding.
+func WithCompressionThreshold(bytes int) Option {
+	return func(c *Config) {
+		c.CompressionThreshold = bytes
 	}
+}
 
-	if parsedURL.Host == "" {
-		return NewError(ErrInvalidConfig, "endpoint must have a host")
+// WithCompression compresses request bodies with the first of compressors
+// the server accepts, sending the matching Content-Encoding header. When
+// the server answers 415 Unsupported Media Type, the batch is resent at
+// once with the next encoding, then gzip, then uncompressed, and the
+// client keeps the encoding that worked. Bodies are compressed from
+// DefaultCompressionThreshold encoded bytes unless WithCompressionThreshold
+// sets another size. zstd is provided by the logwellzstd module:
+//
+//	logwell.WithCompression(logwellzstd.Zstd, logwell.CompressionGzip)
+func WithCompression(compressors ...Compressor) Option {
+	return func(c *Config) {
+		c.Compression = compressors
 	}
+}
 
-	return nil
+// WithAPIKeyFile reads the API key from path, for secrets mounted as files
+// that rotate on disk. The key passed to New is ignored. The file is read at
+// New, re-read every APIKeyRefreshInterval (see WithAPIKeyRefreshInterval),
+// and re-read immediately when the server answers 401, in which case the
+// request is retried once with the new key. Surrounding whitespace in the
+// file is ignored, and an invalid key keeps the previous one and is reported
+// through the OnError callback.
+func WithAPIKeyFile(path string) Option {
+	return func(c *Config) {
+		c.APIKeyFile = path
+	}
 }
 
-// validateAPIKey validates the API key format.
-func validateAPIKey(apiKey string) error {
-	if apiKey == "" {
-		return NewError(ErrInvalidConfig, "apiKey is required")
+// WithTokenProvider obtains the bearer token from fn before each request,
+// for short-lived tokens that replace a static API key. The API key passed
+// to New is then not validated and never sent, so it may be empty. fn is
+// called for every request, including retries, and decides itself whether
+// to cache tokens. If fn returns an error, the batch fails with
+// ErrUnauthorized and is not retried.
+//
+// Example:
+//
+//	client, err := logwell.New(endpoint, "",
+//	    logwell.WithTokenProvider(func(ctx context.Context) (string, error) {
+//	        return tokenSource.Token(ctx)
+//	    }),
+//	)
+func WithTokenProvider(fn func(ctx context.Context) (string, error)) Option {
+	return func(c *Config) {
+		c.TokenProvider = fn
 	}
+}
 
-	if !apiKeyRegex.MatchString(apiKey) {
-		return NewError(ErrInvalidConfig, "apiKey format invalid: must match lw_[a-zA-Z0-9_-]{32,}")
+// WithBasicAuth sends HTTP Basic credentials for a reverse proxy in front of
+// Logwell. Basic auth takes over the Authorization header, so the API key
+// (or WithTokenProvider token) is sent as "Bearer <key>" in the
+// X-Logwell-Authorization header instead. The proxy must check the Basic
+// credentials and forward X-Logwell-Authorization to Logwell as its
+// Authorization header. user must be non-empty and must not contain a colon.
+func WithBasicAuth(user, pass string) Option {
+	return func(c *Config) {
+		c.BasicAuthUser = user
+		c.BasicAuthPassword = pass
 	}
+}
 
-	return nil
+// WithAPIKeyRefreshInterval sets how often the WithAPIKeyFile file is
+// re-read. Zero re-reads it only after the server rejects the key.
+// Must not be negative.
+func WithAPIKeyRefreshInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.APIKeyRefreshInterval = d
+	}
 }
 
-// validateBatchSize validates the batch size configuration.
-func validateBatchSize(batchSize int) error {
-	if batchSize < MinBatchSize || batchSize > MaxBatchSize {
-		return NewError(ErrInvalidConfig, "batchSize must be between 1 and 500")
+// WithService sets the service name attached to all logs.
+func WithService(s string) Option {
+	return func(c *Config) {
+		c.Service = s
 	}
-	return nil
 }
 
-// validateFlushInterval validates the flush interval configuration.
-func validateFlushInterval(flushInterval time.Duration) error {
-	if flushInterval < MinFlushInterval || flushInterval > MaxFlushInterval {
-		return NewError(ErrInvalidConfig, "flushInterval must be between 100ms and 60s")
+// WithEnvironment sets the deployment environment attached to all logs,
+// such as "production", "staging" or "dev". Without it, New reads the
+// environment from the first of the LOGWELL_ENV, ENVIRONMENT, ENV and
+// APP_ENV variables that is set.
+func WithEnvironment(name string) Option {
+	return func(c *Config) {
+		c.Environment = name
 	}
-	return nil
 }
 
-// validateMaxQueueSize validates the max queue size configuration.
-func validateMaxQueueSize(maxQueueSize int) error {
-	if maxQueueSize < MinMaxQueueSize || maxQueueSize > MaxMaxQueueSize {
-		return NewError(ErrInvalidConfig, "maxQueueSize must be between 1 and 10000")
+// WithDisableServiceAutodetect keeps logs without a service name when none
+// is configured. By default New derives one from the executable name, or
+// from the main module path for binaries built by go run, and flags the
+// logs sent under it with the ServiceAutodetectedKey metadata key. Test
+// binaries built by go test get no default.
+func WithDisableServiceAutodetect() Option {
+	return func(c *Config) {
+		c.DisableServiceAutodetect = true
 	}
-	return nil
 }
 
-// validateMaxRetries validates the max retries configuration.
-func validateMaxRetries(maxRetries int) error {
-	if maxRetries < MinMaxRetries || maxRetries > MaxMaxRetries {
-		return NewError(ErrInvalidConfig, "maxRetries must be between 0 and 10")
+// WithMetadataAllowlist guarantees that only the listed metadata keys leave
+// the process. After config and per-log metadata are merged (and
+// flattened, with WithFlattenMetadata, so nested keys are matched by their
+// joined names), any other key is removed and the number removed is
+// reported through OnDrop. Calling it with no keys allows none, removing
+// all metadata; not calling it allows every key.
+func WithMetadataAllowlist(keys ...string) Option {
+	return func(c *Config) {
+		c.MetadataAllowlist = append(make([]string, 0, len(keys)), keys...)
 	}
-	return nil
 }
 
-// validateConfig validates the configuration and returns an error if invalid.
-func validateConfig(c *Config) error {
-	if err := validateEndpoint(c.Endpoint); err != nil {
-		return err
+// WithMetadata sets default metadata attached to all logs.
+// The map is deep-copied, so later changes by the caller do not affect logs.
+func WithMetadata(m map[string]any) Option {
+	return func(c *Config) {
+		c.Metadata = copyMetadata(m)
 	}
+}
 
-	if err := validateAPIKey(c.APIKey); err != nil {
-		return err
+// WithFlattenMetadata flattens nested metadata maps into keys joined by
+// separator before logs are queued, so {"user": {"id": 1}} is sent as
+// {"user.id": 1} and can be queried on the server. Slices and scalar values
+// are left intact. An empty separator disables flattening.
+func WithFlattenMetadata(separator string) Option {
+	return func(c *Config) {
+		c.FlattenSeparator = separator
 	}
+}
 
-	if err := validateBatchSize(c.BatchSize); err != nil {
-		return err
+// WithMinLevel sets the minimum level sent to the server.
+// Logs below this level are discarded without being queued.
+func WithMinLevel(level LogLevel) Option {
+	return func(c *Config) {
+		c.MinLevel = level
 	}
+}
 
-	if err := validateFlushInterval(c.FlushInterval); err != nil {
-		return err
+// WithPriorityLevels queues entries at the given levels in a priority lane.
+// On every flush the lane is sent before other entries, and when the queue
+// overflows other entries are evicted first, so an error is not lost behind
+// a backlog of debug logs. Order is preserved within each lane. The lane
+// shares MaxQueueSize with the rest of the queue; its own oldest entries are
+// evicted only when it holds the whole queue.
+//
+// Example:
+//
+//	logwell.WithPriorityLevels(logwell.LevelError, logwell.LevelFatal)
+func WithPriorityLevels(levels ...LogLevel) Option {
+	return func(c *Config) {
+		c.PriorityLevels = levels
 	}
+}
 
-	if err := validateMaxQueueSize(c.MaxQueueSize); err != nil {
-		return err
+// WithLevelSampling keeps only a fraction of the entries at each level
+// given in rates, from 0 (drop all) to 1 (keep all); levels not in rates are
+// always kept. Each entry is kept or dropped independently at random before
+// it is queued, and dropped entries are reported through OnDrop. The map is
+// copied.
+//
+// Example:
+//
+//	// Keep 10% of debug and info logs and every warning and above
+//	logwell.WithLevelSampling(map[logwell.LogLevel]float64{
+//	    logwell.LevelDebug: 0.1,
+//	    logwell.LevelInfo:  0.1,
+//	})
+func WithLevelSampling(rates map[LogLevel]float64) Option {
+	return func(c *Config) {
+		c.LevelSampling = make(map[LogLevel]float64, len(rates))
+		for level, rate := range rates {
+			c.LevelSampling[level] = rate
+		}
 	}
+}
 
-	if err := validateMaxRetries(c.MaxRetries); err != nil {
+// WithLevelAliases sends levels under the names given in aliases, for
+// servers that expect names such as "warning" or "critical". Only the wire
+// format changes: entries keep their LogLevel everywhere else, including in
+// callbacks, the fallback writer and snapshots. Levels not in aliases are
+// sent as is. The map is copied.
+//
+// Example:
+//
+//	logwell.WithLevelAliases(map[logwell.LogLevel]string{
+//	    logwell.LevelWarn:  "warning",
+//	    logwell.LevelFatal: "critical",
+//	})
+func WithLevelAliases(aliases map[LogLevel]string) Option {
+	return func(c *Config) {
+		c.LevelAliases = make(map[LogLevel]string, len(aliases))
+		for level, alias := range aliases {
+			c.LevelAliases[level] = alias
+		}
+	}
+}
+
+// WithTimestampSkewGuard checks the timestamps callers set on entries
+// passed to Log, LogContext and LogBatch: one more than max before or after
+// now is replaced with the current time, or the entry is dropped with
+// WithTimestampSkewAction(DropSkewed), and an ErrValidationError is
+// reported through OnError. Timestamps generated by the client, and those
+// of replayed entries, are not checked. Timestamps that do not parse as
+// RFC 3339 are sent as is.
+func WithTimestampSkewGuard(max time.Duration) Option {
+	return func(c *Config) {
+		c.TimestampSkewMax = max
+	}
+}
+
+// WithTimestampSkewAction sets what WithTimestampSkewGuard does with an
+// implausible timestamp: ClampSkewed (the default) or DropSkewed.
+func WithTimestampSkewAction(action TimestampSkewAction) Option {
+	return func(c *Config) {
+		c.TimestampSkewAction = action
+	}
+}
+
+// WithMinLevelString sets the minimum level from a string such as "warn".
+// The string is parsed with ParseLevel; New returns ErrInvalidConfig
+// if it is not a known level or alias.
+func WithMinLevelString(s string) Option {
+	return func(c *Config) {
+		level, err := ParseLevel(s)
+		if err != nil {
+			// Keep the raw value so validateConfig reports it
+			c.MinLevel = LogLevel(s)
+			return
+		}
+		c.MinLevel = level
+	}
+}
+
+// WithContextService registers a function that derives the service name
+// from the context passed to DebugContext, InfoContext, and the other
+// *Context methods. Returning "" uses the configured service.
+func WithContextService(fn func(ctx context.Context) string) Option {
+	return func(c *Config) {
+		c.ContextService = fn
+	}
+}
+
+// WithContextMetadata registers a function that derives metadata from the
+// context passed to DebugContext, InfoContext, the other *Context methods
+// and LogContext, such as request attributes carried by the context. It is
+// merged over the client's metadata and under the log's own. Returning nil
+// adds nothing.
+func WithContextMetadata(fn func(ctx context.Context) map[string]any) Option {
+	return func(c *Config) {
+		c.ContextMetadata = fn
+	}
+}
+
+// WithServiceFunc registers a function that derives the service name of
+// each log, e.g. from a shard index in its metadata. It runs for logs
+// without a Service of their own, after ContextService, and before the
+// client's metadata is merged in, so entry.Metadata holds only the
+// metadata passed with the log. Returning "" uses the configured service.
+// The function must not modify entry and may be called concurrently.
+func WithServiceFunc(fn func(entry *LogEntry) string) Option {
+	return func(c *Config) {
+		c.ServiceFunc = fn
+	}
+}
+
+// WithShutdownTimeout sets how long Close waits for queued logs to flush.
+// Must be positive.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.ShutdownTimeout = d
+	}
+}
+
+// WithOnError sets the error callback.
+func WithOnError(fn func(*Error)) Option {
+	return func(c *Config) {
+		c.OnError = fn
+	}
+}
+
+// WithOnRetry sets a callback called before each retry of a failed batch,
+// with the attempt number, the error, the backoff delay, and the batch size.
+// It runs synchronously on the sending goroutine before the backoff wait,
+// so it must be fast: a slow callback delays the retry and later batches.
+func WithOnRetry(fn func(RetryInfo)) Option {
+	return func(c *Config) {
+		c.OnRetry = fn
+	}
+}
+
+// WithTransportDebug sets a hook called after every ingest request, for
+// debugging ingest problems without enabling global HTTP tracing. It
+// receives a copy of the request with the Authorization and
+// X-Logwell-Authorization headers redacted and no body, the response (nil
+// if none was received; its body is already consumed), the response body
+// (nil if it could not be read), and the error send reports, including
+// errors for non-2xx statuses. Retries call it once per attempt. Like
+// OnRetry, it runs on the sending goroutine and must be fast.
+func WithTransportDebug(fn func(req *http.Request, resp *http.Response, body []byte, err error)) Option {
+	return func(c *Config) {
+		c.TransportDebug = fn
+	}
+}
+
+// WithResponseValidator sets a check of successful ingest responses, for
+// proxies that answer with an error page and a 2xx status. fn is called
+// with the status and body of every 2xx response; when it returns an
+// error, the send fails with an ErrServerError wrapping it and is retried
+// like a 5xx, so the batch is not counted as delivered. As the server may
+// have stored the batch, AtMostOnce does not retry it. Like OnRetry, fn
+// runs on the sending goroutine and must be fast.
+//
+// Example:
+//
+//	logwell.WithResponseValidator(func(status int, body []byte) error {
+//	    if len(body) > 0 && !json.Valid(body) {
+//	        return errors.New("response is not JSON")
+//	    }
+//	    return nil
+//	})
+func WithResponseValidator(fn func(status int, body []byte) error) Option {
+	return func(c *Config) {
+		c.ResponseValidator = fn
+	}
+}
+
+// WithConnectionMetrics counts the connections ingest requests are sent
+// on, reported by Stats as ConnsNew and ConnsReused, to debug connection
+// churn such as keep-alives closed by a proxy. Each request is traced with
+// net/http/httptrace while enabled.
+func WithConnectionMetrics(enabled bool) Option {
+	return func(c *Config) {
+		c.ConnectionMetrics = enabled
+	}
+}
+
+// WithDiagnostics writes SDK-internal events at level and above to w, one
+// line each: flush failures and warnings at error and warn, retries at
+// debug, and a shutdown summary at info. Queue overflows are written at most
+// once every 10 seconds with a count of those in between. The content of
+// user logs is never written. Events are also passed to OnError and OnRetry
+// as usual. Writes happen synchronously, so w should be fast, e.g. os.Stderr.
+func WithDiagnostics(w io.Writer, level LogLevel) Option {
+	return func(c *Config) {
+		c.Diagnostics = w
+		c.DiagnosticsLevel = level
+	}
+}
+
+// WithDeadLetter sets a callback that receives the entries abandoned for
+// good, with the error that ended them: batches rejected with a
+// non-retryable error such as a 400, batches that failed after their last
+// retry or went past the max entry age, and entries left unsent when a
+// flush or shutdown deadline ends. Use it to keep them elsewhere, such as
+// object storage or a local file. Entries are not copied; the callback may
+// keep them.
+//
+// The callback runs on a goroutine of its own, one call at a time, and
+// Shutdown waits for pending calls until its context ends. It is separate
+// from OnDrop, which counts entries dropped from the queue by overflow or
+// sampling; entries left unsent at a deadline are reported to both.
+// Batches put back in the queue by RequeueOnFailure are not abandoned.
+func WithDeadLetter(fn func(entries []LogEntry, cause *Error)) Option {
+	return func(c *Config) {
+		c.DeadLetter = fn
+	}
+}
+
+// WithSnapshotOnShutdown makes Shutdown write the entries its final flush
+// could not deliver, because the flush failed with a retryable error or
+// its context ended, to path as JSON lines, so they can be sent later with
+// Replay. The file is created if needed and appended to; it is not written
+// when every entry was delivered. Entries rejected for good, such as by a
+// 400, are not kept, and neither are entries left in the SpillToDisk files.
+// Written entries still count as failed or dropped in ShutdownStats.
+//
+// Example:
+//
+//	client, _ := logwell.New(endpoint, apiKey,
+//	    logwell.WithSnapshotOnShutdown("/var/lib/app/unsent.ndjson"))
+func WithSnapshotOnShutdown(path string) Option {
+	return func(c *Config) {
+		c.SnapshotPath = path
+	}
+}
+
+// WithOnShutdown sets a callback that Shutdown calls after the final flush
+// completes, fails or runs out of time, with the context passed to
+// Shutdown. Use it to flush other buffers or emit a final metric alongside
+// the logger. It is called once, by the first Shutdown or Close of the
+// root client; children never call it.
+func WithOnShutdown(fn func(ctx context.Context)) Option {
+	return func(c *Config) {
+		c.OnShutdown = fn
+	}
+}
+
+// WithOnShutdownStats sets a callback that Shutdown calls with the stats
+// ShutdownWithStats returns, after the final flush and any OnShutdown
+// callback. Use it to emit final sent and dropped gauges or mark logging
+// as no longer available. It is called once, by the first Shutdown or
+// Close of the root client, after the OnFlush and OnError calls for the
+// final batches and with no locks held, so it may call back into the
+// client; children never call it.
+func WithOnShutdownStats(fn func(stats ShutdownStats)) Option {
+	return func(c *Config) {
+		c.OnShutdownStats = fn
+	}
+}
+
+// WithFallbackWriter writes batches the server could not take to w, one
+// JSON log entry per line, once after batches in a row have failed, instead
+// of dropping them. Pointing w at os.Stderr lets container log collection
+// pick logs up during an outage. When a batch is sent again, a recovery
+// notice is written to w and logs go to the server as usual. Failed batches
+// are still reported through OnError; batches put back in the queue by
+// RequeueOnFailure are not written. Writes happen synchronously.
+func WithFallbackWriter(w io.Writer, after int) Option {
+	return func(c *Config) {
+		c.FallbackWriter = w
+		c.FallbackAfter = after
+	}
+}
+
+// WithDryRun makes the client go through batching and flushing as usual
+// but never contact the server, for local development and CI. Every batch
+// counts as accepted, so OnFlush and Stats report it as sent. The endpoint
+// is still validated but need not exist.
+//
+// Example:
+//
+//	logwell.WithDryRun(os.Getenv("CI") != ""),
+//	logwell.WithDryRunWriter(os.Stderr),
+func WithDryRun(enabled bool) Option {
+	return func(c *Config) {
+		c.DryRun = enabled
+	}
+}
+
+// WithDryRunWriter writes the batches of a dry run to w, one JSON log entry
+// per line. It has no effect without WithDryRun. Writes happen
+// synchronously.
+func WithDryRunWriter(w io.Writer) Option {
+	return func(c *Config) {
+		c.DryRunWriter = w
+	}
+}
+
+// WithOfflineMode pauses sending while the ingest endpoint is unreachable,
+// e.g. on a laptop without connectivity. After after consecutive network
+// errors the client goes offline: retries stop, OnError is called once, and
+// logs stay queued up to MaxQueueSize instead of being dropped. The endpoint
+// is probed every probeInterval, and once it answers the queue is drained.
+// Error responses from the server do not count as network errors.
+func WithOfflineMode(after int, probeInterval time.Duration) Option {
+	return func(c *Config) {
+		c.OfflineAfter = after
+		c.OfflineProbeInterval = probeInterval
+	}
+}
+
+// WithFallbackEndpoints adds standby ingest endpoints, such as a cluster in
+// another region. When a send fails with a retryable error, each retry of
+// that batch goes to the next endpoint in turn: the endpoint passed to New,
+// then urls in order. After three such failures in a row on the primary,
+// the client pins to the fallback that took the batch, so later batches
+// start there, and probes the primary every FailbackInterval (see
+// WithFailbackInterval), failing back once it answers. Fallbacks share the
+// API key unless WithFallbackAPIKey sets their own.
+func WithFallbackEndpoints(urls ...string) Option {
+	return func(c *Config) {
+		c.FallbackEndpoints = urls
+	}
+}
+
+// WithFallbackAPIKey sets the API key sent to the fallback endpoint, which
+// must also be passed to WithFallbackEndpoints. It takes precedence over
+// WithAPIKeyFile and WithTokenProvider for that endpoint.
+func WithFallbackAPIKey(endpoint, apiKey string) Option {
+	return func(c *Config) {
+		if c.FallbackAPIKeys == nil {
+			c.FallbackAPIKeys = make(map[string]string)
+		}
+		c.FallbackAPIKeys[endpoint] = apiKey
+	}
+}
+
+// WithFailbackInterval sets how often the primary endpoint is probed while
+// the client is pinned to a fallback. Default: DefaultFailbackInterval.
+func WithFailbackInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.FailbackInterval = d
+	}
+}
+
+// WithEndpoints spreads batches over the endpoint passed to New and urls,
+// for an ingest tier behind several equivalent endpoints such as one per
+// availability zone. RoundRobin sends batches to each in turn and Random
+// picks one per batch; retries go to another endpoint. After three
+// retryable failures in a row an endpoint leaves the rotation and is
+// probed every EndpointCooldown (see WithEndpointCooldown) until it
+// answers, then rejoins. All endpoints share the API key. Per-endpoint
+// counts are reported in Stats.Endpoints. It cannot be combined with
+// WithFallbackEndpoints.
+func WithEndpoints(strategy BalanceStrategy, urls ...string) Option {
+	return func(c *Config) {
+		c.BalanceStrategy = strategy
+		c.Endpoints = urls
+	}
+}
+
+// WithEndpointCooldown sets how often an endpoint taken out of the
+// WithEndpoints rotation is probed to see whether it can rejoin.
+// Default: DefaultEndpointCooldown.
+func WithEndpointCooldown(d time.Duration) Option {
+	return func(c *Config) {
+		c.EndpointCooldown = d
+	}
+}
+
+// WithRetryBudget caps retries across all sends to maxRetriesPerWindow per
+// window, so a flapping server does not see every batch retried MaxRetries
+// times. Once the budget is spent, failed sends fail fast without retrying
+// until the window resets. The budget is shared with child loggers.
+func WithRetryBudget(maxRetriesPerWindow int, window time.Duration) Option {
+	return func(c *Config) {
+		c.RetryBudget = maxRetriesPerWindow
+		c.RetryBudgetWindow = window
+	}
+}
+
+// WithOnFlush sets the flush callback.
+func WithOnFlush(fn func(int)) Option {
+	return func(c *Config) {
+		c.OnFlush = fn
+	}
+}
+
+// WithOnDrop sets the callback invoked with the number of entries
+// dropped due to queue overflow, level sampling, or a flush or shutdown
+// deadline. With WithMetadataAllowlist it is also called with the number of
+// metadata keys removed from an entry.
+func WithOnDrop(fn func(int)) Option {
+	return func(c *Config) {
+		c.OnDrop = fn
+	}
+}
+
+// WithQueueHighWatermark calls fn when the queue fills to fraction of
+// MaxQueueSize, so callers can shed load before entries are dropped. fn
+// fires once as the length crosses the threshold upward and is re-armed when
+// a flush brings the queue back below it. It runs on the logging goroutine
+// that crossed the threshold, outside the queue lock, so it may call
+// QueueLen. fraction must be greater than 0 and at most 1.
+func WithQueueHighWatermark(fraction float64, fn func()) Option {
+	return func(c *Config) {
+		c.QueueHighWatermark = fraction
+		c.OnQueueHighWatermark = fn
+	}
+}
+
+// WithOverflowReportInterval batches queue overflow notifications so that
+// OnError and OnDrop fire at most once per interval with the total number
+// of entries dropped, instead of once per entry. Zero reports every drop.
+func WithOverflowReportInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.OverflowReportInterval = d
+	}
+}
+
+// WithCaptureSourceLocation enables or disables source location capture.
+func WithCaptureSourceLocation(enabled bool) Option {
+	return func(c *Config) {
+		c.CaptureSourceLocation = enabled
+	}
+}
+
+// WithSlowThreshold makes the timers started with StartTimer log at WARN
+// level instead of INFO when the operation took d or longer. Must be 0
+// (disabled) or positive.
+func WithSlowThreshold(d time.Duration) Option {
+	return func(c *Config) {
+		c.SlowThreshold = d
+	}
+}
+
+// WithHeartbeat logs a "service alive" info entry every interval, even
+// when the application logs nothing, for liveness dashboards. Its metadata
+// holds heartbeat: true, the client's uptime in seconds and its queue and
+// send counters, plus whatever metadataFn, if not nil, returns. Heartbeats
+// go through the normal pipeline, so they are subject to the minimum level
+// and sampling, and stop when Shutdown starts. metadataFn is called on the
+// heartbeat goroutine. Must be 0 (disabled) or positive.
+func WithHeartbeat(interval time.Duration, metadataFn func() M) Option {
+	return func(c *Config) {
+		c.HeartbeatInterval = interval
+		c.HeartbeatMetadata = metadataFn
+	}
+}
+
+// WithStartupDiagnostic makes New enqueue a "logwell client started" info
+// log whose metadata holds the effective configuration (batch size, flush
+// interval, service, and so on). The API key is never included. Like any
+// other log, it is discarded if the minimum level is above info.
+func WithStartupDiagnostic(enabled bool) Option {
+	return func(c *Config) {
+		c.StartupDiagnostic = enabled
+	}
+}
+
+// WithHTTPClient sets a custom HTTP client, used as-is in place of the
+// client the SDK builds. That client has a request timeout, dial, TLS
+// handshake and idle connection timeouts, keeps enough idle connections
+// for MaxConcurrentFlushes and attempts HTTP/2; a custom client should set
+// its own timeouts.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Config) {
+		c.HTTPClient = client
+	}
+}
+
+// WithInsecureSkipVerify disables TLS certificate verification, for local
+// development against servers with self-signed certificates. It has no effect
+// when a custom client is set with WithHTTPClient. When enabled, New reports
+// a one-time warning through the OnError callback.
+func WithInsecureSkipVerify(skip bool) Option {
+	return func(c *Config) {
+		c.InsecureSkipVerify = skip
+	}
+}
+
+// WithRequestTimeout sets how long one request attempt may take with the
+// HTTP client the SDK builds, from dialing to reading the response; each
+// retry gets its own. 0 removes the limit, leaving only the context passed
+// to Flush or Shutdown. It is ignored when a custom client is set with
+// WithHTTPClient; set that client's Timeout instead.
+// Default: DefaultRequestTimeout.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.RequestTimeout = d
+	}
+}
+
+// WithDialTimeout sets the connection timeout of the HTTP transport the
+// SDK builds. It is ignored when a custom client is set with WithHTTPClient;
+// configure the timeout on that client's transport instead.
+// Default: DefaultDialTimeout.
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.DialTimeout = d
+	}
+}
+
+// WithIdleConnTimeout sets how long the HTTP transport the SDK builds keeps
+// an idle connection open for reuse. Keep it below the idle timeout of any
+// load balancer or proxy in front of the server, or flushes may pick a
+// connection it has already closed. It is ignored when a custom client is
+// set with WithHTTPClient. Default: DefaultIdleConnTimeout.
+func WithIdleConnTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.IdleConnTimeout = d
+	}
+}
+
+// WithResponseHeaderTimeout sets how long the HTTP transport the SDK builds
+// waits for response headers. It is ignored when a custom client is set with
+// WithHTTPClient; configure the timeout on that client's transport instead.
+func WithResponseHeaderTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.ResponseHeaderTimeout = d
+	}
+}
+
+// newDefaultConfig creates a Config with default values.
+func newDefaultConfig(endpoint, apiKey string) *Config {
+	return &Config{
+		Endpoint:              endpoint,
+		APIKey:                apiKey,
+		BatchSize:             DefaultBatchSize,
+		FlushInterval:         DefaultFlushInterval,
+		MaxQueueSize:          DefaultMaxQueueSize,
+		MaxRetries:            DefaultMaxRetries,
+		MaxConcurrentFlushes:  DefaultMaxConcurrentFlushes,
+		DeliveryMode:          AtLeastOnce,
+		TimestampSkewAction:   ClampSkewed,
+		OverflowPolicy:        DropOldest,
+		Encoder:               json.Marshal,
+		MinLevel:              LevelDebug,
+		ShutdownTimeout:       DefaultShutdownTimeout,
+		APIKeyRefreshInterval: DefaultAPIKeyRefreshInterval,
+		CaptureSourceLocation: false,
+		HTTPClient:            http.DefaultClient,
+		RequestTimeout:        DefaultRequestTimeout,
+		DialTimeout:           DefaultDialTimeout,
+		IdleConnTimeout:       DefaultIdleConnTimeout,
+		FailbackInterval:      DefaultFailbackInterval,
+		BalanceStrategy:       RoundRobin,
+		EndpointCooldown:      DefaultEndpointCooldown,
+	}
+}
+
+// validateEndpoint validates the endpoint configuration.
+func validateEndpoint(endpoint string) error {
+	if endpoint == "" {
+		return NewError(ErrInvalidConfig, "endpoint is required")
+	}
+
+	parsedURL, err := url.Parse(endpoint)
+	if err != nil {
+		return NewError(ErrInvalidConfig, "endpoint is not a valid URL: "+err.Error())
+	}
+
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return NewError(ErrInvalidConfig, "endpoint must use http or https scheme")
+	}
+
+	if parsedURL.Host == "" {
+		return NewError(ErrInvalidConfig, "endpoint must have a host")
+	}
+
+	return nil
+}
+
+// validateAPIKey validates the API key format.
+func validateAPIKey(apiKey string) error {
+	if apiKey == "" {
+		return NewError(ErrInvalidConfig, "apiKey is required")
+	}
+
+	if !apiKeyRegex.MatchString(apiKey) {
+		return NewError(ErrInvalidConfig, "apiKey format invalid: must match lw_[a-zA-Z0-9_-]{32,}")
+	}
+
+	return nil
+}
+
+// validateFallbackEndpoints validates the fallback endpoints and their
+// API keys.
+func validateFallbackEndpoints(endpoint string, fallbacks []string, apiKeys map[string]string, failbackInterval time.Duration) error {
+	seen := map[string]bool{endpoint: true}
+	for _, fallback := range fallbacks {
+		if err := validateEndpoint(fallback); err != nil {
+			return NewError(ErrInvalidConfig, "fallback "+err.(*Error).Message)
+		}
+		if seen[fallback] {
+			return NewError(ErrInvalidConfig, "fallback endpoints must be distinct from each other and the endpoint")
+		}
+		seen[fallback] = true
+	}
+	for fallback, apiKey := range apiKeys {
+		if !seen[fallback] || fallback == endpoint {
+			return NewError(ErrInvalidConfig, "fallback API key set for an endpoint that is not a fallback: "+fallback)
+		}
+		if err := validateAPIKey(apiKey); err != nil {
+			return NewError(ErrInvalidConfig, "fallback "+err.(*Error).Message)
+		}
+	}
+	if failbackInterval <= 0 {
+		return NewError(ErrInvalidConfig, "failbackInterval must be positive")
+	}
+	return nil
+}
+
+// validateEndpoints validates the load balanced endpoints.
+func validateEndpoints(endpoint string, endpoints []string, strategy BalanceStrategy, cooldown time.Duration, fallbacks []string) error {
+	if len(endpoints) == 0 {
+		return nil
+	}
+	if len(fallbacks) > 0 {
+		return NewError(ErrInvalidConfig, "endpoints and fallback endpoints cannot be combined")
+	}
+	seen := map[string]bool{endpoint: true}
+	for _, e := range endpoints {
+		if err := validateEndpoint(e); err != nil {
+			return err
+		}
+		if seen[e] {
+			return NewError(ErrInvalidConfig, "endpoints must be distinct from each other and the endpoint")
+		}
+		seen[e] = true
+	}
+	if strategy != RoundRobin && strategy != Random {
+		return NewError(ErrInvalidConfig, "balanceStrategy must be RoundRobin or Random")
+	}
+	if cooldown <= 0 {
+		return NewError(ErrInvalidConfig, "endpointCooldown must be positive")
+	}
+	return nil
+}
+
+// validateAPIKeyRefreshInterval validates the API key refresh interval configuration.
+func validateAPIKeyRefreshInterval(d time.Duration) error {
+	if d < 0 {
+		return NewError(ErrInvalidConfig, "apiKeyRefreshInterval must not be negative")
+	}
+	return nil
+}
+
+// validateBasicAuth validates the basic auth configuration.
+func validateBasicAuth(user, pass string) error {
+	if user == "" && pass != "" {
+		return NewError(ErrInvalidConfig, "basic auth user is required when a password is set")
+	}
+	if strings.Contains(user, ":") {
+		return NewError(ErrInvalidConfig, "basic auth user must not contain a colon")
+	}
+	return nil
+}
+
+// validateBatchSize validates the batch size configuration.
+func validateBatchSize(batchSize int) error {
+	if batchSize < MinBatchSize || batchSize > MaxBatchSize {
+		return NewError(ErrInvalidConfig, "batchSize must be between 1 and 500")
+	}
+	return nil
+}
+
+// validateBatchBytes validates the batch bytes configuration.
+func validateBatchBytes(n int) error {
+	if n < 0 {
+		return NewError(ErrInvalidConfig, "batchBytes must be 0 or positive")
+	}
+	return nil
+}
+
+// validateMinFlushBytes validates the min flush bytes configuration.
+func validateMinFlushBytes(n int, maxBufferAge time.Duration) error {
+	if n < 0 {
+		return NewError(ErrInvalidConfig, "minFlushBytes must be 0 or positive")
+	}
+	if n > 0 && maxBufferAge <= 0 {
+		return NewError(ErrInvalidConfig, "minFlushBytes requires maxBufferAge, which bounds how long logs wait")
+	}
+	return nil
+}
+
+// validateFlushInterval validates the flush interval configuration.
+func validateFlushInterval(flushInterval time.Duration) error {
+	if flushInterval < MinFlushInterval || flushInterval > MaxFlushInterval {
+		return NewError(ErrInvalidConfig, "flushInterval must be between 100ms and 60s")
+	}
+	return nil
+}
+
+// validateMaxBufferAge validates the max buffer age configuration.
+func validateMaxBufferAge(d time.Duration) error {
+	if d != 0 && d < MinFlushInterval {
+		return NewError(ErrInvalidConfig, "maxBufferAge must be 0 or at least 100ms")
+	}
+	return nil
+}
+
+// validateSlowThreshold validates the slow threshold configuration.
+func validateSlowThreshold(d time.Duration) error {
+	if d < 0 {
+		return NewError(ErrInvalidConfig, "slowThreshold must be 0 or positive")
+	}
+	return nil
+}
+
+// validateHeartbeat validates the heartbeat configuration.
+func validateHeartbeat(interval time.Duration) error {
+	if interval < 0 {
+		return NewError(ErrInvalidConfig, "heartbeat interval must be 0 or positive")
+	}
+	return nil
+}
+
+// validateMaxEntryAge validates the max entry age configuration.
+func validateMaxEntryAge(d time.Duration) error {
+	if d != 0 && d < MinFlushInterval {
+		return NewError(ErrInvalidConfig, "maxEntryAge must be 0 or at least 100ms")
+	}
+	return nil
+}
+
+// validateMaxQueueSize validates the max queue size configuration.
+func validateMaxQueueSize(maxQueueSize int) error {
+	if maxQueueSize < MinMaxQueueSize || maxQueueSize > MaxMaxQueueSize {
+		return NewError(ErrInvalidConfig, "maxQueueSize must be between 1 and 10000")
+	}
+	return nil
+}
+
+// validateMaxInFlightBytes validates the max in-flight bytes configuration.
+func validateMaxInFlightBytes(n int64) error {
+	if n < 0 {
+		return NewError(ErrInvalidConfig, "maxInFlightBytes must be 0 or positive")
+	}
+	return nil
+}
+
+// validateMaxRetries validates the max retries configuration.
+func validateMaxRetries(maxRetries int) error {
+	if maxRetries < MinMaxRetries || maxRetries > MaxMaxRetries {
+		return NewError(ErrInvalidConfig, "maxRetries must be between 0 and 10")
+	}
+	return nil
+}
+
+// validateAdaptiveBatching validates the adaptive batching bounds.
+func validateAdaptiveBatching(minSize, maxSize int) error {
+	if minSize == 0 && maxSize == 0 {
+		return nil
+	}
+	if minSize < MinBatchSize || maxSize > MaxBatchSize || minSize > maxSize {
+		return NewError(ErrInvalidConfig, "adaptive batching bounds must be between 1 and 500 with min <= max")
+	}
+	return nil
+}
+
+// validateMaxConcurrentFlushes validates the max concurrent flushes configuration.
+func validateMaxConcurrentFlushes(n int) error {
+	if n < MinMaxConcurrentFlushes || n > MaxMaxConcurrentFlushes {
+		return NewError(ErrInvalidConfig, "maxConcurrentFlushes must be between 1 and 32")
+	}
+	return nil
+}
+
+// validateQueueHighWatermark validates the queue high watermark configuration.
+func validateQueueHighWatermark(fraction float64) error {
+	if !(fraction >= 0 && fraction <= 1) {
+		return NewError(ErrInvalidConfig, "queueHighWatermark must be between 0 and 1")
+	}
+	return nil
+}
+
+// validateOverflowReportInterval validates the overflow report interval configuration.
+func validateOverflowReportInterval(d time.Duration) error {
+	if d < 0 {
+		return NewError(ErrInvalidConfig, "overflowReportInterval must not be negative")
+	}
+	return nil
+}
+
+// validateShutdownTimeout validates the shutdown timeout configuration.
+func validateShutdownTimeout(d time.Duration) error {
+	if d <= 0 {
+		return NewError(ErrInvalidConfig, "shutdownTimeout must be positive")
+	}
+	return nil
+}
+
+// validateTransportTimeouts validates the dial and response header timeouts.
+func validateTransportTimeouts(requestTimeout, dialTimeout, responseHeaderTimeout, idleConnTimeout time.Duration) error {
+	if requestTimeout < 0 {
+		return NewError(ErrInvalidConfig, "requestTimeout must not be negative")
+	}
+	if dialTimeout < 0 {
+		return NewError(ErrInvalidConfig, "dialTimeout must not be negative")
+	}
+	if responseHeaderTimeout < 0 {
+		return NewError(ErrInvalidConfig, "responseHeaderTimeout must not be negative")
+	}
+	if idleConnTimeout < 0 {
+		return NewError(ErrInvalidConfig, "idleConnTimeout must not be negative")
+	}
+	return nil
+}
+
+// validateTimestampSkew validates the timestamp skew guard configuration.
+func validateTimestampSkew(max time.Duration, action TimestampSkewAction) error {
+	if max < 0 {
+		return NewError(ErrInvalidConfig, "timestampSkewMax must not be negative")
+	}
+	if action != ClampSkewed && action != DropSkewed {
+		return NewError(ErrInvalidConfig, "timestampSkewAction must be ClampSkewed or DropSkewed")
+	}
+	return nil
+}
+
+// validateDeliveryMode validates the delivery mode configuration.
+func validateDeliveryMode(mode DeliveryMode) error {
+	if mode != AtLeastOnce && mode != AtMostOnce {
+		return NewError(ErrInvalidConfig, "deliveryMode must be AtLeastOnce or AtMostOnce")
+	}
+	return nil
+}
+
+// validateOverflowPolicy validates the overflow policy configuration.
+func validateOverflowPolicy(policy OverflowPolicy) error {
+	if policy != DropOldest && policy != DropLowestSeverity && policy != Block && policy != SpillToDisk {
+		return NewError(ErrInvalidConfig, "overflowPolicy must be DropOldest, DropLowestSeverity, Block, or SpillToDisk")
+	}
+	return nil
+}
+
+// validateSpill validates the spill directory and size cap of the
+// SpillToDisk overflow policy.
+func validateSpill(policy OverflowPolicy, dir string, maxBytes int64) error {
+	if policy != SpillToDisk {
+		return nil
+	}
+	if dir == "" {
+		return NewError(ErrInvalidConfig, "spill directory is required for SpillToDisk")
+	}
+	if maxBytes <= 0 {
+		return NewError(ErrInvalidConfig, "spill maxBytes must be positive")
+	}
+	return nil
+}
+
+// validateEncoder validates the encoder configuration.
+func validateEncoder(encode func(any) ([]byte, error)) error {
+	if encode == nil {
+		return NewError(ErrInvalidConfig, "encoder must not be nil")
+	}
+	return nil
+}
+
+// validateMinLevel validates the minimum level configuration.
+func validateMinLevel(minLevel LogLevel) error {
+	if !isValidLevel(minLevel) {
+		return NewError(ErrInvalidConfig, "minLevel must be one of debug, info, warn, error, fatal")
+	}
+	return nil
+}
+
+// validatePriorityLevels validates the priority levels configuration.
+func validatePriorityLevels(levels []LogLevel) error {
+	for _, level := range levels {
+		if !isValidLevel(level) {
+			return NewError(ErrInvalidConfig, "priorityLevels must be debug, info, warn, error, or fatal")
+		}
+	}
+	return nil
+}
+
+// validateLevelSampling validates the level sampling configuration.
+func validateLevelSampling(rates map[LogLevel]float64) error {
+	for level, rate := range rates {
+		if !isValidLevel(level) {
+			return NewError(ErrInvalidConfig, "levelSampling levels must be debug, info, warn, error, or fatal")
+		}
+		if !(rate >= 0 && rate <= 1) {
+			return NewError(ErrInvalidConfig, "levelSampling rates must be between 0 and 1")
+		}
+	}
+	return nil
+}
+
+// validateLevelAliases validates the level alias configuration.
+func validateLevelAliases(aliases map[LogLevel]string) error {
+	for level, alias := range aliases {
+		if !isValidLevel(level) {
+			return NewError(ErrInvalidConfig, "levelAliases levels must be debug, info, warn, error, or fatal")
+		}
+		if alias == "" {
+			return NewError(ErrInvalidConfig, "levelAliases names must not be empty")
+		}
+	}
+	return nil
+}
+
+// validateChildLevel validates the level set with ChildWithLevel.
+func validateChildLevel(level LogLevel) error {
+	if !isValidLevel(level) {
+		return NewError(ErrInvalidConfig, "child level must be one of debug, info, warn, error, fatal")
+	}
+	return nil
+}
+
+// validateDiagnosticsLevel validates the diagnostics level configuration.
+func validateDiagnosticsLevel(level LogLevel) error {
+	if !isValidLevel(level) {
+		return NewError(ErrInvalidConfig, "diagnostics level must be one of debug, info, warn, error, fatal")
+	}
+	return nil
+}
+
+// validateFallback validates the fallback writer configuration.
+func validateFallback(w io.Writer, after int) error {
+	if w != nil && after < 1 {
+		return NewError(ErrInvalidConfig, "fallbackAfter must be at least 1")
+	}
+	return nil
+}
+
+// validateOfflineMode validates the offline mode configuration.
+func validateOfflineMode(after int, probeInterval time.Duration) error {
+	if after < 0 {
+		return NewError(ErrInvalidConfig, "offlineAfter must be non-negative")
+	}
+	if after > 0 && probeInterval <= 0 {
+		return NewError(ErrInvalidConfig, "offlineProbeInterval must be positive")
+	}
+	return nil
+}
+
+// validateCompressionThreshold validates the compression threshold.
+func validateCompressionThreshold(bytes int) error {
+	if bytes < 0 {
+		return NewError(ErrInvalidConfig, "compressionThreshold must be non-negative")
+	}
+	return nil
+}
+
+// validateCompression validates the compressors.
+func validateCompression(compressors []Compressor) error {
+	for _, c := range compressors {
+		if c == nil {
+			return NewError(ErrInvalidConfig, "compression must not contain nil compressors")
+		}
+		if c.ContentEncoding() == "" {
+			return NewError(ErrInvalidConfig, "compressor content encoding must not be empty")
+		}
+	}
+	return nil
+}
+
+// validateRetryBudget validates the retry budget configuration.
+func validateRetryBudget(budget int, window time.Duration) error {
+	if budget < 0 {
+		return NewError(ErrInvalidConfig, "retryBudget must be non-negative")
+	}
+	if budget > 0 && window <= 0 {
+		return NewError(ErrInvalidConfig, "retryBudgetWindow must be positive")
+	}
+	return nil
+}
+
+// validateConfig validates the configuration and returns an error if invalid.
+func validateConfig(c *Config) error {
+	if err := validateEndpoint(c.Endpoint); err != nil {
+		return err
+	}
+
+	if c.TokenProvider == nil {
+		if err := validateAPIKey(c.APIKey); err != nil {
+			return err
+		}
+	}
+
+	if err := validateAPIKeyRefreshInterval(c.APIKeyRefreshInterval); err != nil {
+		return err
+	}
+
+	if err := validateBasicAuth(c.BasicAuthUser, c.BasicAuthPassword); err != nil {
+		return err
+	}
+
+	if err := validateBatchSize(c.BatchSize); err != nil {
+		return err
+	}
+
+	if err := validateBatchBytes(c.BatchBytes); err != nil {
+		return err
+	}
+
+	if err := validateFlushInterval(c.FlushInterval); err != nil {
+		return err
+	}
+
+	if err := validateMaxQueueSize(c.MaxQueueSize); err != nil {
+		return err
+	}
+
+	if err := validateMaxInFlightBytes(c.MaxInFlightBytes); err != nil {
+		return err
+	}
+
+	if err := validateMaxEntryAge(c.MaxEntryAge); err != nil {
+		return err
+	}
+
+	if err := validateMaxBufferAge(c.MaxBufferAge); err != nil {
+		return err
+	}
+	if err := validateMinFlushBytes(c.MinFlushBytes, c.MaxBufferAge); err != nil {
+		return err
+	}
+
+	if err := validateHeartbeat(c.HeartbeatInterval); err != nil {
+		return err
+	}
+
+	if err := validateSlowThreshold(c.SlowThreshold); err != nil {
+		return err
+	}
+
+	if err := validateMaxRetries(c.MaxRetries); err != nil {
+		return err
+	}
+
+	if err := validateAdaptiveBatching(c.AdaptiveBatchMin, c.AdaptiveBatchMax); err != nil {
+		return err
+	}
+
+	if err := validateMaxConcurrentFlushes(c.MaxConcurrentFlushes); err != nil {
+		return err
+	}
+
+	if err := validateDeliveryMode(c.DeliveryMode); err != nil {
+		return err
+	}
+	if err := validateTimestampSkew(c.TimestampSkewMax, c.TimestampSkewAction); err != nil {
+		return err
+	}
+
+	if err := validateOverflowPolicy(c.OverflowPolicy); err != nil {
+		return err
+	}
+	if err := validateSpill(c.OverflowPolicy, c.SpillDir, c.SpillMaxBytes); err != nil {
+		return err
+	}
+
+	if err := validateEncoder(c.Encoder); err != nil {
+		return err
+	}
+
+	if err := validateMinLevel(c.MinLevel); err != nil {
+		return err
+	}
+
+	if err := validatePriorityLevels(c.PriorityLevels); err != nil {
+		return err
+	}
+	if err := validateLevelSampling(c.LevelSampling); err != nil {
+		return err
+	}
+	if err := validateLevelAliases(c.LevelAliases); err != nil {
+		return err
+	}
+
+	if c.Diagnostics != nil {
+		if err := validateDiagnosticsLevel(c.DiagnosticsLevel); err != nil {
+			return err
+		}
+	}
+
+	if err := validateFallback(c.FallbackWriter, c.FallbackAfter); err != nil {
+		return err
+	}
+
+	if err := validateOfflineMode(c.OfflineAfter, c.OfflineProbeInterval); err != nil {
+		return err
+	}
+	if err := validateFallbackEndpoints(c.Endpoint, c.FallbackEndpoints, c.FallbackAPIKeys, c.FailbackInterval); err != nil {
+		return err
+	}
+	if err := validateEndpoints(c.Endpoint, c.Endpoints, c.BalanceStrategy, c.EndpointCooldown, c.FallbackEndpoints); err != nil {
+		return err
+	}
+	if err := validateRetryBudget(c.RetryBudget, c.RetryBudgetWindow); err != nil {
+		return err
+	}
+	if err := validateCompressionThreshold(c.CompressionThreshold); err != nil {
+		return err
+	}
+	if err := validateCompression(c.Compression); err != nil {
+		return err
+	}
+
+	if err := validateOverflowReportInterval(c.OverflowReportInterval); err != nil {
+		return err
+	}
+
+	if err := validateQueueHighWatermark(c.QueueHighWatermark); err != nil {
+		return err
+	}
+
+	if err := validateShutdownTimeout(c.ShutdownTimeout); err != nil {
+		return err
+	}
+
+	if err := validateTransportTimeouts(c.RequestTimeout, c.DialTimeout, c.ResponseHeaderTimeout, c.IdleConnTimeout); err != nil {
 		return err
 	}
 
@@ -1,6 +1,7 @@
 package logwell
 
 import (
+    "math"
     "net/http"
     "testing"
     "time"
@@ -334,6 +335,53 @@ func TestConfigValidateBatchSize(t *testing.T) {
     }
 }
 
+func TestConfigValidateBatchBytes(t *testing.T) {
+    for n, wantError := range map[int]bool{0: false, 1: false, 1 << 20: false, -1: true} {
+        cfg := newDefaultConfig(validEndpoint(), validAPIKey())
+        cfg.BatchBytes = n
+        err := validateConfig(cfg)
+        if (err != nil) != wantError {
+            t.Errorf("validateConfig() error = %v for batchBytes %d, want error %v", err, n, wantError)
+        }
+    }
+}
+
+func TestConfigValidateMinFlushBytes(t *testing.T) {
+    tests := []struct {
+        name         string
+        n            int
+        maxBufferAge time.Duration
+        wantError    bool
+    }{
+        {"disabled", 0, 0, false},
+        {"with max buffer age", 4096, time.Second, false},
+        {"negative", -1, time.Second, true},
+        {"without max buffer age", 4096, 0, true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := newDefaultConfig(validEndpoint(), validAPIKey())
+            cfg.MinFlushBytes = tt.n
+            cfg.MaxBufferAge = tt.maxBufferAge
+            err := validateConfig(cfg)
+            if (err != nil) != tt.wantError {
+                t.Errorf("validateConfig() error = %v, want error %v", err, tt.wantError)
+            }
+        })
+    }
+}
+
+func TestConfigValidateMaxInFlightBytes(t *testing.T) {
+    for n, wantError := range map[int64]bool{0: false, 1 << 20: false, -1: true} {
+        cfg := newDefaultConfig(validEndpoint(), validAPIKey())
+        cfg.MaxInFlightBytes = n
+        err := validateConfig(cfg)
+        if (err != nil) != wantError {
+            t.Errorf("validateConfig() error = %v for maxInFlightBytes %d, want error %v", err, n, wantError)
+        }
+    }
+}
+
 func TestConfigValidateFlushInterval(t *testing.T) {
     tests := []struct {
         name          string
@@ -443,6 +491,68 @@ func TestConfigValidateMaxRetries(t *testing.T) {
     }
 }
 
+func TestConfigValidateMaxConcurrentFlushes(t *testing.T) {
+    tests := []struct {
+        name      string
+        n         int
+        wantError bool
+    }{
+        {"minimum valid (1)", 1, false},
+        {"maximum valid (32)", 32, false},
+        {"mid range (4)", 4, false},
+
+        {"zero", 0, true},
+        {"negative", -1, true},
+        {"above max (33)", 33, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := newDefaultConfig(validEndpoint(), validAPIKey())
+            WithMaxConcurrentFlushes(tt.n)(cfg)
+            err := validateConfig(cfg)
+
+            if tt.wantError && err == nil {
+                t.Errorf("validateConfig() error = nil, want error for maxConcurrentFlushes %d", tt.n)
+            }
+            if !tt.wantError && err != nil {
+                t.Errorf("validateConfig() error = %v, want nil for maxConcurrentFlushes %d", err, tt.n)
+            }
+        })
+    }
+}
+
+func TestConfigValidateAdaptiveBatching(t *testing.T) {
+    tests := []struct {
+        name      string
+        min, max  int
+        wantError bool
+    }{
+        {"disabled", 0, 0, false},
+        {"full range", 1, 500, false},
+        {"equal bounds", 50, 50, false},
+
+        {"min zero", 0, 100, true},
+        {"max above limit", 10, 501, true},
+        {"min above max", 100, 10, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := newDefaultConfig(validEndpoint(), validAPIKey())
+            WithAdaptiveBatching(tt.min, tt.max)(cfg)
+            err := validateConfig(cfg)
+
+            if tt.wantError && err == nil {
+                t.Errorf("validateConfig() error = nil, want error for bounds %d-%d", tt.min, tt.max)
+            }
+            if !tt.wantError && err != nil {
+                t.Errorf("validateConfig() error = %v, want nil for bounds %d-%d", err, tt.min, tt.max)
+            }
+        })
+    }
+}
+
 func TestConfigOptions(t *testing.T) {
     t.Run("WithBatchSize", func(t *testing.T) {
         cfg := &Config{}
@@ -493,6 +603,27 @@ func TestConfigOptions(t *testing.T) {
         }
     })
 
+    t.Run("WithMetadata deep-copies the map", func(t *testing.T) {
+        cfg := &Config{}
+        nested := map[string]any{"region": "us-east"}
+        meta := map[string]any{"env": "test", "cloud": nested, "tags": []any{"a"}}
+        WithMetadata(meta)(cfg)
+
+        meta["env"] = "mutated"
+        nested["region"] = "mutated"
+        meta["tags"].([]any)[0] = "mutated"
+
+        if cfg.Metadata["env"] != "test" {
+            t.Errorf("Metadata[env] = %v, want %q", cfg.Metadata["env"], "test")
+        }
+        if cfg.Metadata["cloud"].(map[string]any)["region"] != "us-east" {
+            t.Errorf("Metadata[cloud][region] = %v, want %q", cfg.Metadata["cloud"], "us-east")
+        }
+        if cfg.Metadata["tags"].([]any)[0] != "a" {
+            t.Errorf("Metadata[tags] = %v, want [a]", cfg.Metadata["tags"])
+        }
+    })
+
     t.Run("WithCaptureSourceLocation", func(t *testing.T) {
         cfg := &Config{}
         WithCaptureSourceLocation(true)(cfg)
@@ -539,6 +670,17 @@ func TestConfigOptions(t *testing.T) {
     })
 }
 
+func TestConfigValidateOverflowReportInterval(t *testing.T) {
+    cfg := newDefaultConfig(validEndpoint(), validAPIKey())
+    WithOverflowReportInterval(-time.Second)(cfg)
+    assertConfigError(t, validateConfig(cfg), ErrInvalidConfig)
+
+    WithOverflowReportInterval(time.Second)(cfg)
+    if err := validateConfig(cfg); err != nil {
+        t.Errorf("validateConfig() error = %v, want nil", err)
+    }
+}
+
 func TestConfigValidationBounds(t *testing.T) {
     t.Run("bounds constants are correct", func(t *testing.T) {
         // BatchSize bounds
@@ -600,3 +742,143 @@ func TestConfigValidationMultipleErrors(t *testing.T) {
         }
     })
 }
+
+func TestConfigValidateMaxEntryAge(t *testing.T) {
+    tests := []struct {
+        name    string
+        age     time.Duration
+        wantErr bool
+    }{
+        {"disabled", 0, false},
+        {"minimum", 100 * time.Millisecond, false},
+        {"large", time.Hour, false},
+        {"too small", 50 * time.Millisecond, true},
+        {"negative", -time.Second, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := validateMaxEntryAge(tt.age)
+            if tt.wantErr {
+                assertConfigError(t, err, ErrInvalidConfig)
+            } else if err != nil {
+                t.Errorf("validateMaxEntryAge(%v) error = %v", tt.age, err)
+            }
+        })
+    }
+}
+
+func TestConfigValidateBasicAuth(t *testing.T) {
+    tests := []struct {
+        name    string
+        user    string
+        pass    string
+        wantErr bool
+    }{
+        {"disabled", "", "", false},
+        {"user and password", "ingest", "secret", false},
+        {"empty password", "ingest", "", false},
+        {"colon in password", "ingest", "a:b", false},
+        {"password without user", "", "secret", true},
+        {"colon in user", "in:gest", "secret", true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := validateBasicAuth(tt.user, tt.pass)
+            if tt.wantErr {
+                assertConfigError(t, err, ErrInvalidConfig)
+            } else if err != nil {
+                t.Errorf("validateBasicAuth(%q, %q) error = %v", tt.user, tt.pass, err)
+            }
+        })
+    }
+}
+
+func TestConfigValidatePriorityLevels(t *testing.T) {
+    if err := validatePriorityLevels(nil); err != nil {
+        t.Errorf("validatePriorityLevels(nil) error = %v", err)
+    }
+    if err := validatePriorityLevels([]LogLevel{LevelError, LevelFatal}); err != nil {
+        t.Errorf("validatePriorityLevels(error, fatal) error = %v", err)
+    }
+    assertConfigError(t, validatePriorityLevels([]LogLevel{LevelError, "critical"}), ErrInvalidConfig)
+}
+
+func TestConfigValidateOverflowPolicy(t *testing.T) {
+    for _, policy := range []OverflowPolicy{DropOldest, DropLowestSeverity, Block} {
+        if err := validateOverflowPolicy(policy); err != nil {
+            t.Errorf("validateOverflowPolicy(%q) error = %v", policy, err)
+        }
+    }
+    assertConfigError(t, validateOverflowPolicy("drop-newest"), ErrInvalidConfig)
+
+    cfg := newDefaultConfig(validEndpoint(), validAPIKey())
+    if cfg.OverflowPolicy != DropOldest {
+        t.Errorf("default OverflowPolicy = %q, want %q", cfg.OverflowPolicy, DropOldest)
+    }
+}
+
+func TestConfigValidateQueueHighWatermark(t *testing.T) {
+    for _, fraction := range []float64{0, 0.5, 1} {
+        if err := validateQueueHighWatermark(fraction); err != nil {
+            t.Errorf("validateQueueHighWatermark(%v) error = %v", fraction, err)
+        }
+    }
+    for _, fraction := range []float64{-0.1, 1.5, math.NaN()} {
+        assertConfigError(t, validateQueueHighWatermark(fraction), ErrInvalidConfig)
+    }
+}
+
+func TestConfigValidateMaxBufferAge(t *testing.T) {
+    for _, d := range []time.Duration{0, 100 * time.Millisecond, time.Minute} {
+        if err := validateMaxBufferAge(d); err != nil {
+            t.Errorf("validateMaxBufferAge(%v) error = %v", d, err)
+        }
+    }
+    for _, d := range []time.Duration{-time.Second, 50 * time.Millisecond} {
+        assertConfigError(t, validateMaxBufferAge(d), ErrInvalidConfig)
+    }
+}
+
+func TestConfigValidateLevelSampling(t *testing.T) {
+    if err := validateLevelSampling(nil); err != nil {
+        t.Errorf("validateLevelSampling(nil) error = %v", err)
+    }
+    if err := validateLevelSampling(map[LogLevel]float64{LevelDebug: 0, LevelInfo: 0.1, LevelError: 1}); err != nil {
+        t.Errorf("validateLevelSampling(valid) error = %v", err)
+    }
+    assertConfigError(t, validateLevelSampling(map[LogLevel]float64{"verbose": 0.5}), ErrInvalidConfig)
+    for _, rate := range []float64{-0.1, 1.5, math.NaN()} {
+        assertConfigError(t, validateLevelSampling(map[LogLevel]float64{LevelInfo: rate}), ErrInvalidConfig)
+    }
+}
+
+func TestConfigValidateLevelAliases(t *testing.T) {
+    if err := validateLevelAliases(map[LogLevel]string{LevelWarn: "warning", LevelFatal: "critical"}); err != nil {
+        t.Errorf("validateLevelAliases(valid) error = %v", err)
+    }
+    assertConfigError(t, validateLevelAliases(map[LogLevel]string{"verbose": "trace"}), ErrInvalidConfig)
+    assertConfigError(t, validateLevelAliases(map[LogLevel]string{LevelWarn: ""}), ErrInvalidConfig)
+}
+
+func TestConfigValidateTimestampSkew(t *testing.T) {
+    for _, action := range []TimestampSkewAction{ClampSkewed, DropSkewed} {
+        if err := validateTimestampSkew(time.Hour, action); err != nil {
+            t.Errorf("validateTimestampSkew(1h, %q) error = %v", action, err)
+        }
+    }
+    assertConfigError(t, validateTimestampSkew(-time.Second, ClampSkewed), ErrInvalidConfig)
+    assertConfigError(t, validateTimestampSkew(time.Hour, "ignore"), ErrInvalidConfig)
+}
+
+func TestConfigValidateRetryBudget(t *testing.T) {
+    if err := validateRetryBudget(5, time.Minute); err != nil {
+        t.Errorf("validateRetryBudget(5, 1m) error = %v", err)
+    }
+    if err := validateRetryBudget(0, 0); err != nil {
+        t.Errorf("validateRetryBudget(0, 0) error = %v", err)
+    }
+    assertConfigError(t, validateRetryBudget(-1, time.Minute), ErrInvalidConfig)
+    assertConfigError(t, validateRetryBudget(5, 0), ErrInvalidConfig)
+}
@@ -0,0 +1,106 @@
+package logwell
+
+import (
+	"context"
+	"sync"
+)
+
+// deadLetterBuffer is how many abandoned batches may wait for the dead
+// letter callback before flushes wait for it.
+const deadLetterBuffer = 64
+
+// deadLetter hands entries that were abandoned for good to the WithDeadLetter
+// callback. Calls run one at a time, in order, on a goroutine of their own,
+// so a slow callback never runs on a logging call and holds up flushes only
+// once deadLetterBuffer batches are waiting.
+type deadLetter struct {
+	fn   func([]LogEntry, *Error)
+	ch   chan deadLetterBatch
+	quit chan struct{}
+	done chan struct{}
+
+	// mu guards closed; senders counts the sends in flight, so that ch is
+	// closed only once none can write to it.
+	mu      sync.Mutex
+	closed  bool
+	senders sync.WaitGroup
+}
+
+// deadLetterBatch is one call to the dead letter callback.
+type deadLetterBatch struct {
+	entries []LogEntry
+	cause   *Error
+}
+
+// newDeadLetter starts the goroutine that calls fn.
+func newDeadLetter(fn func([]LogEntry, *Error)) *deadLetter {
+	d := &deadLetter{
+		fn:   fn,
+		ch:   make(chan deadLetterBatch, deadLetterBuffer),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// run calls fn for each batch until close.
+func (d *deadLetter) run() {
+	defer close(d.done)
+	for batch := range d.ch {
+		d.fn(batch.entries, batch.cause)
+	}
+}
+
+// send passes entries abandoned because of cause to the callback. Entries
+// abandoned after close, by sends that outlived shutdown, are passed on the
+// calling goroutine, as are those of a send still waiting for room when
+// close is called. It is a no-op on a nil receiver.
+func (d *deadLetter) send(entries []LogEntry, cause error) {
+	if d == nil || len(entries) == 0 {
+		return
+	}
+	batch := deadLetterBatch{entries: entries, cause: toError(cause)}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		d.fn(batch.entries, batch.cause)
+		return
+	}
+	d.senders.Add(1)
+	d.mu.Unlock()
+	defer d.senders.Done()
+
+	select {
+	case d.ch <- batch:
+	case <-d.quit:
+		d.fn(batch.entries, batch.cause)
+	}
+}
+
+// close stops accepting batches and waits until the callback has been
+// called for every batch sent before, or until ctx ends. It is a no-op on a
+// nil receiver.
+func (d *deadLetter) close(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.quit)
+		go func() {
+			d.senders.Wait()
+			close(d.ch)
+		}()
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,196 @@
+package logwell
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// deadLetterCall is one call to the dead letter callback.
+type deadLetterCall struct {
+	entries []LogEntry
+	cause   *Error
+}
+
+// deadLetterClient creates a client for ts whose dead letter calls are sent
+// on the returned channel.
+func deadLetterClient(t *testing.T, ts *testServer, opts ...Option) (*Client, <-chan deadLetterCall) {
+	t.Helper()
+	calls := make(chan deadLetterCall, 10)
+	client := createTestClient(t, ts, append(opts, WithDeadLetter(func(entries []LogEntry, cause *Error) {
+		calls <- deadLetterCall{entries: entries, cause: cause}
+	}))...)
+	return client, calls
+}
+
+// waitForDeadLetter returns the next dead letter call.
+func waitForDeadLetter(t *testing.T, calls <-chan deadLetterCall) deadLetterCall {
+	t.Helper()
+	select {
+	case call := <-calls:
+		return call
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead letter callback not called")
+		return deadLetterCall{}
+	}
+}
+
+// TestClientDeadLetter tests that entries abandoned for good are passed to
+// the dead letter callback with the error that ended them.
+func TestClientDeadLetter(t *testing.T) {
+	t.Run("rejected batch", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		})
+
+		var dropCount int32
+		client, calls := deadLetterClient(t, ts, WithOnDrop(func(n int) { atomic.AddInt32(&dropCount, int32(n)) }))
+		defer client.Shutdown(context.Background())
+
+		client.Info("invalid", M{"key": "value"})
+		client.Info("also invalid")
+		if err := client.Flush(context.Background()); err == nil {
+			t.Fatal("Flush() error = nil, want validation error")
+		}
+
+		call := waitForDeadLetter(t, calls)
+		if len(call.entries) != 2 || call.entries[0].Message != "invalid" || call.entries[1].Message != "also invalid" {
+			t.Errorf("dead letter entries = %+v, want the two rejected entries", call.entries)
+		}
+		if call.entries[0].Metadata["key"] != "value" {
+			t.Errorf("dead letter metadata = %v, want the entry's metadata", call.entries[0].Metadata)
+		}
+		if call.cause.Code != ErrValidationError || call.cause.StatusCode != http.StatusBadRequest {
+			t.Errorf("dead letter cause = %v, want a 400 validation error", call.cause)
+		}
+		if got := atomic.LoadInt32(&dropCount); got != 0 {
+			t.Errorf("OnDrop total = %d, want 0", got)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+		var attempts int32
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		client, calls := deadLetterClient(t, ts)
+		client.transport.maxRetries = 1
+		defer client.Shutdown(context.Background())
+
+		client.Error("unlucky")
+		client.Flush(context.Background())
+
+		call := waitForDeadLetter(t, calls)
+		if got := atomic.LoadInt32(&attempts); got != 2 {
+			t.Errorf("attempts = %d, want 2", got)
+		}
+		if len(call.entries) != 1 || call.entries[0].Message != "unlucky" {
+			t.Errorf("dead letter entries = %+v, want the failed entry", call.entries)
+		}
+		if call.cause.Code != ErrServerError {
+			t.Errorf("dead letter cause code = %q, want %q", call.cause.Code, ErrServerError)
+		}
+	})
+
+	t.Run("unsent at shutdown", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+		release := make(chan struct{})
+		defer close(release)
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		})
+
+		client, calls := deadLetterClient(t, ts)
+		client.transport.maxRetries = 0
+
+		client.Info("first")
+		client.Info("second")
+		client.Info("third")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if err := client.Shutdown(ctx); err == nil {
+			t.Fatal("Shutdown() error = nil, want deadline error")
+		}
+
+		var messages []string
+		for len(messages) < 3 {
+			call := waitForDeadLetter(t, calls)
+			if call.cause == nil {
+				t.Fatal("dead letter cause = nil")
+			}
+			for _, entry := range call.entries {
+				messages = append(messages, entry.Message)
+			}
+		}
+		if messages[0] != "first" || messages[1] != "second" || messages[2] != "third" {
+			t.Errorf("dead letter messages = %q, want all three in order", messages)
+		}
+	})
+
+	t.Run("successful sends not reported", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client, calls := deadLetterClient(t, ts)
+		client.Info("delivered")
+		if err := client.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+		select {
+		case call := <-calls:
+			t.Errorf("dead letter called with %+v after successful send", call.entries)
+		default:
+		}
+	})
+}
+
+// TestDeadLetterCloseWhileFull tests that close does not wait for a send
+// blocked on a full buffer, and that the blocked send then calls the
+// callback itself.
+func TestDeadLetterCloseWhileFull(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	d := newDeadLetter(func([]LogEntry, *Error) {
+		<-release
+		atomic.AddInt32(&calls, 1)
+	})
+
+	// One batch held by the callback and deadLetterBuffer more fill ch
+	for i := 0; i < deadLetterBuffer+1; i++ {
+		d.send([]LogEntry{{Message: "queued"}}, ErrNetworkError)
+	}
+	sent := make(chan struct{})
+	go func() {
+		d.send([]LogEntry{{Message: "blocked"}}, ErrNetworkError)
+		close(sent)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("close() error = %v, want the context's error", err)
+	}
+
+	close(release)
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked send did not return after close")
+	}
+	if err := d.close(context.Background()); err != nil {
+		t.Fatalf("second close() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != deadLetterBuffer+2 {
+		t.Errorf("callback calls = %d, want %d", got, deadLetterBuffer+2)
+	}
+}
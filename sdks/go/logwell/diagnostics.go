@@ -0,0 +1,107 @@
+package logwell
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diagnosticsOverflowInterval is the minimum time between overflow events
+// written to the diagnostics writer; overflows in between are counted.
+const diagnosticsOverflowInterval = 10 * time.Second
+
+// diagnostics writes SDK-internal events enabled by WithDiagnostics, such as
+// flush failures, retries, and overflows, to a writer. It never writes the
+// content of user logs.
+type diagnostics struct {
+	w     io.Writer
+	level LogLevel
+
+	mu           sync.Mutex
+	lastOverflow time.Time
+	suppressed   int
+}
+
+// newDiagnostics creates a diagnostics logger writing events at level and
+// above to w.
+func newDiagnostics(w io.Writer, level LogLevel) *diagnostics {
+	return &diagnostics{w: w, level: level}
+}
+
+// logf writes a formatted event at level. It is a no-op on a nil receiver,
+// so callers need not check whether diagnostics are enabled.
+func (d *diagnostics) logf(level LogLevel, format string, args ...any) {
+	if d == nil || !levelEnabled(level, d.level) {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.writeLocked(level, fmt.Sprintf(format, args...))
+}
+
+// reportError writes err at a level chosen from its code. Overflow errors
+// are written at most once per diagnosticsOverflowInterval, with a count of
+// those suppressed in between.
+func (d *diagnostics) reportError(err *Error) {
+	level := LevelError
+	switch err.Code {
+	case ErrQueueOverflow, ErrEnqueueCanceled, ErrInvalidConfig, ErrTailGap:
+		level = LevelWarn
+	}
+	if !levelEnabled(level, d.level) {
+		return
+	}
+
+	msg := fmt.Sprintf("%s [%s]", err.Message, string(err.Code))
+	if err.StatusCode > 0 {
+		msg += fmt.Sprintf(" (status %d)", err.StatusCode)
+	}
+	if err.Cause != nil {
+		msg += ": " + err.Cause.Error()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err.Code == ErrQueueOverflow {
+		now := clockNow()
+		if !d.lastOverflow.IsZero() && now.Sub(d.lastOverflow) < diagnosticsOverflowInterval {
+			d.suppressed++
+			return
+		}
+		d.lastOverflow = now
+		if d.suppressed > 0 {
+			msg += fmt.Sprintf(" (%d more overflow events since last report)", d.suppressed)
+			d.suppressed = 0
+		}
+	}
+	d.writeLocked(level, msg)
+}
+
+// writeLocked writes one event line. Write errors are ignored.
+// The caller must hold d.mu.
+func (d *diagnostics) writeLocked(level LogLevel, msg string) {
+	fmt.Fprintf(d.w, "logwell %s %s %s\n", clockNow().UTC().Format(timestampLayout), strings.ToUpper(string(level)), msg)
+}
+
+// onError returns an OnError callback that writes to d and then calls next.
+func (d *diagnostics) onError(next func(*Error)) func(*Error) {
+	return func(err *Error) {
+		d.reportError(err)
+		if next != nil {
+			next(err)
+		}
+	}
+}
+
+// onRetry returns an OnRetry callback that writes to d and then calls next.
+func (d *diagnostics) onRetry(next func(RetryInfo)) func(RetryInfo) {
+	return func(info RetryInfo) {
+		d.logf(LevelDebug, "retrying batch of %d logs (attempt %d) in %v: %v", info.BatchSize, info.Attempt, info.Delay, info.Err)
+		if next != nil {
+			next(info)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package logwell
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines returns the lines written so far.
+func (b *syncBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.FieldsFunc(b.buf.String(), func(r rune) bool { return r == '\n' })
+}
+
+// TestDiagnostics tests that internal events reach the diagnostics writer.
+func TestDiagnostics(t *testing.T) {
+	t.Run("flush failure without user content", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "invalid level"})
+		})
+
+		var out syncBuffer
+		var onErrorCalls int
+		client := createTestClient(t, ts,
+			WithBatchSize(1),
+			WithDiagnostics(&out, LevelWarn),
+			WithOnError(func(*Error) { onErrorCalls++ }),
+		)
+		client.Info("secret user message")
+		client.Shutdown(context.Background())
+
+		lines := out.lines()
+		if len(lines) != 1 {
+			t.Fatalf("diagnostics lines = %q, want 1 flush failure", lines)
+		}
+		if !strings.HasPrefix(lines[0], "logwell ") || !strings.Contains(lines[0], " ERROR ") || !strings.Contains(lines[0], "VALIDATION_ERROR") {
+			t.Errorf("line = %q, want an ERROR validation failure", lines[0])
+		}
+		if strings.Contains(lines[0], "secret user message") {
+			t.Errorf("line = %q contains user log content", lines[0])
+		}
+		if onErrorCalls != 1 {
+			t.Errorf("OnError called %d times, want 1", onErrorCalls)
+		}
+	})
+
+	t.Run("overflow is throttled", func(t *testing.T) {
+		clock := newFakeClock(t)
+		ts := newTestServer()
+		defer ts.Close()
+
+		var out syncBuffer
+		client := createTestClient(t, ts,
+			WithBatchSize(500),
+			WithFlushInterval(60*time.Second),
+			WithMaxQueueSize(2),
+			WithDiagnostics(&out, LevelWarn),
+		)
+		defer client.Shutdown(context.Background())
+
+		for i := 0; i < 10; i++ {
+			client.Info("overflowing")
+		}
+		if lines := out.lines(); len(lines) != 1 {
+			t.Fatalf("diagnostics lines after 8 overflows = %q, want 1", lines)
+		}
+
+		clock.Advance(diagnosticsOverflowInterval)
+		client.Info("overflowing")
+
+		lines := out.lines()
+		if len(lines) != 2 {
+			t.Fatalf("diagnostics lines after the interval = %q, want 2", lines)
+		}
+		if !strings.Contains(lines[1], " WARN ") || !strings.Contains(lines[1], "7 more overflow events") {
+			t.Errorf("line = %q, want a WARN overflow with 7 suppressed", lines[1])
+		}
+	})
+
+	t.Run("level filters events", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+		var requests int
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+		})
+
+		var debugOut, errorOut syncBuffer
+		debugClient := createTestClient(t, ts, WithBatchSize(500), WithDiagnostics(&debugOut, LevelDebug))
+		debugClient.Info("retried")
+		debugClient.Shutdown(context.Background())
+
+		debugText := strings.Join(debugOut.lines(), "\n")
+		if !strings.Contains(debugText, "DEBUG retrying batch of 1 logs (attempt 1)") {
+			t.Errorf("debug diagnostics = %q, want a retry event", debugText)
+		}
+		if !strings.Contains(debugText, "INFO shutdown: flushed=1 failed=0 dropped=0") {
+			t.Errorf("debug diagnostics = %q, want a shutdown summary", debugText)
+		}
+
+		errorClient := createTestClient(t, ts,
+			WithBatchSize(500),
+			WithMaxQueueSize(1),
+			WithDiagnostics(&errorOut, LevelError),
+		)
+		errorClient.Info("first")
+		errorClient.Info("overflow")
+		errorClient.Shutdown(context.Background())
+		if lines := errorOut.lines(); len(lines) != 0 {
+			t.Errorf("error-level diagnostics = %q, want none", lines)
+		}
+	})
+}
+
+func TestConfigValidateDiagnosticsLevel(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := New(validEndpoint(), validAPIKey(), WithDiagnostics(&out, "verbose")); err == nil {
+		t.Error("New() with invalid diagnostics level should fail")
+	}
+	client, err := New(validEndpoint(), validAPIKey(), WithDiagnostics(&out, LevelInfo))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client.Shutdown(context.Background())
+}
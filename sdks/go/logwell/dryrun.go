@@ -0,0 +1,38 @@
+package logwell
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// dryRun stands in for the server under WithDryRun: batches are accepted
+// without any request being made, and written to w as JSON lines when w is
+// set.
+type dryRun struct {
+	w    io.Writer
+	diag *diagnostics
+
+	mu sync.Mutex
+}
+
+// newDryRun creates a dry run that writes batches to w, which may be nil.
+func newDryRun(w io.Writer, diag *diagnostics) *dryRun {
+	return &dryRun{w: w, diag: diag}
+}
+
+// send accepts logs as if the server had taken all of them.
+func (d *dryRun) send(logs []LogEntry) (*IngestResponse, error) {
+	if d.w != nil {
+		d.mu.Lock()
+		enc := json.NewEncoder(d.w)
+		for i := range logs {
+			if err := enc.Encode(logs[i]); err != nil {
+				d.diag.logf(LevelError, "dry run: write failed: %v", err)
+				break
+			}
+		}
+		d.mu.Unlock()
+	}
+	return &IngestResponse{Accepted: len(logs)}, nil
+}
@@ -0,0 +1,52 @@
+package logwell
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestClientDryRun tests that a dry run makes no HTTP requests but reports
+// batches through OnFlush and writes them to the dry-run writer.
+func TestClientDryRun(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var flushed atomic.Int64
+	var out bytes.Buffer
+	client := createTestClient(t, ts,
+		WithBatchSize(2),
+		WithDryRun(true),
+		WithDryRunWriter(&out),
+		WithOnFlush(func(n int) { flushed.Add(int64(n)) }),
+	)
+
+	client.Info("first")
+	client.Warn("second")
+	client.Error("third")
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if got := len(ts.getRequests()); got != 0 {
+		t.Errorf("server received %d requests, want 0", got)
+	}
+	if got := flushed.Load(); got != 3 {
+		t.Errorf("OnFlush total = %d, want 3", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("dry-run writer got %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	var entry LogEntry
+	if err := json.Unmarshal([]byte(lines[2]), &entry); err != nil {
+		t.Fatalf("line is not a JSON log entry: %v", err)
+	}
+	if entry.Message != "third" || entry.Level != LevelError {
+		t.Errorf("last entry = %q at %s, want the error", entry.Message, entry.Level)
+	}
+}
@@ -0,0 +1,88 @@
+package logwell
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// ingestEndpoint is one endpoint batches may be sent to when several are
+// configured.
+type ingestEndpoint struct {
+	baseURL   string
+	ingestURL string
+	apiKey    string // overrides the transport's credentials when set
+
+	// Counters reported in Stats.Endpoints.
+	sent   atomic.Int64
+	failed atomic.Int64
+}
+
+// newIngestEndpoints returns the endpoints for urls, in order. apiKeys
+// holds the API keys of endpoints that do not share the client's.
+func newIngestEndpoints(urls []string, apiKeys map[string]string) []*ingestEndpoint {
+	endpoints := make([]*ingestEndpoint, len(urls))
+	for i, url := range urls {
+		endpoints[i] = &ingestEndpoint{
+			baseURL:   url,
+			ingestURL: url + "/v1/ingest",
+			apiKey:    apiKeys[url],
+		}
+	}
+	return endpoints
+}
+
+// endpointRouter picks the endpoint each send attempt goes to when several
+// are configured: failover for WithFallbackEndpoints, balancer for
+// WithEndpoints. Endpoints are identified by their index in all.
+type endpointRouter interface {
+	// first returns the endpoint a batch starts on.
+	first() int
+	// next returns the endpoint to retry on after a failure on ep.
+	next(ep int) int
+	// all returns every endpoint.
+	all() []*ingestEndpoint
+	// active reports whether batches may start on ep.
+	active(ep int) bool
+	// record updates the router with the outcome of one send to ep;
+	// retryable is set for retryable failures.
+	record(ep int, err error, retryable bool)
+	// stop ends any background probing.
+	stop()
+}
+
+// probeEndpoint reports whether e answers a HEAD request without a server
+// error.
+func probeEndpoint(ctx context.Context, httpClient *http.Client, e *ingestEndpoint) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, e.baseURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// endpointStats returns the Stats.Endpoints entries for router, or nil
+// without one.
+func endpointStats(router endpointRouter) []EndpointStats {
+	if router == nil {
+		return nil
+	}
+	endpoints := router.all()
+	stats := make([]EndpointStats, len(endpoints))
+	for i, e := range endpoints {
+		stats[i] = EndpointStats{
+			URL:            e.baseURL,
+			SentBatches:    e.sent.Load(),
+			FailedAttempts: e.failed.Load(),
+			Active:         router.active(i),
+		}
+	}
+	return stats
+}
@@ -0,0 +1,55 @@
+package logwell
+
+import (
+	"os"
+	"strings"
+)
+
+// Environment variables read by NewFromEnv.
+const (
+	EnvEndpoint = "LOGWELL_ENDPOINT"
+	EnvAPIKey   = "LOGWELL_API_KEY"
+	EnvService  = "LOGWELL_SERVICE"
+	EnvMinLevel = "LOGWELL_MIN_LEVEL"
+)
+
+// EnvEnvironment is the environment variable New reads the deployment
+// environment from when WithEnvironment is not set.
+const EnvEnvironment = "LOGWELL_ENV"
+
+// environmentVars are the variables the deployment environment is read
+// from, in order of precedence.
+var environmentVars = []string{EnvEnvironment, "ENVIRONMENT", "ENV", "APP_ENV"}
+
+// detectEnvironment returns the first non-empty value of environmentVars,
+// or "" if none is set.
+func detectEnvironment() string {
+	for _, name := range environmentVars {
+		if env := strings.TrimSpace(os.Getenv(name)); env != "" {
+			return env
+		}
+	}
+	return ""
+}
+
+// NewFromEnv creates a new Logwell client configured from environment variables.
+// LOGWELL_ENDPOINT and LOGWELL_API_KEY are required. LOGWELL_SERVICE and
+// LOGWELL_MIN_LEVEL are optional; the level is parsed with ParseLevel.
+// Options passed explicitly are applied after the environment and take precedence.
+//
+// Example:
+//
+//	client, err := logwell.NewFromEnv(logwell.WithBatchSize(50))
+func NewFromEnv(opts ...Option) (*Client, error) {
+	var envOpts []Option
+
+	if service := os.Getenv(EnvService); service != "" {
+		envOpts = append(envOpts, WithService(service))
+	}
+
+	if level := os.Getenv(EnvMinLevel); level != "" {
+		envOpts = append(envOpts, WithMinLevelString(level))
+	}
+
+	return New(os.Getenv(EnvEndpoint), os.Getenv(EnvAPIKey), append(envOpts, opts...)...)
+}
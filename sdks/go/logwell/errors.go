@@ -1,10 +1,22 @@
 package logwell
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
 
 // ErrorCode represents the type of error that occurred.
+// ErrorCode implements error so that codes can be used as errors.Is targets:
+// errors.Is(err, logwell.ErrUnauthorized) reports whether err, or any error
+// it wraps, is an *Error with that code.
 type ErrorCode string
 
+// Error implements the error interface.
+func (c ErrorCode) Error() string {
+	return "logwell: " + string(c)
+}
+
 // Error code constants matching the TypeScript and Python SDKs.
 const (
 	// ErrNetworkError indicates a network-level failure (connection, timeout).
@@ -31,9 +43,50 @@ const (
 	// This error is not retryable.
 	ErrQueueOverflow ErrorCode = "QUEUE_OVERFLOW"
 
+	// ErrEnqueueCanceled indicates a log was dropped under the Block
+	// overflow policy because its context ended or the client shut down
+	// while it waited for queue space. This error is not retryable.
+	ErrEnqueueCanceled ErrorCode = "ENQUEUE_CANCELED"
+
 	// ErrInvalidConfig indicates invalid client configuration.
 	// This error is not retryable.
 	ErrInvalidConfig ErrorCode = "INVALID_CONFIG"
+
+	// ErrKeyNotFound indicates VerifyKey was given an API key the server
+	// does not know. This error is not retryable.
+	ErrKeyNotFound ErrorCode = "KEY_NOT_FOUND"
+
+	// ErrKeyExpired indicates VerifyKey was given an API key that has
+	// expired. This error is not retryable.
+	ErrKeyExpired ErrorCode = "KEY_EXPIRED"
+
+	// ErrTailGap indicates Tail may have skipped logs because the stored
+	// logs replayed on resume reached the server's limit. This error is
+	// not retryable.
+	ErrTailGap ErrorCode = "TAIL_GAP"
+
+	// ErrSnapshot indicates Shutdown could not write the logs its final
+	// flush failed to deliver to the WithSnapshotOnShutdown file. This
+	// error is not retryable.
+	ErrSnapshot ErrorCode = "SNAPSHOT_ERROR"
+)
+
+// ErrorKind refines ErrNetworkError with the kind of network failure.
+type ErrorKind string
+
+// Network error kinds. A network error whose cause is none of these has an
+// empty Kind.
+const (
+	// NetworkTimeout indicates the connection or request timed out,
+	// including a context deadline. Usually transient.
+	NetworkTimeout ErrorKind = "timeout"
+
+	// NetworkDNS indicates the endpoint's host name could not be resolved,
+	// which usually points at a configuration problem.
+	NetworkDNS ErrorKind = "dns"
+
+	// NetworkRefused indicates the server actively refused the connection.
+	NetworkRefused ErrorKind = "refused"
 )
 
 // Error represents a Logwell SDK error.
@@ -41,6 +94,10 @@ type Error struct {
 	// Code is the error classification code.
 	Code ErrorCode
 
+	// Kind refines Code for network errors (see NetworkTimeout,
+	// NetworkDNS, NetworkRefused). Empty when unclassified.
+	Kind ErrorKind
+
 	// Message is the human-readable error message.
 	Message string
 
@@ -50,16 +107,88 @@ type Error struct {
 	// Retryable indicates whether this error can be retried.
 	Retryable bool
 
+	// RequestID is the X-Request-Id header of the server's error response,
+	// if any, for matching the failure with server or proxy logs.
+	RequestID string
+
 	// Cause is the underlying error, if any.
 	Cause error
+
+	// ambiguous is set for network errors that occurred after the request
+	// was fully written, when the server may have processed it.
+	ambiguous bool
+
+	// expired is set when retries were abandoned because the batch held
+	// entries older than the max entry age limit.
+	expired bool
+
+	// stopped is set when shutdown interrupted a retry backoff.
+	stopped bool
+
+	// offline is set when retries ended because the endpoint was declared
+	// unreachable.
+	offline bool
+
+	// encodingRejected is set for 415 responses to a compressed body.
+	encodingRejected bool
 }
 
 // Error implements the error interface.
 func (e *Error) Error() string {
 	if e.StatusCode > 0 {
-		return fmt.Sprintf("logwell: %s [%s] (status %d)", e.Message, e.Code, e.StatusCode)
+		return fmt.Sprintf("logwell: %s [%s] (status %d)", e.Message, string(e.Code), e.StatusCode)
+	}
+	return fmt.Sprintf("logwell: %s [%s]", e.Message, string(e.Code))
+}
+
+// errorJSON is the JSON form of Error.
+type errorJSON struct {
+	Code       ErrorCode `json:"code"`
+	Kind       ErrorKind `json:"kind,omitempty"`
+	Message    string    `json:"message"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Retryable  bool      `json:"retryable"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Cause      string    `json:"cause,omitempty"`
+}
+
+// MarshalJSON encodes e as a JSON object. The cause, with everything it
+// wraps, is flattened to its error string.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	v := errorJSON{
+		Code:       e.Code,
+		Kind:       e.Kind,
+		Message:    e.Message,
+		StatusCode: e.StatusCode,
+		Retryable:  e.Retryable,
+		RequestID:  e.RequestID,
+	}
+	if e.Cause != nil {
+		v.Cause = e.Cause.Error()
 	}
-	return fmt.Sprintf("logwell: %s [%s]", e.Message, e.Code)
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes an error encoded by MarshalJSON. A cause is
+// restored as a plain error with the same message, so errors.Is and
+// errors.As no longer match the original cause.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var v errorJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = Error{
+		Code:       v.Code,
+		Kind:       v.Kind,
+		Message:    v.Message,
+		StatusCode: v.StatusCode,
+		Retryable:  v.Retryable,
+		RequestID:  v.RequestID,
+	}
+	if v.Cause != "" {
+		e.Cause = errors.New(v.Cause)
+	}
+	return nil
 }
 
 // Unwrap returns the underlying error for errors.Is/As support.
@@ -67,6 +196,21 @@ func (e *Error) Unwrap() error {
 	return e.Cause
 }
 
+// Is reports whether target is e's ErrorCode, so that
+// errors.Is(err, logwell.ErrRateLimited) works through wrapped errors.
+// Other targets, including other *Error values, match only by identity.
+func (e *Error) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code == e.Code
+}
+
+// IsRetryable reports whether err, or any error it wraps, is an *Error
+// marked Retryable.
+func IsRetryable(err error) bool {
+	var logwellErr *Error
+	return errors.As(err, &logwellErr) && logwellErr.Retryable
+}
+
 // NewError creates a new Error with the given code and message.
 func NewError(code ErrorCode, message string) *Error {
 	return &Error{
@@ -0,0 +1,141 @@
+package logwell
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// TestErrorIs tests matching errors by code through wrap chains.
+func TestErrorIs(t *testing.T) {
+	unauthorized := NewErrorWithStatus(ErrUnauthorized, "invalid API key", http.StatusUnauthorized)
+	wrapped := fmt.Errorf("sending batch: %w", unauthorized)
+	doubleWrapped := fmt.Errorf("flush: %w", wrapped)
+
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{"direct", unauthorized, ErrUnauthorized, true},
+		{"wrapped", wrapped, ErrUnauthorized, true},
+		{"double wrapped", doubleWrapped, ErrUnauthorized, true},
+		{"other code", wrapped, ErrRateLimited, false},
+		{"cause chain", NewErrorWithCause(ErrNetworkError, "request failed", io.ErrUnexpectedEOF), io.ErrUnexpectedEOF, true},
+		{"non-SDK error", errors.New("boom"), ErrServerError, false},
+		{"sentinel identity", fmt.Errorf("log: %w", ErrClientShutdown), ErrClientShutdown, true},
+		{"same code is not identity", NewError(ErrValidationError, "bad"), ErrClientShutdown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.err, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsRetryable tests classifying wrapped errors as retryable.
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"server error", NewErrorWithStatus(ErrServerError, "HTTP 503", 503), true},
+		{"wrapped rate limit", fmt.Errorf("flush: %w", NewError(ErrRateLimited, "slow down")), true},
+		{"wrapped unauthorized", fmt.Errorf("flush: %w", NewError(ErrUnauthorized, "bad key")), false},
+		{"non-SDK error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestErrorJSON tests round-tripping errors through JSON.
+func TestErrorJSON(t *testing.T) {
+	rateLimited := NewErrorWithStatus(ErrRateLimited, "rate limited: slow down", 429)
+	rateLimited.RequestID = "req-123"
+	refused := newNetworkError("request failed", fmt.Errorf("dial tcp: %w", fmt.Errorf("connect: %w", errors.New("connection refused"))))
+	refused.Kind = NetworkRefused
+
+	tests := []struct {
+		name      string
+		err       *Error
+		wantCause string
+	}{
+		{"without cause", rateLimited, ""},
+		{"with cause", NewErrorWithCause(ErrValidationError, "failed to marshal logs", io.ErrUnexpectedEOF), "unexpected EOF"},
+		{"nested wrapped cause", refused, "dial tcp: connect: connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.err)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+
+			var fields map[string]any
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatalf("json.Unmarshal() into map error = %v", err)
+			}
+			if fields["code"] != string(tt.err.Code) || fields["retryable"] != tt.err.Retryable {
+				t.Errorf("JSON = %s, want code %s and retryable %v", data, tt.err.Code, tt.err.Retryable)
+			}
+			if got, _ := fields["cause"].(string); got != tt.wantCause {
+				t.Errorf("JSON cause = %q, want %q", got, tt.wantCause)
+			}
+
+			var decoded Error
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if decoded.Error() != tt.err.Error() {
+				t.Errorf("decoded Error() = %q, want %q", decoded.Error(), tt.err.Error())
+			}
+			if decoded.Code != tt.err.Code || decoded.Kind != tt.err.Kind ||
+				decoded.StatusCode != tt.err.StatusCode || decoded.Retryable != tt.err.Retryable ||
+				decoded.RequestID != tt.err.RequestID {
+				t.Errorf("decoded = %+v, want %+v", decoded, *tt.err)
+			}
+			if tt.wantCause == "" && decoded.Cause != nil {
+				t.Errorf("decoded Cause = %v, want nil", decoded.Cause)
+			}
+			if tt.wantCause != "" && (decoded.Cause == nil || decoded.Cause.Error() != tt.wantCause) {
+				t.Errorf("decoded Cause = %v, want %q", decoded.Cause, tt.wantCause)
+			}
+		})
+	}
+}
+
+// TestErrorString tests the exact Error() text with and without a status.
+func TestErrorString(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *Error
+		want string
+	}{
+		{"without status", NewError(ErrQueueOverflow, "queue full"), "logwell: queue full [QUEUE_OVERFLOW]"},
+		{"with status", NewErrorWithStatus(ErrUnauthorized, "unauthorized", 401), "logwell: unauthorized [UNAUTHORIZED] (status 401)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
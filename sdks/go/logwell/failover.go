@@ -0,0 +1,128 @@
+package logwell
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// failoverPinAfter is how many retryable failures in a row on the primary
+// endpoint pin the client to the fallback that took the batch instead.
+const failoverPinAfter = 3
+
+// failover spreads sends over the primary endpoint and its fallbacks.
+// Every batch starts on the active endpoint, the primary unless pinned, and
+// each retry moves on to the next endpoint. After failoverPinAfter
+// retryable failures in a row on the primary, the client pins to the
+// fallback that next accepts a batch and probes the primary every
+// probeInterval, failing back once it answers.
+type failover struct {
+	endpoints     []*ingestEndpoint // primary first
+	probeInterval time.Duration
+	httpClient    *http.Client
+
+	current atomic.Int32 // index of the endpoint batches start on
+
+	mu       sync.Mutex
+	failures int // retryable failures in a row on the primary
+	timer    *time.Timer
+	stopped  bool
+}
+
+// newFailover creates a failover over the primary endpoint and fallbacks,
+// probing the primary every probeInterval while pinned. apiKeys holds
+// the API keys of fallbacks that do not share the primary's.
+func newFailover(primary string, fallbacks []string, apiKeys map[string]string, probeInterval time.Duration, httpClient *http.Client) *failover {
+	return &failover{
+		endpoints:     newIngestEndpoints(append([]string{primary}, fallbacks...), apiKeys),
+		probeInterval: probeInterval,
+		httpClient:    httpClient,
+	}
+}
+
+// first returns the index of the endpoint a batch starts on.
+func (f *failover) first() int {
+	return int(f.current.Load())
+}
+
+// next returns the index of the endpoint to retry on after ep.
+func (f *failover) next(ep int) int {
+	return (ep + 1) % len(f.endpoints)
+}
+
+// all returns the endpoints, primary first.
+func (f *failover) all() []*ingestEndpoint {
+	return f.endpoints
+}
+
+// active reports whether batches start on endpoint ep: the primary
+// unless pinned to a fallback.
+func (f *failover) active(ep int) bool {
+	return ep == f.first()
+}
+
+// record updates the failover with the outcome of one send to endpoint ep.
+func (f *failover) record(ep int, err error, retryable bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case ep == 0 && err != nil && retryable:
+		f.failures++
+	case ep == 0:
+		f.failures = 0
+	case err == nil:
+		// Pin to a fallback that works once the primary keeps failing
+		if f.current.Load() == 0 && f.failures >= failoverPinAfter && !f.stopped {
+			f.current.Store(int32(ep))
+			f.timer = time.AfterFunc(f.probeInterval, f.probe)
+		}
+	case retryable && int(f.current.Load()) == ep:
+		// The pinned fallback failed too; start from the primary again
+		f.unpinLocked()
+	}
+}
+
+// probe checks whether the primary answers, failing back to it if it
+// does and scheduling the next probe if not.
+func (f *failover) probe() {
+	ctx, cancel := context.WithTimeout(context.Background(), f.probeInterval)
+	defer cancel()
+
+	reachable := probeEndpoint(ctx, f.httpClient, f.endpoints[0])
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.current.Load() == 0 || f.stopped {
+		return
+	}
+	if reachable {
+		f.unpinLocked()
+		return
+	}
+	f.timer = time.AfterFunc(f.probeInterval, f.probe)
+}
+
+// unpinLocked makes batches start on the primary again.
+// The caller must hold f.mu.
+func (f *failover) unpinLocked() {
+	f.current.Store(0)
+	f.failures = 0
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+}
+
+// stop ends probing.
+func (f *failover) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = true
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+}
@@ -0,0 +1,161 @@
+package logwell
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestClientFailover tests that sends continue on a fallback endpoint while
+// the primary is down, that the client pins to the fallback, and that it
+// fails back once the primary answers again.
+func TestClientFailover(t *testing.T) {
+	primary := newSwitchableServer()
+	defer primary.Close()
+	fallback := newTestServer()
+	defer fallback.Close()
+
+	client, err := New(primary.URL, validAPIKey(),
+		WithBatchSize(100),
+		WithFallbackEndpoints(fallback.URL),
+		WithFailbackInterval(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	client.Info("before outage")
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	primary.down.Store(true)
+	for i := 0; i < failoverPinAfter; i++ {
+		client.Info(fmt.Sprintf("during outage %d", i))
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() during outage error = %v", err)
+		}
+	}
+	logs := fallback.getLogs()
+	assertLogCount(t, logs, failoverPinAfter)
+	for i, log := range logs {
+		if want := fmt.Sprintf("during outage %d", i); log.Message != want {
+			t.Errorf("fallback log %d = %q, want %q", i, log.Message, want)
+		}
+	}
+
+	// Pinned: the next batch goes straight to the fallback
+	if got := client.transport.router.first(); got != 1 {
+		t.Fatalf("active endpoint = %d, want the fallback", got)
+	}
+	attempts := primary.attempts.Load()
+	client.Info("pinned")
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() while pinned error = %v", err)
+	}
+	if got := primary.attempts.Load(); got != attempts {
+		t.Errorf("primary attempts while pinned = %d, want %d", got, attempts)
+	}
+
+	// Fail back once a probe finds the primary up
+	primary.down.Store(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for client.transport.router.first() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not fail back to the primary")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	client.Info("after failback")
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() after failback error = %v", err)
+	}
+
+	primaryLogs := primary.getLogs()
+	if len(primaryLogs) != 2 || primaryLogs[0].Message != "before outage" || primaryLogs[1].Message != "after failback" {
+		t.Errorf("primary logs = %+v, want the logs before and after the outage", primaryLogs)
+	}
+	assertLogCount(t, fallback.getLogs(), failoverPinAfter+1)
+}
+
+// TestClientFailoverAPIKey tests that a fallback with its own API key
+// receives it, and that the primary's key is sent otherwise.
+func TestClientFailoverAPIKey(t *testing.T) {
+	primary := newSwitchableServer()
+	defer primary.Close()
+	primary.down.Store(true)
+
+	var (
+		mu   sync.Mutex
+		auth []string
+	)
+	recordAuth := func(ts *testServer) {
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			auth = append(auth, r.Header.Get("Authorization"))
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+	shared := newTestServer()
+	defer shared.Close()
+	recordAuth(shared)
+	own := newTestServer()
+	defer own.Close()
+	recordAuth(own)
+
+	ownKey := "lw_fallbackfallbackfallbackfallback00"
+	client, err := New(primary.URL, validAPIKey(),
+		WithFallbackEndpoints(shared.URL, own.URL),
+		WithFallbackAPIKey(own.URL, ownKey),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	for ep, want := range map[int]string{1: "Bearer " + validAPIKey(), 2: "Bearer " + ownKey} {
+		mu.Lock()
+		auth = nil
+		mu.Unlock()
+		if _, err := client.transport.sendBatch(context.Background(), ep, 1, []LogEntry{{Level: LevelInfo, Message: "key"}}); err != nil {
+			t.Fatalf("sendBatch(%d) error = %v", ep, err)
+		}
+		mu.Lock()
+		if len(auth) != 1 || auth[0] != want {
+			t.Errorf("endpoint %d Authorization = %q, want %q", ep, auth, want)
+		}
+		mu.Unlock()
+	}
+}
+
+// TestConfigValidateFallbackEndpoints tests validation of the failover
+// options.
+func TestConfigValidateFallbackEndpoints(t *testing.T) {
+	fallback := "https://standby.example.com"
+
+	_, err := New(validEndpoint(), validAPIKey(), WithFallbackEndpoints("ftp://standby.example.com"))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	_, err = New(validEndpoint(), validAPIKey(), WithFallbackEndpoints(validEndpoint()))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	_, err = New(validEndpoint(), validAPIKey(), WithFallbackEndpoints(fallback), WithFallbackAPIKey(fallback, "invalid"))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	_, err = New(validEndpoint(), validAPIKey(), WithFallbackAPIKey(fallback, validAPIKey()))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	_, err = New(validEndpoint(), validAPIKey(), WithFallbackEndpoints(fallback), WithFailbackInterval(0))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	client, err := New(validEndpoint(), validAPIKey(), WithFallbackEndpoints(fallback), WithFallbackAPIKey(fallback, validAPIKey()))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client.Shutdown(context.Background())
+}
@@ -0,0 +1,88 @@
+package logwell
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// fallbackRecoveredMessage is the notice written to the fallback writer
+// when batches reach the server again.
+const fallbackRecoveredMessage = "logwell: ingest endpoint reachable again, resumed sending logs"
+
+// fallbackWriter writes batches that could not be sent to a local writer,
+// such as os.Stderr, once sends have failed a number of times in a row, so
+// an outage does not lose them.
+type fallbackWriter struct {
+	w       io.Writer
+	after   int
+	service string
+	diag    *diagnostics
+
+	mu       sync.Mutex
+	failures int  // consecutive failed batches
+	active   bool // failed batches are being written to w
+}
+
+// newFallbackWriter creates a fallback writer that takes over after the
+// given number of consecutive failed batches. The recovery notice is
+// attributed to service.
+func newFallbackWriter(w io.Writer, after int, service string, diag *diagnostics) *fallbackWriter {
+	return &fallbackWriter{w: w, after: after, service: service, diag: diag}
+}
+
+// failed records a batch that could not be sent. Once failures in a row
+// reach after, the batch is written to w as JSON lines. Reports whether
+// the whole batch was written. It is a no-op on a nil receiver.
+func (f *fallbackWriter) failed(logs []LogEntry) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures++
+	if f.failures < f.after {
+		return false
+	}
+	if !f.active {
+		f.active = true
+		f.diag.logf(LevelWarn, "fallback: %d consecutive failed flushes, writing failed batches locally", f.failures)
+	}
+
+	enc := json.NewEncoder(f.w)
+	for i := range logs {
+		if err := enc.Encode(logs[i]); err != nil {
+			f.diag.logf(LevelError, "fallback: write failed: %v", err)
+			return false
+		}
+	}
+	return true
+}
+
+// succeeded records a batch that was sent. If failed batches were being
+// written to w, a recovery notice is written and normal sending resumes.
+// It is a no-op on a nil receiver.
+func (f *fallbackWriter) succeeded() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures = 0
+	if !f.active {
+		return
+	}
+	f.active = false
+	f.diag.logf(LevelInfo, "fallback: sends succeeded again, stopped writing locally")
+	notice := LogEntry{
+		Level:     LevelInfo,
+		Message:   fallbackRecoveredMessage,
+		Timestamp: now(),
+		Service:   f.service,
+	}
+	if err := json.NewEncoder(f.w).Encode(notice); err != nil {
+		f.diag.logf(LevelError, "fallback: write failed: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package logwell
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+// TestFallbackWriter tests writing failed batches locally during an outage.
+func TestFallbackWriter(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var down atomic.Bool
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var req ingestRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		ts.mu.Lock()
+		ts.logs = append(ts.logs, req.Logs...)
+		ts.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	})
+
+	var fallback syncBuffer
+	client := createTestClient(t, ts,
+		WithBatchSize(1),
+		WithService("fallback-service"),
+		WithFallbackWriter(&fallback, 2),
+	)
+	client.transport.maxRetries = 0
+	defer client.Shutdown(context.Background())
+
+	// fallbackMessages returns the messages written to the fallback writer.
+	fallbackMessages := func(t *testing.T) []string {
+		t.Helper()
+		var messages []string
+		for _, line := range fallback.lines() {
+			var entry LogEntry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("fallback line %q is not a JSON log entry: %v", line, err)
+			}
+			messages = append(messages, entry.Message)
+		}
+		return messages
+	}
+
+	t.Run("failed batches written after threshold", func(t *testing.T) {
+		down.Store(true)
+		client.Info("first failure")
+		client.Info("second failure")
+		client.Info("third failure")
+		waitForIdle(t, client)
+
+		got := fallbackMessages(t)
+		want := []string{"second failure", "third failure"}
+		if len(got) != len(want) {
+			t.Fatalf("fallback messages = %q, want %q", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("fallback message %d = %q, want %q", i, got[i], want[i])
+			}
+		}
+		assertLogCount(t, ts.getLogs(), 0)
+	})
+
+	t.Run("recovery notice and normal shipping resume", func(t *testing.T) {
+		down.Store(false)
+		client.Info("back online")
+		client.Info("still online")
+		waitForIdle(t, client)
+
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 2)
+		if logs[0].Message != "back online" {
+			t.Errorf("server message = %q, want %q", logs[0].Message, "back online")
+		}
+
+		got := fallbackMessages(t)
+		if len(got) != 3 || got[2] != fallbackRecoveredMessage {
+			t.Fatalf("fallback messages = %q, want the recovery notice last", got)
+		}
+		var notice LogEntry
+		json.Unmarshal([]byte(fallback.lines()[2]), &notice)
+		if notice.Service != "fallback-service" {
+			t.Errorf("notice Service = %q, want %q", notice.Service, "fallback-service")
+		}
+	})
+
+	t.Run("threshold counts again after recovery", func(t *testing.T) {
+		down.Store(true)
+		client.Info("single failure")
+		waitForIdle(t, client)
+
+		if got := len(fallback.lines()); got != 3 {
+			t.Errorf("fallback lines = %d, want 3", got)
+		}
+	})
+}
+
+// TestConfigValidateFallback tests validation of the fallback writer options.
+func TestConfigValidateFallback(t *testing.T) {
+	var buf syncBuffer
+	_, err := New(validEndpoint(), validAPIKey(), WithFallbackWriter(&buf, 0))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	if _, err := New(validEndpoint(), validAPIKey(), WithFallbackWriter(nil, 0)); err != nil {
+		t.Errorf("New() with nil fallback writer error = %v", err)
+	}
+}
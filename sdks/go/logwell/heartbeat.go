@@ -0,0 +1,79 @@
+package logwell
+
+import (
+	"context"
+	"time"
+)
+
+// heartbeatMessage is the message of heartbeat logs.
+const heartbeatMessage = "service alive"
+
+// heartbeat logs a "service alive" entry through the client every
+// interval, whether or not the application logs anything, for liveness
+// dashboards. It runs on the root client until Shutdown starts.
+type heartbeat struct {
+	client     *Client
+	interval   time.Duration
+	metadataFn func() M
+	started    time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// startHeartbeat starts logging heartbeats through c every interval.
+// metadataFn, when set, adds metadata to each heartbeat.
+func startHeartbeat(c *Client, interval time.Duration, metadataFn func() M) *heartbeat {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &heartbeat{
+		client:     c,
+		interval:   interval,
+		metadataFn: metadataFn,
+		started:    time.Now(),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	go h.run()
+	return h
+}
+
+// run logs a heartbeat every interval until stop is called.
+func (h *heartbeat) run() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-ticker.C:
+			h.beat()
+		}
+	}
+}
+
+// beat logs one heartbeat at info level. Its metadata holds the uptime and
+// client stats, then metadataFn's, and always the heartbeat marker.
+func (h *heartbeat) beat() {
+	stats := h.client.Stats()
+	metadata := []map[string]any{{
+		"uptimeSeconds":   int64(time.Since(h.started) / time.Second),
+		"queueLength":     stats.QueueLength,
+		"enqueuedEntries": stats.EnqueuedEntries,
+		"droppedEntries":  stats.DroppedEntries,
+		"sentBatches":     stats.SentBatches,
+	}}
+	if h.metadataFn != nil {
+		metadata = append(metadata, h.metadataFn())
+	}
+	metadata = append(metadata, map[string]any{"heartbeat": true})
+	h.client.log(h.ctx, LevelInfo, heartbeatMessage, metadata...)
+}
+
+// stop ends the heartbeats, and any wait for queue space by one being
+// logged. It is a no-op on a nil receiver.
+func (h *heartbeat) stop() {
+	if h == nil {
+		return
+	}
+	h.cancel()
+}
@@ -0,0 +1,60 @@
+package logwell
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestClientHeartbeat tests that heartbeats are logged every interval with
+// their marker and metadata, and stop once Shutdown starts.
+func TestClientHeartbeat(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts,
+		WithBatchSize(1),
+		WithHeartbeat(20*time.Millisecond, func() M { return M{"region": "eu", "heartbeat": false} }),
+	)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(ts.getLogs()) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d heartbeats, want at least 3", len(ts.getLogs()))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	logs := ts.getLogs()
+	for i, log := range logs {
+		if log.Message != heartbeatMessage || log.Level != LevelInfo {
+			t.Errorf("log %d = %q at %s, want a heartbeat", i, log.Message, log.Level)
+		}
+		if log.Metadata["heartbeat"] != true || log.Metadata["region"] != "eu" {
+			t.Errorf("log %d metadata = %v, want the heartbeat marker and region", i, log.Metadata)
+		}
+		if _, ok := log.Metadata["uptimeSeconds"]; !ok {
+			t.Errorf("log %d metadata = %v, want uptimeSeconds", i, log.Metadata)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if got := len(ts.getLogs()); got != len(logs) {
+		t.Errorf("got %d logs after Shutdown, want %d", got, len(logs))
+	}
+}
+
+// TestConfigValidateHeartbeat tests validation of WithHeartbeat.
+func TestConfigValidateHeartbeat(t *testing.T) {
+	_, err := New(validEndpoint(), validAPIKey(), WithHeartbeat(-time.Second, nil))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	client, err := New(validEndpoint(), validAPIKey(), WithHeartbeat(time.Hour, nil))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client.Shutdown(context.Background())
+}
@@ -1,6 +1,7 @@
 package logwell
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -53,7 +54,8 @@ func clearTestLogs(ts *testServer) {
 }
 
 // logAndWait sends a log entry and waits for it to be flushed.
-func logAndWait(client *Client, ts *testServer, logFn func(string, ...map[string]any), message string, metadata ...map[string]any) LogEntry {
+func logAndWait(t *testing.T, client *Client, ts *testServer, logFn func(string, ...map[string]any), message string, metadata ...map[string]any) LogEntry {
+	t.Helper()
 	clearTestLogs(ts)
 
 	if len(metadata) > 0 {
@@ -62,7 +64,11 @@ func logAndWait(client *Client, ts *testServer, logFn func(string, ...map[string
 		logFn(message)
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle() error = %v", err)
+	}
 
 	logs := ts.getLogs()
 	if len(logs) == 0 {
@@ -72,6 +78,17 @@ func logAndWait(client *Client, ts *testServer, logFn func(string, ...map[string
 	return logs[len(logs)-1]
 }
 
+// waitForIdle waits until every flush triggered so far has completed.
+func waitForIdle(t *testing.T, client *Client) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle() error = %v", err)
+	}
+}
+
 // createTestClient creates a client with the given options and error handling.
 func createTestClient(t *testing.T, ts *testServer, opts ...Option) *Client {
 	t.Helper()
@@ -101,7 +118,7 @@ func childLogHelper(t *testing.T, parent *Client, ts *testServer, childOpts []Ch
 	clearTestLogs(ts)
 
 	child.Info(message)
-	time.Sleep(50 * time.Millisecond)
+	waitForIdle(t, child)
 
 	logs := ts.getLogs()
 	if len(logs) == 0 {
@@ -129,7 +146,7 @@ func setupAndLogWithMetadata(t *testing.T, ts *testServer, clientOpts []Option,
 		client.Info(message)
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	waitForIdle(t, client)
 
 	logs := ts.getLogs()
 	if len(logs) == 0 {
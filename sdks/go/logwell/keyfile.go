@@ -0,0 +1,105 @@
+package logwell
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiKeyFile holds an API key read from a file that may be rotated on disk.
+// The file is re-read once refreshInterval has passed since the last read,
+// and on demand after the server rejects the current key.
+type apiKeyFile struct {
+	path            string
+	refreshInterval time.Duration
+	onError         func(*Error)
+
+	mu       sync.Mutex
+	key      string
+	lastRead time.Time
+}
+
+// newAPIKeyFile reads the key from path and returns a source that keeps it
+// current. Returns an ErrInvalidConfig error if the file cannot be read or
+// does not hold a valid key.
+func newAPIKeyFile(path string, refreshInterval time.Duration, onError func(*Error)) (*apiKeyFile, error) {
+	key, err := readAPIKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &apiKeyFile{
+		path:            path,
+		refreshInterval: refreshInterval,
+		onError:         onError,
+		key:             key,
+		lastRead:        time.Now(),
+	}, nil
+}
+
+// readAPIKeyFile reads and validates the key stored in path.
+// Surrounding whitespace, such as a trailing newline, is ignored.
+func readAPIKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", NewErrorWithCause(ErrInvalidConfig, "failed to read apiKey file", err)
+	}
+
+	key := strings.TrimSpace(string(data))
+	if err := validateAPIKey(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// current returns the key, re-reading the file first if the refresh
+// interval has passed.
+func (f *apiKeyFile) current() string {
+	f.mu.Lock()
+	if f.refreshInterval <= 0 || time.Since(f.lastRead) < f.refreshInterval {
+		key := f.key
+		f.mu.Unlock()
+		return key
+	}
+	_, err := f.reloadLocked()
+	key := f.key
+	f.mu.Unlock()
+
+	f.report(err)
+	return key
+}
+
+// reload re-reads the file immediately and reports whether the key changed.
+// An unreadable or invalid file keeps the previous key.
+func (f *apiKeyFile) reload() bool {
+	f.mu.Lock()
+	changed, err := f.reloadLocked()
+	f.mu.Unlock()
+
+	f.report(err)
+	return changed
+}
+
+// reloadLocked re-reads the file. The caller must hold f.mu.
+func (f *apiKeyFile) reloadLocked() (bool, error) {
+	f.lastRead = time.Now()
+	key, err := readAPIKeyFile(f.path)
+	if err != nil {
+		return false, err
+	}
+
+	changed := key != f.key
+	f.key = key
+	return changed, nil
+}
+
+// report passes a reload error to the OnError callback.
+// Called without f.mu held.
+func (f *apiKeyFile) report(err error) {
+	if err == nil || f.onError == nil {
+		return
+	}
+	if logwellErr, ok := err.(*Error); ok {
+		f.onError(logwellErr)
+	}
+}
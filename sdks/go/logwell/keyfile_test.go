@@ -0,0 +1,196 @@
+package logwell
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rotatedAPIKey is a second valid key used to simulate rotation.
+func rotatedAPIKey() string {
+	return "lw_" + strings.Repeat("r", 32)
+}
+
+// writeKeyFile writes key to path, failing the test on error.
+func writeKeyFile(t *testing.T, path, key string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(key+"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+// keyServer is a test server that only accepts one API key at a time, or
+// any key when accepted is empty, and records the keys it was sent.
+type keyServer struct {
+	*testServer
+	mu       sync.Mutex
+	accepted string
+	seen     []string
+}
+
+func newKeyServer(accepted string) *keyServer {
+	ks := &keyServer{testServer: newTestServer(), accepted: accepted}
+	ks.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		ks.mu.Lock()
+		ks.seen = append(ks.seen, key)
+		ok := ks.accepted == "" || key == ks.accepted
+		ks.mu.Unlock()
+
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid api key"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+	})
+	return ks
+}
+
+func (ks *keyServer) accept(key string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.accepted = key
+}
+
+func (ks *keyServer) lastKey() string {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if len(ks.seen) == 0 {
+		return ""
+	}
+	return ks.seen[len(ks.seen)-1]
+}
+
+func TestAPIKeyFile(t *testing.T) {
+	t.Run("key is read at New", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "api-key")
+		writeKeyFile(t, path, validAPIKey())
+
+		ks := newKeyServer(validAPIKey())
+		defer ks.Close()
+
+		client, err := New(ks.URL, "", WithAPIKeyFile(path))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		client.Info("first")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		if got := ks.lastKey(); got != validAPIKey() {
+			t.Errorf("key sent = %q, want %q", got, validAPIKey())
+		}
+	})
+
+	t.Run("401 re-reads rotated key and retries", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "api-key")
+		writeKeyFile(t, path, validAPIKey())
+
+		ks := newKeyServer(validAPIKey())
+		defer ks.Close()
+
+		client, err := New(ks.URL, "", WithAPIKeyFile(path), WithAPIKeyRefreshInterval(0))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		writeKeyFile(t, path, rotatedAPIKey())
+		ks.accept(rotatedAPIKey())
+
+		client.Info("after rotation")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		if got := ks.lastKey(); got != rotatedAPIKey() {
+			t.Errorf("key sent = %q, want rotated key", got)
+		}
+	})
+
+	t.Run("key is re-read on interval", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "api-key")
+		writeKeyFile(t, path, validAPIKey())
+
+		ks := newKeyServer(validAPIKey())
+		defer ks.Close()
+
+		client, err := New(ks.URL, "", WithAPIKeyFile(path), WithAPIKeyRefreshInterval(50*time.Millisecond))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		// The server accepts both keys, so only the interval can pick up the new one
+		writeKeyFile(t, path, rotatedAPIKey())
+		ks.accept("")
+		time.Sleep(100 * time.Millisecond)
+
+		client.Info("after interval")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		if got := ks.lastKey(); got != rotatedAPIKey() {
+			t.Errorf("key sent = %q, want rotated key", got)
+		}
+	})
+
+	t.Run("invalid rotated key keeps previous key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "api-key")
+		writeKeyFile(t, path, validAPIKey())
+
+		ks := newKeyServer(validAPIKey())
+		defer ks.Close()
+
+		var gotErr *Error
+		client, err := New(ks.URL, "",
+			WithAPIKeyFile(path),
+			WithAPIKeyRefreshInterval(time.Nanosecond),
+			WithOnError(func(err *Error) { gotErr = err }),
+		)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		writeKeyFile(t, path, "not-a-key")
+
+		client.Info("with previous key")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		if got := ks.lastKey(); got != validAPIKey() {
+			t.Errorf("key sent = %q, want previous key", got)
+		}
+		if gotErr == nil || gotErr.Code != ErrInvalidConfig {
+			t.Errorf("OnError got %v, want %s", gotErr, ErrInvalidConfig)
+		}
+	})
+
+	t.Run("missing or invalid file fails New", func(t *testing.T) {
+		dir := t.TempDir()
+
+		_, err := New(validEndpoint(), "", WithAPIKeyFile(filepath.Join(dir, "missing")))
+		assertConfigError(t, err, ErrInvalidConfig)
+
+		path := filepath.Join(dir, "api-key")
+		writeKeyFile(t, path, "lw_short")
+		_, err = New(validEndpoint(), "", WithAPIKeyFile(path))
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("negative refresh interval is rejected", func(t *testing.T) {
+		_, err := New(validEndpoint(), validAPIKey(), WithAPIKeyRefreshInterval(-time.Second))
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,52 @@
+package logwell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// levelSeverity orders log levels from least to most severe.
+var levelSeverity = map[LogLevel]int{
+	LevelDebug: 0,
+	LevelInfo:  1,
+	LevelWarn:  2,
+	LevelError: 3,
+	LevelFatal: 4,
+}
+
+// ParseLevel converts a string such as "warn" or "WARNING" into a LogLevel.
+// Matching is case-insensitive and ignores surrounding whitespace.
+// The aliases "warning", "err", and "critical" map to warn, error, and fatal.
+// Returns an ErrInvalidConfig error for unknown levels.
+func ParseLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error", "err":
+		return LevelError, nil
+	case "fatal", "critical":
+		return LevelFatal, nil
+	default:
+		return "", NewError(ErrInvalidConfig, fmt.Sprintf("unknown log level %q", s))
+	}
+}
+
+// isValidLevel reports whether level is one of the five known levels.
+func isValidLevel(level LogLevel) bool {
+	_, ok := levelSeverity[level]
+	return ok
+}
+
+// levelEnabled reports whether a log at level passes the min level filter.
+// Unknown levels are always enabled so custom entries passed to Log are not lost.
+func levelEnabled(level, minLevel LogLevel) bool {
+	severity, ok := levelSeverity[level]
+	if !ok {
+		return true
+	}
+	return severity >= levelSeverity[minLevel]
+}
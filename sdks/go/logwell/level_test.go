@@ -0,0 +1,194 @@
+package logwell
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      LogLevel
+		wantError bool
+	}{
+		{name: "debug", input: "debug", want: LevelDebug},
+		{name: "info", input: "info", want: LevelInfo},
+		{name: "warn", input: "warn", want: LevelWarn},
+		{name: "error", input: "error", want: LevelError},
+		{name: "fatal", input: "fatal", want: LevelFatal},
+		{name: "warning alias", input: "warning", want: LevelWarn},
+		{name: "err alias", input: "err", want: LevelError},
+		{name: "critical alias", input: "critical", want: LevelFatal},
+		{name: "uppercase", input: "WARN", want: LevelWarn},
+		{name: "mixed case alias", input: "Critical", want: LevelFatal},
+		{name: "surrounding whitespace", input: "  info\n", want: LevelInfo},
+		{name: "whitespace around alias", input: "\tWARNING ", want: LevelWarn},
+		{name: "empty string", input: "", wantError: true},
+		{name: "whitespace only", input: "   ", wantError: true},
+		{name: "unknown level", input: "verbose", wantError: true},
+		{name: "trace is not supported", input: "trace", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.input)
+			if tt.wantError {
+				assertConfigError(t, err, ErrInvalidConfig)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLevel(%q) error = %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithMinLevelString(t *testing.T) {
+	t.Run("valid string sets level", func(t *testing.T) {
+		cfg := &Config{}
+		WithMinLevelString(" Warning ")(cfg)
+		if cfg.MinLevel != LevelWarn {
+			t.Errorf("MinLevel = %q, want %q", cfg.MinLevel, LevelWarn)
+		}
+	})
+
+	t.Run("unknown string fails New", func(t *testing.T) {
+		_, err := New(validEndpoint(), validAPIKey(), WithMinLevelString("loud"))
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("invalid WithMinLevel fails New", func(t *testing.T) {
+		_, err := New(validEndpoint(), validAPIKey(), WithMinLevel("loud"))
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+}
+
+func TestClientMinLevel(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts, WithBatchSize(1), WithMinLevelString("warn"))
+	defer client.Shutdown(context.Background())
+
+	client.Debug("dropped debug")
+	client.Info("dropped info")
+	client.Warn("kept warn")
+	client.Log(LogEntry{Level: LevelInfo, Message: "dropped entry"})
+	client.Log(LogEntry{Level: LevelError, Message: "kept entry"})
+	waitForIdle(t, client)
+
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 2)
+	for _, log := range logs {
+		if log.Level != LevelWarn && log.Level != LevelError {
+			t.Errorf("unexpected log at level %q: %q", log.Level, log.Message)
+		}
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Run("reads endpoint, key, service and level", func(t *testing.T) {
+		t.Setenv(EnvEndpoint, validEndpoint())
+		t.Setenv(EnvAPIKey, validAPIKey())
+		t.Setenv(EnvService, "env-service")
+		t.Setenv(EnvMinLevel, "ERR")
+
+		client, err := NewFromEnv()
+		if err != nil {
+			t.Fatalf("NewFromEnv() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		if client.config.Service != "env-service" {
+			t.Errorf("Service = %q, want %q", client.config.Service, "env-service")
+		}
+		if client.config.MinLevel != LevelError {
+			t.Errorf("MinLevel = %q, want %q", client.config.MinLevel, LevelError)
+		}
+	})
+
+	t.Run("explicit options override environment", func(t *testing.T) {
+		t.Setenv(EnvEndpoint, validEndpoint())
+		t.Setenv(EnvAPIKey, validAPIKey())
+		t.Setenv(EnvMinLevel, "error")
+
+		client, err := NewFromEnv(WithMinLevel(LevelInfo))
+		if err != nil {
+			t.Fatalf("NewFromEnv() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		if client.config.MinLevel != LevelInfo {
+			t.Errorf("MinLevel = %q, want %q", client.config.MinLevel, LevelInfo)
+		}
+	})
+
+	t.Run("invalid level returns error", func(t *testing.T) {
+		t.Setenv(EnvEndpoint, validEndpoint())
+		t.Setenv(EnvAPIKey, validAPIKey())
+		t.Setenv(EnvMinLevel, "noisy")
+
+		_, err := NewFromEnv()
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("missing endpoint returns error", func(t *testing.T) {
+		t.Setenv(EnvEndpoint, "")
+		t.Setenv(EnvAPIKey, validAPIKey())
+
+		_, err := NewFromEnv()
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+}
+
+// TestDetectEnvironment tests the precedence of the environment variables
+// the deployment environment is read from, and that WithEnvironment wins.
+func TestDetectEnvironment(t *testing.T) {
+	vars := []string{EnvEnvironment, "ENVIRONMENT", "ENV", "APP_ENV"}
+	for i, name := range vars {
+		t.Run(name, func(t *testing.T) {
+			for _, other := range vars {
+				t.Setenv(other, "")
+			}
+			// Lower-precedence variables are ignored
+			for j := len(vars) - 1; j >= i; j-- {
+				t.Setenv(vars[j], strings.ToLower(vars[j]))
+			}
+
+			client, err := New(validEndpoint(), validAPIKey())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer client.Shutdown(context.Background())
+			if want := strings.ToLower(name); client.config.Environment != want {
+				t.Errorf("Environment = %q, want %q", client.config.Environment, want)
+			}
+		})
+	}
+
+	t.Run("option overrides variables", func(t *testing.T) {
+		t.Setenv(EnvEnvironment, "staging")
+		client, err := New(validEndpoint(), validAPIKey(), WithEnvironment("production"))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+		if client.config.Environment != "production" {
+			t.Errorf("Environment = %q, want production", client.config.Environment)
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		for _, name := range vars {
+			t.Setenv(name, "")
+		}
+		if env := detectEnvironment(); env != "" {
+			t.Errorf("detectEnvironment() = %q, want empty", env)
+		}
+	})
+}
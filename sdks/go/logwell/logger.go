@@ -0,0 +1,63 @@
+package logwell
+
+// Logger is the logging surface of Client, for code that should depend on
+// an interface rather than the concrete client, such as packages that take
+// a logger as a dependency and tests that substitute a fake.
+//
+// Client.Child returns *Client, so Logger derives child loggers through
+// With and ChildLogger instead, which return Logger.
+type Logger interface {
+	Debug(message string, metadata ...map[string]any)
+	Info(message string, metadata ...map[string]any)
+	Warn(message string, metadata ...map[string]any)
+	Error(message string, metadata ...map[string]any)
+	Fatal(message string, metadata ...map[string]any)
+
+	// With returns a child logger that adds metadata to every log.
+	With(metadata map[string]any) Logger
+
+	// ChildLogger returns a child logger configured by opts.
+	ChildLogger(opts ...ChildOption) Logger
+}
+
+// Compile-time checks that the implementations satisfy Logger.
+var (
+	_ Logger = (*Client)(nil)
+	_ Logger = NopLogger{}
+)
+
+// With returns a child logger with metadata merged into the client's
+// metadata. It is shorthand for Child(ChildWithMetadata(metadata)).
+func (c *Client) With(metadata map[string]any) Logger {
+	return c.Child(ChildWithMetadata(metadata))
+}
+
+// ChildLogger is Child returning the Logger interface.
+func (c *Client) ChildLogger(opts ...ChildOption) Logger {
+	return c.Child(opts...)
+}
+
+// NopLogger is a Logger that discards everything, for tests and for
+// optional logging dependencies.
+type NopLogger struct{}
+
+// Debug discards the log.
+func (NopLogger) Debug(string, ...map[string]any) {}
+
+// Info discards the log.
+func (NopLogger) Info(string, ...map[string]any) {}
+
+// Warn discards the log.
+func (NopLogger) Warn(string, ...map[string]any) {}
+
+// Error discards the log.
+func (NopLogger) Error(string, ...map[string]any) {}
+
+// Fatal discards the log.
+func (NopLogger) Fatal(string, ...map[string]any) {}
+
+// With returns the NopLogger.
+func (n NopLogger) With(map[string]any) Logger { return n }
+
+// ChildLogger returns the NopLogger.
+func (n NopLogger) ChildLogger(...ChildOption) Logger { return n }
@@ -0,0 +1,42 @@
+package logwell
+
+import (
+	"context"
+	"testing"
+)
+
+// TestLogger tests using Client and NopLogger through the Logger interface.
+func TestLogger(t *testing.T) {
+	t.Run("client logs through the interface", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		client := createTestClient(t, ts, WithBatchSize(100), WithMetadata(M{"env": "test"}))
+		var logger Logger = client
+
+		logger.Info("plain")
+		logger.With(M{"requestId": "abc"}).Warn("with metadata")
+		logger.ChildLogger(ChildWithService("worker")).With(M{"job": "sync"}).Error("nested")
+
+		if err := client.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+
+		logs := ts.getLogs()
+		assertLogCount(t, logs, 3)
+		assertLogMetadata(t, logs[1], map[string]string{"env": "test", "requestId": "abc"})
+		if logs[2].Service != "worker" {
+			t.Errorf("Service = %q, want %q", logs[2].Service, "worker")
+		}
+		assertLogMetadata(t, logs[2], map[string]string{"env": "test", "job": "sync"})
+	})
+
+	t.Run("NopLogger discards everything", func(t *testing.T) {
+		var logger Logger = NopLogger{}
+		logger.Debug("ignored", M{"k": "v"})
+		logger.Fatal("ignored")
+		if _, ok := logger.With(M{"k": "v"}).ChildLogger(ChildWithService("x")).(NopLogger); !ok {
+			t.Error("NopLogger children should be NopLogger")
+		}
+	})
+}
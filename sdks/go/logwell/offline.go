@@ -0,0 +1,157 @@
+package logwell
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// offlineDetector tracks whether the ingest endpoint is reachable. After a
+// number of consecutive network errors it declares the client offline, so
+// flushes pause and logs stay queued, and probes the endpoint periodically
+// until it answers again.
+type offlineDetector struct {
+	after         int
+	probeInterval time.Duration
+	probeURL      string
+	httpClient    *http.Client
+
+	// onOffline is called once when the endpoint is declared unreachable,
+	// with the error that tipped it; onOnline once when a probe succeeds.
+	onOffline func(err error)
+	onOnline  func()
+
+	offline atomic.Bool
+
+	mu       sync.Mutex
+	failures int // consecutive network errors
+	timer    *time.Timer
+	stopped  bool
+}
+
+// newOfflineDetector creates a detector that goes offline after the given
+// number of consecutive network errors and then probes probeURL every
+// probeInterval.
+func newOfflineDetector(after int, probeInterval time.Duration, probeURL string, httpClient *http.Client) *offlineDetector {
+	return &offlineDetector{
+		after:         after,
+		probeInterval: probeInterval,
+		probeURL:      probeURL,
+		httpClient:    httpClient,
+	}
+}
+
+// isOffline reports whether the endpoint is considered unreachable.
+// It returns false on a nil receiver.
+func (d *offlineDetector) isOffline() bool {
+	return d != nil && d.offline.Load()
+}
+
+// record updates the detector with the outcome of one send attempt. Any
+// response from the server, even an error status, counts as reachable and
+// ends offline mode.
+// It is a no-op on a nil receiver.
+func (d *offlineDetector) record(err error) {
+	if d == nil {
+		return
+	}
+	if !isConnectivityError(err) {
+		d.online()
+		return
+	}
+
+	d.mu.Lock()
+	d.failures++
+	if d.failures < d.after || d.offline.Load() || d.stopped {
+		d.mu.Unlock()
+		return
+	}
+	d.offline.Store(true)
+	d.timer = time.AfterFunc(d.probeInterval, d.probe)
+	d.mu.Unlock()
+
+	if d.onOffline != nil {
+		d.onOffline(err)
+	}
+}
+
+// probe checks whether the endpoint answers and schedules the next probe
+// if it does not.
+func (d *offlineDetector) probe() {
+	ctx, cancel := context.WithTimeout(context.Background(), d.probeInterval)
+	defer cancel()
+
+	reachable := false
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, d.probeURL, nil)
+	if err == nil {
+		if resp, err := d.httpClient.Do(req); err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			reachable = true
+		}
+	}
+
+	if reachable {
+		d.online()
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.offline.Load() && !d.stopped {
+		d.timer = time.AfterFunc(d.probeInterval, d.probe)
+	}
+}
+
+// online resets the failure count and, if the client was offline, ends
+// probing and calls onOnline.
+func (d *offlineDetector) online() {
+	d.mu.Lock()
+	d.failures = 0
+	if !d.offline.Load() {
+		d.mu.Unlock()
+		return
+	}
+	d.offline.Store(false)
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.mu.Unlock()
+
+	if d.onOnline != nil {
+		d.onOnline()
+	}
+}
+
+// stop ends probing. It is a no-op on a nil receiver.
+func (d *offlineDetector) stop() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
+
+// isConnectivityError reports whether err is a network error that suggests
+// the endpoint is unreachable, as opposed to an error response.
+func isConnectivityError(err error) bool {
+	var logwellErr *Error
+	return errors.As(err, &logwellErr) && logwellErr.Code == ErrNetworkError && logwellErr.StatusCode == 0
+}
+
+// isOfflineError reports whether err ended a send because the endpoint was
+// declared unreachable.
+func isOfflineError(err error) bool {
+	logwellErr, ok := err.(*Error)
+	return ok && logwellErr.offline
+}
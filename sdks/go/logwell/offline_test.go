@@ -0,0 +1,142 @@
+package logwell
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// switchableServer is an ingest server that can be taken offline: while
+// down it drops every connection without a response, as an unreachable
+// network would.
+type switchableServer struct {
+	*httptest.Server
+	down     atomic.Bool
+	attempts atomic.Int32 // ingest requests made while down
+
+	mu   sync.Mutex
+	logs []LogEntry
+}
+
+func newSwitchableServer() *switchableServer {
+	s := &switchableServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.down.Load() {
+			if r.Method != http.MethodHead {
+				s.attempts.Add(1)
+			}
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		var req ingestRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.mu.Lock()
+		s.logs = append(s.logs, req.Logs...)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	}))
+	return s
+}
+
+func (s *switchableServer) getLogs() []LogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]LogEntry(nil), s.logs...)
+}
+
+// waitForOffline waits until client.Stats().Offline is want.
+func waitForOffline(t *testing.T, client *Client, want bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Stats().Offline != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Stats().Offline did not become %v", want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestClientOfflineMode tests buffering while the endpoint is unreachable
+// and draining once it recovers.
+func TestClientOfflineMode(t *testing.T) {
+	server := newSwitchableServer()
+	defer server.Close()
+
+	var errorCount int32
+	client, err := New(server.URL, validAPIKey(),
+		WithBatchSize(2),
+		WithOfflineMode(2, 100*time.Millisecond),
+		WithOnError(func(*Error) { atomic.AddInt32(&errorCount, 1) }),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	server.down.Store(true)
+	client.Info("offline 1")
+	client.Info("offline 2") // flushes: two failed attempts take the client offline
+	waitForOffline(t, client, true)
+
+	if got := server.attempts.Load(); got != 2 {
+		t.Errorf("ingest attempts before going offline = %d, want 2", got)
+	}
+	if got := atomic.LoadInt32(&errorCount); got != 1 {
+		t.Errorf("OnError calls = %d, want 1 state-change notification", got)
+	}
+
+	// Batches filled while offline stay queued without any attempt
+	for i := 0; i < 6; i++ {
+		client.Info("buffered")
+	}
+	if err := client.Flush(context.Background()); err == nil {
+		t.Error("Flush() while offline error = nil, want offline error")
+	}
+	if got := client.QueueLen(); got != 8 {
+		t.Errorf("QueueLen() while offline = %d, want 8", got)
+	}
+	if got := server.attempts.Load(); got != 3 {
+		t.Errorf("ingest attempts while offline = %d, want 3 (one for Flush)", got)
+	}
+	if got := atomic.LoadInt32(&errorCount); got != 1 {
+		t.Errorf("OnError calls while offline = %d, want 1", got)
+	}
+
+	// Probes find the endpoint again and the backlog is drained
+	server.down.Store(false)
+	waitForOffline(t, client, false)
+	waitForIdle(t, client)
+
+	if got := len(server.getLogs()); got != 8 {
+		t.Errorf("server received %d logs after recovery, want 8", got)
+	}
+}
+
+// TestConfigValidateOfflineMode tests validation of the offline mode options.
+func TestConfigValidateOfflineMode(t *testing.T) {
+	_, err := New(validEndpoint(), validAPIKey(), WithOfflineMode(-1, time.Second))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	_, err = New(validEndpoint(), validAPIKey(), WithOfflineMode(3, 0))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	client, err := New(validEndpoint(), validAPIKey(), WithOfflineMode(3, time.Second))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client.Shutdown(context.Background())
+}
@@ -0,0 +1,249 @@
+package logwell
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxQueryResponseSize caps how much of a getJSON response is read. A
+// query page holds up to a few hundred logs with their metadata.
+const maxQueryResponseSize = 16 << 20
+
+// QueryParams selects the logs returned by Query. Zero fields do not
+// filter.
+type QueryParams struct {
+	// Service keeps logs of this service.
+	Service string
+
+	// Levels keeps logs at any of these levels.
+	Levels []LogLevel
+
+	// From and To keep logs with timestamps in the range, inclusive.
+	From time.Time
+	To   time.Time
+
+	// Search is a full-text query; every word must match.
+	Search string
+
+	// Limit is the page size. The server clamps it to its own bounds and
+	// picks a default when it is zero.
+	Limit int
+
+	// Cursor continues from the page that returned it as NextCursor.
+	Cursor string
+}
+
+// QueryResult is one page of logs returned by Query, newest first.
+type QueryResult struct {
+	Logs []QueryEntry `json:"logs"`
+
+	// HasMore reports whether further pages exist; NextCursor fetches
+	// the next one.
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"nextCursor"`
+}
+
+// QueryEntry is a log stored by the server.
+type QueryEntry struct {
+	ID         string         `json:"id"`
+	Level      LogLevel       `json:"level"`
+	Message    string         `json:"message"`
+	Service    string         `json:"serviceName"`
+	Timestamp  time.Time      `json:"timestamp"`
+	Metadata   map[string]any `json:"metadata"`
+	SourceFile string         `json:"sourceFile"`
+	LineNumber int            `json:"lineNumber"`
+	RequestID  string         `json:"requestId"`
+	UserID     string         `json:"userId"`
+	IPAddress  string         `json:"ipAddress"`
+}
+
+// Query searches the logs the server has stored for the client's project,
+// returning one page. Requests use the client's credentials and are retried
+// on network errors, 5xx and 429 like batches; errors are *Error values
+// with the same codes, such as ErrUnauthorized for a rejected API key.
+//
+// Example:
+//
+//	params := logwell.QueryParams{Service: "billing", Levels: []logwell.LogLevel{logwell.LevelError}}
+//	for {
+//	    page, err := client.Query(ctx, params)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    for _, entry := range page.Logs {
+//	        fmt.Println(entry.Timestamp, entry.Message)
+//	    }
+//	    if !page.HasMore {
+//	        break
+//	    }
+//	    params.Cursor = page.NextCursor
+//	}
+func (c *Client) Query(ctx context.Context, params QueryParams) (*QueryResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := validateQueryParams(params); err != nil {
+		return nil, err
+	}
+	return c.transport.queryWithRetry(ctx, params)
+}
+
+// validateQueryParams validates the filters of a query.
+func validateQueryParams(params QueryParams) error {
+	for _, level := range params.Levels {
+		if !isValidLevel(level) {
+			return NewError(ErrValidationError, "query level must be debug, info, warn, error, or fatal")
+		}
+	}
+	if params.Limit < 0 {
+		return NewError(ErrValidationError, "query limit must not be negative")
+	}
+	if !params.From.IsZero() && !params.To.IsZero() && params.To.Before(params.From) {
+		return NewError(ErrValidationError, "query To must not be before From")
+	}
+	return nil
+}
+
+// queryValues encodes params as the query string of the search endpoint.
+func queryValues(params QueryParams) url.Values {
+	values := url.Values{}
+	if params.Service != "" {
+		values.Set("service", params.Service)
+	}
+	if len(params.Levels) > 0 {
+		levels := make([]string, len(params.Levels))
+		for i, level := range params.Levels {
+			levels[i] = string(level)
+		}
+		values.Set("level", strings.Join(levels, ","))
+	}
+	if !params.From.IsZero() {
+		values.Set("from", params.From.UTC().Format(time.RFC3339Nano))
+	}
+	if !params.To.IsZero() {
+		values.Set("to", params.To.UTC().Format(time.RFC3339Nano))
+	}
+	if params.Search != "" {
+		values.Set("search", params.Search)
+	}
+	if params.Limit > 0 {
+		values.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Cursor != "" {
+		values.Set("cursor", params.Cursor)
+	}
+	return values
+}
+
+// retryRequest runs do, a request other than a batch send, with the
+// backoff and retry rules of sendWithRetry.
+func (t *httpTransport) retryRequest(ctx context.Context, do func() error) error {
+	var lastErr error
+	for attempt := 0; attempt <= t.maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := t.calculateBackoff(attempt)
+			if t.onRetry != nil {
+				t.onRetry(RetryInfo{Attempt: attempt, Err: lastErr, Delay: delay})
+			}
+			select {
+			case <-ctx.Done():
+				return newNetworkError("context canceled during retry", ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+
+		err := do()
+		if err != nil && t.keyFile != nil && isUnauthorizedError(err) && t.keyFile.reload() {
+			// The key file was rotated; retry at once with the new key
+			err = do()
+		}
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if !t.isRetryableError(err) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return newNetworkError("context canceled", ctx.Err())
+		}
+		if attempt < t.maxRetries && !t.retryBudget.take() {
+			return err
+		}
+	}
+	return lastErr
+}
+
+// queryWithRetry runs a query, retrying it as retryRequest does.
+func (t *httpTransport) queryWithRetry(ctx context.Context, params QueryParams) (*QueryResult, error) {
+	var result QueryResult
+	err := t.retryRequest(ctx, func() error {
+		result = QueryResult{}
+		return t.getJSON(ctx, t.queryURL+"?"+queryValues(params).Encode(), &result, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// getJSON sends a GET request for rawURL with the client's credentials
+// and decodes the JSON response into out. Error responses become an *Error
+// from mapError, when set and it returns one, or from createError.
+func (t *httpTransport) getJSON(ctx context.Context, rawURL string, out any, mapError func(status int, body []byte) *Error) error {
+	token, err := t.bearerToken(ctx)
+	if err != nil {
+		return NewErrorWithCause(ErrUnauthorized, "token provider failed", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return NewErrorWithCause(ErrNetworkError, "failed to create request", err)
+	}
+	t.authorize(req, token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		netErr := newNetworkError("request failed", err)
+		t.debugExchange(req, nil, nil, netErr)
+		return netErr
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxQueryResponseSize))
+	if err != nil {
+		netErr := newNetworkError("failed to read response", err)
+		t.debugExchange(req, resp, nil, netErr)
+		return netErr
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr *Error
+		if mapError != nil {
+			apiErr = mapError(resp.StatusCode, body)
+		}
+		if apiErr == nil {
+			apiErr = t.createError(resp.StatusCode, t.parseErrorMessage(body, resp.StatusCode))
+		}
+		apiErr.RequestID = resp.Header.Get(requestIDHeader)
+		t.debugExchange(req, resp, body, apiErr)
+		return apiErr
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		parseErr := NewErrorWithCause(ErrServerError, "failed to parse response", err)
+		t.debugExchange(req, resp, body, parseErr)
+		return parseErr
+	}
+	t.debugExchange(req, resp, body, nil)
+	return nil
+}
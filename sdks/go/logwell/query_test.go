@@ -0,0 +1,217 @@
+package logwell
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newQueryServer starts a server answering the search endpoint with
+// handler.
+func newQueryServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/logs" {
+			t.Errorf("request = %s %s, want GET /v1/logs", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestClientQuery tests that filters are sent as query parameters and that
+// pages are followed through their cursors.
+func TestClientQuery(t *testing.T) {
+	pages := map[string]string{
+		"": `{"logs":[
+			{"id":"3","level":"error","message":"third","serviceName":"billing","timestamp":"2024-01-01T00:00:03.000Z","metadata":{"order":3}},
+			{"id":"2","level":"error","message":"second","serviceName":"billing","timestamp":"2024-01-01T00:00:02.000Z"}
+		],"total":3,"has_more":true,"nextCursor":"page-2"}`,
+		"page-2": `{"logs":[
+			{"id":"1","level":"fatal","message":"first","serviceName":"billing","timestamp":"2024-01-01T00:00:01.000Z","requestId":"req-1"}
+		],"total":3,"has_more":false,"nextCursor":null}`,
+	}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	srv := newQueryServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer "+validAPIKey() {
+			t.Errorf("Authorization = %q, want the API key", got)
+		}
+		q := r.URL.Query()
+		want := map[string]string{
+			"service": "billing", "level": "error,fatal", "search": "payment failed", "limit": "2",
+			"from": "2024-01-01T00:00:00Z", "to": "2024-01-01T01:00:00Z",
+		}
+		for k, v := range want {
+			if q.Get(k) != v {
+				t.Errorf("query %s = %q, want %q", k, q.Get(k), v)
+			}
+		}
+		w.Write([]byte(pages[q.Get("cursor")]))
+	})
+
+	client, err := New(srv.URL, validAPIKey())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	params := QueryParams{
+		Service: "billing",
+		Levels:  []LogLevel{LevelError, LevelFatal},
+		From:    from,
+		To:      to,
+		Search:  "payment failed",
+		Limit:   2,
+	}
+	var messages []string
+	for page := 0; ; page++ {
+		if page == 2 {
+			t.Fatal("Query() did not stop paginating")
+		}
+		result, err := client.Query(context.Background(), params)
+		if err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		for _, entry := range result.Logs {
+			messages = append(messages, entry.Message)
+		}
+		if !result.HasMore {
+			if result.NextCursor != "" {
+				t.Errorf("last page NextCursor = %q, want empty", result.NextCursor)
+			}
+			break
+		}
+		params.Cursor = result.NextCursor
+	}
+
+	if len(messages) != 3 || messages[0] != "third" || messages[2] != "first" {
+		t.Errorf("messages = %v, want [third second first]", messages)
+	}
+}
+
+// TestClientQueryEntry tests that stored logs decode into typed entries.
+func TestClientQueryEntry(t *testing.T) {
+	srv := newQueryServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"logs":[{"id":"abc","level":"warn","message":"slow","serviceName":"api",
+			"timestamp":"2024-01-01T00:00:01.500Z","metadata":{"ms":900},"sourceFile":"main.go",
+			"lineNumber":42,"requestId":"req-7","userId":"u-1","ipAddress":"10.0.0.1"}],"has_more":false}`))
+	})
+	client, err := New(srv.URL, validAPIKey())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	result, err := client.Query(context.Background(), QueryParams{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(result.Logs) != 1 {
+		t.Fatalf("len(Logs) = %d, want 1", len(result.Logs))
+	}
+	want := QueryEntry{
+		ID: "abc", Level: LevelWarn, Message: "slow", Service: "api",
+		Timestamp:  time.Date(2024, 1, 1, 0, 0, 1, 500e6, time.UTC),
+		SourceFile: "main.go", LineNumber: 42, RequestID: "req-7", UserID: "u-1", IPAddress: "10.0.0.1",
+	}
+	got := result.Logs[0]
+	if got.Metadata["ms"] != float64(900) {
+		t.Errorf("Metadata = %v, want ms 900", got.Metadata)
+	}
+	got.Metadata = nil
+	if !got.Timestamp.Equal(want.Timestamp) {
+		t.Errorf("Timestamp = %v, want %v", got.Timestamp, want.Timestamp)
+	}
+	got.Timestamp = want.Timestamp
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entry = %+v, want %+v", got, want)
+	}
+}
+
+// TestClientQueryErrors tests that a rejected API key is not retried, that
+// 5xx responses are, and that invalid filters fail before any request.
+func TestClientQueryErrors(t *testing.T) {
+	t.Run("unauthorized", func(t *testing.T) {
+		var requests int32
+		srv := newQueryServer(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.Header().Set(requestIDHeader, "req-401")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized","message":"Invalid API key"}`))
+		})
+		client, err := New(srv.URL, validAPIKey())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		_, err = client.Query(context.Background(), QueryParams{})
+		var logwellErr *Error
+		if !errors.As(err, &logwellErr) || logwellErr.Code != ErrUnauthorized {
+			t.Fatalf("Query() error = %v, want %s", err, ErrUnauthorized)
+		}
+		if logwellErr.StatusCode != http.StatusUnauthorized || logwellErr.RequestID != "req-401" {
+			t.Errorf("error = %+v, want status 401 and the request ID", logwellErr)
+		}
+		if got := atomic.LoadInt32(&requests); got != 1 {
+			t.Errorf("requests = %d, want 1", got)
+		}
+	})
+
+	t.Run("server error retried", func(t *testing.T) {
+		var requests int32
+		srv := newQueryServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"logs":[],"has_more":false}`))
+		})
+		client, err := New(srv.URL, validAPIKey())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		if _, err := client.Query(context.Background(), QueryParams{}); err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Errorf("requests = %d, want 2", got)
+		}
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		srv := newQueryServer(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Error("request sent for invalid params")
+		})
+		client, err := New(srv.URL, validAPIKey())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		now := time.Now()
+		for _, params := range []QueryParams{
+			{Levels: []LogLevel{"loud"}},
+			{Limit: -1},
+			{From: now, To: now.Add(-time.Minute)},
+		} {
+			_, err := client.Query(context.Background(), params)
+			var logwellErr *Error
+			if !errors.As(err, &logwellErr) || logwellErr.Code != ErrValidationError {
+				t.Errorf("Query(%+v) error = %v, want %s", params, err, ErrValidationError)
+			}
+		}
+	})
+}
@@ -1,115 +1,1131 @@
 package logwell
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // batchQueue is a thread-safe queue for batching log entries.
 // It holds entries until explicitly flushed, batch size is reached,
 // or flush interval elapses.
+//
+// Entries are stored in a ring buffer preallocated from maxQueueSize, so
+// adding never allocates and the storage is reused across flushes. With no
+// maxQueueSize the ring grows as needed.
+//
+// Entries at priorityLevels go to a separate priority lane instead. The lane
+// is flushed ahead of the ring and shares maxQueueSize with it, but overflow
+// evicts ring entries first.
 type batchQueue struct {
-	entries []LogEntry
-	mu      sync.Mutex
+	mu    sync.Mutex
+	buf   []LogEntry
+	head  int // index of the oldest entry
+	count int
 
-	// Timer-based auto-flush
+	// Priority lane, oldest first. priorityLevels is set by the owning
+	// client before the queue is used.
+	priority       []LogEntry
+	priorityLevels map[LogLevel]bool
+
+	// Timer-based auto-flush. The timer and flushInterval have their own
+	// lock so that adds never touch them while holding mu; timerArmed lets
+	// adds skip that lock once a timer is running.
 	flushInterval time.Duration
 	flushFn       func()
+	timerMu       sync.Mutex
 	timer         *time.Timer
+	timerArmed    atomic.Bool
 
 	// Overflow protection
 	maxQueueSize int
+	spaceCh      chan struct{} // closed by the next flush; see tryAdd
 	onError      func(*Error)
+	onDrop       func(int)
+	droppedTotal int
+
+	// High watermark notification. onHighWatermark fires when the length
+	// reaches highWatermark while watermarkArmed, which a flush re-sets.
+	highWatermark   int
+	onHighWatermark func()
+	watermarkArmed  bool
+
+	// Rate-limited overflow reporting. When overflowReportInterval > 0,
+	// drops are counted and reported as one summary per interval.
+	overflowReportInterval time.Duration
+	droppedPending         int
+	lastOverflowReport     time.Time
+	overflowTimer          *time.Timer
+
+	// Bounded concurrent flushing. Each running flush holds a flushSem slot;
+	// flushPending records triggers that arrived while every slot was taken
+	// so a holder runs another cycle instead of being skipped. Every batch
+	// being sent holds a sendSem slot, capping batches in flight.
+	// exclusiveSem serializes Flush and Shutdown, which take all flushSem
+	// slots to wait for running flushes.
+	flushSem     chan struct{}
+	sendSem      chan struct{}
+	exclusiveSem chan struct{}
+	flushPending atomic.Bool
+	inFlight     atomic.Int64
+
+	// Shutdown signaling for background flushes. stopRetries is closed when
+	// shutdown starts, ending their backoff waits so the shutdown drain
+	// makes the final attempt. sendCtx is canceled if shutdown stops waiting
+	// for them, aborting requests still in progress.
+	stopRetries chan struct{}
+	stopOnce    sync.Once
+	sendCtx     context.Context
+	cancelSends context.CancelFunc
+
+	// Idle tracking for WaitForIdle. drains counts running drains and
+	// idleCh, when non-nil, is closed once the queue is empty with no
+	// drain running. Both are guarded by mu.
+	drains int
+	idleCh chan struct{}
+
+	// Lifetime counters reported by Client.Stats.
+	enqueuedTotal atomic.Int64
+	sentBatches   atomic.Int64
+	sendNanos     atomic.Int64
+
+	// batchSize is the queue length that triggers a flush and the size of
+	// each chunk sent. It is set by the owning client and may change at
+	// runtime via SetBatchSize or adaptive batching.
+	batchSize atomic.Int64
+
+	// maxBufferAge, when positive, replaces flushInterval as the delay of
+	// the oldest-entry timer, and idleTimer flushes after flushInterval
+	// without adds. idleTimer is guarded by timerMu.
+	maxBufferAge time.Duration
+	idleTimer    *time.Timer
+
+	// minFlushBytes, when positive, makes idleTimer skip its flush while
+	// queuedBytes is below it, leaving the entries to the oldest-entry
+	// timer.
+	minFlushBytes int64
+
+	// maxEntryAge, when positive, makes adds record enqueue times and
+	// shortens the flush timer so entries are sent before reaching it.
+	maxEntryAge time.Duration
+
+	// Adaptive batching bounds; both zero when disabled.
+	adaptiveMin int
+	adaptiveMax int
+
+	// batchBytes, when positive, makes the queue ready to flush once the
+	// estimated serialized size of its in-memory entries reaches it, and
+	// maxBytes, when positive, caps that size like maxQueueSize caps the
+	// length. queuedBytes holds the estimate, accumulated per entry as
+	// they are stored and removed, while either or minFlushBytes is set; it
+	// is only written under mu.
+	batchBytes  int64
+	maxBytes    int64
+	queuedBytes atomic.Int64
+
+	// dropLowest makes overflow evict the least severe entry, the oldest
+	// among equals, instead of the oldest. Set by the owning client for
+	// the DropLowestSeverity policy.
+	dropLowest bool
+
+	// spill, when set, takes entries that do not fit in maxQueueSize
+	// instead of evicting. While it holds entries, new entries are spilled
+	// too so they stay behind them; flush reads them back oldest first.
+	// Guarded by mu.
+	spill *spillStore
 }
 
+// minRingCapacity is the initial capacity of an unbounded queue.
+const minRingCapacity = 16
+
+// adaptiveSlowFlush is the send duration at which adaptive batching treats
+// a flush as slow and shrinks the batch size.
+const adaptiveSlowFlush = 2 * time.Second
+
 // newBatchQueue creates a new batch queue with optional auto-flush and overflow protection.
 // If flushInterval > 0 and flushFn is provided, the queue will
-// automatically call flushFn after flushInterval of inactivity.
+// automatically call flushFn flushInterval after the oldest unflushed entry
+// was added.
 // If maxQueueSize > 0, the queue will drop oldest entries when capacity is reached.
 func newBatchQueue(flushInterval time.Duration, flushFn func(), maxQueueSize int, onError func(*Error)) *batchQueue {
+	capacity := minRingCapacity
+	if maxQueueSize > 0 {
+		capacity = maxQueueSize
+	}
+	sendCtx, cancelSends := context.WithCancel(context.Background())
 	return &batchQueue{
-		entries:       make([]LogEntry, 0),
+		buf:           make([]LogEntry, capacity),
 		flushInterval: flushInterval,
 		flushFn:       flushFn,
 		maxQueueSize:  maxQueueSize,
 		onError:       onError,
+		flushSem:      make(chan struct{}, 1),
+		sendSem:       make(chan struct{}, 1),
+		exclusiveSem:  make(chan struct{}, 1),
+		stopRetries:   make(chan struct{}),
+		sendCtx:       sendCtx,
+		cancelSends:   cancelSends,
+	}
+}
+
+// setAdaptiveBatching enables adaptive batch sizing between minSize and
+// maxSize, clamping the current batch size into that range.
+// Must be called before the queue is used.
+func (q *batchQueue) setAdaptiveBatching(minSize, maxSize int) {
+	if minSize == 0 && maxSize == 0 {
+		return
+	}
+	q.adaptiveMin = minSize
+	q.adaptiveMax = maxSize
+	size := int(q.batchSize.Load())
+	q.batchSize.Store(int64(min(max(size, minSize), maxSize)))
+}
+
+// adaptBatchSize adjusts the batch size after a batch was sent in elapsed
+// time with result err. Fast successes grow the size by a quarter; 413
+// responses, timeouts, and slow sends halve it.
+func (q *batchQueue) adaptBatchSize(err error, elapsed time.Duration) {
+	if q.adaptiveMax == 0 {
+		return
+	}
+
+	shrink := elapsed >= adaptiveSlowFlush || isPayloadTooLarge(err) || isTimeoutError(err)
+	if err != nil && !shrink {
+		return // Other failures say nothing about the batch size
+	}
+
+	for {
+		size := q.batchSize.Load()
+		next := size + max(size/4, 1)
+		if shrink {
+			next = size / 2
+		}
+		next = min(max(next, int64(q.adaptiveMin)), int64(q.adaptiveMax))
+		if next == size || q.batchSize.CompareAndSwap(size, next) {
+			return
+		}
+	}
+}
+
+// setPriorityLevels routes entries at levels to the priority lane.
+// Must be called before the queue is used.
+func (q *batchQueue) setPriorityLevels(levels []LogLevel) {
+	if len(levels) == 0 {
+		return
+	}
+	q.priorityLevels = make(map[LogLevel]bool, len(levels))
+	for _, level := range levels {
+		q.priorityLevels[level] = true
+	}
+}
+
+// setHighWatermark calls fn when the queue length reaches fraction of
+// maxQueueSize. It has no effect on an unbounded queue.
+// Must be called before the queue is used.
+func (q *batchQueue) setHighWatermark(fraction float64, fn func()) {
+	if fraction <= 0 || fn == nil || q.maxQueueSize <= 0 {
+		return
 	}
+	q.highWatermark = max(int(math.Ceil(fraction*float64(q.maxQueueSize))), 1)
+	q.onHighWatermark = fn
+	q.watermarkArmed = true
 }
 
-// add appends a log entry to the queue.
-// If timer-based auto-flush is configured, starts or resets the timer.
-// If the queue is at max capacity, drops the oldest entry and calls onError.
-func (q *batchQueue) add(entry LogEntry) {
+// crossedWatermarkLocked reports whether the queue has just reached the
+// high watermark, disarming it until the next flush.
+// The caller must hold q.mu.
+func (q *batchQueue) crossedWatermarkLocked() bool {
+	if !q.watermarkArmed || q.lenLocked() < q.highWatermark {
+		return false
+	}
+	q.watermarkArmed = false
+	return true
+}
+
+// setMaxConcurrentFlushes allows up to n batches to be sent at once.
+// Must be called before the queue is used.
+func (q *batchQueue) setMaxConcurrentFlushes(n int) {
+	q.flushSem = make(chan struct{}, n)
+	q.sendSem = make(chan struct{}, n)
+}
+
+// add appends a log entry to the queue and returns the new queue length.
+// If timer-based auto-flush is configured, starts the timer if not running.
+// If the queue is at max capacity, evicts an entry as the overflow policy
+// says and calls onError.
+// The drop and append happen under a single lock acquisition, and callbacks
+// run after the lock is released, so the queue never exceeds maxQueueSize.
+func (q *batchQueue) add(entry LogEntry) int {
+	q.stamp(&entry)
+
+	q.mu.Lock()
+
+	var line []byte
+	if q.mightSpillLocked(1, q.sizeLocked(&entry)) {
+		// Encode without holding the lock; pushLocked checks again
+		q.mu.Unlock()
+		line, _ = encodeSpill(entry)
+		q.mu.Lock()
+	}
+
+	// Evict an entry if at max capacity
+	dropped := q.pushLocked(entry, line)
+	q.droppedTotal += dropped
+	if dropped > 0 && q.overflowReportInterval > 0 {
+		q.recordDropsLocked(dropped)
+		dropped = 0
+	}
+	n := q.pendingLocked()
+	crossed := q.crossedWatermarkLocked()
+
+	onError, onDrop := q.onError, q.onDrop
+	q.mu.Unlock()
+
+	q.startTimer()
+
+	// Call callbacks outside the lock to avoid deadlock
+	if crossed {
+		q.onHighWatermark()
+	}
+	if dropped > 0 {
+		if onDrop != nil {
+			onDrop(dropped)
+		}
+		if onError != nil {
+			onError(q.overflowError(dropped))
+		}
+	}
+	return n
+}
+
+// overflowError returns the error reported for dropped entries, naming
+// what the overflow policy dropped: under DropLowestSeverity the least
+// severe entries, which may include the incoming one.
+func (q *batchQueue) overflowError(dropped int) *Error {
+	if q.dropLowest {
+		noun := "entries"
+		if dropped == 1 {
+			noun = "entry"
+		}
+		return NewError(ErrQueueOverflow, fmt.Sprintf("queue overflow: dropped %d %s (policy: drop lowest severity)", dropped, noun))
+	}
+	if dropped == 1 {
+		return NewError(ErrQueueOverflow, "queue overflow: dropping oldest entry")
+	}
+	return NewError(ErrQueueOverflow, fmt.Sprintf("queue overflow: dropped %d oldest entries", dropped))
+}
+
+// tryAdd appends entry if the queue has room and returns the new queue
+// length and true. A full queue is left unchanged and tryAdd returns a
+// channel that the next flush closes instead, so callers can wait for space
+// without missing a flush that runs in between.
+func (q *batchQueue) tryAdd(entry LogEntry) (int, <-chan struct{}, bool) {
+	q.stamp(&entry)
+
 	q.mu.Lock()
+	if q.fullLocked(q.sizeLocked(&entry)) {
+		if q.spaceCh == nil {
+			q.spaceCh = make(chan struct{})
+		}
+		space := q.spaceCh
+		q.mu.Unlock()
+		return 0, space, false
+	}
+	q.pushLocked(entry, nil)
+	n := q.pendingLocked()
+	crossed := q.crossedWatermarkLocked()
+	q.mu.Unlock()
+
+	q.startTimer()
+	if crossed {
+		q.onHighWatermark()
+	}
+	return n, nil, true
+}
+
+// addDropped counts n entries dropped outside of queue overflow.
+func (q *batchQueue) addDropped(n int) {
+	q.mu.Lock()
+	q.droppedTotal += n
+	q.mu.Unlock()
+}
+
+// stamp records the enqueue time on entry when a max entry age is
+// configured.
+func (q *batchQueue) stamp(entry *LogEntry) {
+	if q.maxEntryAge > 0 {
+		entry.enqueuedAt = clockNow()
+	}
+}
+
+// entryOverhead approximates the JSON keys, quoting and timestamp of a
+// serialized entry beyond its variable-length fields.
+const entryOverhead = 96
+
+// estimateEntrySize cheaply approximates the size of entry once serialized,
+// without marshaling it. It is meant for batch size triggers, not limits.
+func estimateEntrySize(entry LogEntry) int {
+	n := entryOverhead + len(entry.Level) + len(entry.Message) + len(entry.Service) +
+		len(entry.Environment) + len(entry.SourceFile) + len(entry.FunctionName)
+	if len(entry.Metadata) > 0 {
+		n += estimateValueSize(map[string]any(entry.Metadata))
+	}
+	return n
+}
+
+// estimateValueSize approximates the JSON size of a metadata value.
+// Values of other types, and containers nested deeper than
+// maxFlattenDepth, such as those of a cycle, are counted as a short scalar.
+func estimateValueSize(v any) int {
+	return estimateValueSizeDepth(v, 0)
+}
 
-	// Check for overflow - drop oldest entry if at max capacity
-	if q.maxQueueSize > 0 && len(q.entries) >= q.maxQueueSize {
-		// Drop oldest entry (FIFO)
-		q.entries = q.entries[1:]
+// estimateValueSizeDepth implements estimateValueSize for a value nested
+// depth containers deep.
+func estimateValueSizeDepth(v any, depth int) int {
+	if depth > maxFlattenDepth {
+		return 16
+	}
+	switch v := v.(type) {
+	case nil:
+		return 4
+	case string:
+		return len(v) + 2
+	case []byte:
+		return base64.StdEncoding.EncodedLen(len(v)) + 2
+	case map[string]any:
+		n := 2
+		for k, val := range v {
+			n += len(k) + 4 + estimateValueSizeDepth(val, depth+1)
+		}
+		return n
+	case M:
+		return estimateValueSizeDepth(map[string]any(v), depth)
+	case []any:
+		n := 2
+		for _, val := range v {
+			n += estimateValueSizeDepth(val, depth+1) + 1
+		}
+		return n
+	case []string:
+		n := 2
+		for _, val := range v {
+			n += len(val) + 3
+		}
+		return n
+	default:
+		return 16
+	}
+}
+
+// entrySize returns the estimated serialized size of entry, caching it on
+// the entry so it is estimated once however often it is queued.
+func entrySize(entry *LogEntry) int64 {
+	if entry.size == 0 {
+		entry.size = estimateEntrySize(*entry)
+	}
+	return int64(entry.size)
+}
 
-		// Call onError callback outside the lock to avoid deadlock
-		if q.onError != nil {
-			onError := q.onError
+// addAll appends multiple log entries under a single lock acquisition and
+// returns the new queue length.
+// Overflow evicts entries as the overflow policy says and calls onError
+// once with the number dropped.
+// The flush timer is started if not already running.
+func (q *batchQueue) addAll(entries []LogEntry) int {
+	if len(entries) == 0 {
+		return q.size()
+	}
+
+	var enqueuedAt time.Time
+	if q.maxEntryAge > 0 {
+		enqueuedAt = clockNow()
+	}
+
+	q.mu.Lock()
+
+	var lines [][]byte
+	if q.spill != nil {
+		var size int64
+		for i := range entries {
+			size += q.sizeLocked(&entries[i])
+		}
+		if q.mightSpillLocked(len(entries), size) {
+			// Encode without holding the lock; pushLocked checks again
 			q.mu.Unlock()
-			onError(NewError(ErrQueueOverflow, "queue overflow: dropping oldest entry"))
+			lines = make([][]byte, len(entries))
+			for i, entry := range entries {
+				entry.enqueuedAt = enqueuedAt
+				lines[i], _ = encodeSpill(entry)
+			}
 			q.mu.Lock()
 		}
 	}
 
-	q.entries = append(q.entries, entry)
+	// Drop oldest entries beyond max capacity (FIFO)
+	dropped := 0
+	for i, entry := range entries {
+		entry.enqueuedAt = enqueuedAt
+		var line []byte
+		if lines != nil {
+			line = lines[i]
+		}
+		dropped += q.pushLocked(entry, line)
+	}
+	q.droppedTotal += dropped
+
+	if dropped > 0 && q.overflowReportInterval > 0 {
+		q.recordDropsLocked(dropped)
+		dropped = 0
+	}
+	n := q.pendingLocked()
+	crossed := q.crossedWatermarkLocked()
+
+	onError, onDrop := q.onError, q.onDrop
+	q.mu.Unlock()
+
+	q.startTimer()
+
+	// Call callbacks outside the lock to avoid deadlock
+	if crossed {
+		q.onHighWatermark()
+	}
+	if dropped > 0 && onDrop != nil {
+		onDrop(dropped)
+	}
+	if dropped > 0 && onError != nil {
+		onError(q.overflowError(dropped))
+	}
+	return n
+}
+
+// requeue puts entries, oldest first, back at the front of their lanes so
+// they are sent ahead of anything queued since. Entries that do not fit in
+// maxQueueSize or maxBytes are dropped, oldest first, and reported like
+// overflow; with a spill store the newest queued entries move to the front
+// of the spill instead. The flush timer is started so the entries are
+// retried even if no new logs arrive.
+func (q *batchQueue) requeue(entries []LogEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	q.mu.Lock()
+
+	dropped := 0
+	if q.spill != nil {
+		dropped = q.requeueSpillLocked(entries)
+	} else if q.maxQueueSize > 0 {
+		space := max(q.maxQueueSize-q.lenLocked(), 0)
+		if len(entries) > space {
+			dropped = len(entries) - space
+			entries = entries[dropped:]
+		}
+	}
+	q.droppedTotal += dropped
+
+	if q.spill == nil {
+		for i := len(entries) - 1; i >= 0; i-- {
+			q.prependLocked(entries[i])
+		}
+		// Past maxBytes, drop the oldest, which are the requeued entries
+		for q.maxBytes > 0 && q.lenLocked() > 1 && q.queuedBytes.Load() > q.maxBytes {
+			q.evictLocked()
+			dropped++
+		}
+	}
+
+	if dropped > 0 && q.overflowReportInterval > 0 {
+		q.recordDropsLocked(dropped)
+		dropped = 0
+	}
+	onError, onDrop := q.onError, q.onDrop
+	q.mu.Unlock()
+
+	q.startTimer()
+
+	if dropped > 0 && onDrop != nil {
+		onDrop(dropped)
+	}
+	if dropped > 0 && onError != nil {
+		onError(NewError(ErrQueueOverflow, fmt.Sprintf("queue overflow: dropped %d requeued entries", dropped)))
+	}
+}
+
+// requeueSpillLocked puts entries ahead of the queued ones, keeping the
+// oldest maxQueueSize in memory and writing the rest to the front of the
+// spill, which holds only newer entries. Returns the number of entries
+// dropped because the spill could not take them.
+// The caller must hold q.mu.
+func (q *batchQueue) requeueSpillLocked(entries []LogEntry) int {
+	all := append(entries[:len(entries):len(entries)], q.takeLocked()...)
+	keep := min(len(all), q.maxQueueSize)
+	for _, entry := range all[:keep] {
+		q.appendLocked(entry)
+	}
+
+	dropped, err := q.spill.prepend(all[keep:])
+	if err != nil {
+		q.spill.diag.logf(LevelError, "spill: writing requeued entries failed: %v", err)
+		dropped = len(all) - keep
+	}
+	return dropped
+}
+
+// prependLocked stores entry at the head of its lane.
+// The caller must hold q.mu.
+func (q *batchQueue) prependLocked(entry LogEntry) {
+	q.countBytesLocked(&entry, 1)
+	if q.priorityLevels[entry.Level] {
+		q.priority = append(q.priority, LogEntry{})
+		copy(q.priority[1:], q.priority)
+		q.priority[0] = entry
+		return
+	}
+
+	if q.count == len(q.buf) {
+		q.growLocked()
+	}
+	q.head = (q.head - 1 + len(q.buf)) % len(q.buf)
+	q.buf[q.head] = entry
+	q.count++
+}
+
+// mightSpillLocked reports whether adding n entries of size estimated
+// bytes in total could spill any of them, so that adds encode them before
+// taking the lock. It errs towards true.
+// The caller must hold q.mu.
+func (q *batchQueue) mightSpillLocked(n int, size int64) bool {
+	return q.spill != nil && (q.spill.len() > 0 ||
+		(q.maxQueueSize > 0 && q.lenLocked()+n > q.maxQueueSize) ||
+		(q.maxBytes > 0 && q.queuedBytes.Load()+size > q.maxBytes))
+}
+
+// pushLocked stores entry at the tail of its lane and returns the number of
+// entries dropped. When the queue is full (see fullLocked), or the spill
+// already holds entries, entry is spilled if a spill store is set, using
+// line when it holds the entry already encoded by encodeSpill; otherwise
+// entries are evicted by evictLocked until it fits. An unbounded queue
+// grows instead.
+// The caller must hold q.mu.
+func (q *batchQueue) pushLocked(entry LogEntry, line []byte) int {
+	q.enqueuedTotal.Add(1)
+	size := q.sizeLocked(&entry)
+	dropped := 0
+	if q.spill != nil && (q.fullLocked(size) || q.spill.len() > 0) {
+		var err error
+		if line == nil {
+			line, err = encodeSpill(entry)
+		}
+		if err == nil {
+			if dropped, err = q.spill.append(line); err == nil {
+				return dropped
+			}
+		}
+		// Keep the entry in memory rather than lose it
+		q.spill.diag.logf(LevelError, "spill: writing entry failed: %v", err)
+	}
 
-	// Start or reset the flush timer if auto-flush is enabled
-	if q.flushInterval > 0 && q.flushFn != nil {
-		if q.timer == nil {
-			// Start new timer
-			q.timer = time.AfterFunc(q.flushInterval, q.flushFn)
+	// One eviction makes room unless maxBytes needs several
+	for q.fullLocked(size) {
+		dropped++
+		if q.dropLowest {
+			if !q.evictLowestLocked(entry.Level) {
+				return dropped // entry itself is the least severe
+			}
 		} else {
-			// Reset existing timer
-			q.timer.Reset(q.flushInterval)
+			q.evictLocked()
 		}
 	}
+	q.appendLocked(entry)
+	return dropped
+}
+
+// appendLocked stores entry at the tail of its lane, growing the ring if
+// it is full.
+// The caller must hold q.mu.
+func (q *batchQueue) appendLocked(entry LogEntry) {
+	q.countBytesLocked(&entry, 1)
+	if q.priorityLevels[entry.Level] {
+		q.priority = append(q.priority, entry)
+		return
+	}
+
+	if q.count == len(q.buf) {
+		q.growLocked()
+	}
+	q.buf[(q.head+q.count)%len(q.buf)] = entry
+	q.count++
+}
+
+// evictLocked removes the oldest ring entry, or the oldest priority entry
+// if the ring is empty.
+// The caller must hold q.mu.
+func (q *batchQueue) evictLocked() {
+	if q.count > 0 {
+		q.countBytesLocked(&q.buf[q.head], -1)
+		q.buf[q.head] = LogEntry{}
+		q.head = (q.head + 1) % len(q.buf)
+		q.count--
+		return
+	}
+
+	q.countBytesLocked(&q.priority[0], -1)
+	n := copy(q.priority, q.priority[1:])
+	q.priority[n] = LogEntry{}
+	q.priority = q.priority[:n]
+}
+
+// evictLowestLocked removes the least severe entry, the oldest among
+// equals, unless every entry is more severe than an incoming entry at
+// level, in which case it removes nothing and returns false. The ring is
+// scanned before the priority lane, whose entries are usually the most
+// severe. Scanning is linear in the bounded queue length.
+// The caller must hold q.mu.
+func (q *batchQueue) evictLowestLocked(level LogLevel) bool {
+	lowest := levelSeverity[level]
+	ringIdx, prioIdx := -1, -1
+	for i := 0; i < q.count; i++ {
+		if s := levelSeverity[q.buf[(q.head+i)%len(q.buf)].Level]; s < lowest || (s == lowest && ringIdx < 0 && prioIdx < 0) {
+			lowest, ringIdx = s, i
+		}
+	}
+	for i := range q.priority {
+		if s := levelSeverity[q.priority[i].Level]; s < lowest || (s == lowest && ringIdx < 0 && prioIdx < 0) {
+			lowest, ringIdx, prioIdx = s, -1, i
+		}
+	}
+
+	switch {
+	case ringIdx >= 0:
+		q.countBytesLocked(&q.buf[(q.head+ringIdx)%len(q.buf)], -1)
+		// Shift the older entries up one slot over the evicted one
+		for i := ringIdx; i > 0; i-- {
+			q.buf[(q.head+i)%len(q.buf)] = q.buf[(q.head+i-1)%len(q.buf)]
+		}
+		q.buf[q.head] = LogEntry{}
+		q.head = (q.head + 1) % len(q.buf)
+		q.count--
+	case prioIdx >= 0:
+		q.countBytesLocked(&q.priority[prioIdx], -1)
+		n := copy(q.priority[prioIdx:], q.priority[prioIdx+1:])
+		q.priority[prioIdx+n] = LogEntry{}
+		q.priority = q.priority[:prioIdx+n]
+	default:
+		return false
+	}
+	return true
+}
+
+// countBytesLocked adds the estimated size of entry to queuedBytes, or
+// subtracts it when sign is -1. It does nothing unless batchBytes or
+// maxBytes is set.
+// The caller must hold q.mu.
+func (q *batchQueue) countBytesLocked(entry *LogEntry, sign int64) {
+	if q.batchBytes > 0 || q.maxBytes > 0 || q.minFlushBytes > 0 {
+		q.queuedBytes.Add(sign * entrySize(entry))
+	}
+}
+
+// fullLocked reports whether the queue has no room for an entry of size
+// estimated bytes: it is at maxQueueSize, or the entry would take it past
+// maxBytes. An empty queue always has room, however large the entry.
+// The caller must hold q.mu.
+func (q *batchQueue) fullLocked(size int64) bool {
+	n := q.lenLocked()
+	return (q.maxQueueSize > 0 && n >= q.maxQueueSize) ||
+		(q.maxBytes > 0 && n > 0 && q.queuedBytes.Load()+size > q.maxBytes)
+}
+
+// sizeLocked returns the estimated size of entry when the byte cap is
+// set, and 0 otherwise.
+// The caller must hold q.mu.
+func (q *batchQueue) sizeLocked(entry *LogEntry) int64 {
+	if q.maxBytes > 0 {
+		return entrySize(entry)
+	}
+	return 0
+}
+
+// bytesReached reports whether the estimated size of the queued entries
+// has reached batchBytes.
+func (q *batchQueue) bytesReached() bool {
+	return q.batchBytes > 0 && q.queuedBytes.Load() >= q.batchBytes
+}
+
+// lenLocked returns the number of entries in both lanes.
+// The caller must hold q.mu.
+func (q *batchQueue) lenLocked() int {
+	return q.count + len(q.priority)
+}
+
+// pendingLocked returns the number of entries waiting to be sent, in memory
+// and spilled.
+// The caller must hold q.mu.
+func (q *batchQueue) pendingLocked() int {
+	return q.lenLocked() + q.spill.len()
+}
+
+// growLocked doubles the capacity of an unbounded ring.
+// The caller must hold q.mu.
+func (q *batchQueue) growLocked() {
+	buf := make([]LogEntry, 2*len(q.buf))
+	q.copyLocked(buf)
+	q.buf = buf
+	q.head = 0
+}
+
+// copyLocked copies the queued entries, oldest first, into dst.
+// The caller must hold q.mu.
+func (q *batchQueue) copyLocked(dst []LogEntry) {
+	n := copy(dst, q.buf[q.head:min(q.head+q.count, len(q.buf))])
+	copy(dst[n:], q.buf[:q.count-n])
+}
 
+// recordDropsLocked counts dropped entries for rate-limited reporting.
+// The first drop after a quiet interval is reported right away; later drops
+// are accumulated and reported when the interval elapses.
+// The caller must hold q.mu.
+func (q *batchQueue) recordDropsLocked(n int) {
+	q.droppedPending += n
+
+	if q.overflowTimer != nil {
+		return
+	}
+
+	wait := q.overflowReportInterval - time.Since(q.lastOverflowReport)
+	if wait < 0 {
+		wait = 0
+	}
+	q.overflowTimer = time.AfterFunc(wait, q.reportOverflow)
+}
+
+// reportOverflow delivers one summary for all drops counted since the last report.
+func (q *batchQueue) reportOverflow() {
+	q.mu.Lock()
+	dropped := q.droppedPending
+	q.droppedPending = 0
+	q.lastOverflowReport = time.Now()
+	if q.overflowTimer != nil {
+		q.overflowTimer.Stop()
+		q.overflowTimer = nil
+	}
+	onError, onDrop := q.onError, q.onDrop
 	q.mu.Unlock()
+
+	if dropped == 0 {
+		return
+	}
+	if onDrop != nil {
+		onDrop(dropped)
+	}
+	if onError != nil {
+		onError(q.overflowError(dropped))
+	}
 }
 
-// flush returns all queued entries and clears the queue.
-// Stops the flush timer if running.
+// flush returns all queued entries, priority lane first, and clears the
+// queue. Stops the flush timer if running. The ring and lane storage is
+// kept for reuse; only the returned slice is allocated.
+//
+// With a spill store, up to maxQueueSize spilled entries are read back and
+// returned after the queued ones; the caller flushes again while spilled
+// reports more.
 func (q *batchQueue) flush() []LogEntry {
+	// Stop the timer before taking entries: an add that races with the
+	// flush either has its entry taken here or arms a new timer
+	q.stopFlushTimer()
+
+	q.mu.Lock()
+
+	entries := q.takeLocked()
+	lost := 0
+	if q.spill.len() > 0 {
+		var spilled []LogEntry
+		spilled, lost = q.spill.read(q.maxQueueSize)
+		entries = append(entries, spilled...)
+		q.droppedTotal += lost
+	}
+	if len(entries) == 0 {
+		entries = nil
+	}
+
+	// The queue is empty again, so the next rise crosses the watermark
+	q.watermarkArmed = q.onHighWatermark != nil
+
+	// Wake producers waiting in tryAdd for space
+	if q.spaceCh != nil {
+		close(q.spaceCh)
+		q.spaceCh = nil
+	}
+	onDrop := q.onDrop
+	q.mu.Unlock()
+
+	if lost > 0 && onDrop != nil {
+		onDrop(lost)
+	}
+	return entries
+}
+
+// spilled returns the number of entries waiting in the spill store.
+func (q *batchQueue) spilled() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.spill.len()
+}
+
+// closeSpill writes the entries still in memory to the front of the spill,
+// so the next client using its directory sends them, and closes it. It is
+// a no-op without a spill store.
+func (q *batchQueue) closeSpill() {
 	q.mu.Lock()
 	defer q.mu.Unlock()
+	if q.spill == nil {
+		return
+	}
 
-	// Stop the flush timer if running
-	if q.timer != nil {
-		q.timer.Stop()
-		q.timer = nil
+	entries := q.takeLocked()
+	if _, err := q.spill.prepend(entries); err != nil {
+		q.spill.diag.logf(LevelError, "spill: writing %d entries at shutdown failed: %v", len(entries), err)
+	}
+	q.spill.closeTail()
+}
+
+// takeLocked removes and returns all entries in memory, priority lane
+// first.
+// The caller must hold q.mu.
+func (q *batchQueue) takeLocked() []LogEntry {
+	if q.lenLocked() == 0 {
+		return nil
+	}
+
+	entries := make([]LogEntry, q.lenLocked())
+	n := copy(entries, q.priority)
+	q.copyLocked(entries[n:])
+	clear(q.priority)
+	q.priority = q.priority[:0]
+
+	// Release references held by the ring so taken entries can be collected
+	end := q.head + q.count
+	if end > len(q.buf) {
+		clear(q.buf[q.head:])
+		clear(q.buf[:end-len(q.buf)])
+	} else {
+		clear(q.buf[q.head:end])
 	}
+	q.head = 0
+	q.count = 0
+	q.queuedBytes.Store(0)
 
-	if len(q.entries) == 0 {
+	return entries
+}
+
+// beginDrain records that a drain is starting. It must be called before the
+// drain takes the queued entries, so the queue never looks idle while they
+// are being sent.
+func (q *batchQueue) beginDrain() {
+	q.mu.Lock()
+	q.drains++
+	q.mu.Unlock()
+}
+
+// endDrain records that a drain has finished, including its callbacks and
+// any requeue, and wakes idle waiters if nothing is left to send.
+func (q *batchQueue) endDrain() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.drains--
+	if q.drains == 0 && q.pendingLocked() == 0 && q.idleCh != nil {
+		close(q.idleCh)
+		q.idleCh = nil
+	}
+}
+
+// idle returns nil if the queue is empty with no drain running, or a
+// channel that is closed when it next becomes so.
+func (q *batchQueue) idle() <-chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.drains == 0 && q.pendingLocked() == 0 {
 		return nil
 	}
+	if q.idleCh == nil {
+		q.idleCh = make(chan struct{})
+	}
+	return q.idleCh
+}
 
-	// Take ownership of current entries
-	entries := q.entries
-	// Allocate new slice for future entries
-	q.entries = make([]LogEntry, 0)
+// snapshot returns a copy of the queued entries in flush order without
+// removing them.
+func (q *batchQueue) snapshot() []LogEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
+	entries := make([]LogEntry, q.lenLocked())
+	n := copy(entries, q.priority)
+	q.copyLocked(entries[n:])
 	return entries
 }
 
-// size returns the current number of entries in the queue.
+// size returns the current number of entries in the queue, including
+// spilled ones.
 func (q *batchQueue) size() int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
-	return len(q.entries)
+	return q.pendingLocked()
+}
+
+// startTimer starts the auto-flush timer if it is enabled and not
+// already running. The timer is not reset by later adds, so the interval is
+// measured from the oldest unflushed entry and every entry is flushed within
+// flushInterval (or maxBufferAge) even under steady traffic below the batch
+// size. With maxBufferAge set, the idle timer is restarted on every add.
+func (q *batchQueue) startTimer() {
+	// No auto-flush once shutdown has started
+	if q.flushFn == nil || q.stopping() {
+		return
+	}
+	if q.maxBufferAge > 0 {
+		q.resetIdleTimer()
+	}
+	if q.timerArmed.Load() {
+		return
+	}
+
+	q.timerMu.Lock()
+	defer q.timerMu.Unlock()
+	if q.flushInterval <= 0 || q.timer != nil {
+		return
+	}
+	q.armTimerLocked()
+}
+
+// armTimerLocked starts a new auto-flush timer for flushDelayLocked.
+// The caller must hold q.timerMu.
+func (q *batchQueue) armTimerLocked() {
+	var timer *time.Timer
+	timer = time.AfterFunc(q.flushDelayLocked(), func() {
+		// Clear the fired timer so the next add starts a new one
+		q.timerMu.Lock()
+		if q.timer == timer {
+			q.timer = nil
+			q.timerArmed.Store(false)
+		}
+		q.timerMu.Unlock()
+
+		q.flushFn()
+	})
+	q.timer = timer
+	q.timerArmed.Store(true)
+}
+
+// resetIdleTimer restarts the timer that flushes after flushInterval
+// without adds.
+func (q *batchQueue) resetIdleTimer() {
+	q.timerMu.Lock()
+	defer q.timerMu.Unlock()
+	if q.flushInterval <= 0 {
+		return
+	}
+	if q.idleTimer == nil {
+		q.idleTimer = time.AfterFunc(q.flushInterval, q.idleFlush)
+		return
+	}
+	q.idleTimer.Reset(q.flushInterval)
+}
+
+// idleFlush is the flush of the idle timer. It is skipped while the queued
+// entries are smaller than minFlushBytes; the oldest-entry timer flushes
+// them by maxBufferAge.
+func (q *batchQueue) idleFlush() {
+	if q.minFlushBytes > 0 && q.queuedBytes.Load() < q.minFlushBytes && q.spilled() == 0 {
+		return
+	}
+	q.flushFn()
+}
+
+// flushDelayLocked returns how long after the oldest entry the timer fires:
+// flushInterval, or maxBufferAge when set, shortened to 90% of maxEntryAge
+// when that is sooner so the send can finish before entries reach the max
+// age.
+// The caller must hold q.timerMu.
+func (q *batchQueue) flushDelayLocked() time.Duration {
+	delay := q.flushInterval
+	if q.maxBufferAge > 0 {
+		delay = q.maxBufferAge
+	}
+	if q.maxEntryAge > 0 {
+		if ageDelay := q.maxEntryAge * 9 / 10; ageDelay < delay {
+			delay = ageDelay
+		}
+	}
+	return delay
+}
+
+// setFlushInterval changes the auto-flush interval. A running timer is
+// restarted with the new interval; a timer that has already fired is left
+// to flush as scheduled.
+func (q *batchQueue) setFlushInterval(d time.Duration) {
+	q.timerMu.Lock()
+	defer q.timerMu.Unlock()
+
+	q.flushInterval = d
+	if q.timer != nil && q.timer.Stop() {
+		q.armTimerLocked()
+	}
+}
+
+// stopFlushTimer stops the auto-flush and idle timers if running.
+func (q *batchQueue) stopFlushTimer() {
+	q.timerMu.Lock()
+	defer q.timerMu.Unlock()
+	if q.idleTimer != nil {
+		q.idleTimer.Stop()
+	}
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+		q.timerArmed.Store(false)
+	}
+}
+
+// stop signals background flushes that shutdown has started.
+func (q *batchQueue) stop() {
+	q.stopOnce.Do(func() { close(q.stopRetries) })
+}
+
+// stopping reports whether stop has been called.
+func (q *batchQueue) stopping() bool {
+	select {
+	case <-q.stopRetries:
+		return true
+	default:
+		return false
+	}
+}
+
+// droppedCount returns the total number of entries dropped due to overflow.
+func (q *batchQueue) droppedCount() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.droppedTotal
 }
 
 // stopTimer stops the auto-flush timer if running.
 // Used during shutdown to prevent timer fires after shutdown starts.
+// Any overflow drops still awaiting a rate-limited report are reported now.
 func (q *batchQueue) stopTimer() {
+	q.stopFlushTimer()
+
 	q.mu.Lock()
-	defer q.mu.Unlock()
-	if q.timer != nil {
-		q.timer.Stop()
-		q.timer = nil
+	pending := q.droppedPending > 0
+	q.mu.Unlock()
+
+	if pending {
+		q.reportOverflow()
 	}
 }
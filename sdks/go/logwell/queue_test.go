@@ -142,8 +142,8 @@ func TestQueue_TimerFlush(t *testing.T) {
     }
 }
 
-// TestQueue_TimerResetOnAdd tests that timer resets on each add.
-func TestQueue_TimerResetOnAdd(t *testing.T) {
+// TestQueue_TimerNotResetOnAdd tests that adds do not postpone a running flush timer.
+func TestQueue_TimerNotResetOnAdd(t *testing.T) {
     var flushed int32
     flushFn := func() {
         atomic.AddInt32(&flushed, 1)
@@ -159,21 +159,38 @@ func TestQueue_TimerResetOnAdd(t *testing.T) {
     time.Sleep(40 * time.Millisecond)
     q.add(LogEntry{Level: LevelInfo, Message: "2"})
 
-    // Wait less than interval then add another
-    time.Sleep(40 * time.Millisecond)
-    q.add(LogEntry{Level: LevelInfo, Message: "3"})
+    // The timer started with the first entry must not have been reset
+    time.Sleep(70 * time.Millisecond)
 
-    // At this point, 80ms have passed but timer should have reset each time
-    // so no flush should have occurred yet
-    if atomic.LoadInt32(&flushed) != 0 {
-        t.Errorf("flushed = %d, want 0 (timer should reset on add)", flushed)
+    if atomic.LoadInt32(&flushed) != 1 {
+        t.Errorf("flushed = %d, want 1 (timer should not reset on add)", flushed)
     }
+}
 
-    // Wait for final timer to fire
-    time.Sleep(100 * time.Millisecond)
+// TestQueue_TimerSteadyLowRateTraffic tests that continuous sub-batch traffic
+// still flushes every interval.
+func TestQueue_TimerSteadyLowRateTraffic(t *testing.T) {
+    var flushes, flushedEntries int32
+    var q *batchQueue
+    q = newBatchQueue(60*time.Millisecond, func() {
+        entries := q.flush()
+        atomic.AddInt32(&flushes, 1)
+        atomic.AddInt32(&flushedEntries, int32(len(entries)))
+    }, 0, nil)
 
-    if atomic.LoadInt32(&flushed) != 1 {
-        t.Errorf("flushed = %d, want 1", flushed)
+    // One entry every 20ms for 300ms, always arriving before the interval ends
+    for i := 0; i < 15; i++ {
+        q.add(LogEntry{Level: LevelInfo, Message: "steady"})
+        time.Sleep(20 * time.Millisecond)
+    }
+
+    if got := atomic.LoadInt32(&flushes); got < 3 {
+        t.Errorf("flushes = %d, want at least 3 during steady traffic", got)
+    }
+
+    time.Sleep(100 * time.Millisecond)
+    if got := atomic.LoadInt32(&flushedEntries); got != 15 {
+        t.Errorf("flushed entries = %d, want 15", got)
     }
 }
 
@@ -272,6 +289,37 @@ func TestQueue_OverflowCallsOnError(t *testing.T) {
     }
 }
 
+// TestQueue_OverflowMessage tests that the overflow error names what the
+// policy dropped.
+func TestQueue_OverflowMessage(t *testing.T) {
+    var messages []string
+    onError := func(err *Error) {
+        messages = append(messages, err.Message)
+    }
+
+    q := newBatchQueue(0, nil, 1, onError)
+    q.add(LogEntry{Level: LevelInfo, Message: "first"})
+    q.add(LogEntry{Level: LevelInfo, Message: "second"})
+
+    lowest := newBatchQueue(0, nil, 1, onError)
+    lowest.dropLowest = true
+    lowest.add(LogEntry{Level: LevelError, Message: "error"})
+    lowest.add(LogEntry{Level: LevelDebug, Message: "debug"}) // rejected itself
+
+    want := []string{
+        "queue overflow: dropping oldest entry",
+        "queue overflow: dropped 1 entry (policy: drop lowest severity)",
+    }
+    if len(messages) != len(want) {
+        t.Fatalf("messages = %q, want %q", messages, want)
+    }
+    for i := range want {
+        if messages[i] != want[i] {
+            t.Errorf("messages[%d] = %q, want %q", i, messages[i], want[i])
+        }
+    }
+}
+
 // TestQueue_OverflowMultiple tests multiple overflows in sequence.
 func TestQueue_OverflowMultiple(t *testing.T) {
     var errorCount int32
@@ -307,6 +355,138 @@ func TestQueue_OverflowMultiple(t *testing.T) {
     }
 }
 
+// TestQueue_AddAll tests adding multiple entries at once with overflow.
+func TestQueue_AddAll(t *testing.T) {
+    var errorCount int32
+    var lastError *Error
+
+    onError := func(err *Error) {
+        atomic.AddInt32(&errorCount, 1)
+        lastError = err
+    }
+
+    q := newBatchQueue(0, nil, 3, onError)
+
+    q.add(LogEntry{Level: LevelInfo, Message: "1"})
+    q.addAll([]LogEntry{
+        {Level: LevelInfo, Message: "2"},
+        {Level: LevelInfo, Message: "3"},
+        {Level: LevelInfo, Message: "4"},
+        {Level: LevelInfo, Message: "5"},
+    })
+
+    // One report for both dropped entries
+    if atomic.LoadInt32(&errorCount) != 1 {
+        t.Errorf("errorCount = %d, want 1", errorCount)
+    }
+    if lastError == nil || lastError.Message != "queue overflow: dropped 2 oldest entries" {
+        t.Errorf("lastError = %v, want 2 oldest entries dropped", lastError)
+    }
+
+    entries := q.flush()
+    if len(entries) != 3 {
+        t.Fatalf("len(entries) = %d, want 3", len(entries))
+    }
+    if entries[0].Message != "3" || entries[2].Message != "5" {
+        t.Errorf("entries = %v, want messages 3..5", entries)
+    }
+}
+
+// TestQueue_OverflowRateLimited tests that overflow reports are batched per interval.
+func TestQueue_OverflowRateLimited(t *testing.T) {
+    var errorCount, dropCalls, dropTotal int32
+
+    q := newBatchQueue(0, nil, 10, func(err *Error) {
+        atomic.AddInt32(&errorCount, 1)
+    })
+    q.onDrop = func(n int) {
+        atomic.AddInt32(&dropCalls, 1)
+        atomic.AddInt32(&dropTotal, int32(n))
+    }
+    q.overflowReportInterval = 50 * time.Millisecond
+
+    // Flood the queue: 10 fit, 2000 are dropped
+    for i := 0; i < 2010; i++ {
+        q.add(LogEntry{Level: LevelInfo, Message: "flood"})
+    }
+    q.addAll(make([]LogEntry, 5))
+
+    time.Sleep(150 * time.Millisecond)
+    q.stopTimer()
+
+    if got := atomic.LoadInt32(&dropTotal); got != 2005 {
+        t.Errorf("total dropped = %d, want 2005", got)
+    }
+    if got := atomic.LoadInt32(&dropCalls); got > 3 {
+        t.Errorf("onDrop called %d times, want at most 3", got)
+    }
+    if got := atomic.LoadInt32(&errorCount); got != atomic.LoadInt32(&dropCalls) {
+        t.Errorf("onError called %d times, want %d", got, atomic.LoadInt32(&dropCalls))
+    }
+}
+
+// TestQueue_OverflowReportedOnStop tests that pending overflow counts are reported on stop.
+func TestQueue_OverflowReportedOnStop(t *testing.T) {
+    var dropTotal int32
+
+    q := newBatchQueue(0, nil, 1, nil)
+    q.onDrop = func(n int) { atomic.AddInt32(&dropTotal, int32(n)) }
+    q.overflowReportInterval = time.Hour
+    q.lastOverflowReport = time.Now()
+
+    q.add(LogEntry{Level: LevelInfo, Message: "1"})
+    q.add(LogEntry{Level: LevelInfo, Message: "2"})
+    q.add(LogEntry{Level: LevelInfo, Message: "3"})
+
+    if got := atomic.LoadInt32(&dropTotal); got != 0 {
+        t.Errorf("total dropped before stop = %d, want 0", got)
+    }
+
+    q.stopTimer()
+
+    if got := atomic.LoadInt32(&dropTotal); got != 2 {
+        t.Errorf("total dropped after stop = %d, want 2", got)
+    }
+}
+
+// TestQueue_ConcurrentOverflowRespectsCapacity tests that concurrent adds
+// at capacity never grow the queue beyond maxQueueSize.
+func TestQueue_ConcurrentOverflowRespectsCapacity(t *testing.T) {
+    const maxSize = 5
+
+    var q *batchQueue
+    var exceeded int32
+
+    // The callback inspects the queue while other goroutines keep adding
+    q = newBatchQueue(0, nil, maxSize, func(err *Error) {
+        if q.size() > maxSize {
+            atomic.StoreInt32(&exceeded, 1)
+        }
+    })
+
+    var wg sync.WaitGroup
+    for i := 0; i < 10; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 200; j++ {
+                q.add(LogEntry{Level: LevelInfo, Message: "overflow"})
+                if q.size() > maxSize {
+                    atomic.StoreInt32(&exceeded, 1)
+                }
+            }
+        }()
+    }
+    wg.Wait()
+
+    if atomic.LoadInt32(&exceeded) != 0 {
+        t.Errorf("queue size exceeded maxQueueSize %d", maxSize)
+    }
+    if q.size() != maxSize {
+        t.Errorf("size() = %d, want %d", q.size(), maxSize)
+    }
+}
+
 // TestQueue_NoTimerWhenNotConfigured tests that no timer fires when interval is 0.
 func TestQueue_NoTimerWhenNotConfigured(t *testing.T) {
     var flushed int32
@@ -556,3 +736,267 @@ func TestQueue_TimerAfterFlush(t *testing.T) {
         t.Errorf("flushed = %d, want 1", flushed)
     }
 }
+
+// TestQueue_RingWrapAround tests FIFO order when overflow has wrapped the
+// ring and that the storage is reused across flushes.
+func TestQueue_RingWrapAround(t *testing.T) {
+    q := newBatchQueue(0, nil, 3, nil)
+
+    for round := 0; round < 2; round++ {
+        for i := 0; i < 5; i++ {
+            q.add(LogEntry{Level: LevelInfo, Message: string(rune('A' + i))})
+        }
+
+        entries := q.flush()
+        if len(entries) != 3 {
+            t.Fatalf("round %d: len(entries) = %d, want 3", round, len(entries))
+        }
+        for i, want := range []string{"C", "D", "E"} {
+            if entries[i].Message != want {
+                t.Errorf("round %d: entries[%d].Message = %q, want %q", round, i, entries[i].Message, want)
+            }
+        }
+        if len(q.buf) != 3 {
+            t.Errorf("round %d: ring capacity = %d, want 3", round, len(q.buf))
+        }
+    }
+}
+
+// TestQueue_PriorityLane tests that priority entries flush first, keep their
+// order, and are evicted only after every other entry.
+func TestQueue_PriorityLane(t *testing.T) {
+    q := newBatchQueue(0, nil, 4, nil)
+    q.setPriorityLevels([]LogLevel{LevelError, LevelFatal})
+
+    q.add(LogEntry{Level: LevelDebug, Message: "d1"})
+    q.add(LogEntry{Level: LevelError, Message: "e1"})
+    q.add(LogEntry{Level: LevelDebug, Message: "d2"})
+    q.add(LogEntry{Level: LevelFatal, Message: "f1"})
+    q.add(LogEntry{Level: LevelDebug, Message: "d3"}) // evicts d1
+    q.add(LogEntry{Level: LevelError, Message: "e2"}) // evicts d2
+
+    assertMessages := func(entries []LogEntry, want ...string) {
+        t.Helper()
+        if len(entries) != len(want) {
+            t.Fatalf("len(entries) = %d, want %d", len(entries), len(want))
+        }
+        for i, w := range want {
+            if entries[i].Message != w {
+                t.Errorf("entries[%d].Message = %q, want %q", i, entries[i].Message, w)
+            }
+        }
+    }
+
+    assertMessages(q.flush(), "e1", "f1", "e2", "d3")
+    if q.droppedCount() != 2 {
+        t.Errorf("droppedCount() = %d, want 2", q.droppedCount())
+    }
+
+    // With only priority entries queued, the oldest of them is evicted
+    for _, msg := range []string{"e1", "e2", "e3", "e4", "e5"} {
+        q.add(LogEntry{Level: LevelError, Message: msg})
+    }
+    assertMessages(q.flush(), "e2", "e3", "e4", "e5")
+    if q.size() != 0 {
+        t.Errorf("size() = %d after flush, want 0", q.size())
+    }
+}
+
+// TestQueue_DropLowestSeverity tests that overflow evicts the least severe
+// entry, the oldest among equals, and drops an incoming entry less severe
+// than everything queued.
+func TestQueue_DropLowestSeverity(t *testing.T) {
+    q := newBatchQueue(0, nil, 4, nil)
+    q.dropLowest = true
+
+    q.add(LogEntry{Level: LevelDebug, Message: "d1"})
+    q.add(LogEntry{Level: LevelError, Message: "e1"})
+    q.add(LogEntry{Level: LevelDebug, Message: "d2"})
+    q.add(LogEntry{Level: LevelDebug, Message: "d3"})
+    q.add(LogEntry{Level: LevelDebug, Message: "d4"}) // evicts d1
+    q.add(LogEntry{Level: LevelWarn, Message: "w1"})  // evicts d2
+    q.add(LogEntry{Level: LevelWarn, Message: "w2"})  // evicts d3
+    q.add(LogEntry{Level: LevelDebug, Message: "d5"}) // evicts d4
+    q.add(LogEntry{Level: LevelInfo, Message: "i1"})  // evicts d5
+    q.add(LogEntry{Level: LevelDebug, Message: "d6"}) // dropped itself
+
+    entries := q.flush()
+    want := []string{"e1", "w1", "w2", "i1"}
+    if len(entries) != len(want) {
+        t.Fatalf("len(entries) = %d, want %d", len(entries), len(want))
+    }
+    for i, w := range want {
+        if entries[i].Message != w {
+            t.Errorf("entries[%d].Message = %q, want %q", i, entries[i].Message, w)
+        }
+    }
+    if q.droppedCount() != 6 {
+        t.Errorf("droppedCount() = %d, want 6", q.droppedCount())
+    }
+}
+
+// TestQueue_UnboundedGrowth tests that a queue without maxQueueSize grows
+// past its initial capacity without dropping entries.
+func TestQueue_UnboundedGrowth(t *testing.T) {
+    q := newBatchQueue(0, nil, 0, nil)
+
+    const n = minRingCapacity*2 + 5
+    for i := 0; i < n; i++ {
+        q.add(LogEntry{Level: LevelInfo, LineNumber: i})
+    }
+
+    entries := q.flush()
+    if len(entries) != n {
+        t.Fatalf("len(entries) = %d, want %d", len(entries), n)
+    }
+    for i, entry := range entries {
+        if entry.LineNumber != i {
+            t.Fatalf("entries[%d].LineNumber = %d, want %d", i, entry.LineNumber, i)
+        }
+    }
+    if q.droppedCount() != 0 {
+        t.Errorf("droppedCount() = %d, want 0", q.droppedCount())
+    }
+}
+
+// TestQueue_AdaptBatchSize tests how adaptive batching reacts to results.
+func TestQueue_AdaptBatchSize(t *testing.T) {
+    q := newBatchQueue(0, nil, 0, nil)
+    q.batchSize.Store(100)
+    q.setAdaptiveBatching(10, 40)
+
+    if got := q.batchSize.Load(); got != 40 {
+        t.Fatalf("batchSize after enabling = %d, want 40 (clamped)", got)
+    }
+
+    q.adaptBatchSize(nil, adaptiveSlowFlush)
+    if got := q.batchSize.Load(); got != 20 {
+        t.Errorf("batchSize after slow flush = %d, want 20", got)
+    }
+
+    q.adaptBatchSize(NewErrorWithStatus(ErrServerError, "server error", 500), time.Millisecond)
+    if got := q.batchSize.Load(); got != 20 {
+        t.Errorf("batchSize after 500 = %d, want 20 (unchanged)", got)
+    }
+
+    q.adaptBatchSize(NewErrorWithStatus(ErrServerError, "too large", 413), time.Millisecond)
+    q.adaptBatchSize(NewErrorWithStatus(ErrServerError, "too large", 413), time.Millisecond)
+    if got := q.batchSize.Load(); got != 10 {
+        t.Errorf("batchSize after 413s = %d, want 10 (min)", got)
+    }
+
+    q.adaptBatchSize(nil, time.Millisecond)
+    if got := q.batchSize.Load(); got != 12 {
+        t.Errorf("batchSize after fast success = %d, want 12", got)
+    }
+}
+
+// TestQueue_BatchBytes tests that the estimated queued size follows adds,
+// evictions and flushes.
+func TestQueue_BatchBytes(t *testing.T) {
+    q := newBatchQueue(0, nil, 2, nil)
+    q.batchBytes = 1000
+
+    small := LogEntry{Level: LevelInfo, Message: "small"}
+    large := LogEntry{Level: LevelInfo, Message: "large", Metadata: M{"payload": make([]byte, 900)}}
+    if estimateEntrySize(large) < 1000 {
+        t.Fatalf("estimateEntrySize(large) = %d, want at least 1000", estimateEntrySize(large))
+    }
+
+    // A cycle is counted down to a capped depth instead of recursing forever
+    cyclic := map[string]any{"name": "root"}
+    cyclic["self"] = cyclic
+    if got := estimateEntrySize(LogEntry{Level: LevelInfo, Message: "cyclic", Metadata: cyclic}); got <= 0 {
+        t.Errorf("estimateEntrySize(cyclic) = %d, want a positive estimate", got)
+    }
+
+    q.add(small)
+    if q.bytesReached() {
+        t.Errorf("bytesReached() after small entry = true, want false")
+    }
+    q.add(large)
+    if !q.bytesReached() {
+        t.Errorf("bytesReached() after large entry = false, want true")
+    }
+
+    // Overflow evicts small, then large
+    q.add(small)
+    q.add(small)
+    if q.bytesReached() {
+        t.Errorf("bytesReached() after evicting large entry = true, want false")
+    }
+    if got, want := q.queuedBytes.Load(), int64(2*estimateEntrySize(small)); got != want {
+        t.Errorf("queuedBytes = %d, want %d", got, want)
+    }
+
+    q.flush()
+    if got := q.queuedBytes.Load(); got != 0 {
+        t.Errorf("queuedBytes after flush = %d, want 0", got)
+    }
+}
+
+// TestQueue_MaxBytes tests that the byte cap makes the queue overflow
+// before its count cap, evicting by the overflow policy.
+func TestQueue_MaxBytes(t *testing.T) {
+    large := func(level LogLevel, msg string) LogEntry {
+        return LogEntry{Level: level, Message: msg, Metadata: M{"payload": make([]byte, 700)}}
+    }
+    // Caps get slack for the differences in level and message length
+    size := int64(estimateEntrySize(large(LevelInfo, "0")))
+    const slack = 50
+
+    t.Run("drops oldest", func(t *testing.T) {
+        q := newBatchQueue(0, nil, 100, nil)
+        q.maxBytes = 3 * size
+
+        dropped := 0
+        for i := 0; i < 5; i++ {
+            dropped += q.pushLocked(large(LevelInfo, string(rune('a'+i))), nil)
+        }
+        if dropped != 2 || q.size() != 3 {
+            t.Fatalf("dropped %d with %d left, want 2 dropped and 3 left", dropped, q.size())
+        }
+        if got := q.queuedBytes.Load(); got != 3*size {
+            t.Errorf("queuedBytes = %d, want %d", got, 3*size)
+        }
+        if entries := q.flush(); entries[0].Message != "c" {
+            t.Errorf("oldest kept = %q, want %q", entries[0].Message, "c")
+        }
+    })
+
+    t.Run("evicts as many as needed", func(t *testing.T) {
+        q := newBatchQueue(0, nil, 100, nil)
+        q.maxBytes = 3*size + slack
+        for i := 0; i < 3; i++ {
+            q.add(LogEntry{Level: LevelInfo, Message: "small"})
+        }
+        for i := 0; i < 3; i++ {
+            q.add(large(LevelInfo, "0"))
+        }
+        if got := q.size(); got != 3 {
+            t.Errorf("size() = %d, want 3 large entries", got)
+        }
+    })
+
+    t.Run("drops lowest severity", func(t *testing.T) {
+        q := newBatchQueue(0, nil, 100, nil)
+        q.maxBytes = 2*size + slack
+        q.dropLowest = true
+        q.add(large(LevelError, "error"))
+        q.add(large(LevelDebug, "debug"))
+        q.add(large(LevelWarn, "warn"))
+        entries := q.flush()
+        if len(entries) != 2 || entries[0].Message != "error" || entries[1].Message != "warn" {
+            t.Errorf("kept %d entries, want the error and warn entries", len(entries))
+        }
+    })
+
+    t.Run("accepts one entry over the cap", func(t *testing.T) {
+        q := newBatchQueue(0, nil, 100, nil)
+        q.maxBytes = size / 2
+        q.add(large(LevelInfo, "huge"))
+        if got := q.size(); got != 1 {
+            t.Errorf("size() = %d, want 1", got)
+        }
+    })
+}
@@ -0,0 +1,151 @@
+package logwell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Metadata keys of the panic logs built by RecoveryMiddleware, next to the
+// AccessLogMethod, AccessLogRoute and AccessLogRequestID keys.
+const (
+	RecoveryPanic = "panic"
+	RecoveryStack = "stack"
+)
+
+// maxRecoveryFrames caps the stack frames logged for a panic.
+const maxRecoveryFrames = 32
+
+// recoveryFlushTimeout bounds how long RecoveryMiddleware waits for the
+// panic log to be sent, including a flush already in progress.
+const recoveryFlushTimeout = 2 * time.Second
+
+// RecoveryOption configures RecoveryMiddleware.
+type RecoveryOption func(*recoveryConfig)
+
+type recoveryConfig struct {
+	respond func(w http.ResponseWriter, r *http.Request, recovered any)
+}
+
+// RecoveryWithResponse sets how the response to a request whose handler
+// panicked is written, replacing the default 500 Internal Server Error.
+// It is only called when the handler had not started the response.
+func RecoveryWithResponse(fn func(w http.ResponseWriter, r *http.Request, recovered any)) RecoveryOption {
+	return func(c *recoveryConfig) {
+		c.respond = fn
+	}
+}
+
+// RecoveryMiddleware returns net/http middleware that recovers panics of
+// the handler. A panic is logged at FATAL through client with the panic
+// value, the stack trimmed of runtime frames, and the request's method,
+// path and X-Request-ID header, and is flushed before the middleware
+// returns, waiting up to two seconds for it and any flush in progress to
+// be sent. Its source location, when captured, is the frame that
+// panicked. The client then answers with 500 Internal Server Error unless
+// the response had started or RecoveryWithResponse is set.
+// http.ErrAbortHandler is not recovered.
+//
+// Wrapped by AccessLogMiddleware, the request is not logged a second
+// time; wrapping AccessLogMiddleware, the panic skips the access log.
+//
+// Example:
+//
+//	handler := logwell.AccessLogMiddleware(client)(logwell.RecoveryMiddleware(client)(mux))
+func RecoveryMiddleware(client *Client, opts ...RecoveryOption) func(http.Handler) http.Handler {
+	cfg := recoveryConfig{respond: func(w http.ResponseWriter, r *http.Request, recovered any) {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(recovered)
+				}
+
+				stack, origin := panicStack()
+				entry, ok := client.newEntry(r.Context(), LevelFatal, fmt.Sprintf("panic: %v", recovered), M{
+					RecoveryPanic:      fmt.Sprint(recovered),
+					RecoveryStack:      stack,
+					AccessLogMethod:    r.Method,
+					AccessLogRoute:     r.URL.Path,
+					AccessLogRequestID: r.Header.Get("X-Request-ID"),
+				})
+				if ok {
+					// Called from runtime.gopanic, so the source is taken
+					// from the frame that panicked
+					if client.config.CaptureSourceLocation {
+						entry.SourceFile, entry.LineNumber, entry.FunctionName = frameSource(origin)
+					}
+					client.enqueue(r.Context(), entry)
+				}
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), recoveryFlushTimeout)
+				_ = client.Flush(ctx) // Failures reach OnError
+				cancel()
+
+				markRecovered(w)
+				if !rw.wroteHeader {
+					cfg.respond(w, r, recovered)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// panicStack formats the stack of the panicking goroutine, called from a
+// deferred recover, without the runtime and middleware frames. It also
+// returns the first frame kept, where the panic was raised.
+func panicStack() (string, runtime.Frame) {
+	pcs := make([]uintptr, maxRecoveryFrames+8)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var (
+		b      strings.Builder
+		origin runtime.Frame
+	)
+	logged := 0
+	for logged < maxRecoveryFrames {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			if logged == 0 {
+				origin = frame
+			}
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+			logged++
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String(), origin
+}
+
+// markRecovered flags the statusWriter of an enclosing AccessLogMiddleware,
+// if any, so that it does not log the request again.
+func markRecovered(w http.ResponseWriter) {
+	for {
+		switch rw := w.(type) {
+		case *statusWriter:
+			rw.recovered = true
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package logwell
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRecoveryMiddleware tests that a panic is answered with 500 and
+// delivered as one FATAL log, in either order with the access log, with
+// the source location of the panic.
+func TestRecoveryMiddleware(t *testing.T) {
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		name  string
+		chain func(client *Client) http.Handler
+	}{
+		{"alone", func(client *Client) http.Handler {
+			return RecoveryMiddleware(client)(panicking)
+		}},
+		{"inside access log", func(client *Client) http.Handler {
+			return AccessLogMiddleware(client)(RecoveryMiddleware(client)(panicking))
+		}},
+		{"outside access log", func(client *Client) http.Handler {
+			return RecoveryMiddleware(client)(AccessLogMiddleware(client)(panicking))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer()
+			defer ts.Close()
+
+			client := createTestClient(t, ts, WithBatchSize(100), WithCaptureSourceLocation(true))
+			defer client.Shutdown(context.Background())
+
+			req := httptest.NewRequest(http.MethodGet, "/checkout", nil)
+			req.Header.Set("X-Request-ID", "req-9")
+			rec := httptest.NewRecorder()
+			tt.chain(client).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want 500", rec.Code)
+			}
+			// Delivered by the middleware itself, without a Flush
+			logs := ts.getLogs()
+			assertLogCount(t, logs, 1)
+
+			log := logs[0]
+			if log.Level != LevelFatal || log.Message != "panic: boom" {
+				t.Errorf("log = %s %q, want FATAL %q", log.Level, log.Message, "panic: boom")
+			}
+			want := map[string]any{"panic": "boom", "method": "GET", "route": "/checkout", "request_id": "req-9"}
+			for k, v := range want {
+				if log.Metadata[k] != v {
+					t.Errorf("Metadata[%q] = %v, want %v", k, log.Metadata[k], v)
+				}
+			}
+			stack, _ := log.Metadata["stack"].(string)
+			if !strings.Contains(stack, "TestRecoveryMiddleware") || strings.Contains(stack, "runtime.gopanic") {
+				t.Errorf("stack = %q, want the handler frames without runtime frames", stack)
+			}
+			if log.SourceFile != "recovery_test.go" || !strings.HasPrefix(log.FunctionName, "logwell.TestRecoveryMiddleware.") {
+				t.Errorf("source = %s %s, want the panicking handler", log.SourceFile, log.FunctionName)
+			}
+		})
+	}
+}
+
+// TestRecoveryMiddlewareResponse tests the custom response and that a
+// started response is left alone.
+func TestRecoveryMiddlewareResponse(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts, WithBatchSize(100))
+	defer client.Shutdown(context.Background())
+
+	var got any
+	custom := RecoveryMiddleware(client, RecoveryWithResponse(func(w http.ResponseWriter, r *http.Request, recovered any) {
+		got = recovered
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	rec := httptest.NewRecorder()
+	custom(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("custom")
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || got != "custom" {
+		t.Errorf("status = %d, recovered = %v, want 503 and %q", rec.Code, got, "custom")
+	}
+
+	rec = httptest.NewRecorder()
+	custom(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusAccepted || got != "custom" {
+		t.Errorf("status = %d, want the started 202 kept", rec.Code)
+	}
+	assertLogCount(t, ts.getLogs(), 2)
+}
+
+// TestRecoveryMiddlewareConcurrentFlush tests that the panic log is
+// delivered before the middleware returns while another flush is in
+// flight.
+func TestRecoveryMiddlewareConcurrentFlush(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		var req ingestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		once.Do(func() {
+			close(started)
+			<-release
+		})
+		ts.mu.Lock()
+		ts.logs = append(ts.logs, req.Logs...)
+		ts.mu.Unlock()
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	})
+
+	client := createTestClient(t, ts, WithBatchSize(100))
+	defer client.Shutdown(context.Background())
+
+	client.Info("before panic")
+	flushed := make(chan error, 1)
+	go func() { flushed <- client.Flush(context.Background()) }()
+	<-started
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+
+	RecoveryMiddleware(client)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 2)
+	if logs[1].Level != LevelFatal || logs[1].Message != "panic: boom" {
+		t.Errorf("second log = %s %q, want FATAL %q", logs[1].Level, logs[1].Message, "panic: boom")
+	}
+	if err := <-flushed; err != nil {
+		t.Errorf("Flush() error = %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package logwell
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestClientReplay tests that valid NDJSON entries are delivered with
+// defaults applied, and that malformed lines are skipped and reported.
+func TestClientReplay(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var (
+		mu   sync.Mutex
+		errs []*Error
+	)
+	client := createTestClient(t, ts,
+		WithBatchSize(2),
+		WithService("replayer"),
+		WithMetadata(M{"backfill": true}),
+		WithOnError(func(err *Error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}),
+	)
+	defer client.Shutdown(context.Background())
+
+	input := strings.Join([]string{
+		`{"level":"info","message":"first","timestamp":"2024-01-01T00:00:00.000Z"}`,
+		`not json`,
+		``,
+		`{"level":"error","message":"second","service":"billing","metadata":{"order":7}}`,
+		`{"level":"loud","message":"bad level"}`,
+		`{"level":"warn","message":""}`,
+		`{"level":"warn","message":"third"}`,
+	}, "\n")
+
+	n, err := client.Replay(context.Background(), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Replay() = %d, want 3", n)
+	}
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 3)
+	if logs[0].Message != "first" || logs[0].Timestamp != "2024-01-01T00:00:00.000Z" || logs[0].Service != "replayer" {
+		t.Errorf("first log = %+v, want its timestamp kept and the default service", logs[0])
+	}
+	if logs[1].Service != "billing" || logs[1].Metadata["order"] != float64(7) || logs[1].Metadata["backfill"] != true {
+		t.Errorf("second log = %+v, want its service and metadata merged with the defaults", logs[1])
+	}
+	if logs[2].Message != "third" || logs[2].Timestamp == "" {
+		t.Errorf("third log = %+v, want a generated timestamp", logs[2])
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 3 {
+		t.Fatalf("OnError called %d times, want 3", len(errs))
+	}
+	for _, err := range errs {
+		if err.Code != ErrValidationError {
+			t.Errorf("OnError code = %s, want %s", err.Code, ErrValidationError)
+		}
+	}
+	if !strings.Contains(errs[0].Error(), "line 2") {
+		t.Errorf("first error = %v, want it to name line 2", errs[0])
+	}
+}
+
+// TestClientReplayCanceled tests that Replay stops when ctx is done.
+func TestClientReplayCanceled(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts)
+	defer client.Shutdown(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n, err := client.Replay(ctx, strings.NewReader(`{"level":"info","message":"never"}`))
+	if n != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("Replay() = %d, %v, want 0 and a cancellation error", n, err)
+	}
+}
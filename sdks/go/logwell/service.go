@@ -0,0 +1,82 @@
+package logwell
+
+import (
+	"os"
+	"path"
+	"runtime/debug"
+	"strings"
+)
+
+// ServiceAutodetectedKey is the metadata key set to true on logs whose
+// service name was derived from the executable because none was configured.
+const ServiceAutodetectedKey = "serviceAutodetected"
+
+// detectService derives a default service name for the running program.
+// It is a variable so tests can replace it.
+var detectService = func() string {
+	var info *debug.BuildInfo
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info = bi
+	}
+	return serviceFromExecutable(os.Args[0], info)
+}
+
+// serviceFromExecutable returns the name of the executable at arg0 without
+// a ".exe" suffix. Binaries built into a temporary directory by go run
+// have meaningless names such as "main", so the last element of the main
+// module path is returned for them instead, as it is when arg0 has no
+// usable name. Test binaries built by go test are not services, so ""
+// is returned for them, as it is when no name is found.
+func serviceFromExecutable(arg0 string, info *debug.BuildInfo) string {
+	elems := strings.FieldsFunc(arg0, func(r rune) bool { return r == '/' || r == '\\' })
+	name := ""
+	if len(elems) > 0 {
+		name = elems[len(elems)-1]
+		if strings.HasSuffix(strings.ToLower(name), ".exe") {
+			name = name[:len(name)-len(".exe")]
+		}
+		if strings.HasSuffix(name, ".test") {
+			return ""
+		}
+	}
+	for _, elem := range elems {
+		if strings.HasPrefix(elem, "go-build") {
+			name = ""
+			break
+		}
+	}
+	if name != "" && name != "." {
+		return name
+	}
+	if info == nil {
+		return ""
+	}
+	return moduleName(info.Main.Path)
+}
+
+// moduleName returns the last element of a module path, skipping a major
+// version suffix such as "/v2".
+func moduleName(modulePath string) string {
+	if modulePath == "" || modulePath == "command-line-arguments" {
+		return ""
+	}
+	name := path.Base(modulePath)
+	if dir := path.Dir(modulePath); dir != "." && isMajorVersion(name) {
+		name = path.Base(dir)
+	}
+	return name
+}
+
+// isMajorVersion reports whether elem is a module major version suffix
+// such as "v2".
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' {
+		return false
+	}
+	for _, r := range elem[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
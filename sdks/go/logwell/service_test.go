@@ -0,0 +1,96 @@
+package logwell
+
+import (
+	"context"
+	"runtime/debug"
+	"testing"
+)
+
+// TestServiceFromExecutable tests the service names derived from
+// executable paths, including the temporary binaries of go run that fall
+// back to the module name and go test binaries that get none.
+func TestServiceFromExecutable(t *testing.T) {
+	module := &debug.BuildInfo{Main: debug.Module{Path: "github.com/acme/billing"}}
+
+	tests := []struct {
+		name string
+		arg0 string
+		info *debug.BuildInfo
+		want string
+	}{
+		{"binary", "/usr/local/bin/billing-api", module, "billing-api"},
+		{"relative", "./worker", module, "worker"},
+		{"windows", `C:\services\billing-api.exe`, module, "billing-api"},
+		{"uppercase exe", "BILLING.EXE", nil, "BILLING"},
+		{"go run", "/tmp/go-build1234567890/b001/exe/main", module, "billing"},
+		{"go test", "/tmp/go-build987/b123/billing.test", module, ""},
+		{"go test on windows", `C:\Temp\go-build42\b001\billing.test.exe`, module, ""},
+		{"go run on windows", `C:\Users\dev\AppData\Local\Temp\go-build42\b001\exe\main.exe`, module, "billing"},
+		{"major version module", "/tmp/go-build1/b001/exe/main", &debug.BuildInfo{Main: debug.Module{Path: "github.com/acme/billing/v2"}}, "billing"},
+		{"go run of files", "/tmp/go-build1/b001/exe/main", &debug.BuildInfo{Main: debug.Module{Path: "command-line-arguments"}}, ""},
+		{"no build info", "/tmp/go-build1/b001/exe/main", nil, ""},
+		{"empty", "", module, "billing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serviceFromExecutable(tt.arg0, tt.info); got != tt.want {
+				t.Errorf("serviceFromExecutable(%q) = %q, want %q", tt.arg0, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClientServiceAutodetect tests that logs of a client without a
+// service name get the detected one and the autodetected flag, unless
+// their service is set another way or autodetection is disabled.
+func TestClientServiceAutodetect(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	const want = "billing"
+	defer func(detect func() string) { detectService = detect }(detectService)
+	detectService = func() string { return want }
+
+	client := createTestClient(t, ts, WithMetadata(M{"env": "test"}))
+	named := client.Child(ChildWithService("cron"))
+	client.Info("detected")
+	client.Info("detected with metadata", M{"task": "sync"})
+	client.Log(LogEntry{Level: LevelInfo, Message: "entry service", Service: "payments"})
+	client.Log(LogEntry{Level: LevelInfo, Message: "entry service with metadata", Service: "payments", Metadata: M{"task": "sync"}})
+	named.Info("named")
+
+	disabled := createTestClient(t, ts, WithDisableServiceAutodetect())
+	disabled.Info("disabled")
+
+	client.Shutdown(context.Background())
+	disabled.Shutdown(context.Background())
+
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 6)
+	byMessage := make(map[string]LogEntry)
+	for _, log := range logs {
+		byMessage[log.Message] = log
+	}
+
+	for _, message := range []string{"detected", "detected with metadata"} {
+		log := byMessage[message]
+		if log.Service != want {
+			t.Errorf("%s: Service = %q, want %q", message, log.Service, want)
+		}
+		if log.Metadata[ServiceAutodetectedKey] != true || log.Metadata["env"] != "test" {
+			t.Errorf("%s: Metadata = %v, want env and %s", message, log.Metadata, ServiceAutodetectedKey)
+		}
+	}
+	if log := byMessage["named"]; log.Service != "cron" || log.Metadata[ServiceAutodetectedKey] != nil {
+		t.Errorf("named child log = %+v, want service cron without %s", log, ServiceAutodetectedKey)
+	}
+	for _, message := range []string{"entry service", "entry service with metadata"} {
+		if log := byMessage[message]; log.Service != "payments" || log.Metadata[ServiceAutodetectedKey] != nil {
+			t.Errorf("%s: log = %+v, want service payments without %s", message, log, ServiceAutodetectedKey)
+		}
+	}
+	if log := byMessage["disabled"]; log.Service != "" || log.Metadata != nil {
+		t.Errorf("disabled log = %+v, want no service or metadata", log)
+	}
+}
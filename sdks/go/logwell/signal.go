@@ -0,0 +1,81 @@
+package logwell
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FlushOnSignal installs a signal handler that shuts down client when one of
+// signals is received, waiting at most timeout for queued logs to flush.
+// After shutdown the signal is re-raised with the default handler restored,
+// so the process still terminates with the expected status.
+// If no signals are given, SIGINT and SIGTERM are used.
+// The returned stop function deregisters the handler and is safe to call
+// more than once.
+//
+// Example:
+//
+//	stop := logwell.FlushOnSignal(client, 5*time.Second)
+//	defer stop()
+func FlushOnSignal(client *Client, timeout time.Duration, signals ...os.Signal) (stop func()) {
+	return FlushOnSignalFunc(client, timeout, reraiseSignal, signals...)
+}
+
+// FlushOnSignalFunc is like FlushOnSignal but calls after with the received
+// signal once shutdown completes, instead of re-raising it.
+func FlushOnSignalFunc(client *Client, timeout time.Duration, after func(os.Signal), signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigChan, signals...)
+
+	go func() {
+		select {
+		case sig := <-sigChan:
+			signal.Stop(sigChan)
+			handleSignal(client, timeout, sig, after)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sigChan)
+			close(done)
+		})
+	}
+}
+
+// handleSignal shuts down client within timeout and then calls after.
+func handleSignal(client *Client, timeout time.Duration, sig os.Signal, after func(os.Signal)) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_ = client.Shutdown(ctx)
+
+	if after != nil {
+		after(sig)
+	}
+}
+
+// reraiseSignal restores the default handler for sig and sends it to the
+// current process. Exits with status 1 if the signal cannot be delivered.
+func reraiseSignal(sig os.Signal) {
+	signal.Reset(sig)
+
+	process, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = process.Signal(sig)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
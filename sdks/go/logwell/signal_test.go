@@ -0,0 +1,62 @@
+package logwell
+
+import (
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleSignal(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts, WithBatchSize(100))
+	client.Info("pending 1")
+	client.Info("pending 2")
+
+	var received os.Signal
+	handleSignal(client, time.Second, syscall.SIGTERM, func(sig os.Signal) {
+		received = sig
+		// Logs must already be flushed when the callback runs
+		assertLogCount(t, ts.getLogs(), 2)
+	})
+
+	if received != syscall.SIGTERM {
+		t.Errorf("callback signal = %v, want %v", received, syscall.SIGTERM)
+	}
+
+	client.Info("after shutdown")
+	if client.queue.size() != 0 {
+		t.Error("client accepted logs after signal shutdown")
+	}
+}
+
+func TestHandleSignalTimeout(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	client := createTestClient(t, ts, WithBatchSize(100))
+	client.Info("never delivered")
+
+	called := false
+	start := time.Now()
+	handleSignal(client, 100*time.Millisecond, os.Interrupt, func(os.Signal) { called = true })
+
+	if !called {
+		t.Error("callback was not called after shutdown timed out")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("handleSignal took %v, want bounded by timeout", elapsed)
+	}
+}
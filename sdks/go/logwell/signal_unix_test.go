@@ -0,0 +1,64 @@
+//go:build unix
+
+package logwell
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFlushOnSignalStop(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts)
+	defer client.Shutdown(context.Background())
+
+	called := make(chan os.Signal, 1)
+	stop := FlushOnSignalFunc(client, time.Second, func(sig os.Signal) { called <- sig }, syscall.SIGUSR1)
+
+	// Stop is idempotent and deregisters the handler
+	stop()
+	stop()
+
+	select {
+	case sig := <-called:
+		t.Errorf("callback called with %v after stop", sig)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	client.Info("still running")
+	if client.queue.size() != 1 {
+		t.Error("client was shut down without a signal")
+	}
+}
+
+func TestFlushOnSignalDelivery(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts, WithBatchSize(100))
+	client.Info("pending")
+
+	called := make(chan os.Signal, 1)
+	stop := FlushOnSignalFunc(client, time.Second, func(sig os.Signal) { called <- sig }, syscall.SIGUSR1)
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("Kill() error = %v", err)
+	}
+
+	select {
+	case sig := <-called:
+		if sig != syscall.SIGUSR1 {
+			t.Errorf("callback signal = %v, want %v", sig, syscall.SIGUSR1)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback was not called after signal")
+	}
+
+	assertLogCount(t, ts.getLogs(), 1)
+}
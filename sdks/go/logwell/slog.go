@@ -0,0 +1,166 @@
+package logwell
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler is a slog.Handler that sends records to a Client, so code
+// using log/slog can log to Logwell. Attributes become metadata, with groups
+// as nested maps.
+//
+// Example:
+//
+//	logger := slog.New(logwell.NewSlogHandler(client))
+//	logger.Info("user signed in", "userId", 42)
+type SlogHandler struct {
+	client   *Client
+	mapLevel func(slog.Level) LogLevel
+	attrs    map[string]any
+	groups   []string
+}
+
+// SlogOption configures a SlogHandler created by NewSlogHandler.
+type SlogOption func(*SlogHandler)
+
+// SlogWithLevelMapper sets how slog levels map to Logwell levels,
+// replacing DefaultSlogLevel.
+func SlogWithLevelMapper(fn func(slog.Level) LogLevel) SlogOption {
+	return func(h *SlogHandler) {
+		if fn != nil {
+			h.mapLevel = fn
+		}
+	}
+}
+
+// SlogWithLevel sends every record at level, regardless of its slog level.
+func SlogWithLevel(level LogLevel) SlogOption {
+	return SlogWithLevelMapper(func(slog.Level) LogLevel { return level })
+}
+
+// NewSlogHandler returns a slog.Handler that logs through client.
+// Levels are mapped with DefaultSlogLevel unless an option overrides it.
+func NewSlogHandler(client *Client, opts ...SlogOption) *SlogHandler {
+	h := &SlogHandler{client: client, mapLevel: DefaultSlogLevel}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// DefaultSlogLevel is the default slog level mapping: below slog.LevelInfo
+// is debug, below slog.LevelWarn is info, below slog.LevelError is warn, and
+// slog.LevelError and above is error. Nothing maps to fatal by default.
+func DefaultSlogLevel(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarn
+	default:
+		return LevelError
+	}
+}
+
+// Enabled reports whether the client's min level lets a record at level through.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return levelEnabled(h.mapLevel(level), h.client.Level())
+}
+
+// Handle sends r to the client, using ctx as the *Context methods do.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	metadata := copyMetadata(h.attrs)
+	if r.NumAttrs() > 0 {
+		if metadata == nil {
+			metadata = make(map[string]any, r.NumAttrs())
+		}
+		target := groupMap(metadata, h.groups)
+		r.Attrs(func(a slog.Attr) bool {
+			addAttr(target, a)
+			return true
+		})
+	}
+
+	entry := LogEntry{
+		Level:    h.mapLevel(r.Level),
+		Message:  r.Message,
+		Metadata: metadata,
+	}
+	if !r.Time.IsZero() {
+		entry.Timestamp = r.Time.UTC().Format(timestampLayout)
+	}
+	h.client.LogContext(ctx, entry)
+	return nil
+}
+
+// WithAttrs returns a handler that adds attrs to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	clone.attrs = copyMetadata(h.attrs)
+	if clone.attrs == nil {
+		clone.attrs = make(map[string]any, len(attrs))
+	}
+	target := groupMap(clone.attrs, h.groups)
+	for _, a := range attrs {
+		addAttr(target, a)
+	}
+	return &clone
+}
+
+// WithGroup returns a handler that nests later attributes under name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &clone
+}
+
+// groupMap returns the map nested under groups in m, creating it as needed.
+func groupMap(m map[string]any, groups []string) map[string]any {
+	for _, g := range groups {
+		next, ok := m[g].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			m[g] = next
+		}
+		m = next
+	}
+	return m
+}
+
+// addAttr stores a in m, resolving LogValuers and nesting groups.
+// Errors are stored as their message, which JSON would otherwise encode as
+// an empty object. Empty attributes are skipped, as slog handlers should.
+func addAttr(m map[string]any, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		v := a.Value.Any()
+		if err, ok := v.(error); ok && a.Value.Kind() == slog.KindAny {
+			v = err.Error()
+		}
+		m[a.Key] = v
+		return
+	}
+
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+	target := m
+	if a.Key != "" {
+		target = groupMap(m, []string{a.Key})
+	}
+	for _, ga := range attrs {
+		addAttr(target, ga)
+	}
+}
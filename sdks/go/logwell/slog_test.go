@@ -0,0 +1,126 @@
+package logwell
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestSlogHandler tests sending slog records through a client.
+func TestSlogHandler(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts, WithBatchSize(100), WithFlushInterval(60*time.Second))
+	defer client.Shutdown(context.Background())
+
+	flushLogs := func(t *testing.T) []LogEntry {
+		t.Helper()
+		clearTestLogs(ts)
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		return ts.getLogs()
+	}
+
+	t.Run("default level mapping", func(t *testing.T) {
+		logger := slog.New(NewSlogHandler(client))
+		logger.Debug("d")
+		logger.Info("i")
+		logger.Warn("w")
+		logger.Error("e")
+		logger.Log(context.Background(), slog.LevelError+4, "above error")
+
+		logs := flushLogs(t)
+		want := []LogLevel{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelError}
+		assertLogCount(t, logs, len(want))
+		for i, level := range want {
+			if i < len(logs) && logs[i].Level != level {
+				t.Errorf("logs[%d].Level = %q, want %q", i, logs[i].Level, level)
+			}
+		}
+	})
+
+	t.Run("custom level mapper", func(t *testing.T) {
+		const levelCritical = slog.Level(12)
+		mapper := func(level slog.Level) LogLevel {
+			switch {
+			case level >= levelCritical:
+				return LevelFatal
+			case level >= slog.LevelWarn:
+				return LevelError
+			default:
+				return LevelInfo
+			}
+		}
+		logger := slog.New(NewSlogHandler(client, SlogWithLevelMapper(mapper)))
+		logger.Debug("debug becomes info")
+		logger.Warn("warn becomes error")
+		logger.Log(context.Background(), levelCritical, "critical becomes fatal")
+
+		logs := flushLogs(t)
+		want := []LogLevel{LevelInfo, LevelError, LevelFatal}
+		assertLogCount(t, logs, len(want))
+		for i, level := range want {
+			if i < len(logs) && logs[i].Level != level {
+				t.Errorf("logs[%d] %q Level = %q, want %q", i, logs[i].Message, logs[i].Level, level)
+			}
+		}
+	})
+
+	t.Run("fixed level", func(t *testing.T) {
+		logger := slog.New(NewSlogHandler(client, SlogWithLevel(LevelWarn)))
+		logger.Info("info")
+		logger.Error("error")
+
+		for _, log := range flushLogs(t) {
+			if log.Level != LevelWarn {
+				t.Errorf("%q Level = %q, want %q", log.Message, log.Level, LevelWarn)
+			}
+		}
+	})
+
+	t.Run("attributes and groups become metadata", func(t *testing.T) {
+		logger := slog.New(NewSlogHandler(client)).With("requestId", "abc").WithGroup("http")
+		logger.Info("request", "method", "GET", slog.Group("resp", "status", 200))
+
+		logs := flushLogs(t)
+		assertLogCount(t, logs, 1)
+		md := logs[0].Metadata
+		if md["requestId"] != "abc" {
+			t.Errorf("Metadata[requestId] = %v, want abc", md["requestId"])
+		}
+		httpGroup, _ := md["http"].(map[string]any)
+		respGroup, _ := httpGroup["resp"].(map[string]any)
+		if httpGroup["method"] != "GET" || respGroup["status"] != float64(200) {
+			t.Errorf("Metadata[http] = %v, want method=GET and resp.status=200", md["http"])
+		}
+	})
+
+	t.Run("errors become their message", func(t *testing.T) {
+		logger := slog.New(NewSlogHandler(client))
+		logger.Error("failed", "err", fmt.Errorf("open config: %w", os.ErrNotExist))
+
+		logs := flushLogs(t)
+		assertLogCount(t, logs, 1)
+		if got := logs[0].Metadata["err"]; got != "open config: file does not exist" {
+			t.Errorf("Metadata[err] = %#v, want the error message", got)
+		}
+	})
+
+	t.Run("enabled follows the client level", func(t *testing.T) {
+		client.SetLevel(LevelWarn)
+		defer client.SetLevel(LevelDebug)
+
+		h := NewSlogHandler(client)
+		if h.Enabled(context.Background(), slog.LevelInfo) {
+			t.Error("Enabled(info) = true with client level warn")
+		}
+		if !h.Enabled(context.Background(), slog.LevelError) {
+			t.Error("Enabled(error) = false with client level warn")
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package logwell
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// shutdownSnapshot collects the entries that the final flush of Shutdown
+// could not deliver and writes them to a file as JSON lines, the format
+// read by Replay.
+type shutdownSnapshot struct {
+	path string
+	diag *diagnostics
+
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// newShutdownSnapshot creates a snapshot written to path.
+func newShutdownSnapshot(path string, diag *diagnostics) *shutdownSnapshot {
+	return &shutdownSnapshot{path: path, diag: diag}
+}
+
+// add keeps entries left undelivered at shutdown. It is a no-op on a nil
+// receiver.
+func (s *shutdownSnapshot) add(entries []LogEntry) {
+	if s == nil || len(entries) == 0 {
+		return
+	}
+	s.mu.Lock()
+	s.entries = append(s.entries, entries...)
+	s.mu.Unlock()
+}
+
+// write appends the kept entries to the file, creating it if needed, and
+// returns how many were written. Nothing is written when no entries were
+// kept. It is a no-op on a nil receiver.
+func (s *shutdownSnapshot) write() (int, error) {
+	if s == nil {
+		return 0, nil
+	}
+	s.mu.Lock()
+	entries := s.entries
+	s.entries = nil
+	s.mu.Unlock()
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for i := range entries {
+		if err := enc.Encode(entries[i]); err != nil {
+			f.Close()
+			return 0, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	s.diag.logf(LevelWarn, "shutdown: wrote %d undelivered logs to %s", len(entries), s.path)
+	return len(entries), nil
+}
@@ -0,0 +1,116 @@
+package logwell
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestClientSnapshotOnShutdown tests that entries a dead server could not
+// take at shutdown are written to the snapshot and replay once a server is
+// up.
+func TestClientSnapshotOnShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unsent.ndjson")
+
+	dead := newTestServer()
+	dead.Close()
+	client := createTestClient(t, dead, WithBatchSize(100), WithSnapshotOnShutdown(path))
+	client.transport.maxRetries = 0
+
+	client.Info("first", M{"order": 1})
+	client.Warn("second")
+	client.Error("third")
+	if err := client.Shutdown(context.Background()); err == nil {
+		t.Fatal("Shutdown() error = nil, want the send error")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading snapshot: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Fatalf("snapshot has %d lines, want 3:\n%s", lines, data)
+	}
+
+	ts := newTestServer()
+	defer ts.Close()
+	replayer := createTestClient(t, ts, WithBatchSize(100))
+	defer replayer.Shutdown(context.Background())
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening snapshot: %v", err)
+	}
+	defer f.Close()
+	if n, err := replayer.Replay(context.Background(), f); n != 3 || err != nil {
+		t.Fatalf("Replay() = %d, %v, want 3 and no error", n, err)
+	}
+	if err := replayer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	logs := ts.getLogs()
+	assertLogCount(t, logs, 3)
+	for i, want := range []string{"first", "second", "third"} {
+		if logs[i].Message != want {
+			t.Errorf("logs[%d].Message = %q, want %q", i, logs[i].Message, want)
+		}
+	}
+	if logs[0].Metadata["order"] != float64(1) {
+		t.Errorf("logs[0].Metadata = %v, want the original metadata", logs[0].Metadata)
+	}
+}
+
+// TestClientSnapshotOnShutdownDelivered tests that no snapshot is written
+// when the final flush delivers everything.
+func TestClientSnapshotOnShutdownDelivered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unsent.ndjson")
+
+	ts := newTestServer()
+	defer ts.Close()
+	client := createTestClient(t, ts, WithBatchSize(100), WithSnapshotOnShutdown(path))
+
+	client.Info("delivered")
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	assertLogCount(t, ts.getLogs(), 1)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("snapshot stat error = %v, want it not to exist", err)
+	}
+}
+
+// TestClientSnapshotOnShutdownWriteError tests that a snapshot that cannot
+// be written is reported as ErrSnapshot.
+func TestClientSnapshotOnShutdownWriteError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "unsent.ndjson")
+
+	var reported []*Error
+	dead := newTestServer()
+	dead.Close()
+	client := createTestClient(t, dead, WithBatchSize(100), WithSnapshotOnShutdown(path),
+		WithOnError(func(err *Error) { reported = append(reported, err) }))
+	client.transport.maxRetries = 0
+
+	client.Info("unsent")
+	client.Shutdown(context.Background())
+
+	var snapErr *Error
+	for _, err := range reported {
+		if err.Code == ErrSnapshot {
+			snapErr = err
+		}
+	}
+	if snapErr == nil {
+		t.Fatalf("OnError calls = %v, want one with %s", reported, ErrSnapshot)
+	}
+	if !errors.Is(snapErr.Cause, os.ErrNotExist) {
+		t.Errorf("snapshot error cause = %v, want a missing directory", snapErr.Cause)
+	}
+	if IsRetryable(snapErr) {
+		t.Error("IsRetryable(snapshot error) = true, want false")
+	}
+}
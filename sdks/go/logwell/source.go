@@ -3,17 +3,37 @@ package logwell
 import (
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
-// captureSource captures the source file and line number at the call site.
-// The skip parameter specifies how many stack frames to skip.
-// Returns the base name of the file (not full path) and line number.
-// If capture fails, returns empty string and 0.
-func captureSource(skip int) (file string, line int) {
-	_, file, line, ok := runtime.Caller(skip)
+// captureSource captures the source file, line number, and function name at
+// the call site. The skip parameter specifies how many stack frames to skip.
+// Returns the base name of the file (not full path), the line number, and
+// the function name qualified by its package name but not the import path,
+// such as "main.handleRequest" or "main.(*Server).Start".
+// If capture fails, returns empty strings and 0.
+func captureSource(skip int) (file string, line int, function string) {
+	pc, file, line, ok := runtime.Caller(skip)
 	if !ok {
-		return "", 0
+		return "", 0, ""
+	}
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		function = fn.Name()
+		// Strip the import path, keeping the package name
+		if i := strings.LastIndex(function, "/"); i >= 0 {
+			function = function[i+1:]
+		}
 	}
 	// Return just the base filename, not the full path
-	return filepath.Base(file), line
+	return filepath.Base(file), line, function
+}
+
+// frameSource returns the source location of frame in the form returned by
+// captureSource.
+func frameSource(frame runtime.Frame) (file string, line int, function string) {
+	function = frame.Function
+	if i := strings.LastIndex(function, "/"); i >= 0 {
+		function = function[i+1:]
+	}
+	return filepath.Base(frame.File), frame.Line, function
 }
@@ -0,0 +1,370 @@
+package logwell
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spill segment files are named spill-<seq>.jsonl, with seq zero-padded so
+// that name order is queue order. New segments take the next sequence
+// number; entries put back at the front take the one before the head, so
+// numbering starts well above zero. A segment that has been partly read
+// has an offset file beside it, spill-<seq>.offset, holding the offset of
+// its next unread line.
+const (
+	spillPrefix       = "spill-"
+	spillSuffix       = ".jsonl"
+	spillOffsetSuffix = ".offset"
+	spillBaseSeq      = int64(1) << 32
+
+	// spillSegments is how many segments maxBytes is split into, which
+	// sets how much is dropped at once when the cap is reached.
+	spillSegments = 8
+)
+
+// spillSegment is one spill file.
+type spillSegment struct {
+	seq   int64
+	size  int64 // bytes on disk
+	count int   // entries not yet read
+	off   int64 // offset of the next entry to read
+}
+
+// spillRecord is one line of a segment file: the entry with the queue
+// fields its JSON form leaves out.
+type spillRecord struct {
+	LogEntry
+	EnqueuedAt *time.Time `json:"enqueuedAt,omitempty"`
+}
+
+// encodeSpill returns the segment file line for entry.
+func encodeSpill(entry LogEntry) ([]byte, error) {
+	rec := spillRecord{LogEntry: entry}
+	if !entry.enqueuedAt.IsZero() {
+		rec.EnqueuedAt = &entry.enqueuedAt
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// decodeSpill parses a segment file line.
+func decodeSpill(line []byte) (LogEntry, error) {
+	var rec spillRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return LogEntry{}, err
+	}
+	if rec.EnqueuedAt != nil {
+		rec.LogEntry.enqueuedAt = *rec.EnqueuedAt
+	}
+	return rec.LogEntry, nil
+}
+
+// spillStore keeps queue entries that do not fit in memory in a directory
+// of JSON lines segment files, oldest first, for the SpillToDisk overflow
+// policy. Entries are appended to the newest segment and read back from
+// the oldest; when the files exceed maxBytes the oldest segments are
+// deleted. Entries left behind by an earlier process are picked up when
+// the store is opened, starting after those it already read.
+//
+// A spillStore is not safe for concurrent use; the owning queue calls it
+// with its lock held.
+type spillStore struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+	diag         *diagnostics
+
+	segments []*spillSegment // oldest first
+	tail     *os.File        // open for appending to the last segment, if any
+	bytes    int64
+	count    int
+}
+
+// openSpill opens the spill store in dir, creating the directory if needed
+// and loading any segments already there. Offset files whose segment is
+// gone are removed.
+func openSpill(dir string, maxBytes int64, diag *diagnostics) (*spillStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	names, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &spillStore{
+		dir:          dir,
+		maxBytes:     maxBytes,
+		segmentBytes: max(maxBytes/spillSegments, 1),
+		diag:         diag,
+	}
+	offsets := make(map[int64]bool)
+	for _, name := range names {
+		if seq, ok := parseSpillName(name.Name(), spillOffsetSuffix); ok {
+			offsets[seq] = true
+			continue
+		}
+		seq, ok := parseSpillName(name.Name(), spillSuffix)
+		if !ok || !name.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(s.path(seq))
+		if err != nil {
+			return nil, err
+		}
+		seg := &spillSegment{seq: seq, size: int64(len(data)), off: s.readOffset(seq, int64(len(data)))}
+		// Only complete lines count; a partial last line is never read
+		seg.count = bytes.Count(data[seg.off:], []byte{'\n'})
+		s.segments = append(s.segments, seg)
+		s.bytes += seg.size
+		s.count += seg.count
+		delete(offsets, seq)
+	}
+	for seq := range offsets {
+		s.removeOffset(seq)
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+	return s, nil
+}
+
+// parseSpillName returns the sequence number of a spill file name ending
+// in suffix.
+func parseSpillName(name, suffix string) (int64, bool) {
+	if !strings.HasPrefix(name, spillPrefix) || !strings.HasSuffix(name, suffix) {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, spillPrefix), suffix), 10, 64)
+	return seq, err == nil
+}
+
+// path returns the file path of segment seq.
+func (s *spillStore) path(seq int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%019d%s", spillPrefix, seq, spillSuffix))
+}
+
+// offsetPath returns the path of the offset file of segment seq.
+func (s *spillStore) offsetPath(seq int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%019d%s", spillPrefix, seq, spillOffsetSuffix))
+}
+
+// readOffset returns the saved read offset of segment seq, which is size
+// bytes long. A missing or unreadable offset file reads as 0, sending the
+// segment again rather than losing it.
+func (s *spillStore) readOffset(seq, size int64) int64 {
+	data, err := os.ReadFile(s.offsetPath(seq))
+	if err != nil {
+		return 0
+	}
+	off, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || off < 0 {
+		return 0
+	}
+	return min(off, size)
+}
+
+// saveOffset records how far seg has been read, so a later process does
+// not send those entries again.
+func (s *spillStore) saveOffset(seg *spillSegment) {
+	if err := os.WriteFile(s.offsetPath(seg.seq), []byte(strconv.FormatInt(seg.off, 10)), 0o600); err != nil {
+		s.diag.logf(LevelError, "spill: saving read offset of %s failed: %v", s.path(seg.seq), err)
+	}
+}
+
+// removeOffset deletes the offset file of segment seq, if any.
+func (s *spillStore) removeOffset(seq int64) {
+	if err := os.Remove(s.offsetPath(seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.diag.logf(LevelError, "spill: removing %s failed: %v", s.offsetPath(seq), err)
+	}
+}
+
+// len returns the number of entries waiting on disk. It returns 0 on a nil
+// receiver.
+func (s *spillStore) len() int {
+	if s == nil {
+		return 0
+	}
+	return s.count
+}
+
+// append writes a line made by encodeSpill after every entry already
+// spilled and returns the number of entries dropped to stay within
+// maxBytes.
+func (s *spillStore) append(line []byte) (int, error) {
+	if s.tail == nil || s.segments[len(s.segments)-1].size >= s.segmentBytes {
+		if err := s.roll(); err != nil {
+			return 0, err
+		}
+	}
+	seg := s.segments[len(s.segments)-1]
+	n, err := s.tail.Write(line)
+	seg.size += int64(n)
+	s.bytes += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	seg.count++
+	s.count++
+	return s.trim(), nil
+}
+
+// prepend writes entries, oldest first, ahead of every entry already
+// spilled and returns the number of entries dropped to stay within
+// maxBytes.
+func (s *spillStore) prepend(entries []LogEntry) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	var buf bytes.Buffer
+	for i := range entries {
+		line, err := encodeSpill(entries[i])
+		if err != nil {
+			return 0, err
+		}
+		buf.Write(line)
+	}
+
+	seq := spillBaseSeq
+	if len(s.segments) > 0 {
+		seq = s.segments[0].seq - 1
+	}
+	if err := os.WriteFile(s.path(seq), buf.Bytes(), 0o600); err != nil {
+		return 0, err
+	}
+	seg := &spillSegment{seq: seq, size: int64(buf.Len()), count: len(entries)}
+	s.segments = append([]*spillSegment{seg}, s.segments...)
+	s.bytes += seg.size
+	s.count += seg.count
+	return s.trim(), nil
+}
+
+// read removes and returns up to n of the oldest spilled entries. Entries
+// that cannot be read back are skipped and counted in lost.
+func (s *spillStore) read(n int) (entries []LogEntry, lost int) {
+	for len(entries) < n && len(s.segments) > 0 {
+		seg := s.segments[0]
+		if seg.count > 0 {
+			var err error
+			entries, lost, err = s.readSegment(seg, entries, n, lost)
+			if err != nil {
+				s.diag.logf(LevelError, "spill: reading %s failed: %v", s.path(seg.seq), err)
+				lost += seg.count
+				s.count -= seg.count
+				seg.count = 0
+			}
+		}
+		if seg.count == 0 {
+			s.removeOldest()
+		} else {
+			s.saveOffset(seg)
+		}
+	}
+	return entries, lost
+}
+
+// readSegment appends entries read from seg to entries until it holds n
+// or seg is exhausted.
+func (s *spillStore) readSegment(seg *spillSegment, entries []LogEntry, n, lost int) ([]LogEntry, int, error) {
+	f, err := os.Open(s.path(seg.seq))
+	if err != nil {
+		return entries, lost, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(seg.off, io.SeekStart); err != nil {
+		return entries, lost, err
+	}
+
+	r := bufio.NewReader(f)
+	for len(entries) < n && seg.count > 0 {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return entries, lost, err
+		}
+		seg.off += int64(len(line))
+		seg.count--
+		s.count--
+
+		entry, err := decodeSpill(line)
+		if err != nil {
+			s.diag.logf(LevelError, "spill: skipping unreadable entry in %s: %v", s.path(seg.seq), err)
+			lost++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, lost, nil
+}
+
+// roll starts a new segment after the last one and opens it for appending.
+func (s *spillStore) roll() error {
+	seq := spillBaseSeq
+	if len(s.segments) > 0 {
+		seq = s.segments[len(s.segments)-1].seq + 1
+	}
+	f, err := os.OpenFile(s.path(seq), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	s.closeTail()
+	s.tail = f
+	s.segments = append(s.segments, &spillSegment{seq: seq})
+	return nil
+}
+
+// trim deletes the oldest segments until the files fit in maxBytes and
+// returns the number of unread entries they held.
+func (s *spillStore) trim() int {
+	dropped := 0
+	for s.bytes > s.maxBytes && len(s.segments) > 0 {
+		dropped += s.segments[0].count
+		s.count -= s.segments[0].count
+		s.removeOldest()
+	}
+	return dropped
+}
+
+// removeOldest deletes the oldest segment file. Its offset file goes
+// first, so that a crash in between cannot leave an offset behind for a
+// later segment with the same sequence number.
+func (s *spillStore) removeOldest() {
+	seg := s.segments[0]
+	if len(s.segments) == 1 {
+		s.closeTail()
+	}
+	if seg.off > 0 {
+		s.removeOffset(seg.seq)
+	}
+	if err := os.Remove(s.path(seg.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.diag.logf(LevelError, "spill: removing %s failed: %v", s.path(seg.seq), err)
+	}
+	s.bytes -= seg.size
+	s.segments[0] = nil
+	s.segments = s.segments[1:]
+}
+
+// closeTail closes the segment open for appending, if any.
+func (s *spillStore) closeTail() {
+	if s.tail == nil {
+		return
+	}
+	if err := s.tail.Close(); err != nil {
+		s.diag.logf(LevelError, "spill: closing segment failed: %v", err)
+	}
+	s.tail = nil
+}
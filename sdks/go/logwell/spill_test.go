@@ -0,0 +1,231 @@
+package logwell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestClientSpillToDisk tests that entries overflowing the queue while the
+// endpoint is down are spilled and all delivered in order once it recovers.
+func TestClientSpillToDisk(t *testing.T) {
+	server := newSwitchableServer()
+	defer server.Close()
+
+	var dropCount int32
+	client, err := New(server.URL, validAPIKey(),
+		WithBatchSize(5),
+		WithMaxQueueSize(10),
+		WithSpillToDisk(t.TempDir(), 1<<20),
+		WithOfflineMode(1, 100*time.Millisecond),
+		WithOnDrop(func(n int) { atomic.AddInt32(&dropCount, int32(n)) }),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	server.down.Store(true)
+	const total = 100
+	for i := 0; i < total; i++ {
+		client.Info(fmt.Sprintf("log %d", i))
+	}
+	waitForOffline(t, client, true)
+
+	// The batch that failed is requeued once its send gives up
+	deadline := time.Now().Add(5 * time.Second)
+	for client.QueueLen() != total {
+		if time.Now().After(deadline) {
+			t.Fatalf("QueueLen() while down = %d, want %d", client.QueueLen(), total)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := client.queue.spilled(); got != total-10 {
+		t.Errorf("spilled entries = %d, want %d", got, total-10)
+	}
+
+	server.down.Store(false)
+	waitForOffline(t, client, false)
+	waitForIdle(t, client)
+
+	logs := server.getLogs()
+	assertLogCount(t, logs, total)
+	for i, log := range logs {
+		if want := fmt.Sprintf("log %d", i); log.Message != want {
+			t.Fatalf("log %d message = %q, want %q", i, log.Message, want)
+		}
+	}
+	if got := atomic.LoadInt32(&dropCount); got != 0 {
+		t.Errorf("OnDrop total = %d, want 0", got)
+	}
+}
+
+// TestClientSpillToDiskMaxBytes tests that the oldest spilled entries are
+// dropped and reported once the spill files reach their size cap.
+func TestClientSpillToDiskMaxBytes(t *testing.T) {
+	server := newSwitchableServer()
+	defer server.Close()
+
+	var dropCount int32
+	client, err := New(server.URL, validAPIKey(),
+		WithBatchSize(5),
+		WithMaxQueueSize(10),
+		WithSpillToDisk(t.TempDir(), 4096),
+		WithOfflineMode(1, 100*time.Millisecond),
+		WithOnDrop(func(n int) { atomic.AddInt32(&dropCount, int32(n)) }),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	server.down.Store(true)
+	const total = 500
+	for i := 0; i < total; i++ {
+		client.Info(fmt.Sprintf("log %d", i))
+	}
+	waitForOffline(t, client, true)
+
+	client.queue.mu.Lock()
+	spillBytes := client.queue.spill.bytes
+	client.queue.mu.Unlock()
+	if spillBytes > 4096 {
+		t.Errorf("spill size = %d bytes, want at most 4096", spillBytes)
+	}
+
+	server.down.Store(false)
+	waitForOffline(t, client, false)
+	waitForIdle(t, client)
+
+	dropped := int(atomic.LoadInt32(&dropCount))
+	if dropped == 0 {
+		t.Fatal("OnDrop not called after the spill reached its cap")
+	}
+	logs := server.getLogs()
+	assertLogCount(t, logs, total-dropped)
+	// The queued entries are the oldest and the newest survive the cap
+	for i := 0; i < 10; i++ {
+		if want := fmt.Sprintf("log %d", i); logs[i].Message != want {
+			t.Errorf("log %d message = %q, want %q", i, logs[i].Message, want)
+		}
+	}
+	if want := fmt.Sprintf("log %d", total-1); logs[len(logs)-1].Message != want {
+		t.Errorf("last message = %q, want %q", logs[len(logs)-1].Message, want)
+	}
+}
+
+// TestClientSpillToDiskResume tests that entries left in the spill
+// directory at shutdown are sent by the next client using it.
+func TestClientSpillToDiskResume(t *testing.T) {
+	server := newSwitchableServer()
+	defer server.Close()
+	dir := t.TempDir()
+
+	server.down.Store(true)
+	first, err := New(server.URL, validAPIKey(),
+		WithMaxQueueSize(5),
+		WithSpillToDisk(dir, 1<<20),
+		WithOfflineMode(1, time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		first.Info(fmt.Sprintf("log %d", i))
+	}
+	// Shutting down without time to send leaves every entry in the spill
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	first.Shutdown(ctx)
+
+	server.down.Store(false)
+	second, err := New(server.URL, validAPIKey(), WithMaxQueueSize(5), WithSpillToDisk(dir, 1<<20))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer second.Shutdown(context.Background())
+
+	if got := second.QueueLen(); got != 20 {
+		t.Errorf("QueueLen() after reopening = %d, want 20", got)
+	}
+	if err := second.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	logs := server.getLogs()
+	assertLogCount(t, logs, 20)
+	for i, log := range logs {
+		if want := fmt.Sprintf("log %d", i); log.Message != want {
+			t.Fatalf("log %d message = %q, want %q", i, log.Message, want)
+		}
+	}
+}
+
+// TestConfigValidateSpillToDisk tests validation of the spill options.
+func TestConfigValidateSpillToDisk(t *testing.T) {
+	_, err := New(validEndpoint(), validAPIKey(), WithSpillToDisk("", 1024))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	_, err = New(validEndpoint(), validAPIKey(), WithSpillToDisk(t.TempDir(), 0))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	client, err := New(validEndpoint(), validAPIKey(), WithSpillToDisk(t.TempDir(), 1024))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client.Shutdown(context.Background())
+}
+
+// TestSpillStoreReopenAfterRead tests that a reopened spill store resumes
+// after the entries already read instead of returning them again, and
+// keeps their enqueue times.
+func TestSpillStoreReopenAfterRead(t *testing.T) {
+	dir := t.TempDir()
+	enqueuedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	first, err := openSpill(dir, 1<<20, nil)
+	if err != nil {
+		t.Fatalf("openSpill() error = %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		line, err := encodeSpill(LogEntry{Level: LevelInfo, Message: fmt.Sprintf("log %d", i), enqueuedAt: enqueuedAt})
+		if err != nil {
+			t.Fatalf("encodeSpill() error = %v", err)
+		}
+		if _, err := first.append(line); err != nil {
+			t.Fatalf("append() error = %v", err)
+		}
+	}
+	if entries, lost := first.read(4); len(entries) != 4 || lost != 0 {
+		t.Fatalf("read(4) = %d entries, %d lost, want 4 and 0", len(entries), lost)
+	}
+	first.closeTail()
+
+	second, err := openSpill(dir, 1<<20, nil)
+	if err != nil {
+		t.Fatalf("openSpill() error = %v", err)
+	}
+	if got := second.len(); got != 6 {
+		t.Fatalf("len() after reopening = %d, want 6", got)
+	}
+	entries, _ := second.read(10)
+	if len(entries) != 6 || entries[0].Message != "log 4" {
+		t.Fatalf("read() after reopening = %+v, want log 4 to log 9", entries)
+	}
+	for _, entry := range entries {
+		if !entry.enqueuedAt.Equal(enqueuedAt) {
+			t.Errorf("enqueuedAt = %v, want %v", entry.enqueuedAt, enqueuedAt)
+		}
+	}
+	second.closeTail()
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("spill directory holds %d files after draining, want 0", len(files))
+	}
+}
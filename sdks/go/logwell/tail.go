@@ -0,0 +1,329 @@
+package logwell
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TailParams selects the logs streamed by Tail. Zero fields do not filter.
+type TailParams struct {
+	// Service keeps logs of this service.
+	Service string
+
+	// Levels keeps logs at any of these levels.
+	Levels []LogLevel
+
+	// Search keeps logs whose message contains every word.
+	Search string
+
+	// Since, when set, starts the stream with up to 500 logs stored from
+	// this time on. Otherwise only logs arriving after Tail connects are
+	// sent.
+	Since time.Time
+}
+
+// tailBackfillLimit is the most stored logs the server replays when a
+// stream starts from a time. A backfill of this size may have been cut
+// short.
+const tailBackfillLimit = 500
+
+// tailSeenWindow is how many recently received log IDs Tail remembers to
+// skip logs a resumed stream sends again.
+const tailSeenWindow = 2 * tailBackfillLimit
+
+// Tail streams the logs the server receives for the client's project,
+// calling fn with each one in order, like tail -f. It runs until ctx is
+// done, fn returns an error, or the server rejects the request with an
+// error that is not retryable, such as ErrUnauthorized, and returns that
+// error: fn's error as is, or an *Error.
+//
+// When the connection drops or fails with a retryable error, Tail
+// reconnects with backoff and resumes from the newest timestamp it
+// received. The server replays at most 500 stored logs on resume; when a
+// replay reaches that limit, logs may have been skipped and an ErrTailGap
+// error is reported through OnError. Logs are recognized by ID, so none is
+// passed to fn twice. Live logs are passed on in the order the server
+// receives them, whatever their timestamps.
+//
+// Example:
+//
+//	err := client.Tail(ctx, logwell.TailParams{Levels: []logwell.LogLevel{logwell.LevelError}},
+//	    func(entry logwell.LogEntry) error {
+//	        fmt.Println(entry.Timestamp, entry.Service, entry.Message)
+//	        return nil
+//	    })
+func (c *Client) Tail(ctx context.Context, params TailParams, fn func(LogEntry) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := validateQueryParams(QueryParams{Levels: params.Levels}); err != nil {
+		return err
+	}
+
+	t := c.transport
+	s := &tailStream{transport: t, params: params, fn: fn, report: c.reportError}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return newNetworkError("tail canceled", ctx.Err())
+			case <-time.After(t.calculateBackoff(min(attempt, tailMaxBackoffAttempt))):
+			}
+		}
+
+		received, err := s.connect(ctx)
+		var callbackErr *tailCallbackError
+		if errors.As(err, &callbackErr) {
+			return callbackErr.err
+		}
+		if ctx.Err() != nil {
+			return newNetworkError("tail canceled", ctx.Err())
+		}
+		if err != nil && !t.isRetryableError(err) {
+			return err
+		}
+		if received {
+			attempt = 0 // Back off from scratch after a working connection
+		}
+		// Disconnected; resume from the newest log
+	}
+}
+
+// tailMaxBackoffAttempt caps the attempt number Tail computes its
+// reconnect backoff from; later attempts wait as long as this one.
+const tailMaxBackoffAttempt = 6
+
+// tailCallbackError carries an error returned by the Tail callback.
+type tailCallbackError struct {
+	err error
+}
+
+func (e *tailCallbackError) Error() string { return e.err.Error() }
+
+// tailStream is the state of a Tail kept across reconnects.
+type tailStream struct {
+	transport *httpTransport
+	params    TailParams
+	fn        func(LogEntry) error
+	report    func(error)
+
+	// since is the newest timestamp received, from which a resumed stream
+	// replays stored logs. seen and order hold the IDs of the last
+	// tailSeenWindow logs received, most recent first, so that logs sent
+	// again are skipped.
+	since time.Time
+	seen  map[string]*list.Element
+	order *list.List
+}
+
+// connect opens the stream and passes its logs to fn until it ends.
+// Reports whether any log was received; the error is nil when the server
+// closed the stream.
+func (s *tailStream) connect(ctx context.Context) (bool, error) {
+	t := s.transport
+	token, err := t.bearerToken(ctx)
+	if err != nil {
+		return false, NewErrorWithCause(ErrUnauthorized, "token provider failed", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.tailURL+"?"+s.values().Encode(), nil)
+	if err != nil {
+		return false, NewErrorWithCause(ErrNetworkError, "failed to create request", err)
+	}
+	t.authorize(req, token)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	// The stream stays open indefinitely, so RequestTimeout does not apply
+	client := *t.httpClient
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		netErr := newNetworkError("tail request failed", err)
+		t.debugExchange(req, nil, nil, netErr)
+		return false, netErr
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+		apiErr := t.createError(resp.StatusCode, t.parseErrorMessage(body, resp.StatusCode))
+		apiErr.RequestID = resp.Header.Get(requestIDHeader)
+		t.debugExchange(req, resp, body, apiErr)
+		return false, apiErr
+	}
+	t.debugExchange(req, resp, nil, nil)
+
+	received := false
+	cutoff := s.resumeFrom()
+	err = readSSE(resp.Body, func(event string, data []byte) error {
+		backfill := event == "backfill"
+		if !backfill && event != "" && event != "logs" && event != "message" {
+			return nil // heartbeat and other events
+		}
+		entries, err := decodeTailData(data)
+		if err != nil {
+			return NewErrorWithCause(ErrServerError, "failed to parse tail event", err)
+		}
+		if backfill && len(entries) >= tailBackfillLimit {
+			s.report(NewError(ErrTailGap, fmt.Sprintf(
+				"tail backfill reached the server limit of %d logs; logs since %s may have been skipped",
+				tailBackfillLimit, cutoff.UTC().Format(time.RFC3339Nano))))
+		}
+		for _, entry := range entries {
+			if backfill && entry.Timestamp.Before(cutoff) {
+				continue
+			}
+			if !s.keep(entry) {
+				continue
+			}
+			received = true
+			if err := s.fn(entry.logEntry()); err != nil {
+				return &tailCallbackError{err: err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var logwellErr *Error
+		if !errors.As(err, &logwellErr) && !errors.As(err, new(*tailCallbackError)) {
+			err = newNetworkError("tail stream interrupted", err)
+		}
+		return received, err
+	}
+	return received, nil
+}
+
+// resumeFrom returns the time the stream's backfill starts from: the
+// newest timestamp received, or Since before any log was.
+func (s *tailStream) resumeFrom() time.Time {
+	if !s.since.IsZero() {
+		return s.since
+	}
+	return s.params.Since
+}
+
+// values encodes the stream's filters, resuming from the newest log
+// received.
+func (s *tailStream) values() url.Values {
+	values := queryValues(QueryParams{Service: s.params.Service, Levels: s.params.Levels, Search: s.params.Search})
+	if since := s.resumeFrom(); !since.IsZero() {
+		values.Set("since", since.UTC().Format(time.RFC3339Nano))
+	}
+	return values
+}
+
+// keep reports whether entry is new to the stream, and records it. Logs
+// without an ID are always new.
+func (s *tailStream) keep(entry QueryEntry) bool {
+	if entry.Timestamp.After(s.since) {
+		s.since = entry.Timestamp
+	}
+	if entry.ID == "" {
+		return true
+	}
+	if s.seen == nil {
+		s.seen = make(map[string]*list.Element)
+		s.order = list.New()
+	}
+	if elem, ok := s.seen[entry.ID]; ok {
+		s.order.MoveToFront(elem)
+		return false
+	}
+	s.seen[entry.ID] = s.order.PushFront(entry.ID)
+	if s.order.Len() > tailSeenWindow {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.seen, oldest.Value.(string))
+	}
+	return true
+}
+
+// readSSE reads server-sent events from r, calling fn with the event name
+// and data of each. It returns nil at the end of r, or fn's error.
+func readSSE(r io.Reader, fn func(event string, data []byte) error) error {
+	br := bufio.NewReader(r)
+	var (
+		event   string
+		data    bytes.Buffer
+		hasData bool
+	)
+	for {
+		line, err := br.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			return nil // An event cut off by the end of r is dropped
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if hasData {
+				if err := fn(event, data.Bytes()); err != nil {
+					return err
+				}
+			}
+			event, hasData = "", false
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		}
+	}
+}
+
+// decodeTailData decodes the data of a logs event: an array of stored
+// logs, or a single one.
+func decodeTailData(data []byte) ([]QueryEntry, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var entries []QueryEntry
+		err := json.Unmarshal(data, &entries)
+		return entries, err
+	}
+	var entry QueryEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return []QueryEntry{entry}, nil
+}
+
+// logEntry converts a stored log to a LogEntry.
+func (e QueryEntry) logEntry() LogEntry {
+	entry := LogEntry{
+		Level:      e.Level,
+		Message:    e.Message,
+		Service:    e.Service,
+		Metadata:   e.Metadata,
+		SourceFile: e.SourceFile,
+		LineNumber: e.LineNumber,
+	}
+	if !e.Timestamp.IsZero() {
+		entry.Timestamp = e.Timestamp.UTC().Format(timestampLayout)
+	}
+	return entry
+}
@@ -0,0 +1,218 @@
+package logwell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tailLog formats a stored log as the server streams it.
+func tailLog(id string, second int, message string) string {
+	return fmt.Sprintf(`{"id":%q,"level":"info","message":%q,"serviceName":"api","timestamp":"2024-01-01T00:00:%02d.000Z"}`,
+		id, message, second)
+}
+
+// newTailServer starts a server streaming the events of each connection
+// from connections, flushing them one at a time, then dropping the
+// connection. The query string of each connection is recorded.
+func newTailServer(t *testing.T, connections [][]string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/logs/stream" {
+			t.Errorf("request = %s %s, want GET /v1/logs/stream", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		n := len(queries)
+		queries = append(queries, r.URL.RawQuery)
+		mu.Unlock()
+		if n >= len(connections) {
+			// Hold the last connection open until the client leaves
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range connections[n] {
+			fmt.Fprint(w, event)
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), queries...)
+	}
+}
+
+// TestClientTail tests that streamed logs are passed on in order, that
+// live logs with older timestamps are kept, that a dropped stream is
+// resumed from the newest log without repeats, and that the callback's
+// error ends the tail.
+func TestClientTail(t *testing.T) {
+	errDone := errors.New("done")
+	srv, queries := newTailServer(t, [][]string{
+		{
+			"event: heartbeat\ndata: {\"ts\":1}\n\n",
+			"event: logs\ndata: [" + tailLog("a", 1, "one") + "," + tailLog("b", 2, "two") + "]\n\n",
+			": comment\nevent: logs\ndata: [" + tailLog("c", 2, "three") + "]\n\n",
+			// A producer with a slow clock
+			"event: logs\ndata: [" + tailLog("s", 0, "skewed") + "]\n\n",
+			"event: logs\ndata: [" + tailLog("d", 3, "cut"), // dropped mid-event
+		},
+		{
+			// Resumed from second 2: "b" and "c" are replayed again
+			"event: backfill\ndata: [" + tailLog("b", 2, "two") + "," + tailLog("c", 2, "three") + "," + tailLog("d", 3, "four") + "]\n\n",
+			"event: logs\ndata: [" + tailLog("e", 4, "five") + "]\n\n",
+		},
+	})
+
+	client, err := New(srv.URL, validAPIKey())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	var got []LogEntry
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = client.Tail(ctx, TailParams{Service: "api", Levels: []LogLevel{LevelInfo}}, func(entry LogEntry) error {
+		got = append(got, entry)
+		if entry.Message == "five" {
+			return errDone
+		}
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("Tail() error = %v, want the callback's error", err)
+	}
+
+	var messages []string
+	for _, entry := range got {
+		messages = append(messages, entry.Message)
+	}
+	if fmt.Sprint(messages) != "[one two three skewed four five]" {
+		t.Errorf("messages = %v, want [one two three skewed four five]", messages)
+	}
+	if got[0].Service != "api" || got[0].Timestamp != "2024-01-01T00:00:01.000Z" || got[0].Level != LevelInfo {
+		t.Errorf("first entry = %+v, want the stored service, timestamp and level", got[0])
+	}
+
+	q := queries()
+	if len(q) != 2 {
+		t.Fatalf("connections = %d, want 2", len(q))
+	}
+	if q[0] != "level=info&service=api" {
+		t.Errorf("first query = %q, want the filters only", q[0])
+	}
+	if q[1] != "level=info&service=api&since=2024-01-01T00%3A00%3A02Z" {
+		t.Errorf("resumed query = %q, want since the last log", q[1])
+	}
+}
+
+// TestClientTailErrors tests that a rejected API key ends the tail and
+// that canceling ctx stops it.
+func TestClientTailErrors(t *testing.T) {
+	t.Run("unauthorized", func(t *testing.T) {
+		var requests int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized","message":"Invalid API key"}`))
+		}))
+		defer srv.Close()
+
+		client, err := New(srv.URL, validAPIKey())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		err = client.Tail(context.Background(), TailParams{}, func(LogEntry) error { return nil })
+		var logwellErr *Error
+		if !errors.As(err, &logwellErr) || logwellErr.Code != ErrUnauthorized {
+			t.Fatalf("Tail() error = %v, want %s", err, ErrUnauthorized)
+		}
+		if got := atomic.LoadInt32(&requests); got != 1 {
+			t.Errorf("requests = %d, want 1", got)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		srv, _ := newTailServer(t, nil)
+		client, err := New(srv.URL, validAPIKey())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err = client.Tail(ctx, TailParams{}, func(LogEntry) error { return nil })
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Tail() error = %v, want the context's error", err)
+		}
+	})
+}
+
+// TestClientTailBackfillLimit tests that a resume backfill reaching the
+// server's limit is reported as ErrTailGap and still passed on.
+func TestClientTailBackfillLimit(t *testing.T) {
+	stored := make([]string, tailBackfillLimit)
+	for i := range stored {
+		stored[i] = tailLog(fmt.Sprintf("id-%d", i), 10+i%50, fmt.Sprintf("stored %d", i))
+	}
+	srv, _ := newTailServer(t, [][]string{
+		{"event: backfill\ndata: [" + strings.Join(stored, ",") + "]\n\n"},
+	})
+
+	var (
+		mu       sync.Mutex
+		reported []*Error
+	)
+	client, err := New(srv.URL, validAPIKey(), WithOnError(func(err *Error) {
+		mu.Lock()
+		reported = append(reported, err)
+		mu.Unlock()
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	received := 0
+	errDone := errors.New("done")
+	err = client.Tail(ctx, TailParams{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, func(LogEntry) error {
+		if received++; received == tailBackfillLimit {
+			return errDone
+		}
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("Tail() error = %v, want the callback's error", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 1 || reported[0].Code != ErrTailGap {
+		t.Fatalf("OnError calls = %v, want one %s", reported, ErrTailGap)
+	}
+}
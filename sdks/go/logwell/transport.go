@@ -2,12 +2,24 @@ package logwell
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	crand "crypto/rand"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -16,15 +28,262 @@ const (
 	baseRetryDelay    = 100 * time.Millisecond
 	maxRetryDelay     = 10 * time.Second
 	jitterFactor      = 0.3 // 30% jitter
+
+	// dnsBackoffFactor lengthens the backoff after a DNS failure, which is
+	// unlikely to clear as quickly as a dropped connection.
+	dnsBackoffFactor = 4
+
+	// maxResponseBodySize caps how much of a response body is read.
+	// Ingest responses are small JSON objects.
+	maxResponseBodySize = 64 << 10
+
+	// maxPooledBufferSize is the largest buffer returned to bufferPool,
+	// so one oversized batch does not pin its memory in the pool.
+	maxPooledBufferSize = 4 << 20
+
+	// apiKeyHeader carries the API key when basic auth occupies the
+	// Authorization header.
+	apiKeyHeader = "X-Logwell-Authorization"
+
+	// requestIDHeader is the response header copied into Error.RequestID.
+	requestIDHeader = "X-Request-Id"
+
+	// clientIDHeader and batchSeqHeader identify each batch, so the
+	// server can spot gaps in a client's sequence of batches.
+	clientIDHeader = "X-Client-ID"
+	batchSeqHeader = "X-Batch-Seq"
+
+	// redactedValue replaces credential headers passed to the debug hook.
+	redactedValue = "[REDACTED]"
+
+	// defaultStreamThreshold is the estimated encoded size, in bytes,
+	// above which the default encoder streams a batch instead of
+	// buffering all of it.
+	defaultStreamThreshold = 1 << 20
+
+	// streamChunkSize is how much encoded JSON a streamed body buffers
+	// before writing it to the request.
+	streamChunkSize = 32 << 10
 )
 
-// httpTransport sends log batches to the Logwell server.
+// pooledBuffer is a reusable buffer with a JSON encoder writing into it.
+type pooledBuffer struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// bufferPool holds buffers for request and response bodies.
+var bufferPool = sync.Pool{
+	New: func() any {
+		pb := &pooledBuffer{}
+		pb.enc = json.NewEncoder(&pb.buf)
+		return pb
+	},
+}
+
+// getBuffer returns an empty buffer from bufferPool.
+func getBuffer() *pooledBuffer {
+	pb := bufferPool.Get().(*pooledBuffer)
+	pb.buf.Reset()
+	return pb
+}
+
+// putBuffer resets pb and returns it to bufferPool.
+// Oversized buffers are dropped instead.
+func putBuffer(pb *pooledBuffer) {
+	if pb.buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	pb.buf.Reset()
+	bufferPool.Put(pb)
+}
+
+// pooledBody streams a request body from a pooled buffer and returns the
+// buffer to the pool when the HTTP transport closes the body, which may
+// happen after Client.Do returns.
+type pooledBody struct {
+	*bytes.Reader
+	pb   *pooledBuffer
+	once sync.Once
+}
+
+// Close returns the buffer, if any, to the pool.
+// It is safe to call more than once.
+func (b *pooledBody) Close() error {
+	b.once.Do(func() {
+		if b.pb != nil {
+			putBuffer(b.pb)
+		}
+	})
+	return nil
+}
+
+// streamBody encodes a batch into the request body while the HTTP
+// transport reads it, so memory use stays bounded whatever the batch size.
+// The body is sent with chunked transfer encoding.
+type streamBody struct {
+	*io.PipeReader
+	done chan struct{}
+	err  error // encoding error; valid once done is closed
+}
+
+// newStreamBody starts encoding logs as an ingest request into the
+// returned body, compressed with c unless it is nil. The encoding goroutine
+// exits when the body is fully written or closed.
+func newStreamBody(logs []LogEntry, c Compressor) *streamBody {
+	pr, pw := io.Pipe()
+	b := &streamBody{PipeReader: pr, done: make(chan struct{})}
+
+	go func() {
+		defer close(b.done)
+		pb := getBuffer()
+		defer putBuffer(pb)
+
+		var w io.Writer = pw
+		var zw io.WriteCloser
+		if c != nil {
+			zw = c.NewWriter(pw)
+			defer func() {
+				if zw != nil {
+					zw.Close()
+				}
+			}()
+			w = zw
+		}
+
+		pb.buf.WriteString(`{"logs":[`)
+		for i := range logs {
+			if i > 0 {
+				pb.buf.WriteByte(',')
+			}
+			// Encode marshals fully before appending, so a failed entry
+			// leaves nothing partial in the buffer
+			if err := pb.enc.Encode(logs[i]); err != nil {
+				b.err = err
+				pw.CloseWithError(err)
+				return
+			}
+			if pb.buf.Len() >= streamChunkSize {
+				if _, err := w.Write(pb.buf.Bytes()); err != nil {
+					return // The request ended; Do reports why
+				}
+				pb.buf.Reset()
+			}
+		}
+		pb.buf.WriteString("]}")
+		if _, err := w.Write(pb.buf.Bytes()); err != nil {
+			return
+		}
+		if zw != nil {
+			err := zw.Close()
+			zw = nil
+			if err != nil {
+				return
+			}
+		}
+		pw.Close()
+	}()
+	return b
+}
+
+// wait closes the body, waits for the encoding goroutine to exit and
+// returns the error that stopped it from encoding an entry, if any.
+func (b *streamBody) wait() error {
+	b.PipeReader.Close()
+	<-b.done
+	return b.err
+}
+
+// httpTransport sends log batches to the Logwell server. A client's
+// children share its transport, and with it all the delivery state kept
+// here, such as the endpoint router, the compression ladder and the
+// dead-letter callback.
 type httpTransport struct {
 	endpoint   string
 	apiKey     string
 	httpClient *http.Client
 	ingestURL  string
+	queryURL   string
+	tailURL    string
 	maxRetries int
+
+	// keyFile, when set, supplies the API key in place of apiKey.
+	keyFile *apiKeyFile
+
+	// tokenProvider, when set, supplies the bearer token for each request
+	// in place of apiKey and keyFile.
+	tokenProvider func(context.Context) (string, error)
+
+	// basicUser and basicPass, when basicUser is set, are sent as Basic
+	// credentials and the bearer token moves to apiKeyHeader.
+	basicUser string
+	basicPass string
+
+	// deliveryMode controls whether ambiguous failures are retried.
+	deliveryMode DeliveryMode
+
+	// onRetry, when set, is called before each retry.
+	onRetry func(RetryInfo)
+
+	// debug, when set, is called after each request with its outcome.
+	debug func(*http.Request, *http.Response, []byte, error)
+
+	// validateResponse, when set, rejects 2xx ingest responses.
+	validateResponse func(status int, body []byte) error
+
+	// conns, when set, counts the connections requests are sent on.
+	conns *connMetrics
+
+	// entryAgeLimit, when positive, abandons retries of a batch holding
+	// entries queued longer ago than this.
+	entryAgeLimit time.Duration
+
+	// levelAliases, when set, maps levels to the names sent for them.
+	levelAliases map[LogLevel]string
+
+	// encode marshals request bodies. When nil, bodies are encoded with
+	// encoding/json into pooled buffers. Responses always use encoding/json.
+	encode func(any) ([]byte, error)
+
+	// streamThreshold is the estimated encoded size, in bytes, above
+	// which the default encoder streams the body; see streamBody. Zero
+	// disables streaming.
+	streamThreshold int64
+
+	// compression, when set, holds the encodings request bodies are
+	// compressed with. Bodies are compressed from compressThreshold
+	// encoded bytes; streamed bodies, whose size is not known up front,
+	// are always compressed.
+	compression       *compressionLadder
+	compressThreshold int
+
+	// fallback, when set, writes failed batches locally during outages.
+	fallback *fallbackWriter
+
+	// dryRun, when set, accepts batches in place of the server.
+	dryRun *dryRun
+
+	// offline, when set, pauses retries while the endpoint is unreachable.
+	offline *offlineDetector
+
+	// retryBudget, when set, caps retries across all batches per window.
+	retryBudget *retryBudget
+
+	// deadLetter, when set, receives entries abandoned for good.
+	deadLetter *deadLetter
+
+	// snapshot, when set, keeps entries left undelivered at shutdown.
+	snapshot *shutdownSnapshot
+
+	// router, when set, picks the endpoint of each send attempt in place
+	// of ingestURL.
+	router endpointRouter
+
+	// clientID is a random UUID sent with every batch, and batchSeq
+	// numbers the batches, starting at 1. Retries of a batch keep its
+	// number.
+	clientID string
+	batchSeq atomic.Uint64
 }
 
 // newHTTPTransport creates a new HTTP transport.
@@ -32,30 +291,153 @@ func newHTTPTransport(endpoint, apiKey string) *httpTransport {
 	return &httpTransport{
 		endpoint:   endpoint,
 		apiKey:     apiKey,
-		httpClient: &http.Client{},
+		httpClient: newTransportHTTPClient(newDefaultConfig(endpoint, apiKey)),
 		ingestURL:  endpoint + "/v1/ingest",
+		queryURL:   endpoint + "/v1/logs",
+		tailURL:    endpoint + "/v1/logs/stream",
 		maxRetries: defaultMaxRetries,
+		clientID:   newClientID(),
+
+		streamThreshold: defaultStreamThreshold,
+	}
+}
+
+// newConfiguredTransport creates a transport with the credentials, HTTP
+// client and hooks of cfg. Batch delivery settings are left to New.
+func newConfiguredTransport(endpoint string, cfg *Config, keyFile *apiKeyFile) *httpTransport {
+	transport := newHTTPTransport(endpoint, cfg.APIKey)
+	transport.keyFile = keyFile
+	transport.tokenProvider = cfg.TokenProvider
+	transport.basicUser = cfg.BasicAuthUser
+	transport.basicPass = cfg.BasicAuthPassword
+	transport.httpClient = newTransportHTTPClient(cfg)
+	transport.onRetry = cfg.OnRetry
+	transport.debug = cfg.TransportDebug
+	transport.validateResponse = cfg.ResponseValidator
+	transport.levelAliases = cfg.LevelAliases
+	if cfg.ConnectionMetrics {
+		transport.conns = &connMetrics{}
+	}
+	return transport
+}
+
+// aliasLevels returns logs with their levels replaced by their names in
+// aliases. logs is returned as is when no level is aliased, and is never
+// modified, since the caller keeps the entries for retries and callbacks.
+func aliasLevels(logs []LogEntry, aliases map[LogLevel]string) []LogEntry {
+	if len(aliases) == 0 {
+		return logs
+	}
+	var aliased []LogEntry
+	for i := range logs {
+		alias, ok := aliases[logs[i].Level]
+		if !ok {
+			continue
+		}
+		if aliased == nil {
+			aliased = make([]LogEntry, len(logs))
+			copy(aliased, logs)
+		}
+		aliased[i].Level = LogLevel(alias)
+	}
+	if aliased == nil {
+		return logs
+	}
+	return aliased
+}
+
+// isDefaultEncoder reports whether encode is json.Marshal, which the
+// transport replaces with its pooled encoder.
+func isDefaultEncoder(encode func(any) ([]byte, error)) bool {
+	return reflect.ValueOf(encode).Pointer() == reflect.ValueOf(json.Marshal).Pointer()
+}
+
+// newTransportHTTPClient returns the HTTP client the transport should use.
+// A custom client from WithHTTPClient is used as-is. Otherwise a dedicated
+// client is created, tuned for sending batches to one host and with the
+// TLS and timeout settings from cfg applied.
+func newTransportHTTPClient(cfg *Config) *http.Client {
+	if cfg.HTTPClient != nil && cfg.HTTPClient != http.DefaultClient {
+		return cfg.HTTPClient
 	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	transport.TLSHandshakeTimeout = DefaultTLSHandshakeTimeout
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+	// Keep a connection for every batch that may be in flight, so
+	// concurrent flushes do not dial anew each time
+	transport.MaxIdleConnsPerHost = max(cfg.MaxConcurrentFlushes, http.DefaultMaxIdleConnsPerHost)
+
+	if cfg.InsecureSkipVerify {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	transport.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+
+	return &http.Client{Transport: transport, Timeout: cfg.RequestTimeout}
 }
 
 // sendWithRetry sends a batch with exponential backoff retry for transient errors.
 // Network errors, 5xx, and 429 are retried. 400, 401, 403 are not.
 func (t *httpTransport) sendWithRetry(ctx context.Context, logs []LogEntry) (*IngestResponse, error) {
+	return t.sendWithRetryUntil(ctx, nil, logs)
+}
+
+// sendWithRetryUntil is sendWithRetry with a stop channel that, once
+// closed, ends any backoff wait with an error marked stopped instead of
+// retrying. A request already being sent is not interrupted.
+func (t *httpTransport) sendWithRetryUntil(ctx context.Context, stop <-chan struct{}, logs []LogEntry) (*IngestResponse, error) {
 	var lastErr error
+	seq := t.batchSeq.Add(1)
+	ep := t.firstEndpoint()
 
 	for attempt := 0; attempt <= t.maxRetries; attempt++ {
 		// Wait before retry (skip on first attempt)
 		if attempt > 0 {
 			delay := t.calculateBackoff(attempt)
+			if errorKind(lastErr) == NetworkDNS {
+				delay = min(delay*dnsBackoffFactor, maxRetryDelay)
+			}
+			if t.onRetry != nil {
+				t.onRetry(RetryInfo{Attempt: attempt, Err: lastErr, Delay: delay, BatchSize: len(logs)})
+			}
 			select {
 			case <-ctx.Done():
-				return nil, NewErrorWithCause(ErrNetworkError, "context canceled during retry", ctx.Err())
+				return nil, newNetworkError("context canceled during retry", ctx.Err())
+			case <-stop:
+				stoppedErr := NewErrorWithCause(ErrNetworkError, "retry stopped by shutdown", lastErr)
+				stoppedErr.Kind = errorKind(lastErr)
+				stoppedErr.stopped = true
+				return nil, stoppedErr
 			case <-time.After(delay):
 				// Continue with retry
 			}
+			// Each retry moves on to the next endpoint, if any
+			if t.router != nil {
+				ep = t.router.next(ep)
+			}
 		}
 
-		resp, err := t.send(ctx, logs)
+		resp, err := t.sendBatch(ctx, ep, seq, logs)
+		if err != nil && t.keyFile != nil && isUnauthorizedError(err) && t.keyFile.reload() {
+			// The key file was rotated; retry at once with the new key
+			resp, err = t.sendBatch(ctx, ep, seq, logs)
+		}
+		for err != nil && isEncodingRejectedError(err) {
+			// Retry at once with the next encoding down; the ladder ends
+			// in uncompressed bodies, so this stops
+			resp, err = t.sendBatch(ctx, ep, seq, logs)
+		}
+		if ctx.Err() == nil {
+			t.offline.record(err)
+			t.recordEndpoint(ep, err)
+		}
 		if err == nil {
 			return resp, nil
 		}
@@ -67,9 +449,37 @@ func (t *httpTransport) sendWithRetry(ctx context.Context, logs []LogEntry) (*In
 			return nil, err
 		}
 
+		// In at-most-once mode, a failure after the request was fully
+		// written may mean the server already stored the batch
+		if t.deliveryMode == AtMostOnce && isAmbiguousError(err) {
+			return nil, err
+		}
+
 		// Context canceled - don't retry
 		if ctx.Err() != nil {
-			return nil, NewErrorWithCause(ErrNetworkError, "context canceled", ctx.Err())
+			return nil, newNetworkError("context canceled", ctx.Err())
+		}
+
+		// No retries while the endpoint is unreachable
+		if t.offline.isOffline() {
+			offlineErr := NewErrorWithCause(ErrNetworkError, "endpoint unreachable, buffering logs until it recovers", err)
+			offlineErr.Kind = errorKind(err)
+			offlineErr.offline = true
+			return nil, offlineErr
+		}
+
+		// Give up on entries that have waited too long rather than
+		// retrying them indefinitely
+		if t.entryAgeLimit > 0 && oldestEntryAge(logs) >= t.entryAgeLimit {
+			expiredErr := NewErrorWithCause(ErrNetworkError, "giving up on logs older than the max entry age", err)
+			expiredErr.Kind = errorKind(err)
+			expiredErr.expired = true
+			return nil, expiredErr
+		}
+
+		// Fail fast once retries across all batches use up the budget
+		if attempt < t.maxRetries && !t.retryBudget.take() {
+			return nil, err
 		}
 	}
 
@@ -77,6 +487,68 @@ func (t *httpTransport) sendWithRetry(ctx context.Context, logs []LogEntry) (*In
 	return nil, lastErr
 }
 
+// connMetrics counts the connections requests are sent on, new or reused.
+type connMetrics struct {
+	dialed atomic.Int64
+	reused atomic.Int64
+}
+
+// gotConn records the connection of one request, from httptrace.
+func (m *connMetrics) gotConn(info httptrace.GotConnInfo) {
+	if info.Reused {
+		m.reused.Add(1)
+	} else {
+		m.dialed.Add(1)
+	}
+}
+
+// counts returns the new and reused connection counts. It returns zeros
+// on a nil receiver.
+func (m *connMetrics) counts() (dialed, reused int64) {
+	if m == nil {
+		return 0, 0
+	}
+	return m.dialed.Load(), m.reused.Load()
+}
+
+// retryBudget caps the retries made across all batches in each window, so
+// a flapping server does not see every batch retried in full. The window
+// starts with the first retry after the previous one ended.
+type retryBudget struct {
+	max    int
+	window time.Duration
+
+	mu    sync.Mutex
+	start time.Time
+	used  int
+}
+
+// newRetryBudget creates a budget of maxRetries retries per window.
+func newRetryBudget(maxRetries int, window time.Duration) *retryBudget {
+	return &retryBudget{max: maxRetries, window: window}
+}
+
+// take uses one retry from the budget and reports whether one was left.
+// It always succeeds on a nil receiver.
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := clockNow()
+	if now.Sub(b.start) >= b.window {
+		b.start = now
+		b.used = 0
+	}
+	if b.used >= b.max {
+		return false
+	}
+	b.used++
+	return true
+}
+
 // calculateBackoff computes delay with exponential backoff + jitter.
 // Formula: min(baseDelay * 2^attempt, maxDelay) + 30% jitter
 func (t *httpTransport) calculateBackoff(attempt int) time.Duration {
@@ -132,53 +604,407 @@ func (t *httpTransport) isRetryableError(err error) bool {
 	}
 }
 
-// send sends a batch of log entries to the Logwell server.
-// Returns IngestResponse on success, or an Error on failure.
+// send sends a batch of log entries to the Logwell server once, as a new
+// batch. Returns IngestResponse on success, or an Error on failure.
 func (t *httpTransport) send(ctx context.Context, logs []LogEntry) (*IngestResponse, error) {
-	// Build request body
-	reqBody := ingestRequest{Logs: logs}
-	bodyBytes, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, NewErrorWithCause(ErrValidationError, "failed to marshal logs", err)
+	return t.sendBatch(ctx, t.firstEndpoint(), t.batchSeq.Add(1), logs)
+}
+
+// firstEndpoint returns the endpoint of a batch's first attempt: the
+// router's pick, or 0 without a router.
+func (t *httpTransport) firstEndpoint() int {
+	if t.router == nil {
+		return 0
+	}
+	return t.router.first()
+}
+
+// recordEndpoint counts the outcome of one send to endpoint ep and passes
+// it to the router. It is a no-op without a router.
+func (t *httpTransport) recordEndpoint(ep int, err error) {
+	if t.router == nil {
+		return
+	}
+	if e := t.router.all()[ep]; err == nil {
+		e.sent.Add(1)
+	} else {
+		e.failed.Add(1)
+	}
+	t.router.record(ep, err, err != nil && t.isRetryableError(err))
+}
+
+// sendBatch sends logs once as batch number seq to endpoint ep of the
+// router, or to the endpoint without one.
+func (t *httpTransport) sendBatch(ctx context.Context, ep int, seq uint64, logs []LogEntry) (*IngestResponse, error) {
+	logs = aliasLevels(logs, t.levelAliases)
+	if t.dryRun != nil {
+		return t.dryRun.send(logs)
 	}
 
+	ingestURL := t.ingestURL
+	var token string
+	if t.router != nil {
+		endpoint := t.router.all()[ep]
+		ingestURL, token = endpoint.ingestURL, endpoint.apiKey
+	}
+	if token == "" {
+		var err error
+		if token, err = t.bearerToken(ctx); err != nil {
+			return nil, NewErrorWithCause(ErrUnauthorized, "token provider failed", err)
+		}
+	}
+
+	// Large batches from the default encoder are streamed; everything else
+	// is encoded up front so the length is known
+	var (
+		body          io.ReadCloser
+		contentLength int64
+		stream        *streamBody
+		compressor    Compressor
+	)
+	available, level := t.compression.current()
+	if t.encode == nil && t.streamThreshold > 0 && estimatedSizeOver(logs, t.streamThreshold) {
+		compressor = available
+		stream = newStreamBody(logs, compressor)
+		defer stream.wait()
+		body, contentLength = stream, -1
+	} else {
+		pooled, err := t.encodeBody(ingestRequest{Logs: logs})
+		if err != nil {
+			return nil, NewErrorWithCause(ErrValidationError, "failed to marshal logs", err)
+		}
+		// Small bodies are not worth the CPU and latency of compressing
+		if available != nil && pooled.Len() >= t.compressThreshold {
+			compressor = available
+			pooled = compressBody(pooled, compressor)
+		}
+		body, contentLength = pooled, int64(pooled.Len())
+	}
+
+	// Track whether the full request was written, so failures after that
+	// point can be told apart from failures before the server saw anything
+	var requestWritten atomic.Bool
+	trace := &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			requestWritten.Store(info.Err == nil)
+		},
+	}
+	if t.conns != nil {
+		trace.GotConn = t.conns.gotConn
+	}
+	ctx = httptrace.WithClientTrace(ctx, trace)
+
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.ingestURL, bytes.NewReader(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ingestURL, body)
 	if err != nil {
+		body.Close()
 		return nil, NewErrorWithCause(ErrNetworkError, "failed to create request", err)
 	}
+	// GetBody is left unset: the pooled buffer may be reused once the
+	// transport closes the body, so failed requests are retried by
+	// sendWithRetry instead of being replayed by net/http
+	req.ContentLength = contentLength
 
-	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	t.authorize(req, token)
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(clientIDHeader, t.clientID)
+	req.Header.Set(batchSeqHeader, strconv.FormatUint(seq, 10))
+	if compressor != nil {
+		req.Header.Set("Content-Encoding", compressor.ContentEncoding())
+	}
 
 	// Execute request
 	resp, err := t.httpClient.Do(req)
 	if err != nil {
-		return nil, NewErrorWithCause(ErrNetworkError, "request failed", err)
+		if stream != nil {
+			if encodeErr := stream.wait(); encodeErr != nil {
+				return nil, NewErrorWithCause(ErrValidationError, "failed to marshal logs", encodeErr)
+			}
+		}
+		netErr := newNetworkError("request failed", err)
+		netErr.ambiguous = requestWritten.Load()
+		t.debugExchange(req, nil, nil, netErr)
+		return nil, netErr
 	}
 	defer resp.Body.Close()
 
-	// Read response body
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, NewErrorWithCause(ErrNetworkError, "failed to read response", err)
+	// Read response body, which is only needed until this method returns.
+	// net/http only decompresses gzip it asked for itself, so a server
+	// that compresses regardless, or a custom HTTPClient with compression
+	// disabled, is handled here. The size cap applies after decompression.
+	var respReader io.Reader = resp.Body
+	if !resp.Uncompressed && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		switch {
+		case err == io.EOF:
+			respReader = http.NoBody // Empty body despite the header
+		case err != nil:
+			netErr := newNetworkError("failed to decompress response", err)
+			netErr.ambiguous = true
+			t.debugExchange(req, resp, nil, netErr)
+			return nil, netErr
+		default:
+			defer gz.Close()
+			respReader = gz
+		}
+	}
+	respBuf := getBuffer()
+	defer putBuffer(respBuf)
+	if _, err := respBuf.buf.ReadFrom(io.LimitReader(respReader, maxResponseBodySize)); err != nil {
+		netErr := newNetworkError("failed to read response", err)
+		netErr.ambiguous = true
+		t.debugExchange(req, resp, nil, netErr)
+		return nil, netErr
 	}
+	respBody := respBuf.buf.Bytes()
 
 	// Handle error responses
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		errorMsg := t.parseErrorMessage(respBody, resp.StatusCode)
-		return nil, t.createError(resp.StatusCode, errorMsg)
+		apiErr := t.createError(resp.StatusCode, errorMsg)
+		apiErr.RequestID = resp.Header.Get(requestIDHeader)
+		if resp.StatusCode == http.StatusUnsupportedMediaType && compressor != nil {
+			// The server does not accept this encoding; later requests
+			// use the next one down
+			t.compression.reject(level)
+			apiErr.encodingRejected = true
+		}
+		t.debugExchange(req, resp, respBody, apiErr)
+		return nil, apiErr
 	}
 
-	// Parse successful response
+	if t.validateResponse != nil {
+		if err := t.validateResponse(resp.StatusCode, respBody); err != nil {
+			invalidErr := NewErrorWithCause(ErrServerError, "response rejected by validator", err)
+			invalidErr.StatusCode = resp.StatusCode
+			invalidErr.RequestID = resp.Header.Get(requestIDHeader)
+			invalidErr.ambiguous = true
+			t.debugExchange(req, resp, respBody, invalidErr)
+			return nil, invalidErr
+		}
+	}
+
+	// Parse successful response. Some deployments answer 204 or an empty
+	// 200; the whole batch was accepted then
 	var ingestResp IngestResponse
+	if len(bytes.TrimSpace(respBody)) == 0 {
+		ingestResp.Accepted = len(logs)
+		t.debugExchange(req, resp, respBody, nil)
+		return &ingestResp, nil
+	}
 	if err := json.Unmarshal(respBody, &ingestResp); err != nil {
-		return nil, NewErrorWithCause(ErrServerError, "failed to parse response", err)
+		parseErr := NewErrorWithCause(ErrServerError, "failed to parse response", err)
+		t.debugExchange(req, resp, respBody, parseErr)
+		return nil, parseErr
 	}
 
+	t.debugExchange(req, resp, respBody, nil)
 	return &ingestResp, nil
 }
 
+// debugExchange passes a finished request to the debug hook, if set, with
+// credentials redacted and the response body copied out of its pooled buffer.
+func (t *httpTransport) debugExchange(req *http.Request, resp *http.Response, body []byte, err error) {
+	if t.debug == nil {
+		return
+	}
+
+	redacted := req.Clone(req.Context())
+	redacted.Body = http.NoBody
+	for _, header := range []string{"Authorization", apiKeyHeader} {
+		if redacted.Header.Get(header) != "" {
+			redacted.Header.Set(header, redactedValue)
+		}
+	}
+	t.debug(redacted, resp, bytes.Clone(body), err)
+}
+
+// encodeBody encodes req into a request body. The default encoder writes
+// into a pooled buffer that is released when the body is closed; a custom
+// encoder's output is used directly.
+func (t *httpTransport) encodeBody(req ingestRequest) (*pooledBody, error) {
+	if t.encode != nil {
+		data, err := t.encode(req)
+		if err != nil {
+			return nil, err
+		}
+		return &pooledBody{Reader: bytes.NewReader(data)}, nil
+	}
+
+	pb := getBuffer()
+	if err := pb.enc.Encode(req); err != nil {
+		putBuffer(pb)
+		return nil, err
+	}
+	return &pooledBody{Reader: bytes.NewReader(pb.buf.Bytes()), pb: pb}, nil
+}
+
+// compressBody compresses body with c into a pooled buffer and closes body.
+func compressBody(body *pooledBody, c Compressor) *pooledBody {
+	defer body.Close()
+
+	pb := getBuffer()
+	zw := c.NewWriter(&pb.buf)
+	// Writes to a bytes.Buffer cannot fail
+	body.WriteTo(zw)
+	zw.Close()
+	return &pooledBody{Reader: bytes.NewReader(pb.buf.Bytes()), pb: pb}
+}
+
+// newClientID returns a random version 4 UUID.
+func newClientID() string {
+	var b [16]byte
+	// crypto/rand.Read does not fail on supported platforms
+	crand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// bearerToken returns the token for the Authorization header: from the
+// token provider if set, else from the key file if set, else the API key.
+func (t *httpTransport) bearerToken(ctx context.Context) (string, error) {
+	if t.tokenProvider != nil {
+		return t.tokenProvider(ctx)
+	}
+	if t.keyFile != nil {
+		return t.keyFile.current(), nil
+	}
+	return t.apiKey, nil
+}
+
+// authorize sets the credentials of req: token as a bearer token, moved to
+// apiKeyHeader when Basic credentials are set.
+func (t *httpTransport) authorize(req *http.Request, token string) {
+	if t.basicUser != "" {
+		req.SetBasicAuth(t.basicUser, t.basicPass)
+		req.Header.Set(apiKeyHeader, "Bearer "+token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// isUnauthorizedError reports whether err is a 401 response from the server.
+func isUnauthorizedError(err error) bool {
+	var logwellErr *Error
+	return errors.As(err, &logwellErr) && logwellErr.StatusCode == http.StatusUnauthorized
+}
+
+// isPayloadTooLarge reports whether err is a 413 response from the server.
+func isPayloadTooLarge(err error) bool {
+	var logwellErr *Error
+	return errors.As(err, &logwellErr) && logwellErr.StatusCode == http.StatusRequestEntityTooLarge
+}
+
+// isTimeoutError reports whether err was caused by a network timeout.
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// newNetworkError creates an ErrNetworkError wrapping cause, with Kind set
+// from the cause.
+func newNetworkError(message string, cause error) *Error {
+	netErr := NewErrorWithCause(ErrNetworkError, message, cause)
+	netErr.Kind = classifyNetworkError(cause)
+	return netErr
+}
+
+// classifyNetworkError returns the kind of network failure err represents,
+// or "" if it is not recognized. A DNS lookup that timed out counts as a
+// timeout, since it says nothing about whether the name exists.
+func classifyNetworkError(err error) ErrorKind {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr) && !dnsErr.IsTimeout:
+		return NetworkDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return NetworkRefused
+	case errors.Is(err, context.DeadlineExceeded) || isTimeoutError(err):
+		return NetworkTimeout
+	default:
+		return ""
+	}
+}
+
+// errorKind returns the Kind of err if it is an *Error, else "".
+func errorKind(err error) ErrorKind {
+	var logwellErr *Error
+	if errors.As(err, &logwellErr) {
+		return logwellErr.Kind
+	}
+	return ""
+}
+
+// estimatedSizeOver reports whether the estimated encoded size of logs is
+// over limit bytes, estimating no more entries than it needs to.
+func estimatedSizeOver(logs []LogEntry, limit int64) bool {
+	var size int64
+	for i := range logs {
+		if size += entrySize(&logs[i]); size > limit {
+			return true
+		}
+	}
+	return false
+}
+
+// oldestEntryAge returns the age of the oldest entry in logs that has an
+// enqueue time, or zero if none has one.
+func oldestEntryAge(logs []LogEntry) time.Duration {
+	var oldest time.Time
+	for _, entry := range logs {
+		if !entry.enqueuedAt.IsZero() && (oldest.IsZero() || entry.enqueuedAt.Before(oldest)) {
+			oldest = entry.enqueuedAt
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+	return clockNow().Sub(oldest)
+}
+
+// earliestDeadline returns the earliest deadline recorded on logs that has
+// not passed yet. Deadlines already passed are ignored: their requests are
+// over, and bounding the send by them would only lose the logs.
+func earliestDeadline(logs []LogEntry) (time.Time, bool) {
+	now := clockNow()
+	var earliest time.Time
+	for _, entry := range logs {
+		if entry.deadline.After(now) && (earliest.IsZero() || entry.deadline.Before(earliest)) {
+			earliest = entry.deadline
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+// isStoppedError reports whether err ended a retry backoff because the
+// client is shutting down.
+func isStoppedError(err error) bool {
+	logwellErr, ok := err.(*Error)
+	return ok && logwellErr.stopped
+}
+
+// isExpiredError reports whether err abandoned a batch whose entries
+// exceeded the max entry age limit.
+func isExpiredError(err error) bool {
+	logwellErr, ok := err.(*Error)
+	return ok && logwellErr.expired
+}
+
+// isAmbiguousError reports whether err is a network failure that happened
+// after the request was fully written, when the server may have stored the batch.
+func isAmbiguousError(err error) bool {
+	logwellErr, ok := err.(*Error)
+	return ok && logwellErr.ambiguous
+}
+
+// isEncodingRejectedError reports whether err is a 415 response to a
+// compressed body.
+func isEncodingRejectedError(err error) bool {
+	logwellErr, ok := err.(*Error)
+	return ok && logwellErr.encodingRejected
+}
+
 // parseErrorMessage tries to extract an error message from the response body.
 func (t *httpTransport) parseErrorMessage(body []byte, statusCode int) string {
 	var errResp struct {
@@ -0,0 +1,205 @@
+package logwell_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+	"github.com/Divkix/Logwell/sdks/go/logwelltest"
+)
+
+// sendOne logs one entry on a client for server and flushes it, returning
+// the flush error.
+func sendOne(t *testing.T, server *logwelltest.Server, opts ...logwell.Option) error {
+	t.Helper()
+
+	client := newClient(t, server.URL, append(opts, logwell.WithFlushInterval(time.Minute))...)
+	client.Info("test message")
+	return client.Flush(context.Background())
+}
+
+// TestTransport_SuccessfulRequest tests that a 200 response succeeds without retry.
+func TestTransport_SuccessfulRequest(t *testing.T) {
+	server := logwelltest.NewServer(logwelltest.WithAPIKey(recorderAPIKey))
+	defer server.Close()
+
+	if err := sendOne(t, server); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("requestCount = %d, want 1", len(requests))
+	}
+	if got := requests[0].Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type header = %q, want %q", got, "application/json")
+	}
+	if len(requests[0].Logs) != 1 {
+		t.Errorf("len(Logs) = %d, want 1", len(requests[0].Logs))
+	}
+}
+
+// TestTransport_RetryOn5xx tests that 5xx errors trigger retries.
+func TestTransport_RetryOn5xx(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statusCode int
+	}{
+		{"500 Internal Server Error", http.StatusInternalServerError},
+		{"502 Bad Gateway", http.StatusBadGateway},
+		{"503 Service Unavailable", http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := logwelltest.NewServer()
+			defer server.Close()
+
+			// Fail first 2 attempts, succeed on third
+			server.FailNext(2, tc.statusCode)
+
+			if err := sendOne(t, server); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if got := len(server.Requests()); got != 3 {
+				t.Errorf("requestCount = %d, want 3", got)
+			}
+			if got := len(server.Entries()); got != 1 {
+				t.Errorf("accepted entries = %d, want 1", got)
+			}
+		})
+	}
+}
+
+// TestTransport_RetryOn429 tests that 429 (rate limited) triggers retries.
+func TestTransport_RetryOn429(t *testing.T) {
+	server := logwelltest.NewServer()
+	defer server.Close()
+
+	// Rate limit first 2 attempts
+	server.RateLimitNext(2, time.Second)
+
+	var retries []logwell.RetryInfo
+	err := sendOne(t, server, logwell.WithOnRetry(func(info logwell.RetryInfo) {
+		retries = append(retries, info)
+	}))
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := len(server.Requests()); got != 3 {
+		t.Errorf("requestCount = %d, want 3 (should retry on 429)", got)
+	}
+	if len(retries) != 2 || !errors.Is(retries[0].Err, logwell.ErrRateLimited) {
+		t.Errorf("retries = %+v, want 2 after rate limiting", retries)
+	}
+}
+
+// TestTransport_NoRetryOnClientError tests that 401 and 400 errors do NOT retry.
+func TestTransport_NoRetryOnClientError(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statusCode int
+		code       logwell.ErrorCode
+	}{
+		{"401 Unauthorized", http.StatusUnauthorized, logwell.ErrUnauthorized},
+		{"400 Bad Request", http.StatusBadRequest, logwell.ErrValidationError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := logwelltest.NewServer()
+			defer server.Close()
+			server.FailNext(1, tc.statusCode)
+
+			err := sendOne(t, server)
+
+			var logwellErr *logwell.Error
+			if !errors.As(err, &logwellErr) {
+				t.Fatalf("Flush() error = %v, want *Error", err)
+			}
+			if logwellErr.Code != tc.code {
+				t.Errorf("error code = %q, want %q", logwellErr.Code, tc.code)
+			}
+			if logwellErr.StatusCode != tc.statusCode {
+				t.Errorf("status code = %d, want %d", logwellErr.StatusCode, tc.statusCode)
+			}
+			if got := len(server.Requests()); got != 1 {
+				t.Errorf("requestCount = %d, want 1 (should NOT retry)", got)
+			}
+		})
+	}
+}
+
+// TestTransport_Latency tests that a slow server is bounded by the shutdown deadline.
+func TestTransport_Latency(t *testing.T) {
+	server := logwelltest.NewServer(logwelltest.WithLatency(time.Second))
+	defer server.Close()
+
+	client := newClient(t, server.URL, logwell.WithFlushInterval(time.Minute))
+	client.Info("slow")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := client.Shutdown(ctx); err == nil {
+		t.Error("Shutdown() error = nil, want deadline error")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Shutdown() took %v, want bounded by its deadline", elapsed)
+	}
+}
+
+// TestTransport_RetryBudget tests that retries stop across batches once
+// the shared retry budget is spent.
+func TestTransport_RetryBudget(t *testing.T) {
+	server := logwelltest.NewServer()
+	defer server.Close()
+	server.FailNext(100, http.StatusServiceUnavailable)
+
+	var retries int
+	client := newClient(t, server.URL,
+		logwell.WithFlushInterval(time.Minute),
+		logwell.WithRetryBudget(2, time.Minute),
+		logwell.WithOnRetry(func(logwell.RetryInfo) { retries++ }),
+	)
+
+	for i := 0; i < 3; i++ {
+		client.Info("test message")
+		if err := client.Flush(context.Background()); err == nil {
+			t.Fatalf("Flush() %d error = nil, want server error", i)
+		}
+	}
+
+	if retries != 2 {
+		t.Errorf("retries = %d, want 2 (the budget)", retries)
+	}
+	// The first batch retries twice; later batches fail on their first attempt
+	if got := len(server.Requests()); got != 5 {
+		t.Errorf("requestCount = %d, want 5", got)
+	}
+}
+
+// TestTransport_RetryBudgetMaxRetries tests that a retry budget with room
+// to spare still stops each batch at MaxRetries.
+func TestTransport_RetryBudgetMaxRetries(t *testing.T) {
+	server := logwelltest.NewServer()
+	defer server.Close()
+	server.FailNext(100, http.StatusServiceUnavailable)
+
+	client := newClient(t, server.URL,
+		logwell.WithFlushInterval(time.Minute),
+		logwell.WithMaxRetries(1),
+		logwell.WithRetryBudget(10, time.Minute),
+	)
+
+	client.Info("test message")
+	if err := client.Flush(context.Background()); err == nil {
+		t.Fatal("Flush() error = nil, want server error")
+	}
+	if got := len(server.Requests()); got != 2 {
+		t.Errorf("requestCount = %d, want 2 (the first attempt and one retry)", got)
+	}
+}
@@ -1,208 +1,25 @@
 package logwell
 
 import (
+	"compress/flate"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 )
 
-// TestTransport_SuccessfulRequest tests that a 200 response succeeds without retry.
-func TestTransport_SuccessfulRequest(t *testing.T) {
-	var requestCount int32
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		atomic.AddInt32(&requestCount, 1)
-
-		// Verify request headers
-		if got := r.Header.Get("Authorization"); got != "Bearer test-api-key" {
-			t.Errorf("Authorization header = %q, want %q", got, "Bearer test-api-key")
-		}
-		if got := r.Header.Get("Content-Type"); got != "application/json" {
-			t.Errorf("Content-Type header = %q, want %q", got, "application/json")
-		}
-
-		// Verify request body
-		var req ingestRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			t.Errorf("failed to decode request body: %v", err)
-		}
-		if len(req.Logs) != 1 {
-			t.Errorf("len(Logs) = %d, want 1", len(req.Logs))
-		}
-
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
-	}))
-	defer server.Close()
-
-	transport := newHTTPTransport(server.URL, "test-api-key")
-	logs := []LogEntry{{Level: LevelInfo, Message: "test message"}}
-
-	resp, err := transport.sendWithRetry(context.Background(), logs)
-	if err != nil {
-		t.Fatalf("sendWithRetry() error = %v", err)
-	}
-	if resp.Accepted != 1 {
-		t.Errorf("Accepted = %d, want 1", resp.Accepted)
-	}
-	if atomic.LoadInt32(&requestCount) != 1 {
-		t.Errorf("requestCount = %d, want 1", requestCount)
-	}
-}
-
-// TestTransport_RetryOn5xx tests that 5xx errors trigger retries.
-func TestTransport_RetryOn5xx(t *testing.T) {
-	testCases := []struct {
-		name       string
-		statusCode int
-	}{
-		{"500 Internal Server Error", http.StatusInternalServerError},
-		{"502 Bad Gateway", http.StatusBadGateway},
-		{"503 Service Unavailable", http.StatusServiceUnavailable},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			var requestCount int32
-
-			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				count := atomic.AddInt32(&requestCount, 1)
-
-				// Fail first 2 attempts, succeed on third
-				if count < 3 {
-					w.WriteHeader(tc.statusCode)
-					json.NewEncoder(w).Encode(map[string]string{"error": "server error"})
-					return
-				}
-
-				w.WriteHeader(http.StatusOK)
-				json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
-			}))
-			defer server.Close()
-
-			transport := newHTTPTransport(server.URL, "test-api-key")
-			logs := []LogEntry{{Level: LevelInfo, Message: "test"}}
-
-			resp, err := transport.sendWithRetry(context.Background(), logs)
-			if err != nil {
-				t.Fatalf("sendWithRetry() error = %v", err)
-			}
-			if resp.Accepted != 1 {
-				t.Errorf("Accepted = %d, want 1", resp.Accepted)
-			}
-			if atomic.LoadInt32(&requestCount) != 3 {
-				t.Errorf("requestCount = %d, want 3", requestCount)
-			}
-		})
-	}
-}
-
-// TestTransport_RetryOn429 tests that 429 (rate limited) triggers retries.
-func TestTransport_RetryOn429(t *testing.T) {
-	var requestCount int32
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		count := atomic.AddInt32(&requestCount, 1)
-
-		// Rate limit first 2 attempts
-		if count < 3 {
-			w.WriteHeader(http.StatusTooManyRequests)
-			json.NewEncoder(w).Encode(map[string]string{"error": "rate limited"})
-			return
-		}
-
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
-	}))
-	defer server.Close()
-
-	transport := newHTTPTransport(server.URL, "test-api-key")
-	logs := []LogEntry{{Level: LevelInfo, Message: "test"}}
-
-	resp, err := transport.sendWithRetry(context.Background(), logs)
-	if err != nil {
-		t.Fatalf("sendWithRetry() error = %v", err)
-	}
-	if resp.Accepted != 1 {
-		t.Errorf("Accepted = %d, want 1", resp.Accepted)
-	}
-	if atomic.LoadInt32(&requestCount) != 3 {
-		t.Errorf("requestCount = %d, want 3 (should retry on 429)", requestCount)
-	}
-}
-
-// TestTransport_NoRetryOn401 tests that 401 errors do NOT retry.
-func TestTransport_NoRetryOn401(t *testing.T) {
-	var requestCount int32
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		atomic.AddInt32(&requestCount, 1)
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
-	}))
-	defer server.Close()
-
-	transport := newHTTPTransport(server.URL, "test-api-key")
-	logs := []LogEntry{{Level: LevelInfo, Message: "test"}}
-
-	_, err := transport.sendWithRetry(context.Background(), logs)
-	if err == nil {
-		t.Fatal("sendWithRetry() expected error for 401, got nil")
-	}
-
-	logwellErr, ok := err.(*Error)
-	if !ok {
-		t.Fatalf("error type = %T, want *Error", err)
-	}
-	if logwellErr.Code != ErrUnauthorized {
-		t.Errorf("error code = %q, want %q", logwellErr.Code, ErrUnauthorized)
-	}
-	if logwellErr.StatusCode != 401 {
-		t.Errorf("status code = %d, want 401", logwellErr.StatusCode)
-	}
-	if atomic.LoadInt32(&requestCount) != 1 {
-		t.Errorf("requestCount = %d, want 1 (should NOT retry on 401)", requestCount)
-	}
-}
-
-// TestTransport_NoRetryOn400 tests that 400 (validation error) does NOT retry.
-func TestTransport_NoRetryOn400(t *testing.T) {
-	var requestCount int32
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		atomic.AddInt32(&requestCount, 1)
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "invalid log format"})
-	}))
-	defer server.Close()
-
-	transport := newHTTPTransport(server.URL, "test-api-key")
-	logs := []LogEntry{{Level: LevelInfo, Message: "test"}}
-
-	_, err := transport.sendWithRetry(context.Background(), logs)
-	if err == nil {
-		t.Fatal("sendWithRetry() expected error for 400, got nil")
-	}
-
-	logwellErr, ok := err.(*Error)
-	if !ok {
-		t.Fatalf("error type = %T, want *Error", err)
-	}
-	if logwellErr.Code != ErrValidationError {
-		t.Errorf("error code = %q, want %q", logwellErr.Code, ErrValidationError)
-	}
-	if logwellErr.StatusCode != 400 {
-		t.Errorf("status code = %d, want 400", logwellErr.StatusCode)
-	}
-	if atomic.LoadInt32(&requestCount) != 1 {
-		t.Errorf("requestCount = %d, want 1 (should NOT retry on 400)", requestCount)
-	}
-}
-
 // TestTransport_RetryOnNetworkError tests retry on network-level errors.
 func TestTransport_RetryOnNetworkError(t *testing.T) {
 	// Create transport pointing to non-existent server (connection refused)
@@ -509,3 +326,1558 @@ func TestTransport_RequestBody(t *testing.T) {
 		t.Errorf("Logs[1].Level = %q, want %q", receivedBody.Logs[1].Level, LevelError)
 	}
 }
+
+// TestTransport_InsecureSkipVerify tests sending to a TLS server with a self-signed certificate.
+func TestTransport_InsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+	}))
+	defer server.Close()
+
+	logs := []LogEntry{{Level: LevelInfo, Message: "tls"}}
+
+	t.Run("verification fails by default", func(t *testing.T) {
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		transport.httpClient = newTransportHTTPClient(newDefaultConfig(server.URL, "test-api-key"))
+
+		if _, err := transport.send(context.Background(), logs); err == nil {
+			t.Error("send() error = nil, want certificate error")
+		}
+	})
+
+	t.Run("succeeds with verification skipped", func(t *testing.T) {
+		cfg := newDefaultConfig(server.URL, "test-api-key")
+		WithInsecureSkipVerify(true)(cfg)
+
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		transport.httpClient = newTransportHTTPClient(cfg)
+
+		if _, err := transport.send(context.Background(), logs); err != nil {
+			t.Errorf("send() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("custom client is used as-is", func(t *testing.T) {
+		custom := &http.Client{Timeout: time.Second}
+		cfg := newDefaultConfig(server.URL, "test-api-key")
+		WithHTTPClient(custom)(cfg)
+		WithInsecureSkipVerify(true)(cfg)
+
+		if newTransportHTTPClient(cfg) != custom {
+			t.Error("newTransportHTTPClient() did not return the custom client")
+		}
+	})
+
+	t.Run("New warns once through OnError", func(t *testing.T) {
+		var warnings []*Error
+		client, err := New(server.URL, validAPIKey(),
+			WithInsecureSkipVerify(true),
+			WithOnError(func(e *Error) { warnings = append(warnings, e) }),
+		)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		if len(warnings) != 1 {
+			t.Fatalf("got %d warnings, want 1", len(warnings))
+		}
+		if !strings.Contains(warnings[0].Message, "verification is disabled") {
+			t.Errorf("warning = %q, want TLS warning", warnings[0].Message)
+		}
+
+		client.Child().Info("child log")
+		if len(warnings) != 1 {
+			t.Errorf("got %d warnings after child creation, want 1", len(warnings))
+		}
+	})
+}
+
+// TestTransport_Timeouts tests the dial and response header timeout options.
+func TestTransport_Timeouts(t *testing.T) {
+	logs := []LogEntry{{Level: LevelInfo, Message: "timeout"}}
+
+	t.Run("dial timeout surfaces network error", func(t *testing.T) {
+		// 10.255.255.1 is unroutable, so the connection attempt hangs
+		cfg := newDefaultConfig("http://10.255.255.1", "test-api-key")
+		WithDialTimeout(100 * time.Millisecond)(cfg)
+
+		transport := newHTTPTransport(cfg.Endpoint, "test-api-key")
+		transport.httpClient = newTransportHTTPClient(cfg)
+
+		start := time.Now()
+		_, err := transport.send(context.Background(), logs)
+		elapsed := time.Since(start)
+
+		if err == nil {
+			t.Fatal("send() error = nil, want network error")
+		}
+		if logwellErr, ok := err.(*Error); !ok || logwellErr.Code != ErrNetworkError {
+			t.Errorf("send() error = %v, want %s", err, ErrNetworkError)
+		}
+		if elapsed > 2*time.Second {
+			t.Errorf("send() took %v, want bounded by dial timeout", elapsed)
+		}
+	})
+
+	t.Run("response header timeout surfaces network error", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		defer server.Close()
+		defer close(release)
+
+		cfg := newDefaultConfig(server.URL, "test-api-key")
+		WithResponseHeaderTimeout(100 * time.Millisecond)(cfg)
+
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		transport.httpClient = newTransportHTTPClient(cfg)
+
+		_, err := transport.send(context.Background(), logs)
+		if logwellErr, ok := err.(*Error); !ok || logwellErr.Code != ErrNetworkError {
+			t.Errorf("send() error = %v, want %s", err, ErrNetworkError)
+		}
+	})
+
+	t.Run("ignored with custom client", func(t *testing.T) {
+		custom := &http.Client{}
+		cfg := newDefaultConfig(validEndpoint(), "test-api-key")
+		WithHTTPClient(custom)(cfg)
+		WithDialTimeout(time.Millisecond)(cfg)
+		WithResponseHeaderTimeout(time.Millisecond)(cfg)
+
+		if newTransportHTTPClient(cfg) != custom {
+			t.Error("newTransportHTTPClient() did not return the custom client")
+		}
+		if custom.Transport != nil {
+			t.Error("custom client transport was modified")
+		}
+	})
+
+	t.Run("request timeout bounds a stalled response body", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		defer server.Close()
+		defer close(release)
+
+		cfg := newDefaultConfig(server.URL, "test-api-key")
+		WithRequestTimeout(100 * time.Millisecond)(cfg)
+
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		transport.httpClient = newTransportHTTPClient(cfg)
+
+		start := time.Now()
+		_, err := transport.send(context.Background(), logs)
+		if logwellErr, ok := err.(*Error); !ok || logwellErr.Code != ErrNetworkError {
+			t.Errorf("send() error = %v, want %s", err, ErrNetworkError)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("send() took %v, want bounded by request timeout", elapsed)
+		}
+	})
+
+	t.Run("default client is tuned", func(t *testing.T) {
+		cfg := newDefaultConfig(validEndpoint(), "test-api-key")
+		WithMaxConcurrentFlushes(8)(cfg)
+		client := newTransportHTTPClient(cfg)
+
+		if client.Timeout != DefaultRequestTimeout {
+			t.Errorf("Timeout = %v, want %v", client.Timeout, DefaultRequestTimeout)
+		}
+		transport := client.Transport.(*http.Transport)
+		if transport.IdleConnTimeout != DefaultIdleConnTimeout {
+			t.Errorf("IdleConnTimeout = %v, want %v", transport.IdleConnTimeout, DefaultIdleConnTimeout)
+		}
+		if transport.TLSHandshakeTimeout != DefaultTLSHandshakeTimeout {
+			t.Errorf("TLSHandshakeTimeout = %v, want %v", transport.TLSHandshakeTimeout, DefaultTLSHandshakeTimeout)
+		}
+		if transport.MaxIdleConnsPerHost != 8 {
+			t.Errorf("MaxIdleConnsPerHost = %d, want 8", transport.MaxIdleConnsPerHost)
+		}
+		if !transport.ForceAttemptHTTP2 {
+			t.Error("ForceAttemptHTTP2 = false, want true")
+		}
+
+		WithIdleConnTimeout(5 * time.Second)(cfg)
+		WithRequestTimeout(0)(cfg)
+		client = newTransportHTTPClient(cfg)
+		if got := client.Transport.(*http.Transport).IdleConnTimeout; got != 5*time.Second {
+			t.Errorf("IdleConnTimeout = %v, want 5s", got)
+		}
+		if client.Timeout != 0 {
+			t.Errorf("Timeout = %v, want no limit", client.Timeout)
+		}
+	})
+
+	t.Run("negative timeouts are invalid", func(t *testing.T) {
+		_, err := New(validEndpoint(), validAPIKey(), WithDialTimeout(-time.Second))
+		assertConfigError(t, err, ErrInvalidConfig)
+
+		_, err = New(validEndpoint(), validAPIKey(), WithResponseHeaderTimeout(-time.Second))
+		assertConfigError(t, err, ErrInvalidConfig)
+
+		_, err = New(validEndpoint(), validAPIKey(), WithRequestTimeout(-time.Second))
+		assertConfigError(t, err, ErrInvalidConfig)
+
+		_, err = New(validEndpoint(), validAPIKey(), WithIdleConnTimeout(-time.Second))
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+}
+
+// TestTransport_ConnectionReuse tests that sequential flushes reuse one
+// keep-alive connection.
+func TestTransport_ConnectionReuse(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		addrs = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ingestRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		addrs[r.RemoteAddr]++
+		mu.Unlock()
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	}))
+	defer server.Close()
+
+	client, err := New(server.URL, validAPIKey(), WithBatchSize(100))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	for i := 0; i < 5; i++ {
+		client.Info(fmt.Sprintf("flush %d", i))
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(addrs) != 1 {
+		t.Errorf("requests came from %d connections %v, want 1", len(addrs), addrs)
+	}
+}
+
+// TestTransport_DeliveryMode tests retry behavior for ambiguous timeouts.
+func TestTransport_DeliveryMode(t *testing.T) {
+	logs := []LogEntry{{Level: LevelInfo, Message: "delivery"}}
+
+	newSlowServer := func(count *int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(count, 1)
+			select {
+			case <-time.After(500 * time.Millisecond):
+			case <-r.Context().Done():
+			}
+		}))
+	}
+
+	t.Run("AtMostOnce does not retry a timeout after the request was sent", func(t *testing.T) {
+		var count int32
+		server := newSlowServer(&count)
+		defer server.Close()
+
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		transport.httpClient = &http.Client{Timeout: 100 * time.Millisecond}
+		transport.deliveryMode = AtMostOnce
+		transport.maxRetries = 2
+
+		_, err := transport.sendWithRetry(context.Background(), logs)
+		if err == nil {
+			t.Fatal("sendWithRetry() error = nil, want timeout error")
+		}
+		if !isAmbiguousError(err) {
+			t.Errorf("error = %v, want ambiguous network error", err)
+		}
+		if got := atomic.LoadInt32(&count); got != 1 {
+			t.Errorf("request count = %d, want 1 (no retries)", got)
+		}
+	})
+
+	t.Run("AtLeastOnce retries the timeout", func(t *testing.T) {
+		var count int32
+		server := newSlowServer(&count)
+		defer server.Close()
+
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		transport.httpClient = &http.Client{Timeout: 100 * time.Millisecond}
+		transport.deliveryMode = AtLeastOnce
+		transport.maxRetries = 1
+
+		if _, err := transport.sendWithRetry(context.Background(), logs); err == nil {
+			t.Fatal("sendWithRetry() error = nil, want timeout error")
+		}
+		if got := atomic.LoadInt32(&count); got != 2 {
+			t.Errorf("request count = %d, want 2", got)
+		}
+	})
+
+	t.Run("AtMostOnce still retries connection failures", func(t *testing.T) {
+		transport := newHTTPTransport("http://127.0.0.1:1", "test-api-key")
+		transport.deliveryMode = AtMostOnce
+		transport.maxRetries = 1
+
+		_, err := transport.sendWithRetry(context.Background(), logs)
+		if err == nil {
+			t.Fatal("sendWithRetry() error = nil, want connection error")
+		}
+		if isAmbiguousError(err) {
+			t.Error("connection refused should not be ambiguous")
+		}
+	})
+
+	t.Run("invalid mode returns error", func(t *testing.T) {
+		_, err := New(validEndpoint(), validAPIKey(), WithDeliveryMode("exactly-once"))
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+}
+
+// TestTransport_TokenProvider tests that the token provider supplies the
+// bearer token for every request.
+func TestTransport_TokenProvider(t *testing.T) {
+	t.Run("each send carries the latest token", func(t *testing.T) {
+		var mu sync.Mutex
+		var seen []string
+		ts := newTestServer()
+		defer ts.Close()
+		ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			seen = append(seen, r.Header.Get("Authorization"))
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+		})
+
+		var calls int32
+		client, err := New(ts.URL, "", WithBatchSize(1), WithTokenProvider(func(ctx context.Context) (string, error) {
+			return fmt.Sprintf("token-%d", atomic.AddInt32(&calls, 1)), nil
+		}))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		for i := 0; i < 3; i++ {
+			client.Info("with token")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		want := []string{"Bearer token-1", "Bearer token-2", "Bearer token-3"}
+		if len(seen) != len(want) {
+			t.Fatalf("requests = %v, want %v", seen, want)
+		}
+		for i := range want {
+			if seen[i] != want[i] {
+				t.Errorf("request %d Authorization = %q, want %q", i, seen[i], want[i])
+			}
+		}
+	})
+
+	t.Run("provider error fails with ErrUnauthorized", func(t *testing.T) {
+		var requestCount int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requestCount, 1)
+		}))
+		defer server.Close()
+
+		var calls int32
+		transport := newHTTPTransport(server.URL, "")
+		transport.tokenProvider = func(ctx context.Context) (string, error) {
+			atomic.AddInt32(&calls, 1)
+			return "", errors.New("token expired")
+		}
+
+		_, err := transport.sendWithRetry(context.Background(), []LogEntry{{Level: LevelInfo, Message: "no token"}})
+		assertConfigError(t, err, ErrUnauthorized)
+		if got := atomic.LoadInt32(&calls); got != 1 {
+			t.Errorf("provider calls = %d, want 1 (not retried)", got)
+		}
+		if got := atomic.LoadInt32(&requestCount); got != 0 {
+			t.Errorf("request count = %d, want 0", got)
+		}
+	})
+
+	t.Run("API key is not validated", func(t *testing.T) {
+		client, err := New(validEndpoint(), "", WithTokenProvider(func(context.Context) (string, error) {
+			return "token", nil
+		}))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		client.Shutdown(context.Background())
+	})
+}
+
+// appendEntriesJSON is a minimal hand-written encoder for ingest requests
+// whose entries carry only level, message, timestamp and service.
+func appendEntriesJSON(v any) ([]byte, error) {
+	req, ok := v.(ingestRequest)
+	if !ok {
+		return json.Marshal(v)
+	}
+	buf := append(make([]byte, 0, 128*len(req.Logs)), `{"logs":[`...)
+	for i, entry := range req.Logs {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"level":`...)
+		buf = strconv.AppendQuote(buf, string(entry.Level))
+		buf = append(buf, `,"message":`...)
+		buf = strconv.AppendQuote(buf, entry.Message)
+		if entry.Timestamp != "" {
+			buf = append(buf, `,"timestamp":`...)
+			buf = strconv.AppendQuote(buf, entry.Timestamp)
+		}
+		if entry.Service != "" {
+			buf = append(buf, `,"service":`...)
+			buf = strconv.AppendQuote(buf, entry.Service)
+		}
+		buf = append(buf, '}')
+	}
+	return append(buf, "]}"...), nil
+}
+
+// TestTransport_Encoder tests that request bodies go through the configured encoder.
+func TestTransport_Encoder(t *testing.T) {
+	logs := []LogEntry{{Level: LevelInfo, Message: "encoded", Service: "svc"}}
+
+	t.Run("custom encoder produces request body", func(t *testing.T) {
+		var received ingestRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+				t.Errorf("failed to decode body: %v", err)
+			}
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: len(received.Logs)})
+		}))
+		defer server.Close()
+
+		var calls int32
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		transport.encode = func(v any) ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			return appendEntriesJSON(v)
+		}
+
+		resp, err := transport.sendWithRetry(context.Background(), logs)
+		if err != nil {
+			t.Fatalf("sendWithRetry() error = %v", err)
+		}
+		if resp.Accepted != 1 {
+			t.Errorf("Accepted = %d, want 1", resp.Accepted)
+		}
+		if got := atomic.LoadInt32(&calls); got != 1 {
+			t.Errorf("encoder calls = %d, want 1", got)
+		}
+		if len(received.Logs) != 1 || received.Logs[0].Message != "encoded" || received.Logs[0].Service != "svc" {
+			t.Errorf("received logs = %+v, want the encoded entry", received.Logs)
+		}
+	})
+
+	t.Run("encoder error is not retried", func(t *testing.T) {
+		var requestCount int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requestCount, 1)
+		}))
+		defer server.Close()
+
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		transport.encode = func(any) ([]byte, error) {
+			return nil, errors.New("cannot encode")
+		}
+
+		_, err := transport.sendWithRetry(context.Background(), logs)
+		var logwellErr *Error
+		if !errors.As(err, &logwellErr) || logwellErr.Code != ErrValidationError {
+			t.Fatalf("error = %v, want %s", err, ErrValidationError)
+		}
+		if got := atomic.LoadInt32(&requestCount); got != 0 {
+			t.Errorf("request count = %d, want 0", got)
+		}
+	})
+
+	t.Run("WithEncoder is used by the client", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		var calls int32
+		client := createTestClient(t, ts, WithBatchSize(1), WithEncoder(func(v any) ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			return json.Marshal(v)
+		}))
+		defer client.Shutdown(context.Background())
+
+		entry := logAndWait(t, client, ts, client.Info, "via client encoder")
+		if entry.Message != "via client encoder" {
+			t.Errorf("Message = %q, want %q", entry.Message, "via client encoder")
+		}
+		if atomic.LoadInt32(&calls) == 0 {
+			t.Error("custom encoder was not called")
+		}
+	})
+
+	t.Run("nil encoder returns error", func(t *testing.T) {
+		_, err := New(validEndpoint(), validAPIKey(), WithEncoder(nil))
+		assertConfigError(t, err, ErrInvalidConfig)
+	})
+}
+
+// TestTransport_PooledBuffers tests that concurrent sends through the pooled
+// default encoder never see each other's request bodies.
+func TestTransport_PooledBuffers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ingestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Logs) != 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		// Echo the message back so the client can check it got its own body
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1, Errors: []string{req.Logs[0].Message}})
+	}))
+	defer server.Close()
+
+	transport := newHTTPTransport(server.URL, "test-api-key")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				message := fmt.Sprintf("sender %d message %d %s", i, j, strings.Repeat("x", i*100))
+				resp, err := transport.send(context.Background(), []LogEntry{{Level: LevelInfo, Message: message}})
+				if err != nil {
+					t.Errorf("send() error = %v", err)
+					return
+				}
+				if len(resp.Errors) != 1 || resp.Errors[0] != message {
+					t.Errorf("server received %v, want %q", resp.Errors, message)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func benchmarkTransportSend(b *testing.B, encode func(any) ([]byte, error)) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"accepted":50}`))
+	}))
+	defer server.Close()
+
+	transport := newHTTPTransport(server.URL, "test-api-key")
+	if encode != nil {
+		transport.encode = encode
+	}
+
+	logs := make([]LogEntry, 50)
+	for i := range logs {
+		logs[i] = LogEntry{
+			Level:     LevelInfo,
+			Message:   "benchmark message " + strconv.Itoa(i),
+			Timestamp: now(),
+			Service:   "bench",
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := transport.send(context.Background(), logs); err != nil {
+			b.Fatalf("send() error = %v", err)
+		}
+	}
+}
+
+func BenchmarkTransportSend_DefaultEncoder(b *testing.B) {
+	benchmarkTransportSend(b, nil)
+}
+
+func BenchmarkTransportSend_CustomEncoder(b *testing.B) {
+	benchmarkTransportSend(b, appendEntriesJSON)
+}
+
+func BenchmarkTransportSend_UnpooledMarshal(b *testing.B) {
+	benchmarkTransportSend(b, json.Marshal)
+}
+
+func TestTransport_BasicAuth(t *testing.T) {
+	var mu sync.Mutex
+	var headers http.Header
+	ts := newTestServer()
+	defer ts.Close()
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = r.Header.Clone()
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+	})
+
+	client := createTestClient(t, ts, WithBatchSize(1), WithBasicAuth("ingest", "s3cret:pass"))
+	defer client.Shutdown(context.Background())
+
+	client.Info("behind proxy")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if headers == nil {
+		t.Fatal("no request received")
+	}
+	wantBasic := "Basic " + base64.StdEncoding.EncodeToString([]byte("ingest:s3cret:pass"))
+	if got := headers.Get("Authorization"); got != wantBasic {
+		t.Errorf("Authorization = %q, want %q", got, wantBasic)
+	}
+	if got, want := headers.Get("X-Logwell-Authorization"), "Bearer "+validAPIKey(); got != want {
+		t.Errorf("X-Logwell-Authorization = %q, want %q", got, want)
+	}
+}
+
+// TestTransport_OnRetry tests that each retry is reported before the backoff.
+func TestTransport_OnRetry(t *testing.T) {
+	var requestCount int32
+	ts := newTestServer()
+	defer ts.Close()
+	ts.setHandler(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requestCount, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "server error"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: 2})
+	})
+
+	var mu sync.Mutex
+	var retries []RetryInfo
+	client := createTestClient(t, ts,
+		WithBatchSize(2),
+		WithOnRetry(func(info RetryInfo) {
+			mu.Lock()
+			retries = append(retries, info)
+			mu.Unlock()
+		}),
+	)
+	defer client.Shutdown(context.Background())
+
+	client.Info("first")
+	client.Info("second")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(retries) != 1 {
+		t.Fatalf("OnRetry called %d times, want 1", len(retries))
+	}
+	info := retries[0]
+	if info.Attempt != 1 {
+		t.Errorf("Attempt = %d, want 1", info.Attempt)
+	}
+	var logwellErr *Error
+	if !errors.As(info.Err, &logwellErr) || logwellErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Err = %v, want the 500 error", info.Err)
+	}
+	if info.Delay <= 0 {
+		t.Errorf("Delay = %v, want positive", info.Delay)
+	}
+	if info.BatchSize != 2 {
+		t.Errorf("BatchSize = %d, want 2", info.BatchSize)
+	}
+}
+
+// TestTransport_NetworkErrorKind tests that network failures are classified by kind.
+func TestTransport_NetworkErrorKind(t *testing.T) {
+	// A port that was just released refuses connections
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := closed.URL
+	closed.Close()
+
+	// A server that never answers times out deterministically, unlike an
+	// unroutable address, which some networks reject at once
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	testCases := []struct {
+		name     string
+		endpoint string
+		client   *http.Client
+		timeout  time.Duration
+		want     ErrorKind
+	}{
+		{"bogus hostname", "http://logwell-test.invalid", &http.Client{}, 0, NetworkDNS},
+		{"closed port", closedURL, &http.Client{}, 0, NetworkRefused},
+		{"client timeout", hanging.URL, &http.Client{Timeout: 50 * time.Millisecond}, 0, NetworkTimeout},
+		{"context deadline", hanging.URL, &http.Client{}, 50 * time.Millisecond, NetworkTimeout},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := newHTTPTransport(tc.endpoint, "test-api-key")
+			transport.httpClient = tc.client
+			transport.maxRetries = 0
+
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+
+			_, err := transport.sendWithRetry(ctx, []LogEntry{{Level: LevelInfo, Message: "test"}})
+			var logwellErr *Error
+			if !errors.As(err, &logwellErr) {
+				t.Fatalf("error = %v, want *Error", err)
+			}
+			if logwellErr.Code != ErrNetworkError {
+				t.Errorf("Code = %s, want %s", logwellErr.Code, ErrNetworkError)
+			}
+			if logwellErr.Kind != tc.want {
+				t.Errorf("Kind = %q, want %q (cause: %v)", logwellErr.Kind, tc.want, logwellErr.Cause)
+			}
+		})
+	}
+
+	t.Run("DNS failure backs off longer", func(t *testing.T) {
+		transport := newHTTPTransport("http://logwell-test.invalid", "test-api-key")
+		transport.maxRetries = 1
+		var delay time.Duration
+		transport.onRetry = func(info RetryInfo) { delay = info.Delay }
+
+		_, _ = transport.sendWithRetry(context.Background(), []LogEntry{{Level: LevelInfo, Message: "test"}})
+		// The first backoff is 200ms +/- 30%, scaled by dnsBackoffFactor
+		if want := 140 * time.Millisecond * dnsBackoffFactor; delay < want {
+			t.Errorf("retry delay = %v, want at least %v", delay, want)
+		}
+	})
+
+	t.Run("unclassified", func(t *testing.T) {
+		if got := classifyNetworkError(io.ErrUnexpectedEOF); got != "" {
+			t.Errorf("classifyNetworkError(EOF) = %q, want empty", got)
+		}
+	})
+}
+
+// TestTransport_RequestID tests that the response request ID is attached to errors.
+func TestTransport_RequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-abc")
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	transport := newHTTPTransport(server.URL, "test-api-key")
+	_, err := transport.sendWithRetry(context.Background(), []LogEntry{{Level: LevelInfo, Message: "test"}})
+
+	var logwellErr *Error
+	if !errors.As(err, &logwellErr) {
+		t.Fatalf("error = %v, want *Error", err)
+	}
+	if logwellErr.RequestID != "req-abc" {
+		t.Errorf("RequestID = %q, want %q", logwellErr.RequestID, "req-abc")
+	}
+}
+
+// TestTransport_ResponseValidator tests that a 2xx response rejected by the
+// validator fails the send and is retried instead of counting as delivered.
+func TestTransport_ResponseValidator(t *testing.T) {
+	errNotJSON := errors.New("response is not JSON")
+	validator := WithResponseValidator(func(status int, body []byte) error {
+		if status != http.StatusOK || !json.Valid(body) {
+			return errNotJSON
+		}
+		return nil
+	})
+	const htmlPage = "<html><body>Service temporarily unavailable</body></html>"
+
+	t.Run("retried", func(t *testing.T) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			if atomic.AddInt32(&requests, 1) == 1 {
+				w.Header().Set("Content-Type", "text/html")
+				io.WriteString(w, htmlPage)
+				return
+			}
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+		}))
+		defer server.Close()
+
+		var flushed int32
+		client, err := New(server.URL, validAPIKey(), validator,
+			WithOnFlush(func(n int) { atomic.AddInt32(&flushed, int32(n)) }))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		client.Info("first")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v, want nil after a retry", err)
+		}
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Errorf("requests = %d, want 2", got)
+		}
+		if got := atomic.LoadInt32(&flushed); got != 1 {
+			t.Errorf("OnFlush count = %d, want 1", got)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			atomic.AddInt32(&requests, 1)
+			io.WriteString(w, htmlPage)
+		}))
+		defer server.Close()
+
+		var flushed int32
+		client, err := New(server.URL, validAPIKey(), validator,
+			WithOnFlush(func(n int) { atomic.AddInt32(&flushed, int32(n)) }))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+		client.transport.maxRetries = 1
+
+		client.Info("lost")
+		err = client.Flush(context.Background())
+		if !errors.Is(err, errNotJSON) || !errors.Is(err, ErrServerError) {
+			t.Fatalf("Flush() error = %v, want the validator's error as %s", err, ErrServerError)
+		}
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Errorf("requests = %d, want 2", got)
+		}
+		if got := atomic.LoadInt32(&flushed); got != 0 {
+			t.Errorf("OnFlush count = %d, want 0", got)
+		}
+	})
+}
+
+// TestTransport_ConnectionMetrics tests that sends over a keep-alive
+// server are counted as one new connection and reused ones after it.
+func TestTransport_ConnectionMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+	}))
+	defer server.Close()
+
+	client, err := New(server.URL, validAPIKey(), WithConnectionMetrics(true))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	for i := 0; i < 4; i++ {
+		client.Info("test")
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+
+	stats := client.Stats()
+	if stats.ConnsNew != 1 || stats.ConnsReused != 3 {
+		t.Errorf("ConnsNew = %d, ConnsReused = %d, want 1 and 3", stats.ConnsNew, stats.ConnsReused)
+	}
+
+	// Off by default
+	plain, err := New(server.URL, validAPIKey())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer plain.Shutdown(context.Background())
+	plain.Info("test")
+	plain.Flush(context.Background())
+	if stats := plain.Stats(); stats.ConnsNew != 0 || stats.ConnsReused != 0 {
+		t.Errorf("counters without WithConnectionMetrics = %d, %d, want 0", stats.ConnsNew, stats.ConnsReused)
+	}
+}
+
+// TestTransport_LevelAliases tests that aliased levels are sent under
+// their names, in encoded and streamed bodies alike, without changing the
+// entries that were sent.
+func TestTransport_LevelAliases(t *testing.T) {
+	var bodies []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+	}))
+	defer server.Close()
+
+	cfg := newDefaultConfig(server.URL, validAPIKey())
+	WithLevelAliases(map[LogLevel]string{LevelWarn: "warning", LevelFatal: "critical"})(cfg)
+	transport := newConfiguredTransport(server.URL, cfg, nil)
+
+	logs := []LogEntry{
+		{Level: LevelWarn, Message: "disk almost full"},
+		{Level: LevelInfo, Message: "started"},
+	}
+	if _, err := transport.sendWithRetry(context.Background(), logs); err != nil {
+		t.Fatalf("sendWithRetry() error = %v", err)
+	}
+	transport.streamThreshold = 1
+	if _, err := transport.sendWithRetry(context.Background(), logs); err != nil {
+		t.Fatalf("sendWithRetry(streamed) error = %v", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("requests = %d, want 2", len(bodies))
+	}
+	for _, body := range bodies {
+		if !strings.Contains(body, `"level":"warning","message":"disk almost full"`) {
+			t.Errorf("body = %s, want the warn log sent as warning", body)
+		}
+		if !strings.Contains(body, `"level":"info","message":"started"`) {
+			t.Errorf("body = %s, want the unmapped info level unchanged", body)
+		}
+	}
+	if logs[0].Level != LevelWarn {
+		t.Errorf("sent entry level = %q, want %q", logs[0].Level, LevelWarn)
+	}
+}
+
+// TestTransport_Debug tests that the debug hook sees each exchange with credentials redacted.
+func TestTransport_Debug(t *testing.T) {
+	type exchange struct {
+		req  *http.Request
+		resp *http.Response
+		body []byte
+		err  error
+	}
+	record := func(transport *httpTransport) *[]exchange {
+		var got []exchange
+		transport.debug = func(req *http.Request, resp *http.Response, body []byte, err error) {
+			got = append(got, exchange{req, resp, body, err})
+		}
+		return &got
+	}
+	logs := []LogEntry{{Level: LevelInfo, Message: "test"}}
+
+	t.Run("success with redacted bearer token", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer test-api-key" {
+				t.Errorf("server Authorization = %q, want the real token", r.Header.Get("Authorization"))
+			}
+			json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+		}))
+		defer server.Close()
+
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		got := record(transport)
+		if _, err := transport.sendWithRetry(context.Background(), logs); err != nil {
+			t.Fatalf("sendWithRetry() error = %v", err)
+		}
+
+		if len(*got) != 1 {
+			t.Fatalf("hook calls = %d, want 1", len(*got))
+		}
+		ex := (*got)[0]
+		if auth := ex.req.Header.Get("Authorization"); auth != "[REDACTED]" {
+			t.Errorf("hook Authorization = %q, want [REDACTED]", auth)
+		}
+		if ex.req.URL.Path != "/v1/ingest" || ex.req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("hook request = %s %v, want the ingest request", ex.req.URL, ex.req.Header)
+		}
+		if ex.resp == nil || ex.resp.StatusCode != http.StatusOK || ex.err != nil {
+			t.Errorf("hook response = %v, err = %v, want 200 and nil", ex.resp, ex.err)
+		}
+		if !strings.Contains(string(ex.body), `"accepted":1`) {
+			t.Errorf("hook body = %q, want the response body", ex.body)
+		}
+	})
+
+	t.Run("basic auth redacts both headers", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		transport.basicUser, transport.basicPass = "ingest", "secret"
+		got := record(transport)
+		_, _ = transport.sendWithRetry(context.Background(), logs)
+
+		if len(*got) != 1 {
+			t.Fatalf("hook calls = %d, want 1", len(*got))
+		}
+		ex := (*got)[0]
+		for _, header := range []string{"Authorization", apiKeyHeader} {
+			if value := ex.req.Header.Get(header); value != "[REDACTED]" {
+				t.Errorf("hook %s = %q, want [REDACTED]", header, value)
+			}
+		}
+		if !errors.Is(ex.err, ErrUnauthorized) || ex.resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("hook err = %v, status = %d, want unauthorized 401", ex.err, ex.resp.StatusCode)
+		}
+	})
+
+	t.Run("network error has no response", func(t *testing.T) {
+		closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		closed.Close()
+
+		transport := newHTTPTransport(closed.URL, "test-api-key")
+		transport.maxRetries = 1
+		got := record(transport)
+		_, _ = transport.sendWithRetry(context.Background(), logs)
+
+		if len(*got) != 2 {
+			t.Fatalf("hook calls = %d, want one per attempt", len(*got))
+		}
+		if ex := (*got)[0]; ex.resp != nil || ex.body != nil || !errors.Is(ex.err, ErrNetworkError) {
+			t.Errorf("hook = %v %q %v, want nil response and a network error", ex.resp, ex.body, ex.err)
+		}
+	})
+}
+
+func TestTransport_StreamedBody(t *testing.T) {
+	type received struct {
+		contentLength int64
+		chunked       bool
+		logs          []LogEntry
+	}
+	var (
+		mu   sync.Mutex
+		last received
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ingestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+			return
+		}
+		mu.Lock()
+		last = received{
+			contentLength: r.ContentLength,
+			chunked:       len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked",
+			logs:          req.Logs,
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	}))
+	defer server.Close()
+
+	transport := newHTTPTransport(server.URL, "test-api-key")
+	transport.streamThreshold = 4096
+
+	t.Run("large batch is streamed intact", func(t *testing.T) {
+		logs := make([]LogEntry, 5000)
+		for i := range logs {
+			logs[i] = LogEntry{
+				Level:    LevelInfo,
+				Message:  fmt.Sprintf("entry %d %s", i, strings.Repeat("x", i%100)),
+				Metadata: map[string]any{"index": i},
+			}
+		}
+
+		resp, err := transport.send(context.Background(), logs)
+		if err != nil {
+			t.Fatalf("send() error = %v", err)
+		}
+		if resp.Accepted != len(logs) {
+			t.Errorf("Accepted = %d, want %d", resp.Accepted, len(logs))
+		}
+
+		mu.Lock()
+		got := last
+		mu.Unlock()
+		if !got.chunked || got.contentLength != -1 {
+			t.Errorf("request chunked = %v, ContentLength = %d; want a chunked body of unknown length", got.chunked, got.contentLength)
+		}
+		if len(got.logs) != len(logs) {
+			t.Fatalf("server received %d logs, want %d", len(got.logs), len(logs))
+		}
+		for i, entry := range got.logs {
+			if entry.Message != logs[i].Message || entry.Metadata["index"] != float64(i) {
+				t.Fatalf("log %d = %q %v, want %q index %d", i, entry.Message, entry.Metadata, logs[i].Message, i)
+			}
+		}
+	})
+
+	t.Run("client batch over the default threshold is streamed", func(t *testing.T) {
+		client, err := New(server.URL, validAPIKey(), WithBatchSize(100), WithFlushInterval(60*time.Second))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		defer client.Shutdown(context.Background())
+
+		message := strings.Repeat("x", 16<<10)
+		for i := 0; i < 100; i++ {
+			client.Info(message)
+		}
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+
+		mu.Lock()
+		got := last
+		mu.Unlock()
+		if !got.chunked || got.contentLength != -1 {
+			t.Errorf("request chunked = %v, ContentLength = %d; want a chunked body of unknown length", got.chunked, got.contentLength)
+		}
+		if len(got.logs) != 100 {
+			t.Errorf("server received %d logs, want 100", len(got.logs))
+		}
+	})
+
+	t.Run("small batch is buffered", func(t *testing.T) {
+		logs := []LogEntry{{Level: LevelInfo, Message: "small"}}
+		if _, err := transport.send(context.Background(), logs); err != nil {
+			t.Fatalf("send() error = %v", err)
+		}
+
+		mu.Lock()
+		got := last
+		mu.Unlock()
+		if got.chunked || got.contentLength <= 0 {
+			t.Errorf("request chunked = %v, ContentLength = %d; want a body of known length", got.chunked, got.contentLength)
+		}
+	})
+
+	t.Run("unencodable entry is a validation error", func(t *testing.T) {
+		logs := make([]LogEntry, 20)
+		for i := range logs {
+			logs[i] = LogEntry{Level: LevelInfo, Message: "entry"}
+		}
+		logs[15].Metadata = map[string]any{"bad": make(chan int)}
+
+		_, err := transport.sendWithRetry(context.Background(), logs)
+		if !errors.Is(err, ErrValidationError) {
+			t.Errorf("sendWithRetry() error = %v, want %v", err, ErrValidationError)
+		}
+	})
+}
+
+func TestTransport_EmptySuccessResponse(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"204 No Content", http.StatusNoContent, ""},
+		{"200 with empty body", http.StatusOK, ""},
+		{"202 with whitespace body", http.StatusAccepted, "\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.Copy(io.Discard, r.Body)
+				w.WriteHeader(tc.status)
+				io.WriteString(w, tc.body)
+			}))
+			defer server.Close()
+
+			var flushed int32
+			client, err := New(server.URL, validAPIKey(),
+				WithBatchSize(100),
+				WithOnFlush(func(n int) { atomic.AddInt32(&flushed, int32(n)) }),
+			)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer client.Shutdown(context.Background())
+
+			client.Info("first")
+			client.Info("second")
+			if err := client.Flush(context.Background()); err != nil {
+				t.Fatalf("Flush() error = %v, want nil", err)
+			}
+			if got := atomic.LoadInt32(&flushed); got != 2 {
+				t.Errorf("OnFlush count = %d, want 2", got)
+			}
+
+			resp, err := client.transport.send(context.Background(), []LogEntry{{Level: LevelInfo, Message: "direct"}})
+			if err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+			if resp.Accepted != 1 {
+				t.Errorf("Accepted = %d, want 1", resp.Accepted)
+			}
+		})
+	}
+}
+
+func TestTransport_GzipResponse(t *testing.T) {
+	newGzipServer := func(status int, body any) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.WriteHeader(status)
+			gz := gzip.NewWriter(w)
+			json.NewEncoder(gz).Encode(body)
+			gz.Close()
+		}))
+	}
+	logs := []LogEntry{{Level: LevelInfo, Message: "one"}, {Level: LevelInfo, Message: "two"}}
+
+	clients := []struct {
+		name   string
+		client *http.Client
+	}{
+		{"compression disabled", &http.Client{Transport: &http.Transport{DisableCompression: true}}},
+		{"default client", &http.Client{}},
+	}
+	for _, c := range clients {
+		t.Run(c.name, func(t *testing.T) {
+			server := newGzipServer(http.StatusOK, IngestResponse{Accepted: 2})
+			defer server.Close()
+
+			transport := newHTTPTransport(server.URL, "test-api-key")
+			transport.httpClient = c.client
+
+			resp, err := transport.send(context.Background(), logs)
+			if err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+			if resp.Accepted != 2 {
+				t.Errorf("Accepted = %d, want 2", resp.Accepted)
+			}
+		})
+	}
+
+	t.Run("gzipped error message", func(t *testing.T) {
+		server := newGzipServer(http.StatusBadRequest, map[string]string{"message": "invalid level"})
+		defer server.Close()
+
+		transport := newHTTPTransport(server.URL, "test-api-key")
+		transport.httpClient = &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+		_, err := transport.send(context.Background(), logs)
+		var logwellErr *Error
+		if !errors.As(err, &logwellErr) || !strings.Contains(logwellErr.Message, "invalid level") {
+			t.Errorf("send() error = %v, want message from the gzipped body", err)
+		}
+	})
+}
+
+func TestTransport_CompressionThreshold(t *testing.T) {
+	type received struct {
+		encoding    string
+		wireLength  int64
+		logs        []LogEntry
+		decodeError error
+	}
+	var (
+		mu   sync.Mutex
+		last received
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := received{encoding: r.Header.Get("Content-Encoding"), wireLength: r.ContentLength}
+		var body io.Reader = r.Body
+		if got.encoding == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				got.decodeError = err
+			} else {
+				body = gz
+			}
+		}
+		var req ingestRequest
+		if got.decodeError == nil {
+			got.decodeError = json.NewDecoder(body).Decode(&req)
+		}
+		got.logs = req.Logs
+		mu.Lock()
+		last = got
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	}))
+	defer server.Close()
+
+	transport := newHTTPTransport(server.URL, "test-api-key")
+	transport.compression = newCompressionLadder(&Config{CompressionThreshold: 1024})
+	transport.compressThreshold = 1024
+
+	// send sends logs and returns what the server received.
+	send := func(t *testing.T, logs []LogEntry) received {
+		t.Helper()
+		if _, err := transport.send(context.Background(), logs); err != nil {
+			t.Fatalf("send() error = %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if last.decodeError != nil {
+			t.Fatalf("server failed to decode body: %v", last.decodeError)
+		}
+		return last
+	}
+
+	t.Run("small body sent uncompressed", func(t *testing.T) {
+		logs := []LogEntry{{Level: LevelInfo, Message: "small"}}
+		got := send(t, logs)
+		if got.encoding != "" {
+			t.Errorf("Content-Encoding = %q, want none", got.encoding)
+		}
+		if len(got.logs) != 1 || got.logs[0].Message != "small" {
+			t.Errorf("server received %+v, want the small entry", got.logs)
+		}
+	})
+
+	t.Run("large body compressed", func(t *testing.T) {
+		logs := make([]LogEntry, 50)
+		for i := range logs {
+			logs[i] = LogEntry{Level: LevelInfo, Message: fmt.Sprintf("entry %d %s", i, strings.Repeat("x", 50))}
+		}
+		got := send(t, logs)
+		if got.encoding != "gzip" {
+			t.Errorf("Content-Encoding = %q, want gzip", got.encoding)
+		}
+		body, _ := json.Marshal(ingestRequest{Logs: logs})
+		if got.wireLength <= 0 || got.wireLength >= int64(len(body)) {
+			t.Errorf("ContentLength = %d, want smaller than the %d byte JSON body", got.wireLength, len(body))
+		}
+		if len(got.logs) != len(logs) {
+			t.Fatalf("server received %d logs, want %d", len(got.logs), len(logs))
+		}
+		for i := range logs {
+			if got.logs[i].Message != logs[i].Message {
+				t.Fatalf("log %d message = %q, want %q", i, got.logs[i].Message, logs[i].Message)
+			}
+		}
+	})
+
+	t.Run("streamed body compressed", func(t *testing.T) {
+		streaming := newHTTPTransport(server.URL, "test-api-key")
+		streaming.compression = transport.compression
+		streaming.compressThreshold = 1024
+		streaming.streamThreshold = 4096
+
+		logs := make([]LogEntry, 2000)
+		for i := range logs {
+			logs[i] = LogEntry{Level: LevelInfo, Message: fmt.Sprintf("entry %d", i)}
+		}
+		if _, err := streaming.send(context.Background(), logs); err != nil {
+			t.Fatalf("send() error = %v", err)
+		}
+		mu.Lock()
+		got := last
+		mu.Unlock()
+		if got.encoding != "gzip" || got.decodeError != nil {
+			t.Fatalf("Content-Encoding = %q, decode error = %v; want a valid gzip body", got.encoding, got.decodeError)
+		}
+		if len(got.logs) != len(logs) || got.logs[len(logs)-1].Message != "entry 1999" {
+			t.Errorf("server received %d logs, want all %d intact", len(got.logs), len(logs))
+		}
+	})
+}
+
+// deflateCompressor is a Compressor for the deflate encoding, standing in
+// for compressors from other modules.
+type deflateCompressor struct{}
+
+func (deflateCompressor) ContentEncoding() string { return "deflate" }
+
+func (deflateCompressor) NewWriter(w io.Writer) io.WriteCloser {
+	fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return fw
+}
+
+// TestTransport_CompressionFallback tests that a 415 response steps down
+// from the preferred encoding to gzip and then to uncompressed bodies,
+// resending the batch at once each time.
+func TestTransport_CompressionFallback(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		accepted  = map[string]bool{"deflate": true, "gzip": true, "": true}
+		encodings []string
+		messages  []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		mu.Lock()
+		encodings = append(encodings, encoding)
+		ok := accepted[encoding]
+		mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var body io.Reader = r.Body
+		switch encoding {
+		case "gzip":
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = gz
+		case "deflate":
+			body = flate.NewReader(r.Body)
+		}
+		var req ingestRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		for _, log := range req.Logs {
+			messages = append(messages, log.Message)
+		}
+		mu.Unlock()
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: len(req.Logs)})
+	}))
+	defer server.Close()
+
+	transport := newHTTPTransport(server.URL, "test-api-key")
+	transport.compression = newCompressionLadder(&Config{Compression: []Compressor{deflateCompressor{}}})
+	transport.compressThreshold = 1
+
+	// send sends one entry and returns the encodings the server saw.
+	send := func(t *testing.T, message string) []string {
+		t.Helper()
+		mu.Lock()
+		encodings = nil
+		mu.Unlock()
+		if _, err := transport.sendWithRetry(context.Background(), []LogEntry{{Level: LevelInfo, Message: message}}); err != nil {
+			t.Fatalf("sendWithRetry() error = %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(messages) == 0 || messages[len(messages)-1] != message {
+			t.Fatalf("server received %q, want %q last", messages, message)
+		}
+		return append([]string(nil), encodings...)
+	}
+
+	if got := send(t, "preferred"); !reflect.DeepEqual(got, []string{"deflate"}) {
+		t.Errorf("encodings = %q, want [deflate]", got)
+	}
+
+	mu.Lock()
+	accepted["deflate"] = false
+	mu.Unlock()
+	if got := send(t, "gzip"); !reflect.DeepEqual(got, []string{"deflate", "gzip"}) {
+		t.Errorf("encodings = %q, want [deflate gzip]", got)
+	}
+	if got := send(t, "gzip again"); !reflect.DeepEqual(got, []string{"gzip"}) {
+		t.Errorf("encodings = %q, want gzip kept after the fallback", got)
+	}
+
+	mu.Lock()
+	accepted["gzip"] = false
+	mu.Unlock()
+	if got := send(t, "identity"); !reflect.DeepEqual(got, []string{"gzip", ""}) {
+		t.Errorf("encodings = %q, want [gzip identity]", got)
+	}
+
+	// With nothing left to fall back to, a 415 is a plain client error
+	mu.Lock()
+	accepted[""] = false
+	mu.Unlock()
+	_, err := transport.sendWithRetry(context.Background(), []LogEntry{{Level: LevelInfo, Message: "rejected"}})
+	var logwellErr *Error
+	if !errors.As(err, &logwellErr) || logwellErr.StatusCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("sendWithRetry() error = %v, want a 415 error", err)
+	}
+}
+
+// TestConfigValidateCompression tests validation of WithCompression.
+func TestConfigValidateCompression(t *testing.T) {
+	_, err := New(validEndpoint(), validAPIKey(), WithCompression(nil))
+	assertConfigError(t, err, ErrInvalidConfig)
+
+	client, err := New(validEndpoint(), validAPIKey(), WithCompression(deflateCompressor{}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+	if client.transport.compressThreshold != DefaultCompressionThreshold {
+		t.Errorf("compressThreshold = %d, want %d", client.transport.compressThreshold, DefaultCompressionThreshold)
+	}
+}
+
+// TestTransport_BatchSequence tests that batches carry the client ID and
+// increasing sequence numbers, and that a retry keeps its batch's number.
+func TestTransport_BatchSequence(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		seqs      []string
+		clientIDs = map[string]bool{}
+		failNext  bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		seqs = append(seqs, r.Header.Get("X-Batch-Seq"))
+		clientIDs[r.Header.Get("X-Client-ID")] = true
+		if failNext {
+			failNext = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: 1})
+	}))
+	defer server.Close()
+
+	client, err := New(server.URL, validAPIKey(), WithBatchSize(100))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	client.Info("first")
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	client.Info("second")
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	mu.Lock()
+	failNext = true
+	mu.Unlock()
+	client.Info("retried")
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"1", "2", "3", "3"}; !reflect.DeepEqual(seqs, want) {
+		t.Errorf("X-Batch-Seq values = %q, want %q", seqs, want)
+	}
+	if len(clientIDs) != 1 {
+		t.Fatalf("X-Client-ID values = %v, want one ID", clientIDs)
+	}
+	for id := range clientIDs {
+		if len(id) != 36 || id[14] != '4' {
+			t.Errorf("X-Client-ID = %q, want a version 4 UUID", id)
+		}
+	}
+	if other := newHTTPTransport(server.URL, "test-api-key"); clientIDs[other.clientID] {
+		t.Error("two transports got the same client ID")
+	}
+}
@@ -1,6 +1,9 @@
 package logwell
 
-import "time"
+import (
+	"sync/atomic"
+	"time"
+)
 
 // LogLevel represents log severity levels matching the Logwell server.
 type LogLevel string
@@ -25,12 +28,17 @@ type LogEntry struct {
 	// Message is the log message content (required).
 	Message string `json:"message"`
 
-	// Timestamp is the ISO8601 timestamp. Auto-generated if not provided.
+	// Timestamp is the ISO8601 timestamp. Auto-generated in UTC with
+	// millisecond precision if not provided.
 	Timestamp string `json:"timestamp,omitempty"`
 
 	// Service is the service name for this log entry.
 	Service string `json:"service,omitempty"`
 
+	// Environment is the deployment environment of this log entry, such
+	// as "production" or "staging".
+	Environment string `json:"environment,omitempty"`
+
 	// Metadata contains arbitrary key-value data.
 	Metadata M `json:"metadata,omitempty"`
 
@@ -39,6 +47,22 @@ type LogEntry struct {
 
 	// LineNumber is the line number where the log was called.
 	LineNumber int `json:"lineNumber,omitempty"`
+
+	// FunctionName is the function where the log was called, qualified by
+	// its package name (e.g. "main.handleRequest").
+	FunctionName string `json:"function,omitempty"`
+
+	// enqueuedAt is when the entry was queued, recorded when a max entry
+	// age is configured.
+	enqueuedAt time.Time
+
+	// deadline is the deadline of the context the entry was logged with,
+	// recorded when context deadline propagation is enabled.
+	deadline time.Time
+
+	// size caches the estimated serialized size of the entry, computed
+	// when a byte-based batch or queue limit is configured.
+	size int
 }
 
 // IngestResponse represents the response from the Logwell ingest API.
@@ -53,13 +77,135 @@ type IngestResponse struct {
 	Errors []string `json:"errors,omitempty"`
 }
 
+// ShutdownStats reports the outcome of draining the queue during shutdown.
+type ShutdownStats struct {
+	// FlushedEntries is the number of entries sent successfully.
+	FlushedEntries int
+
+	// FailedEntries is the number of entries in batches the server rejected
+	// or that failed after all retries.
+	FailedEntries int
+
+	// DroppedEntries is the number of entries discarded without being sent:
+	// queue overflow drops plus entries left unsent when the context ended.
+	DroppedEntries int
+
+	// Elapsed is the total time spent shutting down.
+	Elapsed time.Duration
+}
+
+// add accumulates the entry counts from other into s.
+func (s *ShutdownStats) add(other ShutdownStats) {
+	s.FlushedEntries += other.FlushedEntries
+	s.FailedEntries += other.FailedEntries
+	s.DroppedEntries += other.DroppedEntries
+}
+
+// Stats is a point-in-time snapshot of a client's queue activity.
+type Stats struct {
+	// InFlightBatches is the number of batches currently being sent,
+	// at most MaxConcurrentFlushes.
+	InFlightBatches int
+
+	// BatchSize is the current batch size, which changes over time with
+	// SetBatchSize or adaptive batching.
+	BatchSize int
+
+	// QueueLength is the number of entries waiting in the queue.
+	QueueLength int
+
+	// EnqueuedEntries is the total number of entries added to the queue.
+	EnqueuedEntries int64
+
+	// DroppedEntries is the total number of entries the queue dropped on
+	// overflow or while waiting for space under the Block policy.
+	DroppedEntries int64
+
+	// SentBatches is the total number of batches sent, successfully or
+	// not, and SendDuration the total time spent sending them, retries
+	// included.
+	SentBatches  int64
+	SendDuration time.Duration
+
+	// ConnsNew and ConnsReused count the connections ingest requests
+	// were sent on that were newly dialed and taken from the idle pool.
+	// Both are zero unless WithConnectionMetrics is enabled.
+	ConnsNew    int64
+	ConnsReused int64
+
+	// Offline reports whether offline mode has paused sending because the
+	// endpoint is unreachable. See WithOfflineMode.
+	Offline bool
+
+	// Endpoints reports each endpoint set with WithEndpoints, or the
+	// endpoint and its fallbacks set with WithFallbackEndpoints. It is nil
+	// with a single endpoint.
+	Endpoints []EndpointStats
+}
+
+// EndpointStats reports the sends to one endpoint, in Stats.Endpoints.
+type EndpointStats struct {
+	// URL is the endpoint as configured.
+	URL string
+
+	// SentBatches is the number of batches the endpoint accepted and
+	// FailedAttempts the number of send attempts to it that failed.
+	SentBatches    int64
+	FailedAttempts int64
+
+	// Active reports whether batches start on the endpoint: with
+	// WithEndpoints, whether it is in rotation; with
+	// WithFallbackEndpoints, whether it is the one pinned to.
+	Active bool
+}
+
+// RetryInfo describes a retry of a failed batch, passed to OnRetry.
+type RetryInfo struct {
+	// Attempt is the retry number, starting at 1 for the first retry.
+	Attempt int
+
+	// Err is the error from the attempt that failed.
+	Err error
+
+	// Delay is the backoff the SDK waits before the retry.
+	Delay time.Duration
+
+	// BatchSize is the number of entries in the batch being retried.
+	BatchSize int
+}
+
 // ingestRequest is the internal request structure for the ingest API.
 type ingestRequest struct {
 	Logs []LogEntry `json:"logs"`
 }
 
-// Now returns the current time formatted as ISO8601.
+// clockNow returns the current time for entry age tracking.
+// Tests replace it with a fake clock.
+var clockNow = time.Now
+
+// timestampLayout is ISO8601 in UTC with millisecond precision.
+const timestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// cachedTimestamp is a formatted timestamp and the millisecond it represents.
+type cachedTimestamp struct {
+	unixMilli int64
+	formatted string
+}
+
+// lastTimestamp caches the most recently formatted timestamp so that logs
+// made within the same millisecond share one string.
+var lastTimestamp atomic.Pointer[cachedTimestamp]
+
+// Now returns the current time formatted as ISO8601 with millisecond precision.
 // Used internally for timestamp generation.
 func now() string {
-	return time.Now().UTC().Format(time.RFC3339Nano)
+	t := time.Now().UTC()
+	ms := t.UnixMilli()
+	if cached := lastTimestamp.Load(); cached != nil && cached.unixMilli == ms {
+		return cached.formatted
+	}
+
+	formatted := t.Format(timestampLayout)
+	lastTimestamp.Store(&cachedTimestamp{unixMilli: ms, formatted: formatted})
+	return formatted
 }
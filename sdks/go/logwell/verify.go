@@ -0,0 +1,101 @@
+package logwell
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// keyVerifyPath is the server route that describes the API key a request
+// is made with.
+const keyVerifyPath = "/v1/keys/verify"
+
+// KeyInfo describes an API key, as returned by VerifyKey.
+type KeyInfo struct {
+	// ProjectID and ProjectName identify the project the key logs to.
+	ProjectID   string `json:"projectId"`
+	ProjectName string `json:"projectName"`
+
+	// Service is the service name the key is restricted to, if any.
+	Service string `json:"service,omitempty"`
+
+	// Scopes lists what the key may do, such as "ingest".
+	Scopes []string `json:"scopes"`
+
+	// ExpiresAt is when the key expires; zero if it does not.
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// VerifyKey checks apiKey with the server at endpoint without creating a
+// Client, for provisioning tools that validate keys before rolling out
+// configuration. The endpoint and key are validated as by New, and opts
+// configure the HTTP client as they do for New, such as WithHTTPClient,
+// WithRequestTimeout or WithBasicAuth; options that supply the key in
+// place of apiKey are ignored. Network errors, 5xx and 429 are retried.
+//
+// Errors are *Error values: ErrUnauthorized when the server rejects the
+// key, ErrKeyNotFound when it does not know it, and ErrKeyExpired when the
+// key has expired. When the server describes the key with an expiresAt in
+// the past, its KeyInfo is returned along with ErrKeyExpired; a key_expired
+// rejection returns no KeyInfo.
+//
+// Example:
+//
+//	info, err := logwell.VerifyKey(ctx, "https://logs.example.com", apiKey)
+//	if errors.Is(err, logwell.ErrKeyExpired) {
+//	    // rotate the key
+//	}
+func VerifyKey(ctx context.Context, endpoint, apiKey string, opts ...Option) (*KeyInfo, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cfg := newDefaultConfig(endpoint, apiKey)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.APIKey = apiKey
+	cfg.APIKeyFile = ""
+	cfg.TokenProvider = nil
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	t := newConfiguredTransport(endpoint, cfg, nil)
+	var info KeyInfo
+	err := t.retryRequest(ctx, func() error {
+		info = KeyInfo{}
+		return t.getJSON(ctx, endpoint+keyVerifyPath, &info, verifyKeyError)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !info.ExpiresAt.IsZero() && !info.ExpiresAt.After(time.Now()) {
+		return &info, NewError(ErrKeyExpired, "API key expired at "+info.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return &info, nil
+}
+
+// verifyKeyError maps the responses of the key verification route that
+// describe the key: 404 for an unknown key, and 401 with the key_expired
+// error for an expired one.
+func verifyKeyError(status int, body []byte) *Error {
+	switch status {
+	case http.StatusNotFound:
+		return NewErrorWithStatus(ErrKeyNotFound, "API key not found", status)
+	case http.StatusUnauthorized:
+		var resp struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &resp) == nil && resp.Error == "key_expired" {
+			message := "API key expired"
+			if resp.Message != "" {
+				message += ": " + resp.Message
+			}
+			return NewErrorWithStatus(ErrKeyExpired, message, status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package logwell
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestVerifyKey tests the key verification route's answers for valid,
+// expired and unknown keys.
+func TestVerifyKey(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode ErrorCode
+		wantInfo bool
+	}{
+		{
+			name:     "valid",
+			status:   http.StatusOK,
+			body:     `{"projectId":"p1","projectName":"Billing","scopes":["ingest"],"expiresAt":"` + future.Format(time.RFC3339) + `"}`,
+			wantInfo: true,
+		},
+		{
+			name:     "expired by the server",
+			status:   http.StatusUnauthorized,
+			body:     `{"error":"key_expired","message":"expired on 2024-01-01"}`,
+			wantCode: ErrKeyExpired,
+		},
+		{
+			name:     "expired in the response",
+			status:   http.StatusOK,
+			body:     `{"projectId":"p1","projectName":"Billing","scopes":["ingest"],"expiresAt":"2024-01-01T00:00:00Z"}`,
+			wantCode: ErrKeyExpired,
+			wantInfo: true,
+		},
+		{
+			name:     "rejected",
+			status:   http.StatusUnauthorized,
+			body:     `{"error":"unauthorized","message":"Invalid API key"}`,
+			wantCode: ErrUnauthorized,
+		},
+		{
+			name:     "unknown",
+			status:   http.StatusNotFound,
+			body:     `{"error":"not_found"}`,
+			wantCode: ErrKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				if r.Method != http.MethodGet || r.URL.Path != "/v1/keys/verify" {
+					t.Errorf("request = %s %s, want GET /v1/keys/verify", r.Method, r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer "+validAPIKey() {
+					t.Errorf("Authorization = %q, want the verified key", got)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			info, err := VerifyKey(context.Background(), srv.URL, validAPIKey())
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("VerifyKey() error = %v", err)
+				}
+			} else if !errors.Is(err, tt.wantCode) {
+				t.Fatalf("VerifyKey() error = %v, want %s", err, tt.wantCode)
+			}
+			if got := atomic.LoadInt32(&requests); got != 1 {
+				t.Errorf("requests = %d, want 1", got)
+			}
+
+			if !tt.wantInfo {
+				if info != nil {
+					t.Errorf("VerifyKey() info = %+v, want nil", info)
+				}
+				return
+			}
+			if info == nil || info.ProjectID != "p1" || info.ProjectName != "Billing" || len(info.Scopes) != 1 || info.Scopes[0] != "ingest" {
+				t.Errorf("VerifyKey() info = %+v, want project p1 with the ingest scope", info)
+			}
+		})
+	}
+}
+
+// TestVerifyKeyValidation tests that the endpoint and key are validated
+// before any request, as by New.
+func TestVerifyKeyValidation(t *testing.T) {
+	for _, tt := range []struct{ endpoint, apiKey string }{
+		{"not a url", validAPIKey()},
+		{validEndpoint(), "invalid"},
+	} {
+		_, err := VerifyKey(context.Background(), tt.endpoint, tt.apiKey,
+			WithTokenProvider(func(context.Context) (string, error) { return validAPIKey(), nil }))
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("VerifyKey(%q, %q) error = %v, want %s", tt.endpoint, tt.apiKey, err, ErrInvalidConfig)
+		}
+	}
+}
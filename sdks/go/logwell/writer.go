@@ -0,0 +1,105 @@
+package logwell
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// Writer is an io.Writer that sends each line written to it as a log entry,
+// for libraries that log to an io.Writer such as the standard log package.
+// A line without a trailing newline is held until the next write completes
+// it. By default the level is inferred from the start of each line with
+// InferLevel; WriterWithLevel sets a fixed level instead.
+//
+// Example:
+//
+//	log.SetOutput(logwell.NewWriter(client))
+type Writer struct {
+	client   *Client
+	mapLevel func(line string) (LogLevel, string)
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// WriterOption configures a Writer created by NewWriter.
+type WriterOption func(*Writer)
+
+// WriterWithLevel sends every line at level, without inferring it from the
+// line's content.
+func WriterWithLevel(level LogLevel) WriterOption {
+	return func(w *Writer) {
+		w.mapLevel = func(line string) (LogLevel, string) { return level, line }
+	}
+}
+
+// WriterWithLevelMapper sets how a line's level is determined, replacing
+// InferLevel. fn returns the level and the message to log, which may have
+// the level hint removed.
+func WriterWithLevelMapper(fn func(line string) (LogLevel, string)) WriterOption {
+	return func(w *Writer) {
+		if fn != nil {
+			w.mapLevel = fn
+		}
+	}
+}
+
+// NewWriter returns a Writer that logs through client.
+func NewWriter(client *Client, opts ...WriterOption) *Writer {
+	w := &Writer{client: client, mapLevel: InferLevel}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// InferLevel is the default Writer level mapping. A line starting with a
+// level name accepted by ParseLevel, written in brackets ("[warn] slow"),
+// followed by a colon ("error: disk full"), or in capitals ("WARN slow"), is
+// logged at that level with the hint removed. Other lines, including ones
+// that merely start with a word like "Info", are logged at info unchanged.
+func InferLevel(line string) (LogLevel, string) {
+	word, rest, _ := strings.Cut(line, " ")
+	switch {
+	case strings.HasPrefix(word, "[") && strings.HasSuffix(word, "]"):
+		word = word[1 : len(word)-1]
+	case strings.HasSuffix(word, ":"):
+		word = word[:len(word)-1]
+	case word != strings.ToUpper(word):
+		return LevelInfo, line
+	}
+	if level, err := ParseLevel(word); err == nil {
+		return level, strings.TrimSpace(rest)
+	}
+	return LevelInfo, line
+}
+
+// Write logs each complete line in p. It always consumes all of p.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	w.buf = append(w.buf, p...)
+	var lines []string
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		lines = append(lines, string(bytes.TrimSuffix(w.buf[:i], []byte("\r"))))
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	w.mu.Unlock()
+
+	// Log outside the lock, since logging may flush
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		level, message := w.mapLevel(line)
+		w.client.Log(LogEntry{Level: level, Message: message})
+	}
+	return len(p), nil
+}
@@ -0,0 +1,76 @@
+package logwell
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestWriter tests logging lines written to a Writer.
+func TestWriter(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	client := createTestClient(t, ts, WithBatchSize(100), WithFlushInterval(60*time.Second))
+	defer client.Shutdown(context.Background())
+
+	flushLogs := func(t *testing.T) []LogEntry {
+		t.Helper()
+		clearTestLogs(ts)
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		return ts.getLogs()
+	}
+
+	t.Run("infers levels by default", func(t *testing.T) {
+		w := NewWriter(client)
+		fmt.Fprint(w, "ERROR: disk full\n[warn] slow query\nDEBUG cache miss\nInfo about the system\nplain line\r\n")
+
+		logs := flushLogs(t)
+		want := []struct {
+			level   LogLevel
+			message string
+		}{
+			{LevelError, "disk full"},
+			{LevelWarn, "slow query"},
+			{LevelDebug, "cache miss"},
+			{LevelInfo, "Info about the system"},
+			{LevelInfo, "plain line"},
+		}
+		assertLogCount(t, logs, len(want))
+		for i, w := range want {
+			if i < len(logs) && (logs[i].Level != w.level || logs[i].Message != w.message) {
+				t.Errorf("logs[%d] = %s %q, want %s %q", i, logs[i].Level, logs[i].Message, w.level, w.message)
+			}
+		}
+	})
+
+	t.Run("fixed level skips inference", func(t *testing.T) {
+		w := NewWriter(client, WriterWithLevel(LevelWarn))
+		fmt.Fprint(w, "ERROR: disk full\n")
+
+		logs := flushLogs(t)
+		assertLogCount(t, logs, 1)
+		if logs[0].Level != LevelWarn || logs[0].Message != "ERROR: disk full" {
+			t.Errorf("log = %s %q, want warn with the line unchanged", logs[0].Level, logs[0].Message)
+		}
+	})
+
+	t.Run("buffers partial lines", func(t *testing.T) {
+		w := NewWriter(client)
+		fmt.Fprint(w, "first ")
+		fmt.Fprint(w, "half\nsecond")
+		if got := client.QueueLen(); got != 1 {
+			t.Errorf("QueueLen() = %d, want 1 complete line", got)
+		}
+		fmt.Fprint(w, "\n")
+
+		logs := flushLogs(t)
+		assertLogCount(t, logs, 2)
+		if len(logs) == 2 && (logs[0].Message != "first half" || logs[1].Message != "second") {
+			t.Errorf("messages = %q, %q, want %q, %q", logs[0].Message, logs[1].Message, "first half", "second")
+		}
+	})
+}
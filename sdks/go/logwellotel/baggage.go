@@ -0,0 +1,47 @@
+// Package logwellotel adds OpenTelemetry context to a logwell client's logs.
+// The baggage members of the context given to the *Context logging methods
+// are copied into each log's metadata under the "baggage." prefix, so
+// values such as a tenant or user ID set upstream reach the logs without
+// being passed at every call:
+//
+//	client, err := logwell.New(endpoint, apiKey,
+//		logwellotel.WithBaggage(true))
+package logwellotel
+
+import (
+	"context"
+
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// BaggagePrefix is prepended to the keys of baggage members copied into
+// metadata.
+const BaggagePrefix = "baggage."
+
+// WithBaggage copies the OpenTelemetry baggage members of the context
+// passed to the *Context logging methods into each log's metadata, keyed
+// by BaggagePrefix and the member's key. It registers the client's
+// context metadata extractor, replacing any set with
+// logwell.WithContextMetadata. WithBaggage(false) changes nothing.
+func WithBaggage(enabled bool) logwell.Option {
+	if !enabled {
+		return func(*logwell.Config) {}
+	}
+	return logwell.WithContextMetadata(BaggageMetadata)
+}
+
+// BaggageMetadata returns the baggage members of ctx as metadata keyed by
+// BaggagePrefix and the member's key, or nil if ctx carries no baggage.
+// Use it to combine baggage with other context metadata.
+func BaggageMetadata(ctx context.Context) map[string]any {
+	members := baggage.FromContext(ctx).Members()
+	if len(members) == 0 {
+		return nil
+	}
+	metadata := make(map[string]any, len(members))
+	for _, member := range members {
+		metadata[BaggagePrefix+member.Key()] = member.Value()
+	}
+	return metadata
+}
@@ -0,0 +1,72 @@
+package logwellotel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// TestWithBaggage tests that baggage members of the logging context appear
+// in metadata under the baggage prefix.
+func TestWithBaggage(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		logs []logwell.LogEntry
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Logs []logwell.LogEntry `json:"logs"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		logs = append(logs, req.Logs...)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(logwell.IngestResponse{Accepted: len(req.Logs)})
+	}))
+	defer server.Close()
+
+	client, err := logwell.New(server.URL, "lw_testtesttesttesttesttesttesttest00",
+		logwell.WithBatchSize(100),
+		WithBaggage(true),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	user, _ := baggage.NewMember("user.id", "42")
+	tenant, _ := baggage.NewMember("tenant", "acme")
+	bag, err := baggage.New(user, tenant)
+	if err != nil {
+		t.Fatalf("baggage.New() error = %v", err)
+	}
+	client.InfoContext(baggage.ContextWithBaggage(context.Background(), bag), "with baggage", map[string]any{"tenant": "own"})
+	client.InfoContext(context.Background(), "without baggage")
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(logs) != 2 {
+		t.Fatalf("got %d logs, want 2", len(logs))
+	}
+	want := map[string]any{"baggage.user.id": "42", "baggage.tenant": "acme", "tenant": "own"}
+	for k, v := range want {
+		if logs[0].Metadata[k] != v {
+			t.Errorf("Metadata[%q] = %v, want %v", k, logs[0].Metadata[k], v)
+		}
+	}
+	if len(logs[1].Metadata) != 0 {
+		t.Errorf("Metadata without baggage = %v, want none", logs[1].Metadata)
+	}
+}
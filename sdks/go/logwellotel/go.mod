@@ -0,0 +1,10 @@
+module github.com/Divkix/Logwell/sdks/go/logwellotel
+
+go 1.21
+
+require (
+	github.com/Divkix/Logwell/sdks/go v0.0.0
+	go.opentelemetry.io/otel v1.28.0
+)
+
+replace github.com/Divkix/Logwell/sdks/go => ../
@@ -0,0 +1,68 @@
+// Package logwellprom exports a logwell client's queue statistics as
+// Prometheus metrics.
+//
+// It is a separate module so that the core SDK stays free of dependencies:
+//
+//	prometheus.MustRegister(logwellprom.NewCollector(client))
+package logwellprom
+
+import (
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector that reads a client's Stats on each
+// scrape.
+type Collector struct {
+	client *logwell.Client
+
+	enqueued      *prometheus.Desc
+	dropped       *prometheus.Desc
+	flushDuration *prometheus.Desc
+	queueLength   *prometheus.Desc
+	inFlight      *prometheus.Desc
+}
+
+// Compile-time check that Collector implements prometheus.Collector.
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a Collector for client. constLabels are added to
+// every metric, for example to tell several clients apart.
+func NewCollector(client *logwell.Client, constLabels ...prometheus.Labels) *Collector {
+	var labels prometheus.Labels
+	if len(constLabels) > 0 {
+		labels = constLabels[0]
+	}
+	return &Collector{
+		client: client,
+		enqueued: prometheus.NewDesc("logwell_enqueued_total",
+			"Total number of log entries added to the queue.", nil, labels),
+		dropped: prometheus.NewDesc("logwell_dropped_total",
+			"Total number of log entries dropped by the queue on overflow.", nil, labels),
+		flushDuration: prometheus.NewDesc("logwell_flush_duration_seconds",
+			"Time spent sending batches to the server, retries included.", nil, labels),
+		queueLength: prometheus.NewDesc("logwell_queue_length",
+			"Number of log entries waiting in the queue.", nil, labels),
+		inFlight: prometheus.NewDesc("logwell_inflight_batches",
+			"Number of batches currently being sent.", nil, labels),
+	}
+}
+
+// Describe sends the descriptors of the collected metrics to ch.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.enqueued
+	ch <- c.dropped
+	ch <- c.flushDuration
+	ch <- c.queueLength
+	ch <- c.inFlight
+}
+
+// Collect reads the client's Stats and sends the metrics to ch.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.Stats()
+	ch <- prometheus.MustNewConstMetric(c.enqueued, prometheus.CounterValue, float64(stats.EnqueuedEntries))
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(stats.DroppedEntries))
+	ch <- prometheus.MustNewConstSummary(c.flushDuration, uint64(stats.SentBatches), stats.SendDuration.Seconds(), nil)
+	ch <- prometheus.MustNewConstMetric(c.queueLength, prometheus.GaugeValue, float64(stats.QueueLength))
+	ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(stats.InFlightBatches))
+}
@@ -0,0 +1,82 @@
+package logwellprom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestCollector tests registering the collector and reading its metrics.
+func TestCollector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(logwell.IngestResponse{Accepted: 1})
+	}))
+	defer server.Close()
+
+	client, err := logwell.New(server.URL, "lw_abcdefghijklmnopqrstuvwxyz123456",
+		logwell.WithBatchSize(100),
+		logwell.WithFlushInterval(time.Minute),
+		logwell.WithMaxQueueSize(3),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(NewCollector(client, prometheus.Labels{"client": "test"})); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		client.Info("message")
+	}
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	client.Info("queued")
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		m := family.GetMetric()[0]
+		if got := m.GetLabel()[0].GetValue(); got != "test" {
+			t.Errorf("%s client label = %q, want %q", family.GetName(), got, "test")
+		}
+		switch {
+		case m.Counter != nil:
+			values[family.GetName()] = m.GetCounter().GetValue()
+		case m.Gauge != nil:
+			values[family.GetName()] = m.GetGauge().GetValue()
+		case m.Summary != nil:
+			values[family.GetName()] = float64(m.GetSummary().GetSampleCount())
+		}
+	}
+
+	want := map[string]float64{
+		"logwell_enqueued_total":         5,
+		"logwell_dropped_total":          1,
+		"logwell_flush_duration_seconds": 1,
+		"logwell_queue_length":           1,
+		"logwell_inflight_batches":       0,
+	}
+	for name, value := range want {
+		got, ok := values[name]
+		if !ok {
+			t.Errorf("metric family %s missing", name)
+			continue
+		}
+		if got != value {
+			t.Errorf("%s = %v, want %v", name, got, value)
+		}
+	}
+}
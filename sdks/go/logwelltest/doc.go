@@ -0,0 +1,24 @@
+// Package logwelltest provides a fake Logwell ingest server for testing code
+// that logs through the logwell SDK.
+//
+// A Server accepts ingest requests like a Logwell server and keeps the
+// received entries for assertions. NewRecorder is the shorthand for the
+// common case:
+//
+//	func TestCheckout(t *testing.T) {
+//		rec, url := logwelltest.NewRecorder()
+//		defer rec.Close()
+//
+//		client, _ := logwell.New(url, "lw_"+strings.Repeat("x", 32), logwell.WithBatchSize(1))
+//		defer client.Shutdown(context.Background())
+//
+//		checkout(client)
+//
+//		rec.WaitForCount(t, 1, time.Second)
+//		rec.AssertLogged(t, logwelltest.Level(logwell.LevelInfo), logwelltest.Message("order placed"))
+//	}
+//
+// Scripted responses exercise failure handling: FailNext, RateLimitNext,
+// and RejectNext answer the next requests with errors, rate limits, or
+// partial rejections, and WithLatency delays every response.
+package logwelltest
@@ -0,0 +1,206 @@
+package logwelltest
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+)
+
+// Recorder is a Server without scripted responses, the usual choice for
+// asserting on emitted logs.
+type Recorder = Server
+
+// NewRecorder starts a Server and returns it along with the endpoint URL
+// to pass to logwell.New. Call Close when done.
+func NewRecorder() (*Recorder, string) {
+	s := NewServer()
+	return s, s.URL
+}
+
+// Entries returns a copy of every entry accepted, in arrival order.
+// Entries in requests answered with a scripted failure are not included.
+func (s *Server) Entries() []logwell.LogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entriesLocked()
+}
+
+// entriesLocked returns a copy of every entry received.
+// The caller must hold s.mu.
+func (s *Server) entriesLocked() []logwell.LogEntry {
+	var entries []logwell.LogEntry
+	for _, batch := range s.batches {
+		entries = append(entries, batch...)
+	}
+	return entries
+}
+
+// Batches returns a copy of the entries of each accepted ingest request,
+// one slice per request, in arrival order.
+func (s *Server) Batches() [][]logwell.LogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batches := make([][]logwell.LogEntry, len(s.batches))
+	for i, batch := range s.batches {
+		batches[i] = append([]logwell.LogEntry(nil), batch...)
+	}
+	return batches
+}
+
+// LastEntry returns the most recently received entry, or false if none
+// has been received.
+func (s *Server) LastEntry() (logwell.LogEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.batches) - 1; i >= 0; i-- {
+		if batch := s.batches[i]; len(batch) > 0 {
+			return batch[len(batch)-1], true
+		}
+	}
+	return logwell.LogEntry{}, false
+}
+
+// Reset discards everything recorded so far. Scripted responses not yet
+// served are kept.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = nil
+	s.requests = nil
+}
+
+// WaitForCount waits until at least n entries have been received and
+// returns them, failing t if that takes longer than timeout.
+func (s *Server) WaitForCount(t testing.TB, n int, timeout time.Duration) []logwell.LogEntry {
+	t.Helper()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		s.mu.Lock()
+		entries := s.entriesLocked()
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(entries) >= n {
+			return entries
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			t.Fatalf("logwelltest: got %d entries after %v, want %d", len(entries), timeout, n)
+			return nil
+		}
+	}
+}
+
+// Filter returns the received entries that match all of matchers.
+func (s *Server) Filter(matchers ...Matcher) []logwell.LogEntry {
+	var matched []logwell.LogEntry
+	for _, entry := range s.Entries() {
+		if All(matchers...)(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// AssertLogged fails t unless some received entry matches all of matchers,
+// and returns the first such entry.
+func (s *Server) AssertLogged(t testing.TB, matchers ...Matcher) logwell.LogEntry {
+	t.Helper()
+
+	matched := s.Filter(matchers...)
+	if len(matched) == 0 {
+		t.Fatalf("logwelltest: no entry matches among %d received", len(s.Entries()))
+		return logwell.LogEntry{}
+	}
+	return matched[0]
+}
+
+// AssertNotLogged fails t if any received entry matches all of matchers.
+func (s *Server) AssertNotLogged(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+
+	if matched := s.Filter(matchers...); len(matched) > 0 {
+		t.Fatalf("logwelltest: %d entries match, want none; first: %q", len(matched), matched[0].Message)
+	}
+}
+
+// Matcher reports whether an entry has some property.
+type Matcher func(logwell.LogEntry) bool
+
+// All matches entries that match every one of matchers.
+func All(matchers ...Matcher) Matcher {
+	return func(entry logwell.LogEntry) bool {
+		for _, m := range matchers {
+			if !m(entry) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Level matches entries at level.
+func Level(level logwell.LogLevel) Matcher {
+	return func(entry logwell.LogEntry) bool {
+		return entry.Level == level
+	}
+}
+
+// Message matches entries whose message is exactly message.
+func Message(message string) Matcher {
+	return func(entry logwell.LogEntry) bool {
+		return entry.Message == message
+	}
+}
+
+// MessageContains matches entries whose message contains substr.
+func MessageContains(substr string) Matcher {
+	return func(entry logwell.LogEntry) bool {
+		return strings.Contains(entry.Message, substr)
+	}
+}
+
+// Service matches entries sent with service.
+func Service(service string) Matcher {
+	return func(entry logwell.LogEntry) bool {
+		return entry.Service == service
+	}
+}
+
+// Metadata matches entries whose metadata contains every key in subset
+// with an equal value. Received metadata has been through JSON, so subset
+// is compared after the same round trip: M{"status": 200} matches a
+// status decoded as float64(200).
+func Metadata(subset logwell.M) Matcher {
+	want := jsonRoundTrip(subset)
+	return func(entry logwell.LogEntry) bool {
+		for key, value := range want {
+			got, ok := entry.Metadata[key]
+			if !ok || !reflect.DeepEqual(got, value) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// jsonRoundTrip returns m as it reads after encoding and decoding as JSON,
+// or m itself if it cannot be encoded.
+func jsonRoundTrip(m logwell.M) logwell.M {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return m
+	}
+	var decoded logwell.M
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return m
+	}
+	return decoded
+}
@@ -0,0 +1,72 @@
+package logwelltest
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+)
+
+// TestRecorder tests recording ingest requests and matching entries.
+func TestRecorder(t *testing.T) {
+	rec, url := NewRecorder()
+	defer rec.Close()
+
+	if _, ok := rec.LastEntry(); ok {
+		t.Error("LastEntry() on empty recorder ok = true, want false")
+	}
+
+	post := func(body string) {
+		t.Helper()
+		resp, err := http.Post(url+"/v1/ingest", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("POST error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("POST status = %d, want 200", resp.StatusCode)
+		}
+	}
+	post(`{"logs":[{"level":"info","message":"user signed in","metadata":{"status":200,"user":{"id":"u1"}}}]}`)
+	post(`{"logs":[{"level":"error","message":"payment failed","service":"billing"}]}`)
+
+	entries := rec.WaitForCount(t, 2, time.Second)
+	if len(entries) != 2 || len(rec.Batches()) != 2 {
+		t.Fatalf("got %d entries in %d batches, want 2 in 2", len(entries), len(rec.Batches()))
+	}
+	if last, _ := rec.LastEntry(); last.Message != "payment failed" {
+		t.Errorf("LastEntry().Message = %q, want %q", last.Message, "payment failed")
+	}
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		want    int
+	}{
+		{"level", Level(logwell.LevelError), 1},
+		{"message", Message("user signed in"), 1},
+		{"message contains", MessageContains("failed"), 1},
+		{"service", Service("billing"), 1},
+		{"metadata after JSON round trip", Metadata(logwell.M{"status": 200}), 1},
+		{"nested metadata", Metadata(logwell.M{"user": logwell.M{"id": "u1"}}), 1},
+		{"metadata mismatch", Metadata(logwell.M{"status": 500}), 0},
+		{"all", All(Level(logwell.LevelInfo), MessageContains("failed")), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(rec.Filter(tt.matcher)); got != tt.want {
+				t.Errorf("len(Filter()) = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	rec.AssertLogged(t, Level(logwell.LevelInfo), Metadata(logwell.M{"status": 200}))
+	rec.AssertNotLogged(t, Level(logwell.LevelDebug))
+
+	rec.Reset()
+	if got := len(rec.Entries()); got != 0 {
+		t.Errorf("len(Entries()) after Reset = %d, want 0", got)
+	}
+}
@@ -0,0 +1,226 @@
+package logwelltest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+)
+
+// Server is a fake Logwell ingest server backed by httptest. It records
+// every request, answers with scripted responses while any are queued, and
+// accepts everything otherwise. Gzip request bodies are decoded; other
+// encodings are answered with 415 Unsupported Media Type, so clients fall
+// back as they would against a Logwell server without them. It is safe for
+// concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+	batches  [][]logwell.LogEntry // entries of accepted requests
+	script   []Response
+	latency  time.Duration
+	apiKey   string
+	changed  chan struct{} // closed and replaced on every accepted request
+}
+
+// Request is an ingest request received by a Server.
+type Request struct {
+	// Header is the request's HTTP header.
+	Header http.Header
+
+	// Logs are the entries in the request body.
+	Logs []logwell.LogEntry
+
+	// Status is the HTTP status the Server answered with.
+	Status int
+}
+
+// Response is a scripted answer to one ingest request.
+type Response struct {
+	// Status is the HTTP status. Non-2xx statuses are sent with an error
+	// body and the request's entries are not recorded. Zero means 200.
+	Status int
+
+	// RetryAfter, when positive, is sent as the Retry-After header in
+	// whole seconds.
+	RetryAfter time.Duration
+
+	// Rejected is the number of entries reported as rejected in a 2xx
+	// response, with Errors as the reasons.
+	Rejected int
+	Errors   []string
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithLatency delays every response by d.
+func WithLatency(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.latency = d
+	}
+}
+
+// WithResponses queues scripted responses, served in order to the next
+// requests before the Server goes back to accepting everything.
+func WithResponses(responses ...Response) ServerOption {
+	return func(s *Server) {
+		s.script = append(s.script, responses...)
+	}
+}
+
+// WithAPIKey makes the Server answer 401 unless a request carries key as
+// its bearer token.
+func WithAPIKey(key string) ServerOption {
+	return func(s *Server) {
+		s.apiKey = key
+	}
+}
+
+// NewServer starts a Server configured by opts. Call Close when done.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{changed: make(chan struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// FailNext answers the next n requests with status, typically a 5xx.
+func (s *Server) FailNext(n, status int) {
+	s.queue(n, Response{Status: status})
+}
+
+// RateLimitNext answers the next n requests with 429 and a Retry-After
+// header of retryAfter.
+func (s *Server) RateLimitNext(n int, retryAfter time.Duration) {
+	s.queue(n, Response{Status: http.StatusTooManyRequests, RetryAfter: retryAfter})
+}
+
+// RejectNext accepts the next request but reports rejected of its entries
+// as rejected, with reason as the error.
+func (s *Server) RejectNext(rejected int, reason string) {
+	s.queue(1, Response{Rejected: rejected, Errors: []string{reason}})
+}
+
+// SetLatency changes the delay applied to every response.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// queue appends n copies of resp to the script.
+func (s *Server) queue(n int, resp Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.script = append(s.script, resp)
+	}
+}
+
+// Requests returns a copy of every ingest request received, including
+// those answered with scripted failures, in arrival order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// serveHTTP handles ingest requests. Requests with an unknown encoding are
+// recorded without entries.
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost || req.URL.Path != "/v1/ingest" {
+		http.NotFound(w, req)
+		return
+	}
+
+	var r io.Reader
+	switch encoding := req.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+		r = req.Body
+	case "gzip":
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer gz.Close()
+		r = gz
+	default:
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Header: req.Header.Clone(), Status: http.StatusUnsupportedMediaType})
+		s.mu.Unlock()
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported content encoding "+encoding)
+		return
+	}
+
+	var body struct {
+		Logs []logwell.LogEntry `json:"logs"`
+	}
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	latency := s.latency
+	resp := Response{Status: http.StatusOK}
+	switch {
+	case s.apiKey != "" && req.Header.Get("Authorization") != "Bearer "+s.apiKey:
+		resp.Status = http.StatusUnauthorized
+	case len(s.script) > 0:
+		resp = s.script[0]
+		s.script = s.script[1:]
+		if resp.Status == 0 {
+			resp.Status = http.StatusOK
+		}
+	}
+	s.requests = append(s.requests, Request{Header: req.Header.Clone(), Logs: body.Logs, Status: resp.Status})
+	accepted := resp.Status >= 200 && resp.Status < 300
+	if accepted {
+		s.batches = append(s.batches, body.Logs)
+		close(s.changed)
+		s.changed = make(chan struct{})
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-req.Context().Done():
+			return
+		}
+	}
+
+	if resp.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(resp.RetryAfter.Seconds())))
+	}
+	if !accepted {
+		writeError(w, resp.Status, http.StatusText(resp.Status))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.Status)
+	json.NewEncoder(w).Encode(logwell.IngestResponse{
+		Accepted: len(body.Logs) - resp.Rejected,
+		Rejected: resp.Rejected,
+		Errors:   resp.Errors,
+	})
+}
+
+// writeError writes a JSON error body like the Logwell server's.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
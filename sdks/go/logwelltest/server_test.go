@@ -0,0 +1,168 @@
+package logwelltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+)
+
+// post sends an ingest request with two entries and decodes the response.
+func post(t *testing.T, s *Server, header http.Header) (*http.Response, logwell.IngestResponse) {
+	t.Helper()
+
+	body := `{"logs":[{"level":"info","message":"a"},{"level":"info","message":"b"}]}`
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/v1/ingest", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	var ingest logwell.IngestResponse
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&ingest); err != nil {
+			t.Fatalf("decode response error = %v", err)
+		}
+	}
+	return resp, ingest
+}
+
+// TestServer tests scripted responses, request capture, and latency.
+func TestServer(t *testing.T) {
+	t.Run("scripted responses are served in order", func(t *testing.T) {
+		s := NewServer(WithResponses(Response{Status: http.StatusBadGateway}))
+		defer s.Close()
+		s.FailNext(1, http.StatusServiceUnavailable)
+		s.RateLimitNext(1, 2*time.Second)
+		s.RejectNext(1, "message too long")
+
+		wantStatus := []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK, http.StatusOK}
+		for i, want := range wantStatus {
+			resp, ingest := post(t, s, nil)
+			if resp.StatusCode != want {
+				t.Errorf("request %d status = %d, want %d", i, resp.StatusCode, want)
+			}
+			if want == http.StatusTooManyRequests && resp.Header.Get("Retry-After") != "2" {
+				t.Errorf("Retry-After = %q, want %q", resp.Header.Get("Retry-After"), "2")
+			}
+			if i == 3 && (ingest.Accepted != 1 || ingest.Rejected != 1 || len(ingest.Errors) != 1) {
+				t.Errorf("partial rejection = %+v, want 1 accepted, 1 rejected", ingest)
+			}
+			if i == 4 && ingest.Accepted != 2 {
+				t.Errorf("Accepted = %d, want 2 after the script ends", ingest.Accepted)
+			}
+		}
+
+		requests := s.Requests()
+		if len(requests) != 5 || requests[0].Status != http.StatusBadGateway || len(requests[0].Logs) != 2 {
+			t.Errorf("Requests() = %+v, want 5 captured requests", requests)
+		}
+		// Only accepted requests count as received entries
+		if got := len(s.Entries()); got != 4 {
+			t.Errorf("len(Entries()) = %d, want 4", got)
+		}
+	})
+
+	t.Run("API key is checked", func(t *testing.T) {
+		s := NewServer(WithAPIKey("secret"))
+		defer s.Close()
+
+		if resp, _ := post(t, s, nil); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("status without key = %d, want 401", resp.StatusCode)
+		}
+		if resp, _ := post(t, s, http.Header{"Authorization": {"Bearer secret"}}); resp.StatusCode != http.StatusOK {
+			t.Errorf("status with key = %d, want 200", resp.StatusCode)
+		}
+		if got := s.Requests()[1].Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("captured Authorization = %q, want the request header", got)
+		}
+	})
+
+	t.Run("latency delays responses", func(t *testing.T) {
+		s := NewServer(WithLatency(50 * time.Millisecond))
+		defer s.Close()
+
+		start := time.Now()
+		post(t, s, nil)
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("response took %v, want at least 50ms", elapsed)
+		}
+	})
+}
+
+// identityCompressor claims an encoding the Server does not know and
+// leaves bodies as they are.
+type identityCompressor struct{}
+
+func (identityCompressor) ContentEncoding() string { return "x-unknown" }
+
+func (identityCompressor) NewWriter(w io.Writer) io.WriteCloser { return nopWriteCloser{w} }
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// TestServerContentEncoding tests that gzip bodies are decoded and other
+// encodings are answered with 415 so the client steps down to gzip.
+func TestServerContentEncoding(t *testing.T) {
+	t.Run("gzip bodies are decoded", func(t *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		client, err := logwell.New(s.URL, "lw_"+strings.Repeat("x", 32),
+			logwell.WithBatchSize(1), logwell.WithCompression(logwell.CompressionGzip))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		client.Info(strings.Repeat("x", 4096))
+		if err := client.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+		requests := s.Requests()
+		if len(requests) != 1 || requests[0].Status != http.StatusOK || requests[0].Header.Get("Content-Encoding") != "gzip" {
+			t.Fatalf("Requests() = %+v, want the gzip request accepted", requests)
+		}
+		if got := s.Entries(); len(got) != 1 || got[0].Message != strings.Repeat("x", 4096) {
+			t.Errorf("Entries() = %d entries, want the compressed entry", len(got))
+		}
+	})
+
+	t.Run("unknown encodings fall back", func(t *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		client, err := logwell.New(s.URL, "lw_"+strings.Repeat("x", 32),
+			logwell.WithBatchSize(1), logwell.WithCompression(identityCompressor{}))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		client.Info(strings.Repeat("x", 4096))
+		if err := client.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+
+		requests := s.Requests()
+		if len(requests) != 2 || requests[0].Status != http.StatusUnsupportedMediaType || requests[1].Status != http.StatusOK {
+			t.Fatalf("Requests() = %+v, want 415 then 200", requests)
+		}
+		if got := requests[1].Header.Get("Content-Encoding"); got != "gzip" {
+			t.Errorf("retried Content-Encoding = %q, want gzip", got)
+		}
+		if got := len(s.Entries()); got != 1 {
+			t.Errorf("len(Entries()) = %d, want 1", got)
+		}
+	})
+}
@@ -0,0 +1,10 @@
+module github.com/Divkix/Logwell/sdks/go/logwellzstd
+
+go 1.21
+
+require (
+	github.com/Divkix/Logwell/sdks/go v0.0.0
+	github.com/klauspost/compress v1.17.9
+)
+
+replace github.com/Divkix/Logwell/sdks/go => ../
@@ -0,0 +1,117 @@
+package logwellzstd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Divkix/Logwell/sdks/go/logwell"
+	"github.com/klauspost/compress/zstd"
+)
+
+// TestZstd tests that the server receives zstd bodies it can decode, and
+// that the client falls back to gzip when the server answers 415.
+func TestZstd(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		rejectZstd  bool
+		encodings   []string
+		received    int
+		decodeError error
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		mu.Lock()
+		defer mu.Unlock()
+		encodings = append(encodings, encoding)
+		if encoding == "zstd" && rejectZstd {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		if encoding != "zstd" {
+			// gzip is decoded by the core SDK's tests
+			json.NewEncoder(w).Encode(logwell.IngestResponse{Accepted: 1})
+			return
+		}
+
+		dec, err := zstd.NewReader(r.Body)
+		if err != nil {
+			decodeError = err
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer dec.Close()
+		var req struct {
+			Logs []logwell.LogEntry `json:"logs"`
+		}
+		if err := json.NewDecoder(dec).Decode(&req); err != nil {
+			decodeError = err
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received += len(req.Logs)
+		json.NewEncoder(w).Encode(logwell.IngestResponse{Accepted: len(req.Logs)})
+	}))
+	defer server.Close()
+
+	client, err := logwell.New(server.URL, "lw_abcdefghijklmnopqrstuvwxyz123456",
+		logwell.WithCompression(Zstd),
+		logwell.WithCompressionThreshold(1),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Shutdown(context.Background())
+
+	// Several flushes reuse pooled encoders
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 20; j++ {
+			client.Info(fmt.Sprintf("entry %d %s", j, strings.Repeat("x", 40)))
+		}
+		if err := client.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+	mu.Lock()
+	if decodeError != nil || received != 60 {
+		t.Fatalf("server decoded %d entries, error = %v; want 60", received, decodeError)
+	}
+	for _, encoding := range encodings {
+		if encoding != "zstd" {
+			t.Errorf("encodings = %q, want zstd for every request", encodings)
+			break
+		}
+	}
+	rejectZstd = true
+	encodings = nil
+	mu.Unlock()
+
+	client.Info("after rejection")
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(encodings, ",") != "zstd,gzip" {
+		t.Errorf("encodings = %q, want zstd then gzip", encodings)
+	}
+}
+
+// TestNew tests that invalid encoder options are reported by New.
+func TestNew(t *testing.T) {
+	if _, err := New(zstd.WithWindowSize(3)); err == nil {
+		t.Error("New() error = nil, want invalid window size error")
+	}
+	c, err := New(zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := c.ContentEncoding(); got != "zstd" {
+		t.Errorf("ContentEncoding() = %q, want zstd", got)
+	}
+}
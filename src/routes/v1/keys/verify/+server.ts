@@ -0,0 +1,66 @@
+import { json, type RequestHandler } from '@sveltejs/kit';
+import { eq } from 'drizzle-orm';
+import type { PgliteDatabase } from 'drizzle-orm/pglite';
+import type { PostgresJsDatabase } from 'drizzle-orm/postgres-js';
+import type * as schema from '$lib/server/db/schema';
+import { project } from '$lib/server/db/schema';
+import { validateApiKeyFormat } from '$lib/server/utils/api-key';
+
+// Project API keys may ingest, query and stream logs
+const API_KEY_SCOPES = ['ingest', 'query', 'stream'];
+
+async function getDbClient(
+  locals: App.Locals,
+): Promise<PgliteDatabase<typeof schema> | PostgresJsDatabase<typeof schema>> {
+  if (locals.db) {
+    return locals.db;
+  }
+  const { db } = await import('$lib/server/db');
+  return db;
+}
+
+/**
+ * GET /v1/keys/verify
+ *
+ * Describes the API key the request is made with, for SDKs and provisioning
+ * tools that check a key before using it.
+ * Uses project API key authentication (Authorization: Bearer lw_xxx).
+ *
+ * Responses:
+ * - 200: `{ projectId, projectName, scopes }`
+ * - 401: Missing or malformed key
+ * - 404: Well-formed key that no project has
+ *
+ * Project keys do not expire, so no expiresAt is returned.
+ */
+export const GET: RequestHandler = async ({ request, locals }) => {
+  const authHeader = request.headers.get('authorization');
+  if (!authHeader || !authHeader.startsWith('Bearer ')) {
+    return json(
+      { error: 'unauthorized', message: 'Missing or invalid authorization header' },
+      { status: 401 },
+    );
+  }
+
+  const apiKey = authHeader.substring(7);
+  if (!validateApiKeyFormat(apiKey)) {
+    return json({ error: 'unauthorized', message: 'Invalid API key format' }, { status: 401 });
+  }
+
+  const db = await getDbClient(locals);
+  const [found] = await db
+    .select({ id: project.id, name: project.name })
+    .from(project)
+    .where(eq(project.apiKey, apiKey))
+    .limit(1);
+
+  if (!found) {
+    return json({ error: 'not_found', message: 'API key not found' }, { status: 404 });
+  }
+
+  return json({
+    projectId: found.id,
+    projectName: found.name,
+    scopes: API_KEY_SCOPES,
+  });
+};
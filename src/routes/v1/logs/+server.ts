@@ -1,4 +1,5 @@
 import { json, type RequestHandler } from '@sveltejs/kit';
+import { and, desc, eq, gte, inArray, lt, lte, or, type SQL, sql } from 'drizzle-orm';
 import type { PgliteDatabase } from 'drizzle-orm/pglite';
 import type { PostgresJsDatabase } from 'drizzle-orm/postgres-js';
 import { nanoid } from 'nanoid';
@@ -6,6 +7,7 @@ import type * as schema from '$lib/server/db/schema';
 import { log } from '$lib/server/db/schema';
 import { logEventBus } from '$lib/server/events';
 import { ApiKeyError, validateApiKey } from '$lib/server/utils/api-key';
+import { decodeCursor, encodeCursor } from '$lib/server/utils/cursor';
 import {
   assignIncidentIds,
   prepareLogsForIncidents,
@@ -17,6 +19,12 @@ import {
   normalizeOtlpLogsRequest,
   OtlpValidationError,
 } from '$lib/server/utils/otlp';
+import { buildSearchQuery } from '$lib/server/utils/search';
+import { LOG_LEVELS, type LogLevel } from '$lib/shared/types';
+
+// Page size bounds for GET /v1/logs
+const DEFAULT_QUERY_LIMIT = 100;
+const MAX_QUERY_LIMIT = 500;
 
 async function getDbClient(
   locals: App.Locals,
@@ -41,6 +49,164 @@ function buildPartialSuccess(rejected: number) {
   };
 }
 
+/**
+ * Parse a comma-separated level filter, keeping only known levels
+ */
+function parseLevelFilter(levelParam: string | null): LogLevel[] | null {
+  if (!levelParam) return null;
+
+  const levels = levelParam
+    .split(',')
+    .map((l) => l.trim().toLowerCase())
+    .filter((l): l is LogLevel => LOG_LEVELS.includes(l as LogLevel));
+
+  return levels.length > 0 ? levels : null;
+}
+
+/**
+ * GET /v1/logs
+ *
+ * Searches the logs of the project the API key belongs to, newest first.
+ * Uses project API key authentication (Authorization: Bearer lw_xxx).
+ *
+ * Query Parameters:
+ * - limit: number (1-500, default 100) - Logs per page
+ * - cursor: string - nextCursor of the previous page
+ * - service: string - Filter by service name
+ * - level: string - Filter by level (comma-separated, e.g., "error,fatal")
+ * - search: string - Full-text search query
+ * - from: string (ISO 8601) - Start timestamp filter
+ * - to: string (ISO 8601) - End timestamp filter
+ *
+ * Response:
+ * {
+ *   logs: Array<Log>,
+ *   has_more: boolean,
+ *   nextCursor: string | null
+ * }
+ *
+ * Error responses:
+ * - 401 unauthorized: Missing or invalid API key
+ * - 400 invalid_cursor: Cursor is malformed
+ * - 400 invalid_from: from is not a valid timestamp
+ * - 400 invalid_to: to is not a valid timestamp
+ */
+export const GET: RequestHandler = async ({ request, url, locals }) => {
+  const db = await getDbClient(locals);
+
+  let projectId: string;
+  try {
+    projectId = await validateApiKey(request, db);
+  } catch (err) {
+    if (err instanceof ApiKeyError) {
+      return json({ error: 'unauthorized', message: err.message }, { status: err.status });
+    }
+    throw err;
+  }
+
+  const limitParam = url.searchParams.get('limit');
+  const cursorParam = url.searchParams.get('cursor');
+  const serviceParam = url.searchParams.get('service');
+  const levels = parseLevelFilter(url.searchParams.get('level'));
+  const searchParam = url.searchParams.get('search');
+  const fromParam = url.searchParams.get('from');
+  const toParam = url.searchParams.get('to');
+
+  const parsedLimit = limitParam ? Number.parseInt(limitParam, 10) : Number.NaN;
+  const limit = Number.isNaN(parsedLimit)
+    ? DEFAULT_QUERY_LIMIT
+    : Math.min(Math.max(parsedLimit, 1), MAX_QUERY_LIMIT);
+
+  const conditions: SQL[] = [eq(log.projectId, projectId)];
+
+  if (cursorParam) {
+    try {
+      const { timestamp: cursorTimestamp, id: cursorId } = decodeCursor(cursorParam);
+      conditions.push(
+        or(
+          lt(log.timestamp, cursorTimestamp),
+          and(eq(log.timestamp, cursorTimestamp), lt(log.id, cursorId)),
+        ) as SQL,
+      );
+    } catch (error) {
+      return json(
+        {
+          error: 'invalid_cursor',
+          message: error instanceof Error ? error.message : 'Invalid cursor',
+        },
+        { status: 400 },
+      );
+    }
+  }
+
+  if (serviceParam) {
+    conditions.push(eq(log.serviceName, serviceParam));
+  }
+  if (levels) {
+    conditions.push(inArray(log.level, levels));
+  }
+
+  const fromDate = fromParam ? new Date(fromParam) : null;
+  if (fromDate && Number.isNaN(fromDate.getTime())) {
+    return json(
+      { error: 'invalid_from', message: 'from must be an ISO 8601 timestamp' },
+      { status: 400 },
+    );
+  }
+  const toDate = toParam ? new Date(toParam) : null;
+  if (toDate && Number.isNaN(toDate.getTime())) {
+    return json(
+      { error: 'invalid_to', message: 'to must be an ISO 8601 timestamp' },
+      { status: 400 },
+    );
+  }
+  if (fromDate) {
+    conditions.push(gte(log.timestamp, fromDate));
+  }
+  if (toDate) {
+    conditions.push(lte(log.timestamp, toDate));
+  }
+
+  const tsquery = searchParam ? buildSearchQuery(searchParam) : '';
+  if (tsquery) {
+    conditions.push(sql`${log.search} @@ to_tsquery('english', ${tsquery})`);
+  }
+
+  // Fetch one extra row to learn whether another page exists
+  const rows = await db
+    .select({
+      id: log.id,
+      serviceName: log.serviceName,
+      level: log.level,
+      message: log.message,
+      metadata: log.metadata,
+      sourceFile: log.sourceFile,
+      lineNumber: log.lineNumber,
+      requestId: log.requestId,
+      userId: log.userId,
+      ipAddress: log.ipAddress,
+      timestamp: log.timestamp,
+    })
+    .from(log)
+    .where(and(...conditions))
+    .orderBy(desc(log.timestamp), desc(log.id))
+    .limit(limit + 1);
+
+  const hasMore = rows.length > limit;
+  const logs = hasMore ? rows.slice(0, limit) : rows;
+  const last = logs[logs.length - 1];
+  const nextCursor = hasMore && last ? encodeCursor(last.timestamp as Date, last.id) : null;
+
+  return json({
+    logs: logs.map((l) => ({
+      ...l,
+      timestamp: l.timestamp?.toISOString(),
+    })),
+    has_more: hasMore,
+    nextCursor,
+  });
+};
+
 /**
  * POST /v1/logs (OTLP/HTTP JSON)
  *
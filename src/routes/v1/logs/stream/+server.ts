@@ -0,0 +1,291 @@
+import { json, type RequestHandler } from '@sveltejs/kit';
+import { and, asc, eq, gte, ilike, inArray, type SQL } from 'drizzle-orm';
+import type { PgliteDatabase } from 'drizzle-orm/pglite';
+import type { PostgresJsDatabase } from 'drizzle-orm/postgres-js';
+import { SSE_CONFIG } from '$lib/server/config/performance';
+import type * as schema from '$lib/server/db/schema';
+import { type Log, log } from '$lib/server/db/schema';
+import { logEventBus } from '$lib/server/events';
+import { ApiKeyError, validateApiKey } from '$lib/server/utils/api-key';
+import { LOG_LEVELS, type LogLevel } from '$lib/shared/types';
+
+// Destructure SSE configuration for cleaner access
+const { BATCH_WINDOW_MS, MAX_BATCH_SIZE, HEARTBEAT_INTERVAL_MS } = SSE_CONFIG;
+
+// Maximum number of stored logs replayed for the since parameter
+const MAX_BACKFILL_SIZE = 500;
+
+async function getDbClient(
+  locals: App.Locals,
+): Promise<PgliteDatabase<typeof schema> | PostgresJsDatabase<typeof schema>> {
+  if (locals.db) {
+    return locals.db;
+  }
+  const { db } = await import('$lib/server/db');
+  return db;
+}
+
+/**
+ * Format an SSE event
+ */
+function formatSSEEvent(event: string, data: string): string {
+  return `event: ${event}\ndata: ${data}\n\n`;
+}
+
+/**
+ * Parse a comma-separated level filter, keeping only known levels
+ */
+function parseLevelFilter(levelParam: string | null): LogLevel[] | null {
+  if (!levelParam) return null;
+
+  const levels = levelParam
+    .split(',')
+    .map((l) => l.trim().toLowerCase())
+    .filter((l): l is LogLevel => LOG_LEVELS.includes(l as LogLevel));
+
+  return levels.length > 0 ? levels : null;
+}
+
+/**
+ * Escape the LIKE wildcards of a search term so it matches literally
+ */
+function escapeLikePattern(term: string): string {
+  return term.replace(/[\\%_]/g, '\\$&');
+}
+
+/**
+ * GET /v1/logs/stream
+ *
+ * Server-Sent Events endpoint for real-time log streaming.
+ * Uses project API key authentication (Authorization: Bearer lw_xxx).
+ *
+ * Query parameters:
+ * - `service`: Only stream logs of this service
+ * - `level`: Comma-separated levels to stream
+ * - `search`: Only stream logs, stored or live, whose message contains every
+ *   word, ignoring case
+ * - `since`: ISO timestamp; first replays up to 500 stored logs from this
+ *   time on, oldest first, then continues with live logs
+ *
+ * SSE Events:
+ * - `backfill`: Array of the stored logs replayed for `since`, sent once
+ *   before any live logs, even when empty
+ * - `logs`: Batched array of live Log objects
+ * - `heartbeat`: Keep-alive ping with timestamp
+ *
+ * Batching behaves like POST /api/projects/[id]/logs/stream.
+ */
+export const GET: RequestHandler = async ({ request, url, locals }) => {
+  const db = await getDbClient(locals);
+
+  let projectId: string;
+  try {
+    projectId = await validateApiKey(request, db);
+  } catch (err) {
+    if (err instanceof ApiKeyError) {
+      return json({ error: 'unauthorized', message: err.message }, { status: err.status });
+    }
+    throw err;
+  }
+
+  const serviceParam = url.searchParams.get('service');
+  const levels = parseLevelFilter(url.searchParams.get('level'));
+  const searchParam = url.searchParams.get('search');
+  const sinceParam = url.searchParams.get('since');
+
+  const sinceDate = sinceParam ? new Date(sinceParam) : null;
+  if (sinceDate && Number.isNaN(sinceDate.getTime())) {
+    return json(
+      { error: 'invalid_since', message: 'since must be an ISO 8601 timestamp' },
+      { status: 400 },
+    );
+  }
+
+  const searchTerms = (searchParam ?? '')
+    .toLowerCase()
+    .split(/\s+/)
+    .filter((term) => term.length > 0);
+
+  /**
+   * Check a live log against the stream's filters
+   */
+  const matches = (entry: Log): boolean => {
+    if (serviceParam && entry.serviceName !== serviceParam) return false;
+    if (levels && !levels.includes(entry.level as LogLevel)) return false;
+    if (searchTerms.length > 0) {
+      const message = entry.message.toLowerCase();
+      return searchTerms.every((term) => message.includes(term));
+    }
+    return true;
+  };
+
+  /**
+   * Load the stored logs replayed for the since parameter, oldest first
+   */
+  const loadBackfill = async (since: Date): Promise<Log[]> => {
+    const conditions: SQL[] = [eq(log.projectId, projectId), gte(log.timestamp, since)];
+    if (serviceParam) {
+      conditions.push(eq(log.serviceName, serviceParam));
+    }
+    if (levels) {
+      conditions.push(inArray(log.level, levels));
+    }
+    // Same predicate as matches: the message contains every term, ignoring case
+    for (const term of searchTerms) {
+      conditions.push(ilike(log.message, `%${escapeLikePattern(term)}%`));
+    }
+    return db
+      .select()
+      .from(log)
+      .where(and(...conditions))
+      .orderBy(asc(log.timestamp), asc(log.id))
+      .limit(MAX_BACKFILL_SIZE);
+  };
+
+  // Store cleanup function outside the stream for cancel handler access
+  let cleanupFn: (() => void) | null = null;
+
+  // Create a readable stream for SSE
+  const stream = new ReadableStream({
+    start(controller) {
+      const encoder = new TextEncoder();
+
+      // Buffer for batching logs
+      let batch: Log[] = [];
+      let flushTimeout: ReturnType<typeof setTimeout> | null = null;
+      let isClosed = false;
+
+      // Live logs held back until the backfill has been sent
+      let pending: Log[] | null = sinceDate ? [] : null;
+
+      /**
+       * Send an SSE event to the client
+       */
+      const sendEvent = (eventName: string, data: string): boolean => {
+        if (isClosed) return false;
+        try {
+          controller.enqueue(encoder.encode(formatSSEEvent(eventName, data)));
+          return true;
+        } catch {
+          // Controller closed
+          return false;
+        }
+      };
+
+      /**
+       * Flush the current batch to the client
+       */
+      const flushBatch = () => {
+        if (batch.length > 0) {
+          const success = sendEvent('logs', JSON.stringify(batch));
+          if (!success) {
+            cleanup();
+          }
+          batch = [];
+        }
+        flushTimeout = null;
+      };
+
+      /**
+       * Add a matching log to the batch
+       */
+      const enqueueLog = (entry: Log) => {
+        batch.push(entry);
+
+        // Start batch timer if not already running
+        if (!flushTimeout) {
+          flushTimeout = setTimeout(flushBatch, BATCH_WINDOW_MS);
+        }
+
+        // Flush immediately if batch is full
+        if (batch.length >= MAX_BATCH_SIZE) {
+          if (flushTimeout) {
+            clearTimeout(flushTimeout);
+            flushTimeout = null;
+          }
+          flushBatch();
+        }
+      };
+
+      /**
+       * Handler for incoming logs from event bus
+       */
+      const handleLog = (entry: Log) => {
+        if (isClosed || !matches(entry)) return;
+        if (pending) {
+          pending.push(entry);
+          return;
+        }
+        enqueueLog(entry);
+      };
+
+      // Subscribe before loading the backfill so no log falls between them
+      const unsubscribe = logEventBus.onLog(projectId, handleLog);
+
+      // Set up heartbeat to keep connection alive
+      const heartbeatInterval = setInterval(() => {
+        const success = sendEvent('heartbeat', JSON.stringify({ ts: Date.now() }));
+        if (!success) {
+          cleanup();
+        }
+      }, HEARTBEAT_INTERVAL_MS);
+
+      /**
+       * Cleanup function to unsubscribe and clear timers
+       */
+      const cleanup = () => {
+        if (isClosed) return;
+        isClosed = true;
+        unsubscribe();
+        clearInterval(heartbeatInterval);
+        if (flushTimeout) {
+          clearTimeout(flushTimeout);
+        }
+        try {
+          controller.close();
+        } catch {
+          // Already closed
+        }
+      };
+
+      // Store cleanup for cancel handler
+      cleanupFn = cleanup;
+
+      if (sinceDate) {
+        loadBackfill(sinceDate)
+          .then((stored) => {
+            if (isClosed) return;
+            if (!sendEvent('backfill', JSON.stringify(stored))) {
+              cleanup();
+              return;
+            }
+            // Release live logs, skipping those the backfill already sent
+            const sent = new Set(stored.map((entry) => entry.id));
+            const held = pending ?? [];
+            pending = null;
+            for (const entry of held) {
+              if (!sent.has(entry.id)) enqueueLog(entry);
+            }
+          })
+          .catch(() => {
+            cleanup();
+          });
+      }
+    },
+    cancel() {
+      // Called when client disconnects
+      if (cleanupFn) {
+        cleanupFn();
+      }
+    },
+  });
+
+  return new Response(stream, {
+    status: 200,
+    headers: {
+      'Content-Type': 'text/event-stream',
+      'Cache-Control': 'no-cache',
+      Connection: 'keep-alive',
+    },
+  });
+};
@@ -0,0 +1,159 @@
+import type { PgliteDatabase } from 'drizzle-orm/pglite';
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import type * as schema from '../../../src/lib/server/db/schema';
+import { setupTestDatabase } from '../../../src/lib/server/db/test-db';
+import { clearApiKeyCache } from '../../../src/lib/server/utils/api-key';
+import { GET } from '../../../src/routes/v1/logs/+server';
+import { seedLog, seedLogs, seedProject } from '../../fixtures/db';
+
+function createRequestEvent(request: Request, db: PgliteDatabase<typeof schema>) {
+  return {
+    request,
+    locals: { db },
+    params: {},
+    url: new URL(request.url),
+    platform: undefined,
+    route: { id: '/v1/logs' },
+    isDataRequest: false,
+    isSubRequest: false,
+    isRemoteRequest: false,
+    tracing: null,
+    cookies: {
+      get: () => undefined,
+      getAll: () => [],
+      set: () => {},
+      delete: () => {},
+      serialize: () => '',
+    },
+    fetch: globalThis.fetch,
+    getClientAddress: () => '127.0.0.1',
+    setHeaders: () => {},
+  } as unknown;
+}
+
+function queryRequest(query: string, apiKey?: string): Request {
+  return new Request(`http://localhost/v1/logs${query}`, {
+    method: 'GET',
+    headers: apiKey ? { Authorization: `Bearer ${apiKey}` } : {},
+  });
+}
+
+describe('GET /v1/logs (API key search)', () => {
+  let db: PgliteDatabase<typeof schema>;
+  let cleanup: () => Promise<void>;
+
+  beforeEach(async () => {
+    const setup = await setupTestDatabase();
+    db = setup.db;
+    cleanup = setup.cleanup;
+    clearApiKeyCache();
+  });
+
+  afterEach(async () => {
+    await cleanup();
+  });
+
+  describe('Authentication', () => {
+    it('returns 401 without Authorization header', async () => {
+      const response = await GET(createRequestEvent(queryRequest(''), db) as never);
+      expect(response.status).toBe(401);
+    });
+
+    it('returns 401 with invalid API key', async () => {
+      const request = queryRequest('', 'lw_invalid_key_that_does_not_exist');
+      const response = await GET(createRequestEvent(request, db) as never);
+      expect(response.status).toBe(401);
+    });
+  });
+
+  describe('Filtering', () => {
+    it('returns only the logs of the API key project, newest first', async () => {
+      const project = await seedProject(db);
+      const other = await seedProject(db);
+      const now = Date.now();
+      const older = await seedLog(db, project.id, { timestamp: new Date(now - 2000) });
+      const newer = await seedLog(db, project.id, { timestamp: new Date(now - 1000) });
+      await seedLog(db, other.id);
+
+      const response = await GET(createRequestEvent(queryRequest('', project.apiKey), db) as never);
+
+      expect(response.status).toBe(200);
+      const body = await response.json();
+      expect(body.logs.map((l: { id: string }) => l.id)).toEqual([newer.id, older.id]);
+      expect(body.has_more).toBe(false);
+      expect(body.nextCursor).toBeNull();
+    });
+
+    it('filters by service and level', async () => {
+      const project = await seedProject(db);
+      const match = await seedLog(db, project.id, { serviceName: 'billing', level: 'error' });
+      await seedLog(db, project.id, { serviceName: 'billing', level: 'info' });
+      await seedLog(db, project.id, { serviceName: 'auth', level: 'error' });
+
+      const request = queryRequest('?service=billing&level=error,fatal', project.apiKey);
+      const response = await GET(createRequestEvent(request, db) as never);
+
+      const body = await response.json();
+      expect(body.logs).toHaveLength(1);
+      expect(body.logs[0].id).toBe(match.id);
+      expect(body.logs[0].serviceName).toBe('billing');
+    });
+  });
+
+  describe('Pagination', () => {
+    it('pages through logs with nextCursor', async () => {
+      const project = await seedProject(db);
+      await seedLogs(db, project.id, 5);
+
+      const first = await GET(
+        createRequestEvent(queryRequest('?limit=3', project.apiKey), db) as never,
+      );
+      const firstBody = await first.json();
+      expect(firstBody.logs).toHaveLength(3);
+      expect(firstBody.has_more).toBe(true);
+
+      const second = await GET(
+        createRequestEvent(
+          queryRequest(`?limit=3&cursor=${firstBody.nextCursor}`, project.apiKey),
+          db,
+        ) as never,
+      );
+      const secondBody = await second.json();
+      expect(secondBody.logs).toHaveLength(2);
+      expect(secondBody.has_more).toBe(false);
+
+      const ids = [...firstBody.logs, ...secondBody.logs].map((l: { id: string }) => l.id);
+      expect(new Set(ids).size).toBe(5);
+    });
+
+    it('returns 400 for a malformed cursor', async () => {
+      const project = await seedProject(db);
+      const request = queryRequest('?cursor=not-a-cursor', project.apiKey);
+      const response = await GET(createRequestEvent(request, db) as never);
+
+      expect(response.status).toBe(400);
+      const body = await response.json();
+      expect(body.error).toBe('invalid_cursor');
+    });
+
+    it('returns 400 for a malformed from', async () => {
+      const project = await seedProject(db);
+      const request = queryRequest('?from=yesterday', project.apiKey);
+      const response = await GET(createRequestEvent(request, db) as never);
+
+      expect(response.status).toBe(400);
+      const body = await response.json();
+      expect(body.error).toBe('invalid_from');
+    });
+
+    it('returns 400 for a malformed to', async () => {
+      const project = await seedProject(db);
+      const request = queryRequest('?to=yesterday', project.apiKey);
+      const response = await GET(createRequestEvent(request, db) as never);
+
+      expect(response.status).toBe(400);
+      const body = await response.json();
+      expect(body.error).toBe('invalid_to');
+    });
+  });
+});
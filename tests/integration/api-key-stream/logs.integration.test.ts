@@ -0,0 +1,273 @@
+import type { PgliteDatabase } from 'drizzle-orm/pglite';
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import type * as schema from '../../../src/lib/server/db/schema';
+import type { Log } from '../../../src/lib/server/db/schema';
+import { setupTestDatabase } from '../../../src/lib/server/db/test-db';
+import { logEventBus } from '../../../src/lib/server/events';
+import { clearApiKeyCache } from '../../../src/lib/server/utils/api-key';
+import { GET } from '../../../src/routes/v1/logs/stream/+server';
+import { seedLog, seedProject } from '../../fixtures/db';
+
+function createRequestEvent(request: Request, db: PgliteDatabase<typeof schema>) {
+  return {
+    request,
+    locals: { db },
+    params: {},
+    url: new URL(request.url),
+    platform: undefined,
+    route: { id: '/v1/logs/stream' },
+    isDataRequest: false,
+    isSubRequest: false,
+    isRemoteRequest: false,
+    tracing: null,
+    cookies: {
+      get: () => undefined,
+      getAll: () => [],
+      set: () => {},
+      delete: () => {},
+      serialize: () => '',
+    },
+    fetch: globalThis.fetch,
+    getClientAddress: () => '127.0.0.1',
+    setHeaders: () => {},
+  } as unknown;
+}
+
+function streamRequest(query: string, apiKey?: string): Request {
+  return new Request(`http://localhost/v1/logs/stream${query}`, {
+    method: 'GET',
+    headers: apiKey ? { Authorization: `Bearer ${apiKey}` } : {},
+  });
+}
+
+/**
+ * Helper to parse SSE events from a stream
+ * Returns an async iterator of parsed events
+ */
+async function* parseSSEStream(
+  response: Response,
+): AsyncGenerator<{ event: string; data: string }> {
+  const reader = response.body?.getReader();
+  if (!reader) throw new Error('No response body');
+
+  const decoder = new TextDecoder();
+  let buffer = '';
+
+  try {
+    while (true) {
+      const { done, value } = await reader.read();
+      if (done) break;
+
+      buffer += decoder.decode(value, { stream: true });
+
+      // Parse complete events from buffer
+      const lines = buffer.split('\n');
+      buffer = lines.pop() || ''; // Keep incomplete line in buffer
+
+      let currentEvent = '';
+      let currentData = '';
+
+      for (const line of lines) {
+        if (line.startsWith('event:')) {
+          currentEvent = line.slice(6).trim();
+        } else if (line.startsWith('data:')) {
+          currentData = line.slice(5).trim();
+        } else if (line === '' && currentEvent && currentData) {
+          yield { event: currentEvent, data: currentData };
+          currentEvent = '';
+          currentData = '';
+        }
+      }
+    }
+  } finally {
+    reader.releaseLock();
+  }
+}
+
+/**
+ * Helper to collect N events from SSE stream with timeout
+ */
+async function collectSSEEvents(
+  response: Response,
+  count: number,
+  timeoutMs = 5000,
+): Promise<Array<{ event: string; data: string }>> {
+  const events: Array<{ event: string; data: string }> = [];
+  const stream = parseSSEStream(response);
+
+  let timeoutId: ReturnType<typeof setTimeout> | null = null;
+  let timedOut = false;
+
+  const timeoutPromise = new Promise<void>((resolve) => {
+    timeoutId = setTimeout(() => {
+      timedOut = true;
+      resolve();
+    }, timeoutMs);
+  });
+
+  const collectPromise = (async () => {
+    try {
+      for await (const event of stream) {
+        if (timedOut) break;
+        events.push(event);
+        if (events.length >= count) break;
+      }
+    } catch {
+      // Stream closed or error - return what we have
+    }
+  })();
+
+  await Promise.race([collectPromise, timeoutPromise]);
+
+  if (timeoutId) {
+    clearTimeout(timeoutId);
+  }
+
+  return events;
+}
+
+/**
+ * Create a mock Log object for testing
+ */
+function createMockLog(projectId: string, overrides: Partial<Log> = {}): Log {
+  return {
+    id: `log_${Math.random().toString(36).slice(2, 10)}`,
+    projectId,
+    incidentId: null,
+    fingerprint: null,
+    serviceName: null,
+    level: 'info',
+    message: 'Test log message',
+    metadata: null,
+    timeUnixNano: null,
+    observedTimeUnixNano: null,
+    severityNumber: null,
+    severityText: null,
+    body: null,
+    droppedAttributesCount: null,
+    flags: null,
+    traceId: null,
+    spanId: null,
+    resourceAttributes: null,
+    resourceDroppedAttributesCount: null,
+    resourceSchemaUrl: null,
+    scopeName: null,
+    scopeVersion: null,
+    scopeAttributes: null,
+    scopeDroppedAttributesCount: null,
+    scopeSchemaUrl: null,
+    sourceFile: null,
+    lineNumber: null,
+    requestId: null,
+    userId: null,
+    ipAddress: null,
+    timestamp: new Date(),
+    search: '',
+    ...overrides,
+  };
+}
+
+describe('GET /v1/logs/stream (API key tail)', () => {
+  let db: PgliteDatabase<typeof schema>;
+  let cleanup: () => Promise<void>;
+
+  beforeEach(async () => {
+    const setup = await setupTestDatabase();
+    db = setup.db;
+    cleanup = setup.cleanup;
+    clearApiKeyCache();
+    logEventBus.clear();
+  });
+
+  afterEach(async () => {
+    logEventBus.clear();
+    await cleanup();
+  });
+
+  describe('Authentication', () => {
+    it('returns 401 without Authorization header', async () => {
+      const response = await GET(createRequestEvent(streamRequest(''), db) as never);
+      expect(response.status).toBe(401);
+    });
+
+    it('returns 401 with invalid API key', async () => {
+      const request = streamRequest('', 'lw_invalid_key_that_does_not_exist');
+      const response = await GET(createRequestEvent(request, db) as never);
+      expect(response.status).toBe(401);
+    });
+
+    it('returns 400 for a malformed since', async () => {
+      const project = await seedProject(db);
+      const request = streamRequest('?since=yesterday', project.apiKey);
+      const response = await GET(createRequestEvent(request, db) as never);
+
+      expect(response.status).toBe(400);
+      const body = await response.json();
+      expect(body.error).toBe('invalid_since');
+    });
+  });
+
+  describe('Log Streaming', () => {
+    it('streams only live logs matching the filters', async () => {
+      const project = await seedProject(db);
+      const request = streamRequest('?service=billing&level=error', project.apiKey);
+      const response = await GET(createRequestEvent(request, db) as never);
+
+      expect(response.status).toBe(200);
+      expect(response.headers.get('Content-Type')).toBe('text/event-stream');
+      expect(logEventBus.getListenerCount(project.id)).toBe(1);
+
+      logEventBus.emitLog(createMockLog(project.id, { serviceName: 'auth', level: 'error' }));
+      logEventBus.emitLog(createMockLog(project.id, { serviceName: 'billing', level: 'info' }));
+      const match = createMockLog(project.id, { serviceName: 'billing', level: 'error' });
+      logEventBus.emitLog(match);
+
+      const events = await collectSSEEvents(response, 1, 3000);
+      const logsEvent = events.find((e) => e.event === 'logs');
+      if (!logsEvent) throw new Error('Expected logsEvent to be defined');
+      const logs = JSON.parse(logsEvent.data);
+      expect(logs.map((l: { id: string }) => l.id)).toEqual([match.id]);
+    });
+
+    it('replays stored logs from since before live logs', async () => {
+      const project = await seedProject(db);
+      const now = Date.now();
+      await seedLog(db, project.id, { timestamp: new Date(now - 60_000) });
+      const stored = await seedLog(db, project.id, { timestamp: new Date(now - 1000) });
+
+      const since = new Date(now - 5000).toISOString();
+      const request = streamRequest(`?since=${since}`, project.apiKey);
+      const response = await GET(createRequestEvent(request, db) as never);
+
+      const live = createMockLog(project.id, { message: 'live log' });
+      logEventBus.emitLog(live);
+
+      const events = await collectSSEEvents(response, 2, 3000);
+      expect(events.map((e) => e.event)).toEqual(['backfill', 'logs']);
+      const logs = events.flatMap((e) => JSON.parse(e.data) as Array<{ id: string }>);
+      expect(logs.map((l) => l.id)).toEqual([stored.id, live.id]);
+    });
+
+    it('filters the backfill by search like live logs', async () => {
+      const project = await seedProject(db);
+      const now = Date.now();
+      const match = await seedLog(db, project.id, {
+        message: 'Connection refused by 10_0_0_1',
+        timestamp: new Date(now - 2000),
+      });
+      await seedLog(db, project.id, {
+        message: 'Connected to 10.0.0.1',
+        timestamp: new Date(now - 1000),
+      });
+
+      const since = new Date(now - 5000).toISOString();
+      const request = streamRequest(`?since=${since}&search=CONN%20_0_`, project.apiKey);
+      const response = await GET(createRequestEvent(request, db) as never);
+
+      const events = await collectSSEEvents(response, 1, 3000);
+      expect(events[0].event).toBe('backfill');
+      const logs = JSON.parse(events[0].data) as Array<{ id: string }>;
+      expect(logs.map((l) => l.id)).toEqual([match.id]);
+    });
+  });
+});
@@ -0,0 +1,87 @@
+import type { PgliteDatabase } from 'drizzle-orm/pglite';
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import type * as schema from '../../../src/lib/server/db/schema';
+import { setupTestDatabase } from '../../../src/lib/server/db/test-db';
+import { generateApiKey } from '../../../src/lib/server/utils/api-key';
+import { GET } from '../../../src/routes/v1/keys/verify/+server';
+import { seedProject } from '../../fixtures/db';
+
+function createRequestEvent(request: Request, db: PgliteDatabase<typeof schema>) {
+  return {
+    request,
+    locals: { db },
+    params: {},
+    url: new URL(request.url),
+    platform: undefined,
+    route: { id: '/v1/keys/verify' },
+    isDataRequest: false,
+    isSubRequest: false,
+    isRemoteRequest: false,
+    tracing: null,
+    cookies: {
+      get: () => undefined,
+      getAll: () => [],
+      set: () => {},
+      delete: () => {},
+      serialize: () => '',
+    },
+    fetch: globalThis.fetch,
+    getClientAddress: () => '127.0.0.1',
+    setHeaders: () => {},
+  } as unknown;
+}
+
+function verifyRequest(authorization?: string): Request {
+  return new Request('http://localhost/v1/keys/verify', {
+    method: 'GET',
+    headers: authorization ? { Authorization: authorization } : {},
+  });
+}
+
+describe('GET /v1/keys/verify', () => {
+  let db: PgliteDatabase<typeof schema>;
+  let cleanup: () => Promise<void>;
+
+  beforeEach(async () => {
+    const setup = await setupTestDatabase();
+    db = setup.db;
+    cleanup = setup.cleanup;
+  });
+
+  afterEach(async () => {
+    await cleanup();
+  });
+
+  it('returns 401 without Authorization header', async () => {
+    const response = await GET(createRequestEvent(verifyRequest(), db) as never);
+    expect(response.status).toBe(401);
+  });
+
+  it('returns 401 for a malformed key', async () => {
+    const response = await GET(createRequestEvent(verifyRequest('Bearer lw_short'), db) as never);
+    expect(response.status).toBe(401);
+  });
+
+  it('returns 404 for an unknown key', async () => {
+    const request = verifyRequest(`Bearer ${generateApiKey()}`);
+    const response = await GET(createRequestEvent(request, db) as never);
+
+    expect(response.status).toBe(404);
+    const body = await response.json();
+    expect(body.error).toBe('not_found');
+  });
+
+  it('describes the project of a valid key', async () => {
+    const project = await seedProject(db);
+    const request = verifyRequest(`Bearer ${project.apiKey}`);
+    const response = await GET(createRequestEvent(request, db) as never);
+
+    expect(response.status).toBe(200);
+    const body = await response.json();
+    expect(body).toEqual({
+      projectId: project.id,
+      projectName: project.name,
+      scopes: ['ingest', 'query', 'stream'],
+    });
+  });
+});